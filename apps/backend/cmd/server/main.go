@@ -2,14 +2,40 @@ package main
 
 import (
 	"log"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/kirillsobolev/soul-mirror/backend/internal/api"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/archive"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/config"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/email"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/erasure"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inbound"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inputs"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/jobs"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/logging"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/memory"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/ratelimit"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/redact"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/server"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/sessions"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/telegram"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/tools"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/voice"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/webhooks"
 )
 
 func main() {
@@ -26,27 +52,476 @@ func main() {
 	logger := logging.InitLogger(cfg.Environment)
 	log.Println("✓ Structured logger initialized")
 
-	toolService := tools.NewToolService()
+	store, err := storage.NewFileStore(cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	log.Printf("✓ Storage initialized (data dir: %s)", cfg.DataDir)
+
+	var encryptedStore *storage.EncryptedStore
+	if cfg.HasEncryption() {
+		masterKey := cfg.EncryptionKey
+		if cfg.EncryptionKeyFile != "" {
+			data, err := os.ReadFile(cfg.EncryptionKeyFile)
+			if err != nil {
+				log.Fatalf("Failed to read ENCRYPTION_KEY_FILE: %v", err)
+			}
+			masterKey = string(data)
+		}
+		keyring, err := storage.NewKeyring(store, masterKey, cfg.EncryptionPreviousKeys...)
+		if err != nil {
+			log.Fatalf("Failed to initialize keyring: %v", err)
+		}
+		// Every document holding what users wrote or said is sealed: sessions,
+		// memories, mood entries, transcripts and stored responses repeat
+		// inputs verbatim, and queued webhook payloads carry the profile and
+		// note bodies
+		encryptedStore = storage.NewEncryptedStore(store, keyring,
+			"profiles", "inputs", "notes", "sessions", "jobs", "mood", "memories",
+			"voice_notes", "inbound", "tasks", "goals", "reminders", "nudges", "calendar", "email",
+			"webhooks")
+		store = encryptedStore
+		log.Printf("✓ Encryption at rest enabled (master key %s)", keyring.MasterKeyID())
+	} else {
+		log.Println("⚠️  No ENCRYPTION_KEY - personal data is stored unencrypted")
+	}
+
+	webhookService, err := webhooks.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize webhook service: %v", err)
+	}
+	webhookDispatcher := webhooks.NewDispatcher(webhookService, webhooks.Config{
+		Interval:     cfg.WebhookRetryInterval,
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Backoff:      cfg.WebhookBackoff,
+		AllowPrivate: cfg.WebhookAllowPrivate,
+	})
+	webhookDispatcher.Start()
+	defer webhookDispatcher.Stop()
+	log.Println("✓ Webhook service initialized")
+
+	// The search index is rebuilt from the services below as they come up
+	searchIndex := search.NewIndex()
+
+	// Personal data is kept from everything that leaves the server: the LLM
+	// and a remote embedder
+	var redactor *redact.Redactor
+	if cfg.RedactPII {
+		kinds := redact.AllKinds
+		if len(cfg.RedactionRules) > 0 {
+			if kinds, err = redact.ParseKinds(cfg.RedactionRules); err != nil {
+				log.Fatalf("Invalid REDACTION_RULES: %v", err)
+			}
+		}
+		redactor = redact.New(kinds, cfg.RedactionTerms)
+		log.Printf("✓ PII redaction enabled (%d rules, %d dictionary terms)", len(kinds), len(cfg.RedactionTerms))
+	} else {
+		log.Println("⚠️  PII redaction disabled - raw input is sent to the LLM and the embedder")
+	}
+
+	embedder, err := memory.NewEmbedder(memory.EmbedderConfig{
+		Backend:    cfg.Embedder,
+		URL:        cfg.EmbeddingsURL,
+		APIKey:     cfg.EmbeddingsAPIKey,
+		Model:      cfg.EmbeddingsModel,
+		Dimensions: cfg.EmbeddingDimensions,
+		Timeout:    cfg.EmbeddingsTimeout,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize embedder: %v", err)
+	}
+	// The hashing embedder runs in process and keeps raw text, which
+	// matches better
+	if embedder != nil && redactor != nil && cfg.Embedder == memory.BackendOpenAI {
+		embedder = memory.Redacted(embedder, redactor)
+	}
+	var memoryService memory.MemoryService
+	if embedder != nil {
+		memoryService, err = memory.NewService(store, embedder, memory.Config{
+			TopK:     cfg.MemoryTopK,
+			MinScore: cfg.MemoryMinScore,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize memory service: %v", err)
+		}
+		log.Printf("✓ Memory service initialized (embedder: %s)", embedder.Name())
+	} else {
+		log.Println("⚠️  Memory retrieval disabled (EMBEDDER=none)")
+	}
+
+	inputService, err := inputs.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize input log: %v", err)
+	}
+	inputService = inputs.Indexed(inputService, searchIndex)
+	// Changes made while an input is processed are linked to its log record
+	// and, with memory enabled, remembered
+	inputTracker := inputs.NewTracker()
+	publisher := webhooks.Fanout(webhookService, inputTracker)
+	if memoryService != nil {
+		inputService = inputs.Remembered(inputService, memoryService)
+		publisher = webhooks.Fanout(publisher, memory.NewObserver(memoryService))
+	}
+	log.Println("✓ Input log initialized")
+
+	llmService := llm.NewService(cfg)
+	log.Println("✓ LLM service initialized")
+
+	if redactor != nil {
+		llmService = llm.Redacted(llmService, redactor)
+	}
+
+	taskService, err := tasks.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize task service: %v", err)
+	}
+	taskService = search.ObserveTasks(webhooks.ObserveTasks(taskService, publisher), searchIndex)
+	log.Println("✓ Task service initialized")
+
+	noteService, err := notes.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize note service: %v", err)
+	}
+	noteService = search.ObserveNotes(webhooks.ObserveNotes(noteService, publisher), searchIndex)
+	log.Println("✓ Note service initialized")
+
+	goalService, err := goals.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize goal service: %v", err)
+	}
+	goalService = webhooks.ObserveGoals(goalService, publisher)
+	log.Println("✓ Goal service initialized")
+
+	moodService, err := mood.NewService(store, mood.NewAnalyzer(llmService))
+	if err != nil {
+		log.Fatalf("Failed to initialize mood service: %v", err)
+	}
+	log.Println("✓ Mood service initialized")
+
+	defaultLocation, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		log.Fatalf("Invalid DEFAULT_TIMEZONE %q: %v", cfg.DefaultTimezone, err)
+	}
+	reminderService, err := reminders.NewService(store, defaultLocation)
+	if err != nil {
+		log.Fatalf("Failed to initialize reminder service: %v", err)
+	}
+	reminderService = webhooks.ObserveReminders(reminderService, publisher)
+	log.Printf("✓ Reminder service initialized (default timezone: %s)", defaultLocation)
+
+	fetcher, err := calendar.NewFetcher(calendar.FetcherConfig{
+		BaseURL:      cfg.CalendarFeedBaseURL,
+		Timeout:      calendar.DefaultFetchTimeout,
+		AllowPrivate: cfg.CalendarAllowPrivate,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize calendar fetcher: %v", err)
+	}
+	calendarService, err := calendar.NewService(store, fetcher, reminderService)
+	if err != nil {
+		log.Fatalf("Failed to initialize calendar service: %v", err)
+	}
+	calendarSyncer := calendar.NewSyncer(calendarService, cfg.CalendarSyncInterval)
+	calendarSyncer.Start()
+	defer calendarSyncer.Stop()
+	log.Println("✓ Calendar service initialized")
+
+	toolService := tools.NewToolService(tools.Services{
+		LLM:       llmService,
+		Tasks:     taskService,
+		Notes:     noteService,
+		Goals:     goalService,
+		Reminders: reminderService,
+		Calendar:  calendarService,
+		Search:    searchIndex,
+	})
 	toolsList := toolService.ListTools()
 	log.Println("✓ Tool service initialized with tools:")
 	for _, tool := range toolsList {
 		log.Printf("  - %s: %s", tool.Name(), tool.Description())
 	}
 
-	profileService := profile.NewService()
+	profileService, err := profile.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize profile service: %v", err)
+	}
+	profileService = search.ObserveProfile(webhooks.ObserveProfile(profileService, webhookService), searchIndex)
+	profileCompactor := profile.NewCompactor(profileService, llmService, profile.CompactorConfig{
+		MaxChars:  cfg.ProfileMaxChars,
+		MaxTokens: cfg.ProfileMaxTokens,
+		Interval:  cfg.ProfileCompactionInterval,
+	})
+	if cfg.ProfileCompactionInterval > 0 {
+		profileCompactor.Start()
+		defer profileCompactor.Stop()
+	}
 	log.Println("✓ Profile service initialized")
 
-	llmService := llm.NewService(cfg)
-	log.Println("✓ LLM service initialized")
+	sessionService, err := sessions.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize session service: %v", err)
+	}
+	log.Println("✓ Session service initialized")
+
+	// The eraser refuses inputs for an account while it is being deleted and
+	// waits for the ones already running, so it wraps the orchestrator
+	eraser, err := erasure.NewEraser(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize eraser: %v", err)
+	}
+
+	toolExecutor := tools.NewExecutor(toolService, tools.ExecutorConfig{Workers: cfg.ToolWorkers, Timeout: cfg.ToolTimeout})
+	orch := erasure.Guard(inputs.Log(
+		orchestrator.New(toolService, profileService, llmService, moodService, calendarService, sessionService, memoryService, redactor, toolExecutor),
+		inputService, inputTracker), eraser)
+	log.Printf("✓ Orchestrator initialized (%d tool workers, %s tool timeout)", cfg.ToolWorkers, cfg.ToolTimeout)
+
+	jobService, err := jobs.NewService(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize job queue: %v", err)
+	}
+	jobRunner := jobs.NewRunner(jobService, orch, cfg.JobWorkers, cfg.JobRetention)
+	jobRunner.Start()
+	defer jobRunner.Stop()
+	log.Println("✓ Job queue initialized")
+
+	transcriber, err := voice.NewTranscriber(voice.TranscriberConfig{
+		Backend:  cfg.Transcriber,
+		URL:      cfg.WhisperURL,
+		Binary:   cfg.WhisperBinary,
+		Model:    cfg.WhisperModel,
+		FFmpeg:   cfg.FFmpegBinary,
+		Language: cfg.WhisperLanguage,
+		Timeout:  cfg.TranscriptionTimeout,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize transcriber: %v", err)
+	}
+	var audioSealer storage.BlobSealer
+	if encryptedStore != nil {
+		audioSealer = encryptedStore
+	}
+	voiceService, err := voice.NewService(store, filepath.Join(cfg.DataDir, "voice"), transcriber, audioSealer)
+	if err != nil {
+		log.Fatalf("Failed to initialize voice service: %v", err)
+	}
+	if encryptedStore != nil {
+		encryptedStore.AddBlobOwner(voiceService)
+	}
+	if transcriber != nil {
+		log.Printf("✓ Voice service initialized (transcriber: %s)", transcriber.Name())
+	} else {
+		log.Println("⚠️  No transcriber configured - voice uploads disabled")
+	}
+
+	var telegramBot *telegram.Bot
+	if cfg.HasTelegram() {
+		telegramBot, err = telegram.New(telegram.Config{
+			Token:         cfg.TelegramBotToken,
+			APIBaseURL:    cfg.TelegramAPIBaseURL,
+			Mode:          cfg.TelegramMode,
+			WebhookURL:    cfg.TelegramWebhookURL,
+			WebhookSecret: cfg.TelegramWebhookSecret,
+			PollTimeout:   cfg.TelegramPollTimeout,
+		}, store, orch, voiceService)
+		if err != nil {
+			log.Fatalf("Failed to initialize Telegram bot: %v", err)
+		}
+		if err := telegramBot.Start(); err != nil {
+			log.Fatalf("Failed to start Telegram bot: %v", err)
+		}
+		defer telegramBot.Stop()
+		log.Printf("✓ Telegram bot started (%s mode)", telegramBot.Mode())
+	}
+
+	var mailer email.Mailer
+	if cfg.HasSMTP() {
+		mailer, err = email.NewSMTPMailer(email.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			Security: cfg.SMTPSecurity,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize SMTP mailer: %v", err)
+		}
+	}
+	emailService, err := email.NewService(store, mailer, email.Sources{
+		Tasks:   taskService,
+		Profile: profileService,
+		Mood:    moodService,
+		Locator: reminderService,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize email service: %v", err)
+	}
+	digestWeekday, err := email.ParseWeekday(cfg.DigestWeekday)
+	if err != nil {
+		log.Fatalf("Invalid DIGEST_WEEKDAY: %v", err)
+	}
+	emailNotifier := email.NewNotifier(emailService, email.NotifierConfig{
+		DigestWeekday: digestWeekday,
+		DigestHour:    cfg.DigestHour,
+		Interval:      cfg.DigestCheckInterval,
+	})
+	if emailService.CanSend() {
+		emailNotifier.Start()
+		defer emailNotifier.Stop()
+		log.Printf("✓ Email channel enabled (SMTP: %s:%d)", cfg.SMTPHost, cfg.SMTPPort)
+	} else {
+		log.Println("⚠️  No SMTP server configured - email delivery disabled")
+	}
+
+	inboundVerifier := inbound.NewVerifier(cfg.InboundWebhookSecrets, cfg.InboundWebhookTolerance)
+	// Nonces must outlive the whole window a timestamp is accepted in
+	inboundLedger, err := inbound.NewLedger(store, 2*inboundVerifier.Tolerance())
+	if err != nil {
+		log.Fatalf("Failed to initialize inbound webhook ledger: %v", err)
+	}
+	if inboundVerifier.Enabled() {
+		log.Println("✓ Inbound webhook enabled")
+	}
+
+	nudgeQueue, err := scheduler.NewQueue(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize nudge queue: %v", err)
+	}
+
+	var nudgeScheduler *scheduler.Scheduler
+	if cfg.SchedulerEnabled {
+		evaluator := scheduler.NewEvaluator(scheduler.Services{
+			LLM:      llmService,
+			Tasks:    taskService,
+			Goals:    goalService,
+			Profile:  profileService,
+			Mood:     moodService,
+			Calendar: calendarService,
+			Locator:  reminderService,
+		})
+		nudgeScheduler, err = scheduler.New(scheduler.Config{
+			Interval:         cfg.SchedulerInterval,
+			DispatchInterval: cfg.DispatchInterval,
+			MinGap:           cfg.NudgeMinGap,
+		}, store, nudgeQueue, evaluator, profileService, taskService, goalService, calendarService)
+		if err != nil {
+			log.Fatalf("Failed to initialize scheduler: %v", err)
+		}
+		nudgeScheduler.AddSource(reminders.NewSource(reminderService))
+		if telegramBot != nil {
+			nudgeScheduler.AddDeliverer(telegramBot)
+		}
+		nudgeScheduler.AddDeliverer(webhooks.NewNudgeDeliverer(webhookService))
+		if emailService.CanSend() {
+			nudgeScheduler.AddDeliverer(emailNotifier)
+		}
+		nudgeScheduler.Start()
+		defer nudgeScheduler.Stop()
+		log.Printf("✓ Nudge scheduler started (every %s)", cfg.SchedulerInterval)
+	} else {
+		log.Println("⚠️  Nudge scheduler disabled")
+	}
+
+	archiver := archive.New(archive.Sources{
+		Profile:   profileService,
+		Inputs:    inputService,
+		Sessions:  sessionService,
+		Tasks:     taskService,
+		Notes:     noteService,
+		Goals:     goalService,
+		Reminders: reminderService,
+		Mood:      moodService,
+		Memory:    memoryService,
+	})
 
-	orch := orchestrator.New(toolService, profileService, llmService)
-	log.Println("✓ Orchestrator initialized")
+	// Every store that keeps data about users is registered so deleting an
+	// account leaves nothing behind; caches derived from the others go last
+	eraser.Add("profile", profileService)
+	eraser.Add("inputs", inputService)
+	eraser.Add("sessions", sessionService)
+	eraser.Add("tasks", taskService)
+	eraser.Add("notes", noteService)
+	eraser.Add("goals", goalService)
+	eraser.Add("reminders", reminderService)
+	eraser.Add("mood", moodService)
+	eraser.Add("voice_notes", voiceService)
+	eraser.Add("calendar", calendarService)
+	eraser.Add("webhooks", webhookService)
+	eraser.Add("email", emailService)
+	eraser.Add("nudges", nudgeQueue)
+	if nudgeScheduler != nil {
+		eraser.Add("scheduler", nudgeScheduler)
+	}
+	var telegramChats *telegram.Chats
+	if telegramBot != nil {
+		telegramChats = telegramBot.Chats()
+	} else {
+		// Chats linked while a bot was configured outlive it
+		telegramChats, err = telegram.NewChats(store)
+		if err != nil {
+			log.Fatalf("Failed to load Telegram chats: %v", err)
+		}
+	}
+	eraser.Add("telegram", telegramChats)
+	eraser.Add("inbound", inboundLedger)
+	eraser.Add("jobs", jobService)
+	if encryptedStore != nil {
+		eraser.Add("encryption_keys", encryptedStore.Keyring())
+	}
+	if memoryService != nil {
+		eraser.Add("memories", memoryService)
+	}
+	eraser.Add("search", searchIndex)
 
-	srv := server.New(orch, profileService, toolService, logger, cfg.Environment, cfg.Port)
+	srv := server.New(api.Dependencies{
+		Orchestrator:    orch,
+		Profile:         profileService,
+		Compactor:       profileCompactor,
+		Tools:           toolService,
+		Tasks:           taskService,
+		Notes:           noteService,
+		NoteOrganizer:   notes.NewOrganizer(noteService, llmService),
+		Goals:           goalService,
+		Mood:            moodService,
+		Reminders:       reminderService,
+		ReminderParser:  reminders.NewParser(llmService),
+		Calendar:        calendarService,
+		Nudges:          nudgeQueue,
+		Scheduler:       nudgeScheduler,
+		Voice:           voiceService,
+		Telegram:        telegramBot,
+		InboundVerifier: inboundVerifier,
+		InboundLedger:   inboundLedger,
+		Webhooks:        webhookService,
+		Email:           emailService,
+		Sessions:        sessionService,
+		Inputs:          inputService,
+		Search:          searchIndex,
+		Archiver:        archiver,
+		Eraser:          eraser,
+		Jobs:            jobService,
+		Encryption:      encryptedStore,
+	}, logger, cfg.Environment, cfg.Port)
+	if err := srv.TrustProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	if cfg.RateLimitEnabled {
+		if cfg.RateLimitWindow <= 0 || cfg.RateLimitRequests <= 0 || cfg.LLMRateLimitRequests <= 0 {
+			log.Fatal("Invalid rate limits: RATE_LIMIT_WINDOW, RATE_LIMIT_REQUESTS and LLM_RATE_LIMIT_REQUESTS must be positive")
+		}
+		general := ratelimit.New("general", ratelimit.Limit{Requests: cfg.RateLimitRequests, Window: cfg.RateLimitWindow, Burst: cfg.RateLimitBurst})
+		llmLimiter := ratelimit.New("llm", ratelimit.Limit{Requests: cfg.LLMRateLimitRequests, Window: cfg.RateLimitWindow, Burst: cfg.LLMRateLimitBurst})
+		srv.UseRateLimits(general, llmLimiter)
+		log.Printf("✓ Rate limiting enabled (%s, LLM endpoints %s)", general.Policy(), llmLimiter.Policy())
+	} else {
+		log.Println("⚠️  Rate limiting disabled")
+	}
 	log.Println("✓ Server initialized")
 
 	log.Println("🚀 Starting Soul Mirror backend server...")
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
-}
\ No newline at end of file
+}
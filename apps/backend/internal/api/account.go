@@ -0,0 +1,49 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/erasure"
+)
+
+// DeleteAccountHandler deletes everything kept about the user: profile and
+// history, inputs, sessions, extracted items, embeddings, search entries,
+// queued nudges and webhook deliveries, settings and linked chats. The user
+// ID must be repeated in ?confirm= so it can't happen by accident.
+func (h *Handlers) DeleteAccountHandler(c *gin.Context) {
+	user := userID(c)
+	if c.Query("confirm") != user {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pass the user ID as 'confirm' to delete the account"})
+		return
+	}
+
+	deletion, err := h.eraser.Erase(user)
+	if err != nil {
+		h.logger.Error("Failed to delete account", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	if deletion.Status != erasure.StatusCompleted {
+		h.logger.Error("Account deletion incomplete", slog.String("user_id", user), slog.String("deletion_id", deletion.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account deletion incomplete, try again", "deletion": deletion})
+		return
+	}
+
+	h.logger.Info("Account deleted", slog.String("user_id", user), slog.String("deletion_id", deletion.ID))
+	c.JSON(http.StatusOK, deletion)
+}
+
+// ListDeletionsHandler returns the audit records of the user's account
+// deletions, newest first.
+func (h *Handlers) ListDeletionsHandler(c *gin.Context) {
+	deletions := h.eraser.Deletions(userID(c))
+	c.JSON(http.StatusOK, gin.H{"deletions": deletions, "count": len(deletions)})
+}
+
+// VerifyDeletionHandler checks every store for data still kept about the
+// user. "clean" is true only when nothing remains anywhere.
+func (h *Handlers) VerifyDeletionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.eraser.Verify(userID(c)))
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/archive"
+)
+
+// ExportHandler downloads everything kept about the user. ?format=markdown
+// gives a readable document instead of the JSON archive /api/import takes.
+func (h *Handlers) ExportHandler(c *gin.Context) {
+	user := userID(c)
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'format' parameter, expected json or markdown"})
+		return
+	}
+
+	exported, err := h.archiver.Export(user)
+	if err != nil {
+		h.logger.Error("Failed to export data", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+	h.logger.Info("Data exported", slog.String("user_id", user), slog.String("format", format))
+
+	filename := fmt.Sprintf("soul-mirror-%s-%s", sanitizeFilename(user), exported.ExportedAt.Format("2006-01-02"))
+	if format == "markdown" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, filename))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(archive.Markdown(exported)))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	c.IndentedJSON(http.StatusOK, exported)
+}
+
+// ImportHandler merges a JSON archive from /api/export into the user, who
+// may be new. The archive is validated first; nothing is changed if it is
+// rejected, or larger than archive.MaxImportBytes.
+func (h *Handlers) ImportHandler(c *gin.Context) {
+	user := userID(c)
+
+	var data archive.Archive
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, archive.MaxImportBytes)
+	if err := json.NewDecoder(body).Decode(&data); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Archive is larger than %d MB", archive.MaxImportBytes>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archive: " + err.Error()})
+		return
+	}
+
+	result, err := h.archiver.Import(user, &data)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to import archive", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import archive"})
+		return
+	}
+
+	h.logger.Info("Archive imported", slog.String("user_id", user), slog.String("from_user_id", data.UserID))
+	c.JSON(http.StatusOK, result)
+}
+
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+)
+
+const (
+	defaultCalendarDays = 7
+	maxCalendarDays     = 366
+)
+
+type subscriptionRequest struct {
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+// UpcomingEventsHandler lists event occurrences from now through ?days=
+// (default 7).
+func (h *Handlers) UpcomingEventsHandler(c *gin.Context) {
+	user := userID(c)
+
+	days := defaultCalendarDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxCalendarDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'days' parameter"})
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now()
+	events, err := h.calendarService.Upcoming(user, now, now.AddDate(0, 0, days))
+	if err != nil {
+		h.respondCalendarError(c, err, "Failed to get events")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
+
+func (h *Handlers) ListCalendarSourcesHandler(c *gin.Context) {
+	sources, err := h.calendarService.Sources(userID(c))
+	if err != nil {
+		h.respondCalendarError(c, err, "Failed to list calendar sources")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sources": sources, "count": len(sources)})
+}
+
+// ImportCalendarHandler accepts an .ics file as multipart form field "file"
+// or as the raw request body.
+func (h *Handlers) ImportCalendarHandler(c *gin.Context) {
+	user := userID(c)
+	name := c.Query("name")
+
+	var data io.Reader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'file' field"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+		data = file
+		if formName := c.PostForm("name"); formName != "" {
+			name = formName
+		} else if name == "" {
+			name = header.Filename
+		}
+	} else {
+		data = c.Request.Body
+	}
+
+	source, err := h.calendarService.Import(user, name, data)
+	if err != nil {
+		h.respondCalendarError(c, err, "Failed to import calendar")
+		return
+	}
+
+	h.logger.Info("Calendar imported", slog.String("user_id", user), slog.String("source_id", source.ID), slog.Int("events", source.EventCount))
+	c.JSON(http.StatusCreated, source)
+}
+
+func (h *Handlers) SubscribeCalendarHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'url' field"})
+		return
+	}
+
+	source, err := h.calendarService.Subscribe(user, req.Name, req.URL)
+	if err != nil {
+		h.respondCalendarError(c, err, "Failed to subscribe to calendar")
+		return
+	}
+
+	h.logger.Info("Calendar subscribed", slog.String("user_id", user), slog.String("source_id", source.ID), slog.Int("events", source.EventCount))
+	c.JSON(http.StatusCreated, source)
+}
+
+func (h *Handlers) RefreshCalendarSourceHandler(c *gin.Context) {
+	source, err := h.calendarService.Refresh(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondCalendarError(c, err, "Failed to refresh calendar")
+		return
+	}
+	c.JSON(http.StatusOK, source)
+}
+
+func (h *Handlers) DeleteCalendarSourceHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.calendarService.RemoveSource(user, id); err != nil {
+		h.respondCalendarError(c, err, "Failed to remove calendar source")
+		return
+	}
+
+	h.logger.Info("Calendar source removed", slog.String("user_id", user), slog.String("source_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) respondCalendarError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, calendar.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar source not found"})
+		return
+	case errors.Is(err, calendar.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, calendar.ErrFetch):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("source_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/email"
+)
+
+func (h *Handlers) GetEmailSettingsHandler(c *gin.Context) {
+	settings, err := h.emailService.Settings(userID(c))
+	if err != nil {
+		h.respondEmailError(c, err, "Failed to get email settings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings, "delivery_enabled": h.emailService.CanSend()})
+}
+
+func (h *Handlers) UpdateEmailSettingsHandler(c *gin.Context) {
+	user := userID(c)
+
+	var update email.SettingsUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := h.emailService.UpdateSettings(user, update)
+	if err != nil {
+		h.respondEmailError(c, err, "Failed to update email settings")
+		return
+	}
+
+	h.logger.Info("Email settings updated", slog.String("user_id", user), slog.Bool("nudges", settings.Nudges), slog.Bool("digest", settings.Digest))
+	c.JSON(http.StatusOK, gin.H{"settings": settings, "delivery_enabled": h.emailService.CanSend()})
+}
+
+// PreviewDigestHandler shows the digest the user would get now without
+// sending it (?format=json|text|html).
+func (h *Handlers) PreviewDigestHandler(c *gin.Context) {
+	digest, err := h.emailService.Digest(userID(c), time.Now())
+	if err != nil {
+		h.respondEmailError(c, err, "Failed to build digest")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format == "json" {
+		c.JSON(http.StatusOK, digest)
+		return
+	}
+	text, html, err := email.RenderDigest(digest)
+	if err != nil {
+		h.respondEmailError(c, err, "Failed to render digest")
+		return
+	}
+	switch format {
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'format' parameter"})
+	}
+}
+
+// SendDigestHandler mails the digest right away; the next scheduled one
+// covers the time since.
+func (h *Handlers) SendDigestHandler(c *gin.Context) {
+	user := userID(c)
+
+	digest, err := h.emailService.SendDigest(user, time.Now())
+	if err != nil {
+		h.respondEmailError(c, err, "Failed to send digest")
+		return
+	}
+
+	h.logger.Info("Digest sent", slog.String("user_id", user))
+	c.JSON(http.StatusOK, digest)
+}
+
+func (h *Handlers) respondEmailError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, email.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, email.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email delivery is not configured"})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
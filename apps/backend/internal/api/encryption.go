@@ -0,0 +1,43 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EncryptionStatusHandler reports whether data is encrypted at rest, which
+// documents are, and the versions of the user's data key.
+func (h *Handlers) EncryptionStatusHandler(c *gin.Context) {
+	if h.encryption == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	keyring := h.encryption.Keyring()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":       true,
+		"master_key_id": keyring.MasterKeyID(),
+		"documents":     h.encryption.Documents(),
+		"keys":          keyring.Keys(userID(c)),
+	})
+}
+
+// RotateDataKeyHandler gives the user a new data key and re-encrypts their
+// data with it. The old key is destroyed.
+func (h *Handlers) RotateDataKeyHandler(c *gin.Context) {
+	if h.encryption == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Encryption at rest is not configured"})
+		return
+	}
+	user := userID(c)
+	dataKey, err := h.encryption.Rotate(user)
+	if err != nil {
+		h.logger.Error("Failed to rotate data key", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate data key"})
+		return
+	}
+
+	h.logger.Info("Data key rotated", slog.String("user_id", user), slog.Int("version", dataKey.Version))
+	c.JSON(http.StatusOK, gin.H{"rotated": true, "key": dataKey})
+}
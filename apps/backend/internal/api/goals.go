@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+)
+
+type goalRequest struct {
+	Title      *string           `json:"title"`
+	Motivation *string           `json:"motivation"`
+	Status     *string           `json:"status"`
+	Milestones []string          `json:"milestones"`
+	Habits     []goals.HabitSpec `json:"habits"`
+}
+
+type milestoneRequest struct {
+	Title string `json:"title"`
+}
+
+type habitRequest struct {
+	Title     string `json:"title"`
+	Frequency string `json:"frequency"`
+}
+
+type checkInRequest struct {
+	HabitID string     `json:"habit_id"`
+	Note    string     `json:"note"`
+	At      *time.Time `json:"at"`
+}
+
+func (h *Handlers) ListGoalsHandler(c *gin.Context) {
+	user := userID(c)
+	list, err := h.goalService.List(user, goals.Status(c.Query("status")))
+	if err != nil {
+		h.logger.Error("Failed to list goals", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list goals"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"goals": list, "count": len(list)})
+}
+
+func (h *Handlers) CreateGoalHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req goalRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Title == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'title' field"})
+		return
+	}
+
+	goal := goals.Goal{Title: *req.Title}
+	if req.Motivation != nil {
+		goal.Motivation = *req.Motivation
+	}
+	for _, title := range req.Milestones {
+		goal.Milestones = append(goal.Milestones, goals.Milestone{Title: title})
+	}
+	for _, habit := range req.Habits {
+		goal.Habits = append(goal.Habits, goals.Habit{Title: habit.Title, Frequency: habit.Frequency})
+	}
+
+	created, err := h.goalService.Create(user, goal)
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to create goal")
+		return
+	}
+
+	h.logger.Info("Goal created", slog.String("user_id", user), slog.String("goal_id", created.ID))
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handlers) GetGoalHandler(c *gin.Context) {
+	goal, err := h.goalService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to get goal")
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handlers) UpdateGoalHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req goalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	update := goals.Update{Title: req.Title, Motivation: req.Motivation}
+	if req.Status != nil {
+		status, ok := goals.ParseStatus(*req.Status)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'status' value"})
+			return
+		}
+		update.Status = &status
+	}
+
+	goal, err := h.goalService.Update(user, c.Param("id"), update)
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to update goal")
+		return
+	}
+
+	h.logger.Info("Goal updated", slog.String("user_id", user), slog.String("goal_id", goal.ID))
+	c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handlers) DeleteGoalHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.goalService.Delete(user, id); err != nil {
+		h.respondGoalError(c, err, "Failed to delete goal")
+		return
+	}
+
+	h.logger.Info("Goal deleted", slog.String("user_id", user), slog.String("goal_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) GoalProgressHandler(c *gin.Context) {
+	user := userID(c)
+	goal, err := h.goalService.Get(user, c.Param("id"))
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to get goal progress")
+		return
+	}
+	c.JSON(http.StatusOK, goals.ComputeProgress(*goal, time.Now(), h.userLocation(user)))
+}
+
+func (h *Handlers) GoalsProgressHandler(c *gin.Context) {
+	user := userID(c)
+	list, err := h.goalService.List(user, goals.StatusActive)
+	if err != nil {
+		h.logger.Error("Failed to get goals progress", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get goals progress"})
+		return
+	}
+
+	now, loc := time.Now(), h.userLocation(user)
+	progress := make([]goals.Progress, len(list))
+	for i, goal := range list {
+		progress[i] = goals.ComputeProgress(goal, now, loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"progress": progress, "count": len(progress)})
+}
+
+func (h *Handlers) AddMilestoneHandler(c *gin.Context) {
+	var req milestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'title' field"})
+		return
+	}
+
+	goal, err := h.goalService.AddMilestone(userID(c), c.Param("id"), req.Title)
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to add milestone")
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handlers) CompleteMilestoneHandler(c *gin.Context) {
+	goal, err := h.goalService.CompleteMilestone(userID(c), c.Param("id"), c.Param("milestoneId"))
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to complete milestone")
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handlers) AddHabitHandler(c *gin.Context) {
+	var req habitRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'title' field"})
+		return
+	}
+
+	goal, err := h.goalService.AddHabit(userID(c), c.Param("id"), req.Title, goals.Frequency(req.Frequency))
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to add habit")
+		return
+	}
+	c.JSON(http.StatusOK, goal)
+}
+
+func (h *Handlers) CheckInHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req checkInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	checkIn := goals.CheckIn{HabitID: req.HabitID, Note: req.Note}
+	if req.At != nil {
+		checkIn.At = *req.At
+	}
+
+	goal, err := h.goalService.CheckIn(user, c.Param("id"), checkIn)
+	if err != nil {
+		h.respondGoalError(c, err, "Failed to check in")
+		return
+	}
+
+	h.logger.Info("Goal check-in recorded", slog.String("user_id", user), slog.String("goal_id", goal.ID), slog.String("habit_id", req.HabitID))
+	c.JSON(http.StatusOK, goals.ComputeProgress(*goal, time.Now(), h.userLocation(user)))
+}
+
+func (h *Handlers) respondGoalError(c *gin.Context, err error, message string) {
+	if errors.Is(err, goals.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if errors.Is(err, goals.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("goal_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// userLocation is the user's timezone, kept with their reminders, for
+// counting streaks by the user's days rather than the server's.
+func (h *Handlers) userLocation(userID string) *time.Location {
+	if h.reminderService == nil {
+		return time.UTC
+	}
+	loc, err := h.reminderService.Location(userID)
+	if err != nil {
+		h.logger.Warn("Failed to resolve timezone, using UTC", slog.String("error", err.Error()), slog.String("user_id", userID))
+		return time.UTC
+	}
+	return loc
+}
@@ -1,35 +1,149 @@
 package api
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/archive"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/email"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/erasure"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inbound"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inputs"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/jobs"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/sessions"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/telegram"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/tools"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/voice"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/webhooks"
 )
 
+// Dependencies groups the services the HTTP handlers are built on.
+type Dependencies struct {
+	Orchestrator   orchestrator.Orchestrator
+	Profile        profile.ProfileService
+	Compactor      *profile.Compactor
+	Tools          tools.ToolService
+	Tasks          tasks.TaskService
+	Notes          notes.NoteService
+	NoteOrganizer  *notes.Organizer
+	Goals          goals.GoalService
+	Mood           mood.MoodService
+	Reminders      reminders.ReminderService
+	ReminderParser *reminders.Parser
+	Calendar       calendar.CalendarService
+	Nudges         scheduler.NudgeQueue
+	Voice          voice.VoiceService
+	// Telegram is nil when no bot token is configured.
+	Telegram        *telegram.Bot
+	InboundVerifier *inbound.Verifier
+	InboundLedger   *inbound.Ledger
+	Webhooks        webhooks.WebhookService
+	Email           email.EmailService
+	Sessions        sessions.SessionService
+	Inputs          inputs.InputService
+	Search          *search.Index
+	Archiver        *archive.Archiver
+	Eraser          *erasure.Eraser
+	Jobs            jobs.JobService
+	// Encryption is nil when no master key is configured.
+	Encryption *storage.EncryptedStore
+	// Scheduler is nil when the background scheduler is disabled.
+	Scheduler *scheduler.Scheduler
+}
+
 type Handlers struct {
-	orchestrator   orchestrator.Orchestrator
-	profileService profile.ProfileService
-	toolService    tools.ToolService
-	logger         *slog.Logger
-	environment    string
+	orchestrator    orchestrator.Orchestrator
+	profileService  profile.ProfileService
+	compactor       *profile.Compactor
+	toolService     tools.ToolService
+	taskService     tasks.TaskService
+	noteService     notes.NoteService
+	noteOrganizer   *notes.Organizer
+	goalService     goals.GoalService
+	moodService     mood.MoodService
+	reminderService reminders.ReminderService
+	reminderParser  *reminders.Parser
+	calendarService calendar.CalendarService
+	nudgeQueue      scheduler.NudgeQueue
+	scheduler       *scheduler.Scheduler
+	voiceService    voice.VoiceService
+	telegramBot     *telegram.Bot
+	inboundVerifier *inbound.Verifier
+	inboundLedger   *inbound.Ledger
+	webhookService  webhooks.WebhookService
+	emailService    email.EmailService
+	sessionService  sessions.SessionService
+	inputService    inputs.InputService
+	searchIndex     *search.Index
+	archiver        *archive.Archiver
+	eraser          *erasure.Eraser
+	jobs            jobs.JobService
+	encryption      *storage.EncryptedStore
+	logger          *slog.Logger
+	environment     string
 }
 
-func NewHandlers(orch orchestrator.Orchestrator, profileSvc profile.ProfileService, toolSvc tools.ToolService, logger *slog.Logger, environment string) *Handlers {
+func NewHandlers(deps Dependencies, logger *slog.Logger, environment string) *Handlers {
 	return &Handlers{
-		orchestrator:   orch,
-		profileService: profileSvc,
-		toolService:    toolSvc,
-		logger:         logger,
-		environment:    environment,
+		orchestrator:    deps.Orchestrator,
+		profileService:  deps.Profile,
+		compactor:       deps.Compactor,
+		toolService:     deps.Tools,
+		taskService:     deps.Tasks,
+		noteService:     deps.Notes,
+		noteOrganizer:   deps.NoteOrganizer,
+		goalService:     deps.Goals,
+		moodService:     deps.Mood,
+		reminderService: deps.Reminders,
+		reminderParser:  deps.ReminderParser,
+		calendarService: deps.Calendar,
+		nudgeQueue:      deps.Nudges,
+		scheduler:       deps.Scheduler,
+		voiceService:    deps.Voice,
+		telegramBot:     deps.Telegram,
+		inboundVerifier: deps.InboundVerifier,
+		inboundLedger:   deps.InboundLedger,
+		webhookService:  deps.Webhooks,
+		emailService:    deps.Email,
+		sessionService:  deps.Sessions,
+		inputService:    deps.Inputs,
+		searchIndex:     deps.Search,
+		archiver:        deps.Archiver,
+		eraser:          deps.Eraser,
+		jobs:            deps.Jobs,
+		encryption:      deps.Encryption,
+		logger:          logger,
+		environment:     environment,
 	}
 }
 
+// userID identifies the user a request acts on, from the user_id query
+// parameter or X-User-ID header, falling back to the default user.
+func userID(c *gin.Context) string {
+	if id := c.Query("user_id"); id != "" {
+		return id
+	}
+	if id := c.GetHeader("X-User-ID"); id != "" {
+		return id
+	}
+	return types.DefaultUserID
+}
+
 func (h *Handlers) ProcessHandler(c *gin.Context) {
 	startTime := time.Now()
 	input := c.Query("input")
@@ -47,45 +161,66 @@ func (h *Handlers) ProcessHandler(c *gin.Context) {
 		return
 	}
 
+	request := orchestrator.Request{
+		UserID:    userID(c),
+		Input:     input,
+		Source:    orchestrator.SourceWeb,
+		SessionID: c.Query("session_id"),
+	}
 	if detailed {
-		response, err := h.orchestrator.ProcessInputDetailed(input)
+		response, err := h.orchestrator.Process(request)
 		if err != nil {
-			h.logger.Error("Detailed processing failed",
-				slog.String("error", err.Error()),
-				slog.String("user_input", input))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Processing failed"})
+			h.respondProcessError(c, err, input, "Detailed processing failed")
 			return
 		}
 
 		processingTime := time.Since(startTime)
 		h.logger.Info("Detailed processing completed",
 			slog.String("response", response.Result.FinalResponse),
+			slog.String("session_id", response.SessionID),
 			slog.Duration("processing_time", processingTime))
 
 		c.JSON(http.StatusOK, response)
 	} else {
-		response, err := h.orchestrator.ProcessInput(input)
+		detailedResponse, err := h.orchestrator.Process(request)
 		if err != nil {
-			h.logger.Error("Processing failed",
-				slog.String("error", err.Error()),
-				slog.String("user_input", input))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Processing failed"})
+			h.respondProcessError(c, err, input, "Processing failed")
 			return
 		}
 
+		response := detailedResponse.Result.FinalResponse
 		processingTime := time.Since(startTime)
 		h.logger.Info("Processing completed",
 			slog.String("response", response),
+			slog.String("session_id", detailedResponse.SessionID),
 			slog.Duration("processing_time", processingTime))
 
+		if detailedResponse.SessionID != "" {
+			c.Header(SessionHeader, detailedResponse.SessionID)
+		}
 		c.String(http.StatusOK, response)
 	}
 }
 
+func (h *Handlers) respondProcessError(c *gin.Context, err error, input, message string) {
+	if errors.Is(err, orchestrator.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if errors.Is(err, erasure.ErrErasing) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion in progress"})
+		return
+	}
+	h.logger.Error(message,
+		slog.String("error", err.Error()),
+		slog.String("user_input", input))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Processing failed"})
+}
+
 func (h *Handlers) ProfileHandler(c *gin.Context) {
 	h.logger.Debug("Profile requested")
 	
-	profile, err := h.profileService.Get()
+	profile, err := h.profileService.Get(userID(c))
 	if err != nil {
 		h.logger.Error("Failed to get profile", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
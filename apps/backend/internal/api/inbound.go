@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/erasure"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inbound"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type inboundResponse struct {
+	UserID         string `json:"user_id"`
+	Source         string `json:"source"`
+	Response       string `json:"response"`
+	SessionID      string `json:"session_id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// InboundWebhookHandler accepts a signed inbound.Payload from another app
+// and processes its text for the given user.
+func (h *Handlers) InboundWebhookHandler(c *gin.Context) {
+	if h.inboundVerifier == nil || !h.inboundVerifier.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inbound webhook is not enabled"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, inbound.MaxBodyBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+	if len(body) > inbound.MaxBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Body too large"})
+		return
+	}
+
+	now := time.Now()
+	nonce := c.GetHeader(inbound.HeaderNonce)
+	if err := h.inboundVerifier.Verify(c.GetHeader(inbound.HeaderTimestamp), nonce, c.GetHeader(inbound.HeaderSignature), body, now); err != nil {
+		h.logger.Warn("Rejected inbound webhook", slog.String("error", err.Error()), slog.String("remote_addr", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var payload inbound.Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a JSON object"})
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := c.GetHeader(inbound.HeaderIdempotencyKey)
+	if len(key) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+		return
+	}
+
+	if err := h.inboundLedger.UseNonce(nonce, now); err != nil {
+		if errors.Is(err, inbound.ErrReplay) {
+			// A client retrying after a lost response may send the signed
+			// request again as it was; once it completed, that gets the
+			// stored response, and it is never processed twice
+			if key != "" {
+				if record := h.inboundLedger.Completed(payload.UserID, key, inbound.HashBody(body), now); record != nil {
+					h.replayInbound(c, payload.UserID, key, record)
+					return
+				}
+			}
+			h.logger.Warn("Rejected replayed inbound webhook", slog.String("remote_addr", c.ClientIP()))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to record inbound nonce", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept message"})
+		return
+	}
+
+	if key != "" {
+		record, err := h.inboundLedger.Begin(payload.UserID, key, inbound.HashBody(body), now)
+		switch {
+		case errors.Is(err, inbound.ErrKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, inbound.ErrInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			h.logger.Error("Failed to claim idempotency key", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept message"})
+			return
+		case record != nil:
+			h.replayInbound(c, payload.UserID, key, record)
+			return
+		}
+	}
+
+	h.logger.Info("Processing inbound webhook",
+		slog.String("user_id", payload.UserID),
+		slog.String("source", payload.Source),
+		slog.String("idempotency_key", key),
+		slog.Any("metadata", payload.Metadata))
+
+	response, err := h.orchestrator.Process(orchestrator.Request{
+		UserID:    payload.UserID,
+		Input:     payload.Text,
+		Source:    payload.Source,
+		SessionID: payload.SessionID,
+	})
+	if err != nil {
+		if key != "" {
+			h.inboundLedger.Abandon(payload.UserID, key)
+		}
+		if errors.Is(err, orchestrator.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		if errors.Is(err, erasure.ErrErasing) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account deletion in progress"})
+			return
+		}
+		h.logger.Error("Inbound webhook processing failed", slog.String("error", err.Error()), slog.String("user_id", payload.UserID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Processing failed"})
+		return
+	}
+
+	result := inboundResponse{
+		UserID:         payload.UserID,
+		Source:         payload.Source,
+		Response:       response.Result.FinalResponse,
+		SessionID:      response.SessionID,
+		IdempotencyKey: key,
+	}
+	if key != "" {
+		if err := h.inboundLedger.Complete(payload.UserID, key, http.StatusOK, result); err != nil {
+			h.logger.Error("Failed to store idempotent response", slog.String("error", err.Error()))
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// replayInbound answers a retry with the response stored for its
+// idempotency key.
+func (h *Handlers) replayInbound(c *gin.Context, userID, key string, record *inbound.Record) {
+	h.logger.Info("Replaying inbound webhook response", slog.String("user_id", userID), slog.String("idempotency_key", key))
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.Status, "application/json; charset=utf-8", record.Response)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inputs"
+)
+
+// ListInputsHandler pages through the input log, newest first. Filters:
+// ?source=, ?session_id=, ?status=processed|failed, ?q= (text search),
+// ?from= and ?to= (RFC 3339), ?limit= (default 50) and ?offset=.
+func (h *Handlers) ListInputsHandler(c *gin.Context) {
+	filter := inputs.Filter{
+		Source:    c.Query("source"),
+		SessionID: c.Query("session_id"),
+		Status:    inputs.Status(c.Query("status")),
+		Query:     c.Query("q"),
+	}
+	switch filter.Status {
+	case "", inputs.StatusProcessed, inputs.StatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'status' parameter"})
+		return
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if raw := c.Query(param.name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid '" + param.name + "' parameter, expected RFC 3339"})
+				return
+			}
+			*param.target = parsed
+		}
+	}
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if raw := c.Query(param.name); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid '" + param.name + "' parameter"})
+				return
+			}
+			*param.target = parsed
+		}
+	}
+
+	page, err := h.inputService.List(userID(c), filter)
+	if err != nil {
+		h.respondInputError(c, err, "Failed to list inputs")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"inputs": page.Inputs,
+		"count":  len(page.Inputs),
+		"total":  page.Total,
+		"limit":  page.Limit,
+		"offset": page.Offset,
+	})
+}
+
+func (h *Handlers) GetInputHandler(c *gin.Context) {
+	record, err := h.inputService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondInputError(c, err, "Failed to get input")
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteInputHandler removes an input from the log. Items created from it
+// are kept.
+func (h *Handlers) DeleteInputHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.inputService.Delete(user, id); err != nil {
+		h.respondInputError(c, err, "Failed to delete input")
+		return
+	}
+
+	h.logger.Info("Input deleted", slog.String("user_id", user), slog.String("input_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) respondInputError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, inputs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Input not found"})
+		return
+	case errors.Is(err, inputs.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("input_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/jobs"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+)
+
+type processAsyncRequest struct {
+	Input     string `json:"input"`
+	SessionID string `json:"session_id"`
+}
+
+// ProcessAsyncHandler queues the input and answers right away with the
+// job, which is then polled at GET /api/jobs/:id. The input and session
+// can be given as JSON or as the same query parameters GET /process takes.
+func (h *Handlers) ProcessAsyncHandler(c *gin.Context) {
+	user := userID(c)
+	req := processAsyncRequest{Input: c.Query("input"), SessionID: c.Query("session_id")}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	// A job queued during a deletion would run after it
+	leave, err := h.eraser.Enter(user)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion in progress"})
+		return
+	}
+	job, err := h.jobs.Enqueue(user, req.Input, orchestrator.SourceWeb, req.SessionID)
+	leave()
+	if err != nil {
+		h.respondJobError(c, err, "Failed to queue input")
+		return
+	}
+
+	h.logger.Info("Input queued", slog.String("user_id", user), slog.String("job_id", job.ID))
+	c.Header("Location", "/api/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *Handlers) ListJobsHandler(c *gin.Context) {
+	status := jobs.Status(c.Query("status"))
+	switch status {
+	case "", jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	list, err := h.jobs.List(userID(c), status)
+	if err != nil {
+		h.respondJobError(c, err, "Failed to list jobs")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": list, "count": len(list)})
+}
+
+// GetJobHandler returns a job with its progress, and the full process
+// response once it has succeeded.
+func (h *Handlers) GetJobHandler(c *gin.Context) {
+	job, err := h.jobs.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondJobError(c, err, "Failed to get job")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *Handlers) respondJobError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	case errors.Is(err, jobs.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("job_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+)
+
+const defaultMoodDays = 30
+
+type journalRequest struct {
+	Text string `json:"text"`
+}
+
+// moodRange reads the ?days= window (default 30) ending now.
+func moodRange(c *gin.Context) (time.Time, time.Time, bool) {
+	days := defaultMoodDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return time.Time{}, time.Time{}, false
+		}
+		days = parsed
+	}
+	now := time.Now()
+	return now.AddDate(0, 0, -days), now.Add(time.Second), true
+}
+
+func (h *Handlers) MoodTimelineHandler(c *gin.Context) {
+	user := userID(c)
+	from, to, ok := moodRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'days' parameter"})
+		return
+	}
+
+	entries, err := h.moodService.Timeline(user, from, to)
+	if err != nil {
+		h.logger.Error("Failed to get mood timeline", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mood timeline"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
+
+func (h *Handlers) MoodAggregatesHandler(c *gin.Context) {
+	user := userID(c)
+	from, to, ok := moodRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'days' parameter"})
+		return
+	}
+
+	period := mood.Period(c.DefaultQuery("period", string(mood.PeriodDay)))
+	if period != mood.PeriodDay && period != mood.PeriodWeek {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'period' parameter (day or week)"})
+		return
+	}
+
+	aggregates, err := h.moodService.Aggregate(user, period, from, to)
+	if err != nil {
+		h.logger.Error("Failed to aggregate mood", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate mood"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"period": period, "aggregates": aggregates})
+}
+
+func (h *Handlers) MoodTrendHandler(c *gin.Context) {
+	user := userID(c)
+	trend, err := h.moodService.Trend(user, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to compute mood trend", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute mood trend"})
+		return
+	}
+	c.JSON(http.StatusOK, trend)
+}
+
+// JournalHandler records a journal entry directly, without running the
+// full processing pipeline.
+func (h *Handlers) JournalHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req journalRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'text' field"})
+		return
+	}
+
+	entry, err := h.moodService.Record(user, req.Text, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to record journal entry", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record journal entry"})
+		return
+	}
+
+	h.logger.Info("Journal entry processed", slog.String("user_id", user), slog.Bool("recorded", entry != nil))
+	c.JSON(http.StatusOK, gin.H{"recorded": entry != nil, "entry": entry})
+}
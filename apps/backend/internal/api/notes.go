@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+)
+
+type noteRequest struct {
+	Content      string   `json:"content"`
+	Title        string   `json:"title"`
+	CollectionID string   `json:"collection_id"`
+	Tags         []string `json:"tags"`
+}
+
+type noteLinkRequest struct {
+	RelatedID string `json:"related_id"`
+}
+
+func (h *Handlers) ListNotesHandler(c *gin.Context) {
+	user := userID(c)
+	list, err := h.noteService.List(user, notes.Filter{
+		CollectionID: c.Query("collection_id"),
+		Tag:          c.Query("tag"),
+	})
+	if err != nil {
+		h.logger.Error("Failed to list notes", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list notes"})
+		return
+	}
+
+	h.logger.Debug("Notes listed", slog.String("user_id", user), slog.Int("count", len(list)))
+	c.JSON(http.StatusOK, gin.H{"notes": list, "count": len(list)})
+}
+
+// CreateNoteHandler stores a note. Without an explicit collection_id the note
+// is organized into a collection and linked to related notes automatically.
+func (h *Handlers) CreateNoteHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req noteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'content' field"})
+		return
+	}
+
+	var (
+		note *notes.Note
+		err  error
+	)
+	if req.CollectionID == "" {
+		note, _, err = h.noteOrganizer.Capture(user, req.Content)
+	} else {
+		note, err = h.noteService.Create(user, notes.Note{
+			Title:        req.Title,
+			Content:      req.Content,
+			CollectionID: req.CollectionID,
+			Tags:         req.Tags,
+		})
+	}
+	if err != nil {
+		h.respondNoteError(c, err, "Failed to create note")
+		return
+	}
+
+	h.logger.Info("Note created", slog.String("user_id", user), slog.String("note_id", note.ID), slog.String("collection_id", note.CollectionID))
+	c.JSON(http.StatusCreated, note)
+}
+
+func (h *Handlers) GetNoteHandler(c *gin.Context) {
+	user := userID(c)
+	note, err := h.noteService.Get(user, c.Param("id"))
+	if err != nil {
+		h.respondNoteError(c, err, "Failed to get note")
+		return
+	}
+
+	related := make([]notes.Note, 0, len(note.RelatedIDs))
+	for _, id := range note.RelatedIDs {
+		if relatedNote, err := h.noteService.Get(user, id); err == nil {
+			related = append(related, *relatedNote)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"note": note, "related": related})
+}
+
+func (h *Handlers) LinkNoteHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req noteLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RelatedID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'related_id' field"})
+		return
+	}
+
+	if err := h.noteService.Link(user, c.Param("id"), req.RelatedID); err != nil {
+		h.respondNoteError(c, err, "Failed to link notes")
+		return
+	}
+
+	h.logger.Info("Notes linked", slog.String("user_id", user), slog.String("note_id", c.Param("id")), slog.String("related_id", req.RelatedID))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) DeleteNoteHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.noteService.Delete(user, id); err != nil {
+		h.respondNoteError(c, err, "Failed to delete note")
+		return
+	}
+
+	h.logger.Info("Note deleted", slog.String("user_id", user), slog.String("note_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) ListCollectionsHandler(c *gin.Context) {
+	user := userID(c)
+	collections, err := h.noteService.Collections(user)
+	if err != nil {
+		h.logger.Error("Failed to list collections", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list collections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collections": collections, "count": len(collections)})
+}
+
+func (h *Handlers) GetCollectionHandler(c *gin.Context) {
+	user := userID(c)
+	collection, err := h.noteService.GetCollection(user, c.Param("id"))
+	if err != nil {
+		h.respondNoteError(c, err, "Failed to get collection")
+		return
+	}
+
+	list, err := h.noteService.List(user, notes.Filter{CollectionID: collection.ID})
+	if err != nil {
+		h.respondNoteError(c, err, "Failed to get collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collection": collection, "notes": list})
+}
+
+func (h *Handlers) respondNoteError(c *gin.Context, err error, message string) {
+	if errors.Is(err, notes.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if errors.Is(err, notes.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+)
+
+func (h *Handlers) ListNudgesHandler(c *gin.Context) {
+	user := userID(c)
+
+	status := scheduler.Status(c.Query("status"))
+	switch status {
+	case "", scheduler.StatusPending, scheduler.StatusDelivered, scheduler.StatusDismissed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'status' parameter (pending, delivered or dismissed)"})
+		return
+	}
+
+	nudges, err := h.nudgeQueue.List(user, status)
+	if err != nil {
+		h.logger.Error("Failed to list nudges", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list nudges"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nudges": nudges, "count": len(nudges)})
+}
+
+func (h *Handlers) DismissNudgeHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.nudgeQueue.Dismiss(user, id); err != nil {
+		if errors.Is(err, scheduler.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Nudge not found"})
+			return
+		}
+		h.logger.Error("Failed to dismiss nudge", slog.String("error", err.Error()), slog.String("nudge_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss nudge"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// EvaluateNudgesHandler runs the nudge evaluation for the user right away
+// instead of waiting for the next scheduler tick.
+func (h *Handlers) EvaluateNudgesHandler(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is disabled"})
+		return
+	}
+
+	user := userID(c)
+	nudge, err := h.scheduler.EvaluateNow(user)
+	if err != nil {
+		h.logger.Error("Failed to evaluate nudges", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate nudges"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nudged": nudge != nil, "nudge": nudge})
+}
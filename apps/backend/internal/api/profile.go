@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// StructuredProfileHandler returns the profile as JSON: the learned
+// plain-text summary plus the user's tracked goals and habit streaks.
+func (h *Handlers) StructuredProfileHandler(c *gin.Context) {
+	user := userID(c)
+
+	summary, err := h.profileService.Get(user)
+	if err != nil {
+		h.logger.Error("Failed to get profile", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		return
+	}
+
+	activeGoals, err := h.goalService.List(user, goals.StatusActive)
+	if err != nil {
+		h.logger.Error("Failed to list goals for profile", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		return
+	}
+
+	now, loc := time.Now(), h.userLocation(user)
+	goalSummaries := make([]types.GoalSummary, len(activeGoals))
+	for i, goal := range activeGoals {
+		goalSummaries[i] = summarizeGoal(goal, goals.ComputeProgress(goal, now, loc))
+	}
+
+	c.JSON(http.StatusOK, types.StructuredProfile{
+		UserID:  user,
+		Summary: summary,
+		Goals:   goalSummaries,
+	})
+}
+
+// ProfileHistoryHandler lists changes to the profile other than appended
+// entries, such as compactions, newest first.
+func (h *Handlers) ProfileHistoryHandler(c *gin.Context) {
+	events, err := h.profileService.History(userID(c))
+	if err != nil {
+		h.logger.Error("Failed to get profile history", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
+
+// CompactProfileHandler compacts the profile now if it is past the size
+// limits, or regardless with ?force=true.
+func (h *Handlers) CompactProfileHandler(c *gin.Context) {
+	user := userID(c)
+	event, err := h.compactor.Compact(user, c.Query("force") == "true")
+	if err != nil {
+		if errors.Is(err, profile.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Profile changed during compaction, try again"})
+			return
+		}
+		h.logger.Error("Failed to compact profile", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compact profile"})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusOK, gin.H{"compacted": false})
+		return
+	}
+
+	h.logger.Info("Profile compacted", slog.String("user_id", user), slog.Int("chars_before", event.CharsBefore), slog.Int("chars_after", event.CharsAfter))
+	c.JSON(http.StatusOK, gin.H{"compacted": true, "event": event})
+}
+
+func summarizeGoal(goal goals.Goal, progress goals.Progress) types.GoalSummary {
+	summary := types.GoalSummary{
+		ID:             goal.ID,
+		Title:          goal.Title,
+		Motivation:     goal.Motivation,
+		Status:         string(goal.Status),
+		Completion:     progress.Completion,
+		MilestonesDone: progress.MilestonesDone,
+		MilestonesLeft: make([]string, 0),
+		CheckInStreak:  progress.CheckInStreak,
+		Habits:         make([]types.HabitSummary, len(progress.Habits)),
+	}
+	for _, milestone := range goal.Milestones {
+		if !milestone.Done {
+			summary.MilestonesLeft = append(summary.MilestonesLeft, milestone.Title)
+		}
+	}
+	for i, habit := range progress.Habits {
+		summary.Habits[i] = types.HabitSummary{
+			Title:         habit.Title,
+			Frequency:     string(habit.Frequency),
+			CurrentStreak: habit.CurrentStreak,
+		}
+	}
+	return summary
+}
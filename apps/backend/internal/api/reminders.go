@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+)
+
+// reminderRequest creates a reminder either from a full sentence (text),
+// from a message plus a natural-language time (when), or from a message
+// plus an exact time (at).
+type reminderRequest struct {
+	Text       string                `json:"text"`
+	Message    string                `json:"message"`
+	When       string                `json:"when"`
+	At         *time.Time            `json:"at"`
+	Recurrence *reminders.Recurrence `json:"recurrence"`
+	Timezone   string                `json:"timezone"`
+}
+
+type timezoneRequest struct {
+	Timezone string `json:"timezone"`
+}
+
+func (h *Handlers) ListRemindersHandler(c *gin.Context) {
+	user := userID(c)
+
+	status := reminders.Status(c.Query("status"))
+	if status != "" && !status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'status' parameter (scheduled, fired, cancelled or missed)"})
+		return
+	}
+
+	list, err := h.reminderService.List(user, status)
+	if err != nil {
+		h.logger.Error("Failed to list reminders", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list reminders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reminders": list, "count": len(list)})
+}
+
+func (h *Handlers) CreateReminderHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req reminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	loc, err := h.reminderService.Location(user)
+	if err != nil {
+		h.respondReminderError(c, err, "Failed to create reminder")
+		return
+	}
+	if req.Timezone != "" {
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown 'timezone'"})
+			return
+		}
+	}
+
+	reminder := reminders.Reminder{
+		Message:    req.Message,
+		Recurrence: req.Recurrence,
+		Timezone:   loc.String(),
+	}
+	now := time.Now()
+	switch {
+	case req.Text != "":
+		cmd, err := h.reminderParser.Parse(req.Text, now, loc)
+		if err != nil || cmd.Action != reminders.ActionCreate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not understand the reminder in 'text'"})
+			return
+		}
+		reminder.Message, reminder.FireAt, reminder.Recurrence = cmd.Message, cmd.At, cmd.Recurrence
+	case req.When != "":
+		when, err := reminders.ParseWhen(req.When, now, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not understand 'when': " + err.Error()})
+			return
+		}
+		reminder.FireAt = when.At
+		if reminder.Recurrence == nil {
+			reminder.Recurrence = when.Recurrence
+		}
+	case req.At != nil:
+		reminder.FireAt = *req.At
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide 'text', or 'message' with 'when' or 'at'"})
+		return
+	}
+
+	created, err := h.reminderService.Create(user, reminder)
+	if err != nil {
+		h.respondReminderError(c, err, "Failed to create reminder")
+		return
+	}
+
+	h.logger.Info("Reminder created", slog.String("user_id", user), slog.String("reminder_id", created.ID))
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handlers) GetReminderHandler(c *gin.Context) {
+	reminder, err := h.reminderService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondReminderError(c, err, "Failed to get reminder")
+		return
+	}
+	c.JSON(http.StatusOK, reminder)
+}
+
+func (h *Handlers) CancelReminderHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if _, err := h.reminderService.Cancel(user, id); err != nil {
+		h.respondReminderError(c, err, "Failed to cancel reminder")
+		return
+	}
+
+	h.logger.Info("Reminder cancelled", slog.String("user_id", user), slog.String("reminder_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) GetTimezoneHandler(c *gin.Context) {
+	loc, err := h.reminderService.Location(userID(c))
+	if err != nil {
+		h.respondReminderError(c, err, "Failed to get timezone")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"timezone": loc.String()})
+}
+
+func (h *Handlers) SetTimezoneHandler(c *gin.Context) {
+	var req timezoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Timezone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'timezone' field"})
+		return
+	}
+
+	loc, err := h.reminderService.SetTimezone(userID(c), req.Timezone)
+	if err != nil {
+		h.respondReminderError(c, err, "Failed to set timezone")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"timezone": loc.String()})
+}
+
+func (h *Handlers) respondReminderError(c *gin.Context, err error, message string) {
+	if errors.Is(err, reminders.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+		return
+	}
+	if errors.Is(err, reminders.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("reminder_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
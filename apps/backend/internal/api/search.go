@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+)
+
+// SearchHandler runs a full-text search over the user's inputs, notes,
+// tasks and profile. ?q= is required; ?kind= takes a comma-separated list
+// of input, note, task and profile; ?limit= defaults to 20.
+func (h *Handlers) SearchHandler(c *gin.Context) {
+	query := search.Query{Text: strings.TrimSpace(c.Query("q"))}
+	if query.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'q' parameter is required"})
+		return
+	}
+	if raw := c.Query("kind"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			kind, ok := search.ParseKind(name)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'kind' parameter: " + name})
+				return
+			}
+			query.Kinds = append(query.Kinds, kind)
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'limit' parameter"})
+			return
+		}
+		query.Limit = limit
+	}
+
+	results, err := h.searchIndex.Search(userID(c), query)
+	if err != nil {
+		if errors.Is(err, search.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Search failed", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query.Text,
+		"results": results,
+		"count":   len(results),
+	})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/sessions"
+)
+
+// SessionHeader carries the session ID of plain-text /process responses,
+// which have no body to put it in.
+const SessionHeader = "X-Session-ID"
+
+type createSessionRequest struct {
+	Title string `json:"title"`
+}
+
+func (h *Handlers) ListSessionsHandler(c *gin.Context) {
+	list, err := h.sessionService.List(userID(c))
+	if err != nil {
+		h.respondSessionError(c, err, "Failed to list sessions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": list, "count": len(list)})
+}
+
+// CreateSessionHandler starts an empty session; /process also starts one
+// when called without a session_id.
+func (h *Handlers) CreateSessionHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req createSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	session, err := h.sessionService.Create(user, req.Title)
+	if err != nil {
+		h.respondSessionError(c, err, "Failed to create session")
+		return
+	}
+
+	h.logger.Info("Session created", slog.String("user_id", user), slog.String("session_id", session.ID))
+	c.JSON(http.StatusCreated, session)
+}
+
+func (h *Handlers) GetSessionHandler(c *gin.Context) {
+	session, err := h.sessionService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondSessionError(c, err, "Failed to get session")
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *Handlers) DeleteSessionHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.sessionService.Delete(user, id); err != nil {
+		h.respondSessionError(c, err, "Failed to delete session")
+		return
+	}
+
+	h.logger.Info("Session deleted", slog.String("user_id", user), slog.String("session_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) respondSessionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, sessions.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	case errors.Is(err, sessions.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("session_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
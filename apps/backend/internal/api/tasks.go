@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+type taskRequest struct {
+	Title    *string    `json:"title"`
+	Priority *string    `json:"priority"`
+	DueAt    *time.Time `json:"due_at"`
+	Tags     []string   `json:"tags"`
+}
+
+func (r taskRequest) priority() (*tasks.Priority, bool) {
+	if r.Priority == nil {
+		return nil, true
+	}
+	priority, ok := tasks.ParsePriority(*r.Priority)
+	return &priority, ok
+}
+
+func (h *Handlers) ListTasksHandler(c *gin.Context) {
+	user := userID(c)
+	filter := tasks.Filter{
+		Status: tasks.Status(c.Query("status")),
+		Tag:    c.Query("tag"),
+	}
+
+	list, err := h.taskService.List(user, filter)
+	if err != nil {
+		h.logger.Error("Failed to list tasks", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tasks"})
+		return
+	}
+
+	h.logger.Debug("Tasks listed", slog.String("user_id", user), slog.Int("count", len(list)))
+	c.JSON(http.StatusOK, gin.H{"tasks": list, "count": len(list)})
+}
+
+func (h *Handlers) CreateTaskHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Title == nil || *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'title' field"})
+		return
+	}
+	priority, ok := req.priority()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'priority' value"})
+		return
+	}
+
+	task := tasks.Task{
+		Title: *req.Title,
+		DueAt: req.DueAt,
+		Tags:  req.Tags,
+	}
+	if priority != nil {
+		task.Priority = *priority
+	}
+
+	created, err := h.taskService.Create(user, task)
+	if err != nil {
+		h.logger.Error("Failed to create task", slog.String("error", err.Error()), slog.String("user_id", user))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	h.logger.Info("Task created", slog.String("user_id", user), slog.String("task_id", created.ID))
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handlers) GetTaskHandler(c *gin.Context) {
+	task, err := h.taskService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to get task")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *Handlers) UpdateTaskHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	priority, ok := req.priority()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'priority' value"})
+		return
+	}
+
+	task, err := h.taskService.Update(user, c.Param("id"), tasks.Update{
+		Title:    req.Title,
+		Priority: priority,
+		DueAt:    req.DueAt,
+		Tags:     req.Tags,
+	})
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to update task")
+		return
+	}
+
+	h.logger.Info("Task updated", slog.String("user_id", user), slog.String("task_id", task.ID))
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *Handlers) CompleteTaskHandler(c *gin.Context) {
+	user := userID(c)
+
+	task, err := h.taskService.Complete(user, c.Param("id"))
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to complete task")
+		return
+	}
+
+	h.logger.Info("Task completed", slog.String("user_id", user), slog.String("task_id", task.ID))
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *Handlers) DeleteTaskHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.taskService.Delete(user, id); err != nil {
+		h.respondTaskError(c, err, "Failed to delete task")
+		return
+	}
+
+	h.logger.Info("Task deleted", slog.String("user_id", user), slog.String("task_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) respondTaskError(c *gin.Context, err error, message string) {
+	if errors.Is(err, tasks.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if errors.Is(err, tasks.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("task_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
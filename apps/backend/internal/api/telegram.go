@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/telegram"
+)
+
+// TelegramWebhookHandler receives updates in webhook mode. Telegram retries
+// until it gets a 2xx, so updates are acknowledged first and processed in
+// the background by a fixed pool of workers; when they fall behind, the
+// update is refused and Telegram sends it again later.
+func (h *Handlers) TelegramWebhookHandler(c *gin.Context) {
+	if h.telegramBot == nil || h.telegramBot.Mode() != telegram.ModeWebhook {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Telegram webhook is not enabled"})
+		return
+	}
+	if !h.telegramBot.VerifySecret(c.GetHeader("X-Telegram-Bot-Api-Secret-Token")) {
+		h.logger.Warn("Telegram webhook call with bad secret", slog.String("remote_addr", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret token"})
+		return
+	}
+
+	var update telegram.Update
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update"})
+		return
+	}
+
+	if err := h.telegramBot.Enqueue(update); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many updates in flight, retry later"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// TelegramLinkCodeHandler issues a code the user sends to the bot as
+// "/start <code>" to link that chat to their account.
+func (h *Handlers) TelegramLinkCodeHandler(c *gin.Context) {
+	if !h.requireTelegram(c) {
+		return
+	}
+	code, err := h.telegramBot.Chats().NewLinkCode(userID(c))
+	if err != nil {
+		h.logger.Error("Failed to create Telegram link code", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":       code.Code,
+		"expires_at": code.ExpiresAt,
+		"command":    "/start " + code.Code,
+	})
+}
+
+func (h *Handlers) ListTelegramChatsHandler(c *gin.Context) {
+	if !h.requireTelegram(c) {
+		return
+	}
+	chats := h.telegramBot.Chats().ForUser(userID(c))
+	c.JSON(http.StatusOK, gin.H{"chats": chats, "count": len(chats)})
+}
+
+func (h *Handlers) UnlinkTelegramChatHandler(c *gin.Context) {
+	if !h.requireTelegram(c) {
+		return
+	}
+	user := userID(c)
+	chatID, err := strconv.ParseInt(c.Param("chatId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	if err := h.telegramBot.Chats().Unlink(user, chatID); err != nil {
+		if errors.Is(err, telegram.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Telegram chat not found"})
+			return
+		}
+		h.logger.Error("Failed to unlink Telegram chat", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink chat"})
+		return
+	}
+
+	h.logger.Info("Telegram chat unlinked", slog.String("user_id", user), slog.Int64("chat_id", chatID))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) requireTelegram(c *gin.Context) bool {
+	if h.telegramBot == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Telegram is not configured"})
+		return false
+	}
+	return true
+}
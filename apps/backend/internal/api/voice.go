@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/erasure"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/voice"
+)
+
+// voiceProcessResponse is the detailed process response with the voice
+// note the input came from.
+type voiceProcessResponse struct {
+	*types.ProcessResponse
+	VoiceNote *voice.Note `json:"voice_note"`
+}
+
+// ProcessVoiceHandler accepts a recording as multipart field "audio" (or
+// "file"), transcribes it and processes the transcript like typed input.
+// ?detailed=true returns the full processing details; ?session_id=
+// continues a conversation.
+func (h *Handlers) ProcessVoiceHandler(c *gin.Context) {
+	startTime := time.Now()
+	user := userID(c)
+	detailed := c.Query("detailed") == "true"
+
+	if !h.voiceService.CanTranscribe() {
+		h.respondVoiceError(c, voice.ErrUnavailable, "Voice transcription is not configured")
+		return
+	}
+	// The recording is stored before it is processed, so a deletion waits
+	// for the whole request
+	leave, err := h.eraser.Enter(user)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion in progress"})
+		return
+	}
+	defer leave()
+
+	header, err := c.FormFile("audio")
+	if err != nil {
+		header, err = c.FormFile("file")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'audio' field"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	note, err := h.voiceService.Save(user, header.Filename, file)
+	if err != nil {
+		h.respondVoiceError(c, err, "Failed to save voice note")
+		return
+	}
+
+	note, err = h.voiceService.Transcribe(user, note.ID)
+	if err != nil {
+		h.respondVoiceError(c, err, "Failed to transcribe voice note")
+		return
+	}
+	if note.Transcript == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No speech detected", "voice_note": note})
+		return
+	}
+
+	h.logger.Info("Processing voice note",
+		slog.String("user_id", user),
+		slog.String("voice_note_id", note.ID),
+		slog.String("transcript", note.Transcript),
+		slog.Bool("detailed", detailed))
+
+	response, err := h.orchestrator.Process(orchestrator.Request{
+		UserID:      user,
+		Input:       note.Transcript,
+		Source:      orchestrator.SourceVoice,
+		SessionID:   c.Query("session_id"),
+		VoiceNoteID: note.ID,
+	})
+	if errors.Is(err, orchestrator.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found", "voice_note": note})
+		return
+	}
+	if errors.Is(err, erasure.ErrErasing) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion in progress", "voice_note": note})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Voice note processing failed",
+			slog.String("error", err.Error()),
+			slog.String("voice_note_id", note.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Processing failed", "voice_note": note})
+		return
+	}
+
+	h.logger.Info("Voice note processing completed",
+		slog.String("voice_note_id", note.ID),
+		slog.String("response", response.Result.FinalResponse),
+		slog.Duration("processing_time", time.Since(startTime)))
+
+	if detailed {
+		c.JSON(http.StatusOK, voiceProcessResponse{ProcessResponse: response, VoiceNote: note})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"response": response.Result.FinalResponse, "session_id": response.SessionID, "input_id": response.InputID, "voice_note": note})
+}
+
+func (h *Handlers) ListVoiceNotesHandler(c *gin.Context) {
+	notes, err := h.voiceService.List(userID(c))
+	if err != nil {
+		h.respondVoiceError(c, err, "Failed to list voice notes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"voice_notes": notes, "count": len(notes)})
+}
+
+func (h *Handlers) GetVoiceNoteHandler(c *gin.Context) {
+	note, err := h.voiceService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondVoiceError(c, err, "Failed to get voice note")
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// VoiceNoteAudioHandler streams the original recording.
+func (h *Handlers) VoiceNoteAudioHandler(c *gin.Context) {
+	audio, note, err := h.voiceService.Audio(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondVoiceError(c, err, "Failed to get voice note audio")
+		return
+	}
+	c.Data(http.StatusOK, note.Format.ContentType(), audio)
+}
+
+func (h *Handlers) DeleteVoiceNoteHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.voiceService.Delete(user, id); err != nil {
+		h.respondVoiceError(c, err, "Failed to delete voice note")
+		return
+	}
+
+	h.logger.Info("Voice note deleted", slog.String("user_id", user), slog.String("voice_note_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handlers) respondVoiceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, voice.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Voice note not found"})
+		return
+	case errors.Is(err, voice.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, voice.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Voice transcription is not configured"})
+		return
+	case errors.Is(err, voice.ErrTranscription):
+		h.logger.Error(message, slog.String("error", err.Error()))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("voice_note_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/webhooks"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+type registerWebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+func (h *Handlers) ListWebhooksHandler(c *gin.Context) {
+	endpoints, err := h.webhookService.List(userID(c))
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to list webhooks")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints, "count": len(endpoints), "event_types": webhooks.EventTypes})
+}
+
+// RegisterWebhookHandler registers an endpoint. The response carries the
+// signing secret, which is not shown again.
+func (h *Handlers) RegisterWebhookHandler(c *gin.Context) {
+	user := userID(c)
+
+	var req registerWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	endpoint, err := h.webhookService.Register(user, webhooks.Endpoint{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+	})
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to register webhook")
+		return
+	}
+
+	h.logger.Info("Webhook registered", slog.String("user_id", user), slog.String("webhook_id", endpoint.ID), slog.Any("events", endpoint.Events))
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+func (h *Handlers) GetWebhookHandler(c *gin.Context) {
+	endpoint, err := h.webhookService.Get(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to get webhook")
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *Handlers) UpdateWebhookHandler(c *gin.Context) {
+	var update webhooks.EndpointUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	endpoint, err := h.webhookService.Update(userID(c), c.Param("id"), update)
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *Handlers) DeleteWebhookHandler(c *gin.Context) {
+	user := userID(c)
+	id := c.Param("id")
+
+	if err := h.webhookService.Delete(user, id); err != nil {
+		h.respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	h.logger.Info("Webhook deleted", slog.String("user_id", user), slog.String("webhook_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+// PingWebhookHandler queues a "ping" event so the endpoint can be checked.
+func (h *Handlers) PingWebhookHandler(c *gin.Context) {
+	delivery, err := h.webhookService.Ping(userID(c), c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to ping webhook")
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// WebhookDeliveriesHandler is the delivery log of one endpoint, newest
+// first (?status=pending|succeeded|failed, ?limit=50).
+func (h *Handlers) WebhookDeliveriesHandler(c *gin.Context) {
+	filter := webhooks.DeliveryFilter{
+		Status: webhooks.DeliveryStatus(c.Query("status")),
+		Limit:  defaultDeliveryLimit,
+	}
+	switch filter.Status {
+	case "", webhooks.DeliveryPending, webhooks.DeliverySucceeded, webhooks.DeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'status' parameter"})
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxDeliveryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'limit' parameter"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.webhookService.Deliveries(userID(c), c.Param("id"), filter)
+	if err != nil {
+		h.respondWebhookError(c, err, "Failed to list deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "count": len(deliveries)})
+}
+
+func (h *Handlers) respondWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	case errors.Is(err, webhooks.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(message, slog.String("error", err.Error()), slog.String("webhook_id", c.Param("id")))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,339 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inputs"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/memory"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/sessions"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+// Version is the archive format written by Export. Import accepts archives
+// up to this version.
+const Version = 1
+
+// MaxImportBytes caps the size of an archive accepted for import.
+const MaxImportBytes = 32 << 20
+
+// maxProblems caps how many validation problems are reported at once.
+const maxProblems = 10
+
+var ErrInvalid = errors.New("invalid archive")
+
+// Archive is everything kept about one user.
+type Archive struct {
+	Version     int                  `json:"version"`
+	ExportedAt  time.Time            `json:"exported_at"`
+	UserID      string               `json:"user_id"`
+	Profile     Profile              `json:"profile"`
+	Inputs      []inputs.Record      `json:"inputs"`
+	Sessions    []sessions.Session   `json:"sessions"`
+	Tasks       []tasks.Task         `json:"tasks"`
+	Notes       []notes.Note         `json:"notes"`
+	Collections []notes.Collection   `json:"collections"`
+	Goals       []goals.Goal         `json:"goals"`
+	Timezone    string               `json:"timezone,omitempty"`
+	Reminders   []reminders.Reminder `json:"reminders"`
+	Mood        []mood.Entry         `json:"mood"`
+}
+
+type Profile struct {
+	Text    string          `json:"text"`
+	History []profile.Event `json:"history"`
+}
+
+// Counts holds a number per archive section.
+type Counts struct {
+	Profile   int `json:"profile"`
+	Inputs    int `json:"inputs"`
+	Sessions  int `json:"sessions"`
+	Tasks     int `json:"tasks"`
+	Notes     int `json:"notes"`
+	Goals     int `json:"goals"`
+	Reminders int `json:"reminders"`
+	Mood      int `json:"mood"`
+}
+
+// Result is what an import changed. Items the user already had, by ID or
+// for profile entries by text, are counted as skipped.
+type Result struct {
+	UserID  string `json:"user_id"`
+	Added   Counts `json:"added"`
+	Skipped Counts `json:"skipped"`
+}
+
+// Sources holds the services an archive is read from and merged into.
+// Memory may be nil, in which case imported items are not embedded.
+type Sources struct {
+	Profile   profile.ProfileService
+	Inputs    inputs.InputService
+	Sessions  sessions.SessionService
+	Tasks     tasks.TaskService
+	Notes     notes.NoteService
+	Goals     goals.GoalService
+	Reminders reminders.ReminderService
+	Mood      mood.MoodService
+	Memory    memory.MemoryService
+}
+
+// Archiver exports a user's data as a versioned archive and merges archives
+// back in, into the same or another user.
+type Archiver struct {
+	sources Sources
+}
+
+func New(sources Sources) *Archiver {
+	return &Archiver{sources: sources}
+}
+
+func (a *Archiver) Export(userID string) (*Archive, error) {
+	s := a.sources
+	archive := &Archive{Version: Version, ExportedAt: time.Now().UTC(), UserID: userID}
+
+	text, err := s.Profile.Get(userID)
+	if err != nil {
+		return nil, fmt.Errorf("export profile: %w", err)
+	}
+	history, err := s.Profile.History(userID)
+	if err != nil {
+		return nil, fmt.Errorf("export profile history: %w", err)
+	}
+	archive.Profile = Profile{Text: text, History: history}
+
+	archive.Inputs = make([]inputs.Record, 0)
+	page, err := s.Inputs.List(userID, inputs.Filter{Limit: inputs.MaxLimit})
+	for err == nil && len(page.Inputs) > 0 {
+		archive.Inputs = append(archive.Inputs, page.Inputs...)
+		page, err = s.Inputs.List(userID, inputs.Filter{Limit: inputs.MaxLimit, Offset: page.Offset + len(page.Inputs)})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("export inputs: %w", err)
+	}
+
+	list, err := s.Sessions.List(userID)
+	if err != nil {
+		return nil, fmt.Errorf("export sessions: %w", err)
+	}
+	archive.Sessions = make([]sessions.Session, 0, len(list))
+	for _, summary := range list {
+		// Listings leave out turns
+		session, err := s.Sessions.Get(userID, summary.ID)
+		if err != nil {
+			return nil, fmt.Errorf("export session %s: %w", summary.ID, err)
+		}
+		archive.Sessions = append(archive.Sessions, *session)
+	}
+
+	if archive.Tasks, err = s.Tasks.List(userID, tasks.Filter{}); err != nil {
+		return nil, fmt.Errorf("export tasks: %w", err)
+	}
+	if archive.Notes, err = s.Notes.List(userID, notes.Filter{}); err != nil {
+		return nil, fmt.Errorf("export notes: %w", err)
+	}
+	if archive.Collections, err = s.Notes.Collections(userID); err != nil {
+		return nil, fmt.Errorf("export collections: %w", err)
+	}
+	if archive.Goals, err = s.Goals.List(userID, ""); err != nil {
+		return nil, fmt.Errorf("export goals: %w", err)
+	}
+	if archive.Reminders, err = s.Reminders.List(userID, ""); err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+	location, err := s.Reminders.Location(userID)
+	if err != nil {
+		return nil, fmt.Errorf("export timezone: %w", err)
+	}
+	archive.Timezone = location.String()
+	if archive.Mood, err = s.Mood.Timeline(userID, time.Time{}, time.Time{}); err != nil {
+		return nil, fmt.Errorf("export mood: %w", err)
+	}
+
+	log.Printf("Archiver: Exported data for user %s (%d inputs, %d tasks, %d notes, %d goals)", userID, len(archive.Inputs), len(archive.Tasks), len(archive.Notes), len(archive.Goals))
+	return archive, nil
+}
+
+// Validate checks an archive before anything is merged, so a bad archive
+// changes nothing. Problems are reported together, wrapping ErrInvalid.
+func Validate(archive *Archive) error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	ids := func(section string) func(id string, i int) {
+		seen := make(map[string]bool)
+		return func(id string, i int) {
+			check(id != "", "%s[%d]: missing id", section, i)
+			check(id == "" || !seen[id], "%s[%d]: duplicate id %q", section, i, id)
+			seen[id] = true
+		}
+	}
+
+	check(archive.Version >= 1 && archive.Version <= Version, "unsupported version %d (expected 1 to %d)", archive.Version, Version)
+	if archive.Timezone != "" {
+		_, err := time.LoadLocation(archive.Timezone)
+		check(err == nil, "unknown timezone %q", archive.Timezone)
+	}
+
+	checkID := ids("profile.history")
+	for i, event := range archive.Profile.History {
+		checkID(event.ID, i)
+	}
+	checkID = ids("inputs")
+	for i, record := range archive.Inputs {
+		checkID(record.ID, i)
+		check(strings.TrimSpace(record.Text) != "", "inputs[%d]: missing text", i)
+		check(!record.At.IsZero(), "inputs[%d]: missing time", i)
+	}
+	checkID = ids("sessions")
+	for i, session := range archive.Sessions {
+		checkID(session.ID, i)
+		for j, turn := range session.Turns {
+			check(turn.Role == sessions.RoleUser || turn.Role == sessions.RoleAssistant, "sessions[%d].turns[%d]: invalid role %q", i, j, turn.Role)
+		}
+	}
+	checkID = ids("tasks")
+	for i, task := range archive.Tasks {
+		checkID(task.ID, i)
+		check(strings.TrimSpace(task.Title) != "", "tasks[%d]: missing title", i)
+	}
+	checkID = ids("collections")
+	for i, collection := range archive.Collections {
+		checkID(collection.ID, i)
+		check(strings.TrimSpace(collection.Name) != "", "collections[%d]: missing name", i)
+	}
+	checkID = ids("notes")
+	for i, note := range archive.Notes {
+		checkID(note.ID, i)
+		check(strings.TrimSpace(note.Content) != "" || strings.TrimSpace(note.Title) != "", "notes[%d]: missing content", i)
+	}
+	checkID = ids("goals")
+	for i, goal := range archive.Goals {
+		checkID(goal.ID, i)
+		check(strings.TrimSpace(goal.Title) != "", "goals[%d]: missing title", i)
+	}
+	checkID = ids("reminders")
+	for i, reminder := range archive.Reminders {
+		checkID(reminder.ID, i)
+		check(strings.TrimSpace(reminder.Message) != "", "reminders[%d]: missing message", i)
+		check(!reminder.FireAt.IsZero(), "reminders[%d]: missing fire time", i)
+		check(reminder.Status.Valid(), "reminders[%d]: invalid status %q", i, reminder.Status)
+	}
+	checkID = ids("mood")
+	for i, entry := range archive.Mood {
+		checkID(entry.ID, i)
+		check(!entry.At.IsZero(), "mood[%d]: missing time", i)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxProblems {
+		problems = append(problems[:maxProblems], fmt.Sprintf("and %d more", len(problems)-maxProblems))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+}
+
+// Import validates archive and merges it into userID, which need not exist
+// yet. Items keep their IDs; ones the user already has are left alone, so
+// importing the same archive twice adds nothing the second time.
+func (a *Archiver) Import(userID string, archive *Archive) (*Result, error) {
+	if err := Validate(archive); err != nil {
+		return nil, err
+	}
+	s := a.sources
+	result := &Result{UserID: userID}
+	var err error
+
+	if result.Added.Profile, err = s.Profile.Import(userID, archive.Profile.Text, archive.Profile.History); err != nil {
+		return nil, fmt.Errorf("import profile: %w", err)
+	}
+	result.Skipped.Profile = len(profile.Entries(archive.Profile.Text)) - result.Added.Profile
+	if result.Added.Inputs, err = s.Inputs.Import(userID, archive.Inputs); err != nil {
+		return nil, fmt.Errorf("import inputs: %w", err)
+	}
+	result.Skipped.Inputs = len(archive.Inputs) - result.Added.Inputs
+	if result.Added.Sessions, err = s.Sessions.Import(userID, archive.Sessions); err != nil {
+		return nil, fmt.Errorf("import sessions: %w", err)
+	}
+	result.Skipped.Sessions = len(archive.Sessions) - result.Added.Sessions
+	if result.Added.Tasks, err = s.Tasks.Import(userID, archive.Tasks); err != nil {
+		return nil, fmt.Errorf("import tasks: %w", err)
+	}
+	result.Skipped.Tasks = len(archive.Tasks) - result.Added.Tasks
+	if result.Added.Notes, err = s.Notes.Import(userID, archive.Notes, archive.Collections); err != nil {
+		return nil, fmt.Errorf("import notes: %w", err)
+	}
+	result.Skipped.Notes = len(archive.Notes) - result.Added.Notes
+	if result.Added.Goals, err = s.Goals.Import(userID, archive.Goals); err != nil {
+		return nil, fmt.Errorf("import goals: %w", err)
+	}
+	result.Skipped.Goals = len(archive.Goals) - result.Added.Goals
+	if result.Added.Reminders, err = s.Reminders.Import(userID, archive.Timezone, archive.Reminders); err != nil {
+		return nil, fmt.Errorf("import reminders: %w", err)
+	}
+	result.Skipped.Reminders = len(archive.Reminders) - result.Added.Reminders
+	if result.Added.Mood, err = s.Mood.Import(userID, archive.Mood); err != nil {
+		return nil, fmt.Errorf("import mood: %w", err)
+	}
+	result.Skipped.Mood = len(archive.Mood) - result.Added.Mood
+
+	if s.Memory != nil {
+		a.remember(userID, result)
+	}
+
+	log.Printf("Archiver: Imported archive from user %s into user %s (%+v added)", archive.UserID, userID, result.Added)
+	return result, nil
+}
+
+// remember embeds the extracted items of sections the import added to.
+// Inputs are remembered by the input log itself. Items already remembered
+// are skipped by the memory service.
+func (a *Archiver) remember(userID string, result *Result) {
+	s := a.sources
+	var items []memory.Item
+	add := func(data any) {
+		if item, ok := memory.ItemFor(data); ok {
+			items = append(items, item)
+		}
+	}
+	if result.Added.Tasks > 0 {
+		list, _ := s.Tasks.List(userID, tasks.Filter{})
+		for i := range list {
+			add(&list[i])
+		}
+	}
+	if result.Added.Notes > 0 {
+		list, _ := s.Notes.List(userID, notes.Filter{})
+		for i := range list {
+			add(&list[i])
+		}
+	}
+	if result.Added.Goals > 0 {
+		list, _ := s.Goals.List(userID, "")
+		for i := range list {
+			add(&list[i])
+		}
+	}
+	if result.Added.Reminders > 0 {
+		list, _ := s.Reminders.List(userID, "")
+		for i := range list {
+			add(&list[i])
+		}
+	}
+	if err := s.Memory.Remember(userID, items...); err != nil {
+		// The import itself succeeded; memories catch up as items change
+		log.Printf("Archiver: Failed to remember imported items for user %s: %v", userID, err)
+	}
+}
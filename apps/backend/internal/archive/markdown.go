@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+const dateFormat = "Jan 2, 2006"
+
+// Markdown renders an archive for reading. It is not meant to be imported;
+// the JSON archive is the lossless format.
+func Markdown(archive *Archive) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Soul Mirror export for %s\n\n", archive.UserID)
+	fmt.Fprintf(&b, "Exported %s (format version %d).\n", archive.ExportedAt.Format(time.RFC1123), archive.Version)
+
+	b.WriteString("\n## Profile\n\n")
+	entries := profile.Entries(archive.Profile.Text)
+	if len(entries) == 0 {
+		b.WriteString("_Nothing learned yet._\n")
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "- %s\n", entry)
+	}
+	if len(archive.Profile.History) > 0 {
+		b.WriteString("\n### Profile history\n\n")
+		for _, event := range archive.Profile.History {
+			fmt.Fprintf(&b, "- %s — %s: %s\n", event.At.Format(dateFormat), event.Type, event.Summary)
+			for _, archived := range event.Archived {
+				fmt.Fprintf(&b, "  - archived: %s\n", archived)
+			}
+		}
+	}
+
+	if len(archive.Tasks) > 0 {
+		b.WriteString("\n## Tasks\n\n")
+		for _, task := range archive.Tasks {
+			check := " "
+			if task.Status == tasks.StatusDone {
+				check = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s (%s)", check, task.Title, task.Priority)
+			if task.DueAt != nil {
+				fmt.Fprintf(&b, ", due %s", task.DueAt.Format(dateFormat))
+			}
+			b.WriteString(tags(task.Tags) + "\n")
+		}
+	}
+
+	if len(archive.Goals) > 0 {
+		b.WriteString("\n## Goals\n")
+		for _, goal := range archive.Goals {
+			fmt.Fprintf(&b, "\n### %s (%s)\n", goal.Title, goal.Status)
+			if goal.Motivation != "" {
+				fmt.Fprintf(&b, "\n%s\n", goal.Motivation)
+			}
+			if len(goal.Milestones) > 0 || len(goal.Habits) > 0 {
+				b.WriteString("\n")
+			}
+			for _, milestone := range goal.Milestones {
+				check := " "
+				if milestone.Done {
+					check = "x"
+				}
+				fmt.Fprintf(&b, "- [%s] %s\n", check, milestone.Title)
+			}
+			for _, habit := range goal.Habits {
+				fmt.Fprintf(&b, "- Habit: %s (%s, %d check-ins)\n", habit.Title, habit.Frequency, checkIns(goal, habit.ID))
+			}
+		}
+	}
+
+	if len(archive.Notes) > 0 {
+		collections := make(map[string]string, len(archive.Collections))
+		for _, collection := range archive.Collections {
+			collections[collection.ID] = collection.Name
+		}
+		b.WriteString("\n## Notes\n")
+		for _, note := range archive.Notes {
+			fmt.Fprintf(&b, "\n### %s\n\n", note.Title)
+			fmt.Fprintf(&b, "_%s", note.CreatedAt.Format(dateFormat))
+			if name := collections[note.CollectionID]; name != "" {
+				fmt.Fprintf(&b, " · %s", name)
+			}
+			b.WriteString("_" + tags(note.Tags) + "\n\n")
+			fmt.Fprintf(&b, "%s\n", note.Content)
+		}
+	}
+
+	if len(archive.Reminders) > 0 {
+		b.WriteString("\n## Reminders\n\n")
+		for _, reminder := range archive.Reminders {
+			fmt.Fprintf(&b, "- %s — %s (%s)\n", reminder.FireAt.Format("Jan 2, 2006 15:04 MST"), reminder.Message, reminder.Status)
+		}
+	}
+
+	if len(archive.Mood) > 0 {
+		b.WriteString("\n## Mood\n\n")
+		for _, entry := range archive.Mood {
+			fmt.Fprintf(&b, "- %s — sentiment %+.1f, energy %.1f", entry.At.Format(dateFormat), entry.Sentiment, entry.Energy)
+			if len(entry.Emotions) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(entry.Emotions, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(archive.Inputs) > 0 {
+		b.WriteString("\n## Journal\n\n")
+		for _, record := range archive.Inputs {
+			fmt.Fprintf(&b, "- **%s** (%s): %s\n", record.At.Format("Jan 2, 2006 15:04"), record.Source, oneLine(record.Text))
+		}
+	}
+
+	if len(archive.Sessions) > 0 {
+		b.WriteString("\n## Conversations\n")
+		for _, session := range archive.Sessions {
+			fmt.Fprintf(&b, "\n### %s\n\n", session.Title)
+			for _, turn := range session.Turns {
+				fmt.Fprintf(&b, "- **%s**: %s\n", turn.Role, oneLine(turn.Content))
+			}
+		}
+	}
+	return b.String()
+}
+
+func tags(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return " #" + strings.Join(list, " #")
+}
+
+func checkIns(goal goals.Goal, habitID string) int {
+	count := 0
+	for _, checkIn := range goal.CheckIns {
+		if checkIn.HabitID == habitID {
+			count++
+		}
+	}
+	return count
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,492 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "calendar"
+
+// MaxCalendarBytes caps the size of an imported file or fetched feed.
+const MaxCalendarBytes = 5 << 20
+
+var (
+	ErrNotFound = errors.New("calendar source not found")
+	ErrInvalid  = errors.New("invalid calendar")
+	ErrFetch    = errors.New("calendar feed unavailable")
+)
+
+type SourceKind string
+
+const (
+	SourceFile SourceKind = "file"
+	SourceFeed SourceKind = "feed"
+)
+
+// Source is an imported file or a subscribed feed; its events are
+// replaced wholesale on every refresh.
+type Source struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	Name         string     `json:"name"`
+	Kind         SourceKind `json:"kind"`
+	URL          string     `json:"url,omitempty"`
+	EventCount   int        `json:"event_count"`
+	LastSyncedAt time.Time  `json:"last_synced_at"`
+	LastError    string     `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Event is a VEVENT as stored. Floating events (no timezone in the file,
+// and all-day events) keep their wall clock in UTC and are read in the
+// user's timezone.
+type Event struct {
+	ID           string      `json:"id"`
+	SourceID     string      `json:"source_id"`
+	UID          string      `json:"uid"`
+	Summary      string      `json:"summary"`
+	Description  string      `json:"description,omitempty"`
+	Location     string      `json:"location,omitempty"`
+	Status       string      `json:"status,omitempty"`
+	Start        time.Time   `json:"start"`
+	End          time.Time   `json:"end"`
+	AllDay       bool        `json:"all_day,omitempty"`
+	Floating     bool        `json:"floating,omitempty"`
+	TZID         string      `json:"tzid,omitempty"`
+	RRule        string      `json:"rrule,omitempty"`
+	ExDates      []time.Time `json:"exdates,omitempty"`
+	RecurrenceID *time.Time  `json:"recurrence_id,omitempty"`
+}
+
+// Occurrence is one concrete instance of an event.
+type Occurrence struct {
+	EventID     string    `json:"event_id"`
+	SourceID    string    `json:"source_id"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"all_day,omitempty"`
+	Recurring   bool      `json:"recurring,omitempty"`
+}
+
+// Locator resolves a user's timezone.
+type Locator interface {
+	Location(userID string) (*time.Location, error)
+}
+
+type CalendarService interface {
+	Import(userID, name string, data io.Reader) (*Source, error)
+	Subscribe(userID, name, url string) (*Source, error)
+	Refresh(userID, sourceID string) (*Source, error)
+	// RefreshStale refreshes every feed last synced more than maxAge ago.
+	RefreshStale(now time.Time, maxAge time.Duration)
+	Sources(userID string) ([]Source, error)
+	RemoveSource(userID, sourceID string) error
+	// Upcoming returns event occurrences overlapping [from, to), soonest first.
+	Upcoming(userID string, from, to time.Time) ([]Occurrence, error)
+	// Purge deletes the user's sources and the events fetched from them,
+	// returning how many sources and events there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type userCalendar struct {
+	Sources []Source `json:"sources"`
+	Events  []Event  `json:"events"`
+}
+
+type service struct {
+	store   storage.Store
+	fetcher *Fetcher
+	locator Locator
+	users   map[string]*userCalendar
+	mutex   sync.RWMutex
+}
+
+func NewService(store storage.Store, fetcher *Fetcher, locator Locator) (CalendarService, error) {
+	s := &service{
+		store:   store,
+		fetcher: fetcher,
+		locator: locator,
+		users:   make(map[string]*userCalendar),
+	}
+	if err := store.Load(storeName, &s.users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load calendar: %w", err)
+	}
+	log.Printf("CalendarService: Loaded calendars for %d users", len(s.users))
+	return s, nil
+}
+
+func (s *service) Import(userID, name string, data io.Reader) (*Source, error) {
+	events, err := parseLimited(data)
+	if err != nil {
+		return nil, err
+	}
+
+	source := Source{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		Name:      defaultName(name, "Imported calendar"),
+		Kind:      SourceFile,
+		CreatedAt: time.Now(),
+	}
+	return s.addSource(source, events)
+}
+
+func (s *service) Subscribe(userID, name, url string) (*Source, error) {
+	if s.fetcher == nil {
+		return nil, fmt.Errorf("%w: feed subscriptions are disabled", ErrInvalid)
+	}
+	resolved, err := s.fetcher.Resolve(url)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.fetchEvents(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	source := Source{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		Name:      defaultName(name, resolved),
+		Kind:      SourceFeed,
+		URL:       resolved,
+		CreatedAt: time.Now(),
+	}
+	return s.addSource(source, events)
+}
+
+func (s *service) Refresh(userID, sourceID string) (*Source, error) {
+	s.mutex.RLock()
+	source, exists := s.findSource(userID, sourceID)
+	s.mutex.RUnlock()
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if source.Kind != SourceFeed {
+		return nil, fmt.Errorf("%w: only feeds can be refreshed", ErrInvalid)
+	}
+	if s.fetcher == nil {
+		return nil, fmt.Errorf("%w: feed subscriptions are disabled", ErrInvalid)
+	}
+
+	events, fetchErr := s.fetchEvents(source.URL)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user := s.users[userID]
+	idx := sourceIndex(user, sourceID)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	current := &user.Sources[idx]
+	current.LastSyncedAt = time.Now()
+	if fetchErr != nil {
+		// Keep the previous events; a flaky feed should not wipe the calendar.
+		current.LastError = fetchErr.Error()
+	} else {
+		current.LastError = ""
+		current.EventCount = len(events)
+		user.Events = replaceEvents(user.Events, sourceID, events)
+	}
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	result := *current
+	if fetchErr != nil {
+		log.Printf("CalendarService: Failed to refresh %s for user %s: %v", sourceID, userID, fetchErr)
+		return &result, fetchErr
+	}
+	log.Printf("CalendarService: Refreshed %s for user %s (%d events)", sourceID, userID, len(events))
+	return &result, nil
+}
+
+func (s *service) RefreshStale(now time.Time, maxAge time.Duration) {
+	type pending struct{ userID, sourceID string }
+	var stale []pending
+
+	s.mutex.RLock()
+	for userID, user := range s.users {
+		for _, source := range user.Sources {
+			if source.Kind == SourceFeed && now.Sub(source.LastSyncedAt) >= maxAge {
+				stale = append(stale, pending{userID, source.ID})
+			}
+		}
+	}
+	s.mutex.RUnlock()
+
+	for _, p := range stale {
+		// Errors are recorded on the source and logged by Refresh.
+		s.Refresh(p.userID, p.sourceID)
+	}
+}
+
+func (s *service) Sources(userID string) ([]Source, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Source, 0)
+	if user, exists := s.users[userID]; exists {
+		result = append(result, user.Sources...)
+	}
+	return result, nil
+}
+
+func (s *service) RemoveSource(userID, sourceID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user := s.users[userID]
+	idx := sourceIndex(user, sourceID)
+	if idx == -1 {
+		return ErrNotFound
+	}
+	user.Sources = append(user.Sources[:idx], user.Sources[idx+1:]...)
+	user.Events = replaceEvents(user.Events, sourceID, nil)
+	if err := s.persist(); err != nil {
+		return err
+	}
+
+	log.Printf("CalendarService: Removed source %s for user %s", sourceID, userID)
+	return nil
+}
+
+func (s *service) Upcoming(userID string, from, to time.Time) ([]Occurrence, error) {
+	loc := time.UTC
+	if s.locator != nil {
+		userLoc, err := s.locator.Location(userID)
+		if err != nil {
+			return nil, err
+		}
+		loc = userLoc
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Occurrence, 0)
+	user, exists := s.users[userID]
+	if !exists {
+		return result, nil
+	}
+
+	// Instances moved or edited individually replace the generated ones.
+	overridden := make(map[string]map[int64]bool)
+	for _, event := range user.Events {
+		if event.RecurrenceID != nil {
+			key := event.SourceID + "/" + event.UID
+			if overridden[key] == nil {
+				overridden[key] = make(map[int64]bool)
+			}
+			overridden[key][place(*event.RecurrenceID, event, loc).Unix()] = true
+		}
+	}
+
+	for _, event := range user.Events {
+		start := place(event.Start, event, loc)
+		duration := place(event.End, event, loc).Sub(start)
+
+		if event.RRule == "" || event.RecurrenceID != nil {
+			if start.Before(to) && start.Add(duration).After(from) {
+				result = append(result, occurrence(event, start.In(loc), duration, false))
+			}
+			continue
+		}
+
+		rule, err := ParseRule(event.RRule)
+		if err != nil {
+			continue
+		}
+		excluded := make(map[int64]bool)
+		for _, exdate := range event.ExDates {
+			excluded[place(exdate, event, loc).Unix()] = true
+		}
+		for _, instance := range rule.Occurrences(start, duration, from, to) {
+			if excluded[instance.Unix()] || overridden[event.SourceID+"/"+event.UID][instance.Unix()] {
+				continue
+			}
+			result = append(result, occurrence(event, instance.In(loc), duration, true))
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return 0, nil
+	}
+	delete(s.users, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("CalendarService: Purged %d sources and %d events for user %s", len(user.Sources), len(user.Events), userID)
+	return len(user.Sources) + len(user.Events), nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return 0, nil
+	}
+	return len(user.Sources) + len(user.Events), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.users))
+	for userID := range s.users {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (s *service) addSource(source Source, events []Event) (*Source, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[source.UserID]
+	if !exists {
+		user = &userCalendar{}
+		s.users[source.UserID] = user
+	}
+	source.EventCount = len(events)
+	source.LastSyncedAt = time.Now()
+	user.Sources = append(user.Sources, source)
+	user.Events = replaceEvents(user.Events, source.ID, events)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("CalendarService: Added %s source %s for user %s with %d events", source.Kind, source.ID, source.UserID, len(events))
+	return &source, nil
+}
+
+func (s *service) fetchEvents(url string) ([]Event, error) {
+	data, err := s.fetcher.Fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	return parseLimited(bytes.NewReader(data))
+}
+
+func (s *service) findSource(userID, sourceID string) (Source, bool) {
+	user := s.users[userID]
+	idx := sourceIndex(user, sourceID)
+	if idx == -1 {
+		return Source{}, false
+	}
+	return user.Sources[idx], true
+}
+
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.users); err != nil {
+		return fmt.Errorf("save calendar: %w", err)
+	}
+	return nil
+}
+
+func parseLimited(data io.Reader) ([]Event, error) {
+	limited := io.LimitReader(data, MaxCalendarBytes+1)
+	buf, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	if len(buf) > MaxCalendarBytes {
+		return nil, fmt.Errorf("%w: calendar larger than %d bytes", ErrInvalid, MaxCalendarBytes)
+	}
+	return ParseICS(bytes.NewReader(buf))
+}
+
+// replaceEvents drops sourceID's events from all and appends events,
+// assigning them IDs under that source.
+func replaceEvents(all []Event, sourceID string, events []Event) []Event {
+	kept := make([]Event, 0, len(all)+len(events))
+	for _, event := range all {
+		if event.SourceID != sourceID {
+			kept = append(kept, event)
+		}
+	}
+	for _, event := range events {
+		event.ID = storage.NewID()
+		event.SourceID = sourceID
+		kept = append(kept, event)
+	}
+	return kept
+}
+
+func sourceIndex(user *userCalendar, sourceID string) int {
+	if user == nil {
+		return -1
+	}
+	for i, source := range user.Sources {
+		if source.ID == sourceID {
+			return i
+		}
+	}
+	return -1
+}
+
+// place puts one of event's times in the zone its recurrences are computed
+// in: the event's TZID, or the user's zone for floating times, which keep
+// their wall clock.
+func place(t time.Time, event Event, userLoc *time.Location) time.Time {
+	if event.Floating {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, userLoc)
+	}
+	if event.TZID != "" {
+		if loc, err := time.LoadLocation(event.TZID); err == nil {
+			return t.In(loc)
+		}
+	}
+	return t.In(time.UTC)
+}
+
+func occurrence(event Event, start time.Time, duration time.Duration, recurring bool) Occurrence {
+	return Occurrence{
+		EventID:     event.ID,
+		SourceID:    event.SourceID,
+		Summary:     event.Summary,
+		Description: event.Description,
+		Location:    event.Location,
+		Start:       start,
+		End:         start.Add(duration),
+		AllDay:      event.AllDay,
+		Recurring:   recurring,
+	}
+}
+
+func defaultName(name, fallback string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return fallback
+}
@@ -0,0 +1,128 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/netutil"
+)
+
+// DefaultFetchTimeout bounds a single feed download.
+const DefaultFetchTimeout = 30 * time.Second
+
+// maxRedirects is how many redirects a feed download follows.
+const maxRedirects = 5
+
+type FetcherConfig struct {
+	// BaseURL, when set, is where every feed must live.
+	BaseURL string
+	Timeout time.Duration
+	// AllowPrivate permits feeds on loopback and private networks. Leave
+	// it off in production so feed URLs can't reach internal services.
+	AllowPrivate bool
+}
+
+// Fetcher downloads ICS feeds. When a base URL is configured, feeds are
+// resolved against it and must live under it; this keeps subscriptions
+// pointed at a known calendar endpoint (or a local server in tests).
+// Redirects are checked the same way, hop by hop.
+type Fetcher struct {
+	client  *http.Client
+	baseURL *url.URL
+}
+
+func NewFetcher(config FetcherConfig) (*Fetcher, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultFetchTimeout
+	}
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivate {
+		dialer.Control = netutil.DenyPrivate
+	}
+	f := &Fetcher{}
+	f.client = &http.Client{
+		Timeout:   config.Timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: http.ProxyFromEnvironment},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.check(req.URL)
+		},
+	}
+	if config.BaseURL != "" {
+		parsed, err := url.Parse(config.BaseURL)
+		if err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid calendar feed base URL %q", config.BaseURL)
+		}
+		if !strings.HasSuffix(parsed.Path, "/") {
+			parsed.Path += "/"
+		}
+		f.baseURL = parsed
+	}
+	return f, nil
+}
+
+// Resolve normalizes a feed URL (webcal:// becomes https://) and checks it
+// against the base URL.
+func (f *Fetcher) Resolve(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(strings.ToLower(raw), "webcal://") {
+		raw = "https://" + raw[len("webcal://"):]
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return "", fmt.Errorf("%w: bad feed URL %q", ErrInvalid, raw)
+	}
+	if f.baseURL != nil {
+		parsed = f.baseURL.ResolveReference(parsed)
+	}
+	if err := f.check(parsed); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return parsed.String(), nil
+}
+
+// check tells whether an absolute URL may be fetched.
+func (f *Fetcher) check(target *url.URL) error {
+	if f.baseURL != nil && (target.Scheme != f.baseURL.Scheme || target.Host != f.baseURL.Host || !strings.HasPrefix(target.Path, f.baseURL.Path)) {
+		return fmt.Errorf("feed URL must be under %s", f.baseURL)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return errors.New("feed URL must be http(s)")
+	}
+	if target.Host == "" {
+		return errors.New("feed URL must have a host")
+	}
+	return nil
+}
+
+func (f *Fetcher) Fetch(feedURL string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar, */*;q=0.5")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrFetch, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxCalendarBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	return data, nil
+}
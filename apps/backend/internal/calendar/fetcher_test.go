@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetcherResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"relative to base", "https://cal.example.com/feeds", "team.ics", "https://cal.example.com/feeds/team.ics", false},
+		{"absolute under base", "https://cal.example.com/feeds/", "https://cal.example.com/feeds/a.ics", "https://cal.example.com/feeds/a.ics", false},
+		{"webcal under base", "https://cal.example.com/feeds/", "webcal://cal.example.com/feeds/a.ics", "https://cal.example.com/feeds/a.ics", false},
+		{"other host", "https://cal.example.com/feeds/", "https://evil.example.com/feeds/a.ics", "", true},
+		{"other path", "https://cal.example.com/feeds/", "/admin/a.ics", "", true},
+		{"scheme downgrade", "https://cal.example.com/feeds/", "http://cal.example.com/feeds/a.ics", "", true},
+		{"any public url", "", "https://calendar.example.org/a.ics", "https://calendar.example.org/a.ics", false},
+		{"webcal", "", "webcal://calendar.example.org/a.ics", "https://calendar.example.org/a.ics", false},
+		{"file", "", "file:///etc/passwd", "", true},
+		{"no host", "", "https:///a.ics", "", true},
+		{"empty", "", "  ", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher, err := NewFetcher(FetcherConfig{BaseURL: tt.baseURL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := fetcher.Resolve(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("Resolve(%q) = %q, %v, want ErrInvalid", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetcherFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feeds/a.ics":
+			w.Write([]byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+		case "/feeds/moved.ics":
+			http.Redirect(w, r, "/feeds/a.ics", http.StatusFound)
+		case "/feeds/escape.ics":
+			http.Redirect(w, r, "/internal/secrets", http.StatusFound)
+		case "/feeds/loop.ics":
+			http.Redirect(w, r, "/feeds/loop.ics", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		config  FetcherConfig
+		path    string
+		wantErr bool
+	}{
+		{"private address refused", FetcherConfig{}, "/feeds/a.ics", true},
+		{"private address allowed", FetcherConfig{AllowPrivate: true}, "/feeds/a.ics", false},
+		{"redirect under base", FetcherConfig{BaseURL: server.URL + "/feeds/", AllowPrivate: true}, "/feeds/moved.ics", false},
+		{"redirect out of base", FetcherConfig{BaseURL: server.URL + "/feeds/", AllowPrivate: true}, "/feeds/escape.ics", true},
+		{"redirect loop", FetcherConfig{AllowPrivate: true}, "/feeds/loop.ics", true},
+		{"not found", FetcherConfig{AllowPrivate: true}, "/missing.ics", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher, err := NewFetcher(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := fetcher.Fetch(server.URL + tt.path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrFetch) {
+					t.Fatalf("Fetch = %q, %v, want ErrFetch", data, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if len(data) == 0 {
+				t.Fatal("Fetch returned no data")
+			}
+		})
+	}
+}
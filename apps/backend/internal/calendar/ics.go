@@ -0,0 +1,275 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineBytes bounds a single unfolded ICS content line.
+const maxLineBytes = 1 << 20
+
+// property is one parsed content line, e.g.
+// DTSTART;TZID=Europe/Berlin:20261020T150000.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICS reads the VEVENTs of an iCalendar stream. Times with a TZID are
+// resolved in that zone; floating times are marked so they can be read in
+// the user's timezone later. Cancelled events are dropped.
+func ParseICS(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("%w: not an iCalendar file", ErrInvalid)
+	}
+
+	var events []Event
+	var current []property
+	inEvent := false
+	depth := 0
+	for _, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			inEvent, current, depth = true, nil, 0
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if !inEvent {
+				continue
+			}
+			inEvent = false
+			event, err := buildEvent(current)
+			if err != nil {
+				return nil, err
+			}
+			if event.Status != "CANCELLED" {
+				events = append(events, event)
+			}
+		case inEvent && prop.name == "BEGIN":
+			// Nested components such as VALARM carry their own
+			// properties that must not leak into the event.
+			depth++
+		case inEvent && prop.name == "END":
+			depth--
+		case inEvent && depth == 0:
+			current = append(current, prop)
+		}
+	}
+	return events, nil
+}
+
+// unfold joins continuation lines (RFC 5545 section 3.1).
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseProperty(line string) (property, bool) {
+	// The value starts at the first colon outside a quoted parameter.
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return property{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop, true
+}
+
+func buildEvent(props []property) (Event, error) {
+	var event Event
+	var duration time.Duration
+	hasEnd := false
+
+	for _, prop := range props {
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "LOCATION":
+			event.Location = unescapeText(prop.value)
+		case "STATUS":
+			event.Status = strings.ToUpper(prop.value)
+		case "DTSTART":
+			t, allDay, floating, err := parseDateTime(prop)
+			if err != nil {
+				return Event{}, err
+			}
+			event.Start, event.AllDay, event.Floating = t, allDay, floating
+			event.TZID = prop.params["TZID"]
+		case "DTEND":
+			t, _, _, err := parseDateTime(prop)
+			if err != nil {
+				return Event{}, err
+			}
+			event.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(prop.value)
+			if err != nil {
+				return Event{}, err
+			}
+			duration = d
+		case "RRULE":
+			if _, err := ParseRule(prop.value); err != nil {
+				return Event{}, err
+			}
+			event.RRule = prop.value
+		case "EXDATE":
+			for _, value := range strings.Split(prop.value, ",") {
+				t, _, _, err := parseDateTime(property{params: prop.params, value: value})
+				if err != nil {
+					return Event{}, err
+				}
+				event.ExDates = append(event.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			t, _, _, err := parseDateTime(prop)
+			if err != nil {
+				return Event{}, err
+			}
+			event.RecurrenceID = &t
+		}
+	}
+
+	if event.Start.IsZero() {
+		return Event{}, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalid, event.Summary)
+	}
+	switch {
+	case hasEnd:
+	case duration > 0:
+		event.End = event.Start.Add(duration)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	if event.UID == "" {
+		event.UID = fmt.Sprintf("%s-%d", event.Summary, event.Start.Unix())
+	}
+	return event, nil
+}
+
+// parseDateTime reads DATE and DATE-TIME values. Floating times and dates
+// are returned in UTC with floating set; their wall clock is what counts.
+func parseDateTime(prop property) (t time.Time, allDay, floating bool, err error) {
+	value := strings.TrimSpace(prop.value)
+	if prop.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err = time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, false, fmt.Errorf("%w: bad date %q", ErrInvalid, value)
+		}
+		return t, true, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, false, fmt.Errorf("%w: bad date-time %q", ErrInvalid, value)
+		}
+		return t, false, false, nil
+	}
+
+	loc := time.UTC
+	floating = true
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc, floating = zone, false
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, false, fmt.Errorf("%w: bad date-time %q", ErrInvalid, value)
+	}
+	return t, false, floating, nil
+}
+
+// parseDuration reads RFC 5545 durations such as PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("%w: bad duration %q", ErrInvalid, value)
+	}
+
+	var total time.Duration
+	inTime := false
+	number := 0
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		case r == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("%w: bad duration %q", ErrInvalid, value)
+		}
+		number = 0
+	}
+	return total, nil
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
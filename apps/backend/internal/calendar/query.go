@@ -0,0 +1,87 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+)
+
+// Range is the span of the calendar a question asks about.
+type Range struct {
+	From     time.Time
+	To       time.Time
+	NextOnly bool
+	Label    string
+}
+
+// nextEventHorizon is how far ahead "what's next" looks.
+const nextEventHorizon = 30 * 24 * time.Hour
+
+var nextPhrases = []string{"next meeting", "next event", "what's next", "whats next", "next appointment", "next call"}
+
+// ParseRange reads the span a calendar question refers to ("today",
+// "tomorrow", "this weekend", "on friday", "my next meeting"), defaulting
+// to the next 24 hours.
+func ParseRange(input string, now time.Time, loc *time.Location) Range {
+	lower := strings.ToLower(input)
+	now = now.In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	day := func(offset int) (time.Time, time.Time) {
+		start := midnight.AddDate(0, 0, offset)
+		return start, start.AddDate(0, 0, 1)
+	}
+
+	for _, phrase := range nextPhrases {
+		if strings.Contains(lower, phrase) {
+			return Range{From: now, To: now.Add(nextEventHorizon), NextOnly: true, Label: "next event"}
+		}
+	}
+
+	switch {
+	case strings.Contains(lower, "tomorrow"):
+		from, to := day(1)
+		return Range{From: from, To: to, Label: "tomorrow"}
+	case strings.Contains(lower, "today"), strings.Contains(lower, "tonight"):
+		_, to := day(0)
+		return Range{From: now, To: to, Label: "today"}
+	case strings.Contains(lower, "weekend"):
+		offset := (int(time.Saturday) - int(now.Weekday()) + 7) % 7
+		if now.Weekday() == time.Sunday {
+			offset = -1
+		}
+		from, _ := day(offset)
+		if from.Before(now) {
+			from = now
+		}
+		_, to := day(offset + 1)
+		return Range{From: from, To: to, Label: "this weekend"}
+	case strings.Contains(lower, "week"):
+		return Range{From: now, To: midnight.AddDate(0, 0, 7), Label: "the next 7 days"}
+	}
+
+	for offset := 0; offset < 7; offset++ {
+		from, to := day(offset)
+		if strings.Contains(lower, strings.ToLower(from.Weekday().String())) {
+			if offset == 0 {
+				from = now
+			}
+			return Range{From: from, To: to, Label: from.Weekday().String()}
+		}
+	}
+
+	return Range{From: now, To: now.Add(24 * time.Hour), Label: "the next 24 hours"}
+}
+
+// Describe formats an occurrence for replies, in loc.
+func Describe(occurrence Occurrence, loc *time.Location) string {
+	var when string
+	if occurrence.AllDay {
+		when = occurrence.Start.In(loc).Format("Mon Jan 2") + " (all day)"
+	} else {
+		when = occurrence.Start.In(loc).Format("Mon Jan 2, 3:04 PM") + "–" + occurrence.End.In(loc).Format("3:04 PM")
+	}
+	result := when + ": " + occurrence.Summary
+	if occurrence.Location != "" {
+		result += " @ " + occurrence.Location
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods bounds how many periods (days, weeks, months or years) a rule
+// is walked, so a malformed or very old rule cannot stall a request.
+const maxPeriods = 20000
+
+type Freq string
+
+const (
+	FreqDaily   Freq = "DAILY"
+	FreqWeekly  Freq = "WEEKLY"
+	FreqMonthly Freq = "MONTHLY"
+	FreqYearly  Freq = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry; N is the ordinal within the month or year
+// (e.g. -1 for "last"), or 0 for every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is the subset of RFC 5545 RRULE that calendar apps commonly emit:
+// FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH.
+type Rule struct {
+	Freq       Freq
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func ParseRule(value string) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "RRULE:"), ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		val = strings.ToUpper(val)
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch Freq(val) {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = Freq(val)
+			default:
+				return nil, fmt.Errorf("%w: unsupported recurrence frequency %q", ErrInvalid, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: bad INTERVAL %q", ErrInvalid, val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: bad COUNT %q", ErrInvalid, val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, _, _, err := parseDateTime(property{value: val})
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				if len(code) < 2 {
+					return nil, fmt.Errorf("%w: bad BYDAY %q", ErrInvalid, code)
+				}
+				day, ok := weekdayCodes[code[len(code)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("%w: bad BYDAY %q", ErrInvalid, code)
+				}
+				n := 0
+				if prefix := code[:len(code)-2]; prefix != "" {
+					var err error
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("%w: bad BYDAY %q", ErrInvalid, code)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			for _, s := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(s)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%w: bad BYMONTHDAY %q", ErrInvalid, s)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, s := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(s)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("%w: bad BYMONTH %q", ErrInvalid, s)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: RRULE without FREQ", ErrInvalid)
+	}
+	return rule, nil
+}
+
+// Occurrences returns the start times of the rule's instances that overlap
+// [from, to), given the first instance start and each instance's duration.
+// Instance wall-clock times follow start's location, so they stay put
+// across daylight saving changes.
+func (r *Rule) Occurrences(start time.Time, duration time.Duration, from, to time.Time) []time.Time {
+	var result []time.Time
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.candidates(start, period)
+		if len(candidates) > 0 && candidates[0].After(to) {
+			break
+		}
+		for _, candidate := range candidates {
+			if candidate.Before(start) {
+				continue
+			}
+			if r.Until != nil && candidate.After(*r.Until) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if candidate.Before(to) && candidate.Add(duration).After(from) {
+				result = append(result, candidate)
+			}
+		}
+	}
+	return result
+}
+
+// candidates lists the instance starts in the period-th period after the
+// one containing start, in chronological order.
+func (r *Rule) candidates(start time.Time, period int) []time.Time {
+	loc := start.Location()
+	h, m, s := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, h, m, s, 0, loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		day := at(start.Year(), start.Month(), start.Day()+period*r.Interval)
+		if r.matchesMonth(day.Month()) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			days = append(days, day)
+		}
+
+	case FreqWeekly:
+		// Weeks start on Monday (the RFC 5545 default WKST).
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(start.Year(), start.Month(), start.Day()-offset+7*period*r.Interval)
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, wd := range r.ByDay {
+				weekdays = append(weekdays, wd.Day)
+			}
+		}
+		for _, wd := range weekdays {
+			day := at(monday.Year(), monday.Month(), monday.Day()+(int(wd)+6)%7)
+			if r.matchesMonth(day.Month()) {
+				days = append(days, day)
+			}
+		}
+
+	case FreqMonthly:
+		first := at(start.Year(), start.Month()+time.Month(period*r.Interval), 1)
+		if r.matchesMonth(first.Month()) {
+			days = r.daysInMonth(first, start.Day(), at)
+		}
+
+	case FreqYearly:
+		year := start.Year() + period*r.Interval
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			days = append(days, r.daysInMonth(at(year, month, 1), start.Day(), at)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// daysInMonth expands BYMONTHDAY/BYDAY within the month starting at first,
+// defaulting to defaultDay (skipped when the month is too short).
+func (r *Rule) daysInMonth(first time.Time, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	length := at(year, month+1, 0).Day()
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			day := n
+			if n < 0 {
+				day = length + n + 1
+			}
+			if day >= 1 && day <= length {
+				days = append(days, at(year, month, day))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []int
+			for day := 1; day <= length; day++ {
+				if at(year, month, day).Weekday() == wd.Day {
+					matches = append(matches, day)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				for _, day := range matches {
+					days = append(days, at(year, month, day))
+				}
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, at(year, month, matches[wd.N-1]))
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, at(year, month, matches[len(matches)+wd.N]))
+			}
+		}
+	default:
+		if defaultDay <= length {
+			days = append(days, at(year, month, defaultDay))
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, n := range r.ByMonthDay {
+		if n == day.Day() || (n < 0 && length+n+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
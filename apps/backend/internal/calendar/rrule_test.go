@@ -0,0 +1,138 @@
+package calendar
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", false},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", false},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1;UNTIL=20300101T000000Z", false},
+		{"INTERVAL=2", true},
+		{"FREQ=HOURLY", true},
+		{"FREQ=DAILY;INTERVAL=0", true},
+		{"FREQ=DAILY;COUNT=-1", true},
+		{"FREQ=WEEKLY;BYDAY=XX", true},
+		{"FREQ=MONTHLY;BYDAY=1", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", true},
+		{"FREQ=YEARLY;BYMONTH=13", true},
+		{"FREQ=DAILY;UNTIL=tomorrow", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, err := ParseRule(tt.value)
+			if tt.wantErr && !errors.Is(err, ErrInvalid) {
+				t.Fatalf("ParseRule(%q) = %v, want ErrInvalid", tt.value, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.value, err)
+			}
+		})
+	}
+}
+
+func TestRuleOccurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	date := func(loc *time.Location, year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		duration time.Duration
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name:  "daily with interval and count",
+			rule:  "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start: date(time.UTC, 2024, 1, 1, 9),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2024, 2, 1, 0),
+			want: []string{"2024-01-01 09:00", "2024-01-03 09:00", "2024-01-05 09:00"},
+		},
+		{
+			name:  "weekly on several days until a date",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE,FR;UNTIL=20240110T235959Z",
+			start: date(time.UTC, 2024, 1, 1, 9),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2024, 2, 1, 0),
+			want: []string{"2024-01-01 09:00", "2024-01-03 09:00", "2024-01-05 09:00", "2024-01-08 09:00", "2024-01-10 09:00"},
+		},
+		{
+			name:  "last friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: date(time.UTC, 2024, 1, 26, 18),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2025, 1, 1, 0),
+			want: []string{"2024-01-26 18:00", "2024-02-23 18:00", "2024-03-29 18:00"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: date(time.UTC, 2024, 1, 31, 8),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2025, 1, 1, 0),
+			want: []string{"2024-01-31 08:00", "2024-03-31 08:00", "2024-05-31 08:00"},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			start: date(time.UTC, 2024, 1, 31, 8),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2025, 1, 1, 0),
+			want: []string{"2024-01-31 08:00", "2024-02-29 08:00", "2024-03-31 08:00"},
+		},
+		{
+			name:  "leap day yearly",
+			rule:  "FREQ=YEARLY;COUNT=2",
+			start: date(time.UTC, 2024, 2, 29, 12),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2030, 1, 1, 0),
+			want: []string{"2024-02-29 12:00", "2028-02-29 12:00"},
+		},
+		{
+			name:  "wall clock kept across daylight saving",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: date(berlin, 2024, 3, 25, 9),
+			from:  date(berlin, 2024, 3, 1, 0), to: date(berlin, 2024, 5, 1, 0),
+			want: []string{"2024-03-25 09:00", "2024-04-01 09:00", "2024-04-08 09:00"},
+		},
+		{
+			name:     "only instances overlapping the window",
+			rule:     "FREQ=DAILY",
+			start:    date(time.UTC, 2024, 1, 1, 23),
+			duration: 2 * time.Hour,
+			from:     date(time.UTC, 2024, 1, 10, 0), to: date(time.UTC, 2024, 1, 12, 0),
+			want: []string{"2024-01-09 23:00", "2024-01-10 23:00", "2024-01-11 23:00"},
+		},
+		{
+			name:  "window before the start",
+			rule:  "FREQ=DAILY",
+			start: date(time.UTC, 2024, 6, 1, 9),
+			from:  date(time.UTC, 2024, 1, 1, 0), to: date(time.UTC, 2024, 2, 1, 0),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, occurrence := range rule.Occurrences(tt.start, tt.duration, tt.from, tt.to) {
+				got = append(got, occurrence.In(tt.start.Location()).Format("2006-01-02 15:04"))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Occurrences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package calendar
+
+import (
+	"log"
+	"time"
+)
+
+// Syncer periodically refreshes subscribed feeds in the background.
+type Syncer struct {
+	service  CalendarService
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewSyncer(service CalendarService, interval time.Duration) *Syncer {
+	return &Syncer{service: service, interval: interval}
+}
+
+func (s *Syncer) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	log.Printf("CalendarSyncer: Refreshing feeds every %s", s.interval)
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.service.RefreshStale(time.Now(), s.interval)
+		for {
+			select {
+			case <-ticker.C:
+				s.service.RefreshStale(time.Now(), s.interval)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Syncer) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
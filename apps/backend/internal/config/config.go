@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,105 @@ type Config struct {
 	AnthropicAPIKey string
 	Port            string
 	Environment     string
+	DataDir         string
+	DefaultTimezone string
+
+	CalendarFeedBaseURL  string
+	CalendarSyncInterval time.Duration
+	CalendarAllowPrivate bool
+
+	Transcriber          string
+	WhisperURL           string
+	WhisperBinary        string
+	WhisperModel         string
+	WhisperLanguage      string
+	FFmpegBinary         string
+	TranscriptionTimeout time.Duration
+
+	TelegramBotToken      string
+	TelegramAPIBaseURL    string
+	TelegramMode          string
+	TelegramWebhookURL    string
+	TelegramWebhookSecret string
+	TelegramPollTimeout   time.Duration
+
+	// InboundWebhookSecrets are accepted for signed inbound messages; more
+	// than one allows rotating without downtime.
+	InboundWebhookSecrets   []string
+	InboundWebhookTolerance time.Duration
+
+	WebhookRetryInterval time.Duration
+	WebhookTimeout       time.Duration
+	WebhookMaxAttempts   int
+	WebhookBackoff       time.Duration
+	WebhookAllowPrivate  bool
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// SMTPSecurity is "starttls", "tls" or "none".
+	SMTPSecurity        string
+	DigestWeekday       string
+	DigestHour          int
+	DigestCheckInterval time.Duration
+
+	// Embedder is "hashing" (offline), "openai" or "none".
+	Embedder            string
+	EmbeddingsURL       string
+	EmbeddingsAPIKey    string
+	EmbeddingsModel     string
+	EmbeddingDimensions int
+	EmbeddingsTimeout   time.Duration
+	MemoryTopK          int
+	MemoryMinScore      float64
+
+	ProfileMaxChars  int
+	ProfileMaxTokens int
+	// ProfileCompactionInterval is how often profiles are checked against
+	// the limits; zero disables background compaction.
+	ProfileCompactionInterval time.Duration
+
+	// EncryptionKey is the master key for encryption at rest, base64 or
+	// hex; EncryptionKeyFile names a file holding it instead. Previous keys
+	// are accepted while data keys are rewrapped with the current one.
+	EncryptionKey          string
+	EncryptionKeyFile      string
+	EncryptionPreviousKeys []string
+
+	// RedactPII replaces personal data with placeholders before any text
+	// goes to the LLM. RedactionRules picks the pattern rules (all when
+	// empty); RedactionTerms are dictionary terms such as names of friends.
+	RedactPII      bool
+	RedactionRules []string
+	RedactionTerms []string
+
+	// Requests are counted per API key, user or IP in token buckets that
+	// refill at the given number of requests per window. Endpoints that
+	// make LLM calls also count against the stricter LLM limit.
+	RateLimitEnabled     bool
+	RateLimitWindow      time.Duration
+	RateLimitRequests    int
+	RateLimitBurst       int
+	LLMRateLimitRequests int
+	LLMRateLimitBurst    int
+	TrustedProxies       []string
+
+	// JobWorkers bounds how many queued inputs are processed at once;
+	// finished jobs are kept for JobRetention.
+	JobWorkers   int
+	JobRetention time.Duration
+
+	// ToolWorkers bounds how many of the tools selected for one input run
+	// at once; each may take up to ToolTimeout.
+	ToolWorkers int
+	ToolTimeout time.Duration
+
+	SchedulerEnabled  bool
+	SchedulerInterval time.Duration
+	DispatchInterval  time.Duration
+	NudgeMinGap       time.Duration
 }
 
 func Load() *Config {
@@ -23,6 +125,86 @@ func Load() *Config {
 		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
 		Port:            getEnv("PORT", "8080"),
 		Environment:     getEnv("ENVIRONMENT", "development"),
+		DataDir:         getEnv("DATA_DIR", "data"),
+		DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "UTC"),
+
+		CalendarFeedBaseURL:  getEnv("CALENDAR_FEED_BASE_URL", ""),
+		CalendarSyncInterval: getEnvDuration("CALENDAR_SYNC_INTERVAL", time.Hour),
+		CalendarAllowPrivate: getEnvBool("CALENDAR_ALLOW_PRIVATE", false),
+
+		Transcriber:          getEnv("TRANSCRIBER", ""),
+		WhisperURL:           getEnv("WHISPER_URL", ""),
+		WhisperBinary:        getEnv("WHISPER_BIN", "whisper-cli"),
+		WhisperModel:         getEnv("WHISPER_MODEL", ""),
+		WhisperLanguage:      getEnv("WHISPER_LANGUAGE", "auto"),
+		FFmpegBinary:         getEnv("FFMPEG_BIN", "ffmpeg"),
+		TranscriptionTimeout: getEnvDuration("TRANSCRIPTION_TIMEOUT", 2*time.Minute),
+
+		TelegramBotToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramAPIBaseURL:    getEnv("TELEGRAM_API_BASE_URL", "https://api.telegram.org"),
+		TelegramMode:          getEnv("TELEGRAM_MODE", "polling"),
+		TelegramWebhookURL:    getEnv("TELEGRAM_WEBHOOK_URL", ""),
+		TelegramWebhookSecret: os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+		TelegramPollTimeout:   getEnvDuration("TELEGRAM_POLL_TIMEOUT", 30*time.Second),
+
+		InboundWebhookSecrets:   getEnvList("INBOUND_WEBHOOK_SECRETS"),
+		InboundWebhookTolerance: getEnvDuration("INBOUND_WEBHOOK_TOLERANCE", 5*time.Minute),
+
+		WebhookRetryInterval: getEnvDuration("WEBHOOK_RETRY_INTERVAL", 10*time.Second),
+		WebhookTimeout:       getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:   getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoff:       getEnvDuration("WEBHOOK_BACKOFF", 30*time.Second),
+		WebhookAllowPrivate:  getEnvBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		SMTPHost:            getEnv("SMTP_HOST", ""),
+		SMTPPort:            getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:        os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:        os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:            getEnv("SMTP_FROM", "Soul Mirror <soul-mirror@localhost>"),
+		SMTPSecurity:        getEnv("SMTP_SECURITY", "starttls"),
+		DigestWeekday:       getEnv("DIGEST_WEEKDAY", "sunday"),
+		DigestHour:          getEnvInt("DIGEST_HOUR", 18),
+		DigestCheckInterval: getEnvDuration("DIGEST_CHECK_INTERVAL", time.Hour),
+
+		Embedder:            getEnv("EMBEDDER", "hashing"),
+		EmbeddingsURL:       getEnv("EMBEDDINGS_URL", ""),
+		EmbeddingsAPIKey:    os.Getenv("EMBEDDINGS_API_KEY"),
+		EmbeddingsModel:     getEnv("EMBEDDINGS_MODEL", ""),
+		EmbeddingDimensions: getEnvInt("EMBEDDING_DIMENSIONS", 0),
+		EmbeddingsTimeout:   getEnvDuration("EMBEDDINGS_TIMEOUT", 30*time.Second),
+		MemoryTopK:          getEnvInt("MEMORY_TOP_K", 5),
+		MemoryMinScore:      getEnvFloat("MEMORY_MIN_SCORE", 0.15),
+
+		ProfileMaxChars:           getEnvInt("PROFILE_MAX_CHARS", 6000),
+		ProfileMaxTokens:          getEnvInt("PROFILE_MAX_TOKENS", 1500),
+		ProfileCompactionInterval: getEnvDuration("PROFILE_COMPACTION_INTERVAL", time.Hour),
+
+		EncryptionKey:          os.Getenv("ENCRYPTION_KEY"),
+		EncryptionKeyFile:      getEnv("ENCRYPTION_KEY_FILE", ""),
+		EncryptionPreviousKeys: getEnvList("ENCRYPTION_PREVIOUS_KEYS"),
+
+		RedactPII:      getEnvBool("REDACT_PII", true),
+		RedactionRules: getEnvList("REDACTION_RULES"),
+		RedactionTerms: getEnvList("REDACTION_TERMS"),
+
+		RateLimitEnabled:     getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitWindow:      getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+		RateLimitRequests:    getEnvInt("RATE_LIMIT_REQUESTS", 120),
+		RateLimitBurst:       getEnvInt("RATE_LIMIT_BURST", 60),
+		LLMRateLimitRequests: getEnvInt("LLM_RATE_LIMIT_REQUESTS", 10),
+		LLMRateLimitBurst:    getEnvInt("LLM_RATE_LIMIT_BURST", 5),
+		TrustedProxies:       getEnvList("TRUSTED_PROXIES"),
+
+		JobWorkers:   getEnvInt("JOB_WORKERS", 2),
+		JobRetention: getEnvDuration("JOB_RETENTION", 24*time.Hour),
+
+		ToolWorkers: getEnvInt("TOOL_WORKERS", 4),
+		ToolTimeout: getEnvDuration("TOOL_TIMEOUT", 30*time.Second),
+
+		SchedulerEnabled:  getEnvBool("SCHEDULER_ENABLED", true),
+		SchedulerInterval: getEnvDuration("SCHEDULER_INTERVAL", 15*time.Minute),
+		DispatchInterval:  getEnvDuration("NUDGE_DISPATCH_INTERVAL", time.Minute),
+		NudgeMinGap:       getEnvDuration("NUDGE_MIN_GAP", 4*time.Hour),
 	}
 }
 
@@ -33,10 +215,81 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Invalid boolean for %s: %q, using default %v", key, value, defaultValue)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("Invalid number for %s: %q, using default %g", key, value, defaultValue)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+			return defaultValue
+		}
+		return parsed
+	}
+	return defaultValue
+}
+
 func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
 }
 
+func (c *Config) HasTelegram() bool {
+	return c.TelegramBotToken != ""
+}
+
+func (c *Config) HasSMTP() bool {
+	return c.SMTPHost != ""
+}
+
+func (c *Config) HasEncryption() bool {
+	return c.EncryptionKey != "" || c.EncryptionKeyFile != ""
+}
+
 func (c *Config) HasAnthropicKey() bool {
 	return c.AnthropicAPIKey != ""
-}
\ No newline at end of file
+}
@@ -0,0 +1,419 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+const storeName = "email"
+
+const (
+	// digestWindow is how far back the first digest of a user looks.
+	digestWindow = 7 * 24 * time.Hour
+	// digestWeeks is how many weekly mood aggregates a digest shows.
+	digestWeeks = 4
+	// maxProfileChanges caps the profile lines listed in one digest.
+	maxProfileChanges = 20
+	maxLineLength     = 200
+)
+
+var (
+	ErrInvalid = errors.New("invalid email settings")
+	// ErrUnavailable means no SMTP server is configured.
+	ErrUnavailable = errors.New("email delivery is not configured")
+)
+
+// Settings are a user's email preferences. Both kinds of email are on by
+// default once an address is set.
+type Settings struct {
+	UserID       string     `json:"user_id"`
+	Address      string     `json:"address"`
+	Nudges       bool       `json:"nudges"`
+	Digest       bool       `json:"digest"`
+	LastDigestAt *time.Time `json:"last_digest_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// SettingsUpdate describes a partial change; nil fields are left untouched.
+// An empty address turns email off.
+type SettingsUpdate struct {
+	Address *string `json:"address"`
+	Nudges  *bool   `json:"nudges"`
+	Digest  *bool   `json:"digest"`
+}
+
+// Digest is the weekly summary mailed to a user.
+type Digest struct {
+	UserID         string           `json:"user_id"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	ProfileChanges []string         `json:"profile_changes"`
+	CompletedTasks []tasks.Task     `json:"completed_tasks"`
+	OpenTasks      int              `json:"open_tasks"`
+	Mood           *mood.Trend      `json:"mood,omitempty"`
+	Weeks          []mood.Aggregate `json:"mood_weeks,omitempty"`
+}
+
+// Sources holds what a digest is built from. Locator may be nil, in which
+// case dates are shown in UTC.
+type Sources struct {
+	Tasks   tasks.TaskService
+	Profile profile.ProfileService
+	Mood    mood.MoodService
+	Locator calendar.Locator
+}
+
+type EmailService interface {
+	// CanSend reports whether an SMTP server is configured.
+	CanSend() bool
+	Settings(userID string) (*Settings, error)
+	UpdateSettings(userID string, update SettingsUpdate) (*Settings, error)
+	// Digest builds the digest userID would get now, without sending it.
+	Digest(userID string, now time.Time) (*Digest, error)
+	// SendDigest mails the digest and starts the next one from now.
+	SendDigest(userID string, now time.Time) (*Digest, error)
+	// SendNudge mails a nudge, or returns scheduler.ErrNoRoute if the user
+	// has no address or turned nudge emails off.
+	SendNudge(nudge scheduler.Nudge) error
+	// DigestUsers lists users with the digest turned on.
+	DigestUsers() []string
+	Location(userID string) *time.Location
+	// Purge deletes the user's email settings and digest snapshot,
+	// returning 1 if there were any.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+}
+
+type userState struct {
+	Settings
+	// ProfileSnapshot is the profile as of the last digest, so the next one
+	// can tell what changed.
+	ProfileSnapshot []string `json:"profile_snapshot,omitempty"`
+}
+
+type service struct {
+	store   storage.Store
+	mailer  Mailer
+	sources Sources
+	users   map[string]*userState
+	mutex   sync.Mutex
+}
+
+// NewService creates the email service. mailer may be nil, in which case
+// settings and digest previews still work but nothing is sent.
+func NewService(store storage.Store, mailer Mailer, sources Sources) (EmailService, error) {
+	s := &service{
+		store:   store,
+		mailer:  mailer,
+		sources: sources,
+		users:   make(map[string]*userState),
+	}
+	if err := store.Load(storeName, &s.users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load email settings: %w", err)
+	}
+	if s.users == nil {
+		s.users = make(map[string]*userState)
+	}
+	log.Printf("EmailService: Loaded email settings for %d users", len(s.users))
+	return s, nil
+}
+
+func (s *service) persist() error {
+	return s.store.Save(storeName, s.users)
+}
+
+func (s *service) CanSend() bool {
+	return s.mailer != nil
+}
+
+func (s *service) state(userID string) *userState {
+	state, exists := s.users[userID]
+	if !exists {
+		state = &userState{Settings: Settings{UserID: userID, Nudges: true, Digest: true}}
+	}
+	return state
+}
+
+func (s *service) Settings(userID string) (*Settings, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	settings := s.state(userID).Settings
+	return &settings, nil
+}
+
+func (s *service) UpdateSettings(userID string, update SettingsUpdate) (*Settings, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.state(userID)
+	if update.Address != nil {
+		address := strings.TrimSpace(*update.Address)
+		if address != "" {
+			normalized, err := ValidateAddress(address)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q is not a valid email address", ErrInvalid, address)
+			}
+			address = normalized
+		}
+		state.Address = address
+	}
+	if update.Nudges != nil {
+		state.Nudges = *update.Nudges
+	}
+	if update.Digest != nil {
+		state.Digest = *update.Digest
+	}
+	state.UpdatedAt = time.Now()
+
+	s.users[userID] = state
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	log.Printf("EmailService: Updated settings for user %s (nudges: %t, digest: %t)", userID, state.Nudges, state.Digest)
+	settings := state.Settings
+	return &settings, nil
+}
+
+func (s *service) DigestUsers() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var users []string
+	for userID, state := range s.users {
+		if state.Digest && state.Address != "" {
+			users = append(users, userID)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.users[userID]; !exists {
+		return 0, nil
+	}
+	delete(s.users, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("EmailService: Purged email settings for user %s", userID)
+	return 1, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.users[userID]; exists {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (s *service) Location(userID string) *time.Location {
+	if s.sources.Locator == nil {
+		return time.UTC
+	}
+	location, err := s.sources.Locator.Location(userID)
+	if err != nil {
+		log.Printf("EmailService: Failed to resolve timezone for user %s: %v", userID, err)
+		return time.UTC
+	}
+	return location
+}
+
+func (s *service) Digest(userID string, now time.Time) (*Digest, error) {
+	s.mutex.Lock()
+	state := *s.state(userID)
+	s.mutex.Unlock()
+
+	digest, _, err := s.buildDigest(state, now)
+	return digest, err
+}
+
+func (s *service) SendDigest(userID string, now time.Time) (*Digest, error) {
+	if s.mailer == nil {
+		return nil, ErrUnavailable
+	}
+
+	s.mutex.Lock()
+	state := *s.state(userID)
+	s.mutex.Unlock()
+	if state.Address == "" {
+		return nil, fmt.Errorf("%w: no email address set", ErrInvalid)
+	}
+
+	digest, snapshot, err := s.buildDigest(state, now)
+	if err != nil {
+		return nil, err
+	}
+	text, html, err := render("digest", digest)
+	if err != nil {
+		return nil, fmt.Errorf("render digest: %w", err)
+	}
+	err = s.mailer.Send(Message{
+		To:      state.Address,
+		Subject: fmt.Sprintf("Your week with Soul Mirror (%s)", digest.To.Format("Jan 2")),
+		Text:    text,
+		HTML:    html,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("send digest: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current := s.state(userID)
+	current.LastDigestAt = &now
+	current.ProfileSnapshot = snapshot
+	s.users[userID] = current
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	log.Printf("EmailService: Sent digest to user %s", userID)
+	return digest, nil
+}
+
+// buildDigest assembles the digest for the window since the last one. It
+// also returns the current profile lines, to be kept as the next snapshot.
+func (s *service) buildDigest(state userState, now time.Time) (*Digest, []string, error) {
+	location := s.Location(state.UserID)
+	from := now.Add(-digestWindow)
+	if state.LastDigestAt != nil {
+		from = *state.LastDigestAt
+	}
+	digest := &Digest{
+		UserID:         state.UserID,
+		From:           from.In(location),
+		To:             now.In(location),
+		ProfileChanges: make([]string, 0),
+		CompletedTasks: make([]tasks.Task, 0),
+	}
+
+	var lines []string
+	if s.sources.Profile != nil {
+		text, err := s.sources.Profile.Get(state.UserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load profile: %w", err)
+		}
+		lines = profileLines(text)
+		digest.ProfileChanges = profileChanges(state.ProfileSnapshot, lines)
+	}
+
+	if s.sources.Tasks != nil {
+		done, err := s.sources.Tasks.List(state.UserID, tasks.Filter{Status: tasks.StatusDone})
+		if err != nil {
+			return nil, nil, fmt.Errorf("load tasks: %w", err)
+		}
+		for _, task := range done {
+			if task.CompletedAt != nil && task.CompletedAt.After(from) && !task.CompletedAt.After(now) {
+				digest.CompletedTasks = append(digest.CompletedTasks, task)
+			}
+		}
+		sort.Slice(digest.CompletedTasks, func(i, j int) bool {
+			return digest.CompletedTasks[i].CompletedAt.Before(*digest.CompletedTasks[j].CompletedAt)
+		})
+		open, err := s.sources.Tasks.List(state.UserID, tasks.Filter{Status: tasks.StatusOpen})
+		if err != nil {
+			return nil, nil, fmt.Errorf("load tasks: %w", err)
+		}
+		digest.OpenTasks = len(open)
+	}
+
+	if s.sources.Mood != nil {
+		trend, err := s.sources.Mood.Trend(state.UserID, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load mood trend: %w", err)
+		}
+		weeks, err := s.sources.Mood.Aggregate(state.UserID, mood.PeriodWeek, now.AddDate(0, 0, -7*digestWeeks), now.In(location))
+		if err != nil {
+			return nil, nil, fmt.Errorf("load mood aggregates: %w", err)
+		}
+		if trend.EntriesThisWindow+trend.EntriesPrevWindow > 0 || len(weeks) > 0 {
+			digest.Mood = trend
+			digest.Weeks = weeks
+		}
+	}
+
+	return digest, lines, nil
+}
+
+// profileLines extracts the bullet lines of a profile.
+func profileLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if item, ok := strings.CutPrefix(line, "•"); ok {
+			if item = strings.TrimSpace(item); item != "" {
+				lines = append(lines, item)
+			}
+		}
+	}
+	return lines
+}
+
+// profileChanges returns the lines not in the snapshot, most recent last,
+// keeping only the newest maxProfileChanges.
+func profileChanges(snapshot, lines []string) []string {
+	seen := make(map[string]int, len(snapshot))
+	for _, line := range snapshot {
+		seen[line]++
+	}
+	changes := make([]string, 0)
+	for _, line := range lines {
+		if seen[line] > 0 {
+			seen[line]--
+			continue
+		}
+		changes = append(changes, truncate(line, maxLineLength))
+	}
+	if len(changes) > maxProfileChanges {
+		changes = changes[len(changes)-maxProfileChanges:]
+	}
+	return changes
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+func (s *service) SendNudge(nudge scheduler.Nudge) error {
+	s.mutex.Lock()
+	state := *s.state(nudge.UserID)
+	s.mutex.Unlock()
+
+	if s.mailer == nil || state.Address == "" || !state.Nudges {
+		return scheduler.ErrNoRoute
+	}
+	text, html, err := render("nudge", nudge)
+	if err != nil {
+		return fmt.Errorf("render nudge: %w", err)
+	}
+	return s.mailer.Send(Message{
+		To:      state.Address,
+		Subject: "A nudge from Soul Mirror",
+		Text:    text,
+		HTML:    html,
+	})
+}
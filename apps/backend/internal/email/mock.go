@@ -0,0 +1,23 @@
+package email
+
+import "sync"
+
+// MockMailer keeps sent messages in memory instead of talking to SMTP.
+type MockMailer struct {
+	Sent  []Message
+	mutex sync.Mutex
+}
+
+func NewMockMailer() *MockMailer {
+	return &MockMailer{}
+}
+
+func (m *MockMailer) Send(message Message) error {
+	if _, err := ValidateAddress(message.To); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Sent = append(m.Sent, message)
+	return nil
+}
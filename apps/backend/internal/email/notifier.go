@@ -0,0 +1,135 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+)
+
+// minDigestGap keeps a digest from going out twice in one week, e.g. when
+// the user's timezone changes.
+const minDigestGap = 6 * 24 * time.Hour
+
+type NotifierConfig struct {
+	// DigestWeekday and DigestHour are when digests go out, in each user's
+	// own timezone.
+	DigestWeekday time.Weekday
+	DigestHour    int
+	// Interval is how often users are checked for a due digest.
+	Interval time.Duration
+}
+
+var DefaultNotifierConfig = NotifierConfig{
+	DigestWeekday: time.Sunday,
+	DigestHour:    18,
+	Interval:      time.Hour,
+}
+
+// ParseWeekday accepts full or three-letter English weekday names.
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
+
+// Notifier is the email channel: it delivers nudges for the scheduler and
+// sends weekly digests in the background.
+type Notifier struct {
+	service EmailService
+	config  NotifierConfig
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func NewNotifier(service EmailService, config NotifierConfig) *Notifier {
+	if config.Interval <= 0 {
+		config.Interval = DefaultNotifierConfig.Interval
+	}
+	if config.DigestHour < 0 || config.DigestHour > 23 {
+		config.DigestHour = DefaultNotifierConfig.DigestHour
+	}
+	return &Notifier{
+		service: service,
+		config:  config,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (n *Notifier) Name() string {
+	return "email"
+}
+
+func (n *Notifier) Deliver(nudge scheduler.Nudge) error {
+	return n.service.SendNudge(nudge)
+}
+
+func (n *Notifier) Start() {
+	go n.run()
+	log.Printf("EmailNotifier: Started (digests on %s at %02d:00 local time)", n.config.DigestWeekday, n.config.DigestHour)
+}
+
+func (n *Notifier) Stop() {
+	n.once.Do(func() {
+		close(n.stop)
+		<-n.done
+		log.Printf("EmailNotifier: Stopped")
+	})
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	n.RunOnce(time.Now())
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.RunOnce(time.Now())
+		}
+	}
+}
+
+// RunOnce sends every digest that is due.
+func (n *Notifier) RunOnce(now time.Time) {
+	for _, userID := range n.service.DigestUsers() {
+		select {
+		case <-n.stop:
+			return
+		default:
+		}
+		if !n.digestDue(userID, now) {
+			continue
+		}
+		if _, err := n.service.SendDigest(userID, now); err != nil {
+			log.Printf("EmailNotifier: Failed to send digest to user %s: %v", userID, err)
+		}
+	}
+}
+
+func (n *Notifier) digestDue(userID string, now time.Time) bool {
+	local := now.In(n.service.Location(userID))
+	if local.Weekday() != n.config.DigestWeekday || local.Hour() < n.config.DigestHour {
+		return false
+	}
+	settings, err := n.service.Settings(userID)
+	if err != nil {
+		log.Printf("EmailNotifier: Failed to load settings for user %s: %v", userID, err)
+		return false
+	}
+	return settings.LastDigestAt == nil || now.Sub(*settings.LastDigestAt) >= minDigestGap
+}
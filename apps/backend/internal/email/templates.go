@@ -0,0 +1,39 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var templateFuncs = map[string]any{
+	"join": strings.Join,
+}
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.New("").Funcs(templateFuncs).ParseFS(templateFiles, "templates/*.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(templateFuncs).ParseFS(templateFiles, "templates/*.html.tmpl"))
+)
+
+// render fills the text and HTML variants of a template, e.g. "nudge"
+// renders templates/nudge.txt.tmpl and templates/nudge.html.tmpl.
+func render(name string, data any) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&textBuf, name+".txt.tmpl", data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html.tmpl", data); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+// RenderDigest renders a digest the way it is mailed.
+func RenderDigest(digest *Digest) (text, html string, err error) {
+	return render("digest", digest)
+}
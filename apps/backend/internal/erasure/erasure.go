@@ -0,0 +1,189 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "deletions"
+
+// Holder is anything that keeps data about users. Every holder is
+// registered with the Eraser, so deleting an account leaves nothing behind.
+type Holder interface {
+	// Purge deletes everything kept about the user and returns how many
+	// records went. Purging a user with nothing kept is not an error.
+	Purge(userID string) (int, error)
+	// Count returns how many records are kept about the user.
+	Count(userID string) (int, error)
+}
+
+type Status string
+
+const (
+	StatusCompleted Status = "completed"
+	// StatusIncomplete means some holder failed to purge; deleting the
+	// account again retries it.
+	StatusIncomplete Status = "incomplete"
+)
+
+// Deletion is the audit record of an account deletion. It keeps the user
+// ID and how much was removed where, and nothing of the data itself.
+type Deletion struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"user_id"`
+	Status      Status            `json:"status"`
+	RequestedAt time.Time         `json:"requested_at"`
+	CompletedAt time.Time         `json:"completed_at"`
+	Removed     map[string]int    `json:"removed"`
+	Errors      map[string]string `json:"errors,omitempty"`
+	// Verification is the check run right after the purge.
+	Verification *Verification `json:"verification"`
+}
+
+// Verification reports what every holder still keeps about a user.
+type Verification struct {
+	UserID    string            `json:"user_id"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Clean     bool              `json:"clean"`
+	Remaining map[string]int    `json:"remaining"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+type namedHolder struct {
+	name   string
+	holder Holder
+}
+
+// Eraser deletes a user's data from every registered holder and keeps an
+// audit log of deletions.
+type Eraser struct {
+	store     storage.Store
+	holders   []namedHolder
+	deletions []Deletion
+	// erasing serializes deletions, so two requests don't interleave
+	erasing sync.Mutex
+	mutex   sync.RWMutex
+
+	// active counts the work entered for each user, and closed holds the
+	// users being deleted, who can't enter any. idle is signalled on work
+	// when a user's count drops to zero.
+	work   sync.Mutex
+	active map[string]int
+	closed map[string]bool
+	idle   *sync.Cond
+}
+
+func NewEraser(store storage.Store) (*Eraser, error) {
+	e := &Eraser{store: store, active: make(map[string]int), closed: make(map[string]bool)}
+	e.idle = sync.NewCond(&e.work)
+	if err := store.Load(storeName, &e.deletions); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load deletions: %w", err)
+	}
+	log.Printf("Eraser: Loaded %d deletion records", len(e.deletions))
+	return e, nil
+}
+
+// Add registers a holder under name. Holders are purged in the order they
+// were added, so caches derived from other holders should come last.
+func (e *Eraser) Add(name string, holder Holder) {
+	e.holders = append(e.holders, namedHolder{name: name, holder: holder})
+}
+
+// Erase purges the user from every holder, verifies nothing remains and
+// records the deletion. New work for the user is refused while it runs, and
+// work already running is waited for first, so nothing is written back
+// after the purge. Holders that fail don't stop the others; the deletion
+// is then incomplete and can be retried.
+func (e *Eraser) Erase(userID string) (*Deletion, error) {
+	e.erasing.Lock()
+	defer e.erasing.Unlock()
+
+	deletion := Deletion{
+		ID:          storage.NewID(),
+		UserID:      userID,
+		RequestedAt: time.Now(),
+		Removed:     make(map[string]int),
+	}
+	defer e.reopen(userID)
+	if err := e.close(userID, drainTimeout); err != nil {
+		log.Printf("Eraser: Deleting account %s before its requests finished: %v", userID, err)
+		deletion.Errors = map[string]string{"requests": err.Error()}
+	}
+	for _, h := range e.holders {
+		count, err := h.holder.Purge(userID)
+		if err != nil {
+			log.Printf("Eraser: Failed to purge %s for user %s: %v", h.name, userID, err)
+			if deletion.Errors == nil {
+				deletion.Errors = make(map[string]string)
+			}
+			deletion.Errors[h.name] = err.Error()
+			continue
+		}
+		deletion.Removed[h.name] = count
+	}
+	deletion.CompletedAt = time.Now()
+	deletion.Verification = e.Verify(userID)
+	deletion.Status = StatusCompleted
+	if len(deletion.Errors) > 0 || !deletion.Verification.Clean {
+		deletion.Status = StatusIncomplete
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deletions = append(e.deletions, deletion)
+	if err := e.store.Save(storeName, e.deletions); err != nil {
+		return nil, fmt.Errorf("save deletions: %w", err)
+	}
+
+	log.Printf("Eraser: Deleted account %s (%s, %d holders)", userID, deletion.Status, len(e.holders))
+	return &deletion, nil
+}
+
+// Verify counts what every holder keeps about the user.
+func (e *Eraser) Verify(userID string) *Verification {
+	verification := &Verification{
+		UserID:    userID,
+		CheckedAt: time.Now(),
+		Clean:     true,
+		Remaining: make(map[string]int),
+	}
+	for _, h := range e.holders {
+		count, err := h.holder.Count(userID)
+		if err != nil {
+			if verification.Errors == nil {
+				verification.Errors = make(map[string]string)
+			}
+			verification.Errors[h.name] = err.Error()
+			verification.Clean = false
+			continue
+		}
+		verification.Remaining[h.name] = count
+		if count > 0 {
+			verification.Clean = false
+		}
+	}
+	return verification
+}
+
+// Deletions returns the user's deletion records, newest first.
+func (e *Eraser) Deletions(userID string) []Deletion {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	result := make([]Deletion, 0)
+	for _, deletion := range e.deletions {
+		if deletion.UserID == userID {
+			result = append(result, deletion)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].RequestedAt.After(result[j].RequestedAt)
+	})
+	return result
+}
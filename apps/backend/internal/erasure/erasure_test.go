@@ -0,0 +1,149 @@
+package erasure
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// records is a holder counting records per user.
+type records struct {
+	counts map[string]int
+	mutex  sync.Mutex
+}
+
+func (r *records) add(userID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.counts[userID]++
+}
+
+func (r *records) Purge(userID string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := r.counts[userID]
+	delete(r.counts, userID)
+	return count, nil
+}
+
+func (r *records) Count(userID string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.counts[userID], nil
+}
+
+// slowOrchestrator writes a record once release is closed.
+type slowOrchestrator struct {
+	orchestrator.Orchestrator
+	records *records
+	started chan struct{}
+	release chan struct{}
+}
+
+func (o *slowOrchestrator) Process(req orchestrator.Request) (*types.ProcessResponse, error) {
+	o.started <- struct{}{}
+	<-o.release
+	o.records.add(req.UserID)
+	return &types.ProcessResponse{}, nil
+}
+
+func newEraser(t *testing.T) (*Eraser, *records) {
+	t.Helper()
+	eraser, err := NewEraser(storage.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := &records{counts: make(map[string]int)}
+	eraser.Add("records", holder)
+	return eraser, holder
+}
+
+// waitClosed waits until the eraser refuses work for the user.
+func waitClosed(t *testing.T, eraser *Eraser, userID string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		leave, err := eraser.Enter(userID)
+		if errors.Is(err, ErrErasing) {
+			return
+		}
+		leave()
+	}
+	t.Fatal("deletion never started")
+}
+
+func TestEraseWaitsForRunningInputs(t *testing.T) {
+	eraser, holder := newEraser(t)
+	slow := &slowOrchestrator{Orchestrator: orchestrator.NewMock(), records: holder, started: make(chan struct{}), release: make(chan struct{})}
+	orch := Guard(slow, eraser)
+
+	processed := make(chan error)
+	go func() {
+		_, err := orch.Process(orchestrator.Request{UserID: "alice", Input: "log a run"})
+		processed <- err
+	}()
+	<-slow.started
+
+	erased := make(chan *Deletion)
+	go func() {
+		deletion, err := eraser.Erase("alice")
+		if err != nil {
+			t.Error(err)
+		}
+		erased <- deletion
+	}()
+	waitClosed(t, eraser, "alice")
+
+	if _, err := orch.Process(orchestrator.Request{UserID: "alice", Input: "another"}); !errors.Is(err, ErrErasing) {
+		t.Fatalf("Process during deletion = %v, want ErrErasing", err)
+	}
+	leave, err := eraser.Enter("bob")
+	if err != nil {
+		t.Fatalf("Enter for another user = %v", err)
+	}
+	leave()
+	select {
+	case <-erased:
+		t.Fatal("deletion finished while an input was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The running input writes its record before the purge
+	close(slow.release)
+	if err := <-processed; err != nil {
+		t.Fatal(err)
+	}
+	deletion := <-erased
+	if deletion.Status != StatusCompleted || !deletion.Verification.Clean || deletion.Removed["records"] != 1 {
+		t.Fatalf("deletion = %+v, want the input's record removed", deletion)
+	}
+
+	leave, err = eraser.Enter("alice")
+	if err != nil {
+		t.Fatalf("Enter after deletion = %v", err)
+	}
+	leave()
+}
+
+func TestEraseGivesUpWaiting(t *testing.T) {
+	eraser, _ := newEraser(t)
+	leave, err := eraser.Enter("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leave()
+
+	if err := eraser.close("alice", 10*time.Millisecond); err == nil {
+		t.Fatal("close returned while work was still running")
+	}
+	eraser.reopen("alice")
+	if leave, err := eraser.Enter("alice"); err != nil {
+		t.Fatalf("Enter after reopen = %v", err)
+	} else {
+		leave()
+	}
+}
@@ -0,0 +1,115 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// ErrErasing is returned for work on an account that is being deleted.
+var ErrErasing = errors.New("account deletion in progress")
+
+// drainTimeout bounds how long a deletion waits for work already running
+// for the user. A deletion that gives up waiting is incomplete and can be
+// retried.
+const drainTimeout = 5 * time.Minute
+
+// Enter records work that may write data about the user, such as
+// processing an input, and returns a function to call once it is done. It
+// fails with ErrErasing while the account is being deleted; Erase waits
+// for work already entered before it purges anything.
+func (e *Eraser) Enter(userID string) (func(), error) {
+	e.work.Lock()
+	defer e.work.Unlock()
+
+	if e.closed[userID] {
+		return nil, ErrErasing
+	}
+	e.active[userID]++
+	return func() {
+		e.work.Lock()
+		defer e.work.Unlock()
+		if e.active[userID]--; e.active[userID] == 0 {
+			delete(e.active, userID)
+			e.idle.Broadcast()
+		}
+	}, nil
+}
+
+// close refuses new work for the user and waits up to timeout for the work
+// already entered to finish.
+func (e *Eraser) close(userID string, timeout time.Duration) error {
+	e.work.Lock()
+	e.closed[userID] = true
+	running := e.active[userID]
+	e.work.Unlock()
+	if running == 0 {
+		return nil
+	}
+
+	log.Printf("Eraser: Waiting for %d requests of user %s to finish", running, userID)
+	drained := make(chan struct{})
+	go func() {
+		e.work.Lock()
+		defer e.work.Unlock()
+		for e.active[userID] > 0 {
+			e.idle.Wait()
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("requests still running after %s", timeout)
+	}
+}
+
+// reopen accepts work for the user again.
+func (e *Eraser) reopen(userID string) {
+	e.work.Lock()
+	defer e.work.Unlock()
+	delete(e.closed, userID)
+}
+
+type guardedOrchestrator struct {
+	orchestrator.Orchestrator
+	eraser *Eraser
+}
+
+// Guard wraps an orchestrator so inputs are refused with ErrErasing while
+// the user's account is being deleted, and a deletion waits for the inputs
+// already being processed. It goes outermost, so a refused input isn't
+// logged either.
+func Guard(inner orchestrator.Orchestrator, eraser *Eraser) orchestrator.Orchestrator {
+	return &guardedOrchestrator{Orchestrator: inner, eraser: eraser}
+}
+
+func (g *guardedOrchestrator) ProcessInput(input string) (string, error) {
+	detailed, err := g.ProcessInputDetailed(input)
+	if err != nil {
+		return "", err
+	}
+	return detailed.Result.FinalResponse, nil
+}
+
+func (g *guardedOrchestrator) ProcessInputDetailed(input string) (*types.ProcessResponse, error) {
+	return g.Process(orchestrator.Request{UserID: types.DefaultUserID, Input: input, Source: orchestrator.SourceWeb})
+}
+
+func (g *guardedOrchestrator) Process(req orchestrator.Request) (*types.ProcessResponse, error) {
+	userID := req.UserID
+	if userID == "" {
+		userID = types.DefaultUserID
+	}
+	leave, err := g.eraser.Enter(userID)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	return g.Orchestrator.Process(req)
+}
@@ -0,0 +1,437 @@
+package goals
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "goals"
+
+var (
+	ErrNotFound = errors.New("goal not found")
+	ErrInvalid  = errors.New("invalid goal")
+)
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusAchieved  Status = "achieved"
+	StatusAbandoned Status = "abandoned"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+)
+
+type Goal struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"user_id"`
+	Title      string      `json:"title"`
+	Motivation string      `json:"motivation"`
+	Status     Status      `json:"status"`
+	Milestones []Milestone `json:"milestones"`
+	Habits     []Habit     `json:"habits"`
+	CheckIns   []CheckIn   `json:"check_ins"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type Milestone struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Habit is a recurring practice that supports a goal, e.g. "meditate 10 minutes".
+type Habit struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Frequency Frequency `json:"frequency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CheckIn records progress on a goal, optionally against one of its habits.
+type CheckIn struct {
+	ID      string    `json:"id"`
+	HabitID string    `json:"habit_id,omitempty"`
+	Note    string    `json:"note,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+type Update struct {
+	Title      *string
+	Motivation *string
+	Status     *Status
+}
+
+type GoalService interface {
+	Create(userID string, goal Goal) (*Goal, error)
+	Get(userID, id string) (*Goal, error)
+	List(userID string, status Status) ([]Goal, error)
+	Update(userID, id string, update Update) (*Goal, error)
+	Delete(userID, id string) error
+	// Import adds goals from an archive, keeping their IDs, milestones,
+	// habits and check-ins. Goals the user already has are skipped; it
+	// returns how many were added.
+	Import(userID string, goals []Goal) (int, error)
+	// Purge deletes all of the user's goals with their milestones, habits
+	// and check-ins, returning how many goals there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+	AddMilestone(userID, goalID, title string) (*Goal, error)
+	CompleteMilestone(userID, goalID, milestoneID string) (*Goal, error)
+	AddHabit(userID, goalID, title string, frequency Frequency) (*Goal, error)
+	CheckIn(userID, goalID string, checkIn CheckIn) (*Goal, error)
+}
+
+type service struct {
+	store storage.Store
+	goals map[string][]Goal
+	mutex sync.RWMutex
+}
+
+func NewService(store storage.Store) (GoalService, error) {
+	s := &service{
+		store: store,
+		goals: make(map[string][]Goal),
+	}
+	if err := store.Load(storeName, &s.goals); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load goals: %w", err)
+	}
+	log.Printf("GoalService: Loaded goals for %d users", len(s.goals))
+	return s, nil
+}
+
+func ParseFrequency(s string) (Frequency, bool) {
+	switch Frequency(strings.ToLower(strings.TrimSpace(s))) {
+	case FrequencyDaily, "":
+		return FrequencyDaily, true
+	case FrequencyWeekly:
+		return FrequencyWeekly, true
+	}
+	return "", false
+}
+
+func ParseStatus(s string) (Status, bool) {
+	switch Status(strings.ToLower(strings.TrimSpace(s))) {
+	case StatusActive:
+		return StatusActive, true
+	case StatusAchieved:
+		return StatusAchieved, true
+	case StatusAbandoned:
+		return StatusAbandoned, true
+	}
+	return "", false
+}
+
+func (s *service) Create(userID string, goal Goal) (*Goal, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	goal.Title = strings.TrimSpace(goal.Title)
+	if goal.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+
+	now := time.Now()
+	goal.ID = storage.NewID()
+	goal.UserID = userID
+	goal.Status = StatusActive
+	goal.CheckIns = nil
+	goal.CreatedAt = now
+	goal.UpdatedAt = now
+
+	milestones := make([]Milestone, 0, len(goal.Milestones))
+	for _, m := range goal.Milestones {
+		if title := strings.TrimSpace(m.Title); title != "" {
+			milestones = append(milestones, Milestone{ID: storage.NewID(), Title: title})
+		}
+	}
+	goal.Milestones = milestones
+
+	habits := make([]Habit, 0, len(goal.Habits))
+	for _, h := range goal.Habits {
+		frequency, ok := ParseFrequency(string(h.Frequency))
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown habit frequency %q", ErrInvalid, h.Frequency)
+		}
+		if title := strings.TrimSpace(h.Title); title != "" {
+			habits = append(habits, Habit{ID: storage.NewID(), Title: title, Frequency: frequency, CreatedAt: now})
+		}
+	}
+	goal.Habits = habits
+
+	s.goals[userID] = append(s.goals[userID], goal)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("GoalService: Created goal %s for user %s: %s", goal.ID, userID, goal.Title)
+	return &goal, nil
+}
+
+func (s *service) Get(userID, id string) (*Goal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	goal := s.goals[userID][idx]
+	return &goal, nil
+}
+
+func (s *service) List(userID string, status Status) ([]Goal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Goal, 0)
+	for _, goal := range s.goals[userID] {
+		if status != "" && goal.Status != status {
+			continue
+		}
+		result = append(result, goal)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (s *service) Update(userID, id string, update Update) (*Goal, error) {
+	return s.mutate(userID, id, func(goal *Goal) error {
+		if update.Title != nil {
+			title := strings.TrimSpace(*update.Title)
+			if title == "" {
+				return fmt.Errorf("%w: title is required", ErrInvalid)
+			}
+			goal.Title = title
+		}
+		if update.Motivation != nil {
+			goal.Motivation = strings.TrimSpace(*update.Motivation)
+		}
+		if update.Status != nil {
+			goal.Status = *update.Status
+		}
+		return nil
+	})
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+	userGoals := s.goals[userID]
+	s.goals[userID] = append(userGoals[:idx], userGoals[idx+1:]...)
+	if err := s.persist(); err != nil {
+		return err
+	}
+
+	log.Printf("GoalService: Deleted goal %s for user %s", id, userID)
+	return nil
+}
+
+func (s *service) AddMilestone(userID, goalID, title string) (*Goal, error) {
+	return s.mutate(userID, goalID, func(goal *Goal) error {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return fmt.Errorf("%w: milestone title is required", ErrInvalid)
+		}
+		goal.Milestones = append(goal.Milestones, Milestone{ID: storage.NewID(), Title: title})
+		return nil
+	})
+}
+
+func (s *service) CompleteMilestone(userID, goalID, milestoneID string) (*Goal, error) {
+	return s.mutate(userID, goalID, func(goal *Goal) error {
+		for i := range goal.Milestones {
+			milestone := &goal.Milestones[i]
+			if milestone.ID != milestoneID {
+				continue
+			}
+			if !milestone.Done {
+				now := time.Now()
+				milestone.Done = true
+				milestone.CompletedAt = &now
+			}
+			return nil
+		}
+		return ErrNotFound
+	})
+}
+
+func (s *service) AddHabit(userID, goalID, title string, frequency Frequency) (*Goal, error) {
+	return s.mutate(userID, goalID, func(goal *Goal) error {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return fmt.Errorf("%w: habit title is required", ErrInvalid)
+		}
+		frequency, ok := ParseFrequency(string(frequency))
+		if !ok {
+			return fmt.Errorf("%w: unknown habit frequency", ErrInvalid)
+		}
+		goal.Habits = append(goal.Habits, Habit{
+			ID:        storage.NewID(),
+			Title:     title,
+			Frequency: frequency,
+			CreatedAt: time.Now(),
+		})
+		return nil
+	})
+}
+
+func (s *service) CheckIn(userID, goalID string, checkIn CheckIn) (*Goal, error) {
+	return s.mutate(userID, goalID, func(goal *Goal) error {
+		if checkIn.HabitID != "" && findHabit(goal.Habits, checkIn.HabitID) == nil {
+			return fmt.Errorf("%w: unknown habit %s", ErrInvalid, checkIn.HabitID)
+		}
+		checkIn.ID = storage.NewID()
+		checkIn.Note = strings.TrimSpace(checkIn.Note)
+		if checkIn.At.IsZero() {
+			checkIn.At = time.Now()
+		}
+		goal.CheckIns = append(goal.CheckIns, checkIn)
+		return nil
+	})
+}
+
+// mutate applies fn to a goal under the write lock and persists the result.
+func (s *service) mutate(userID, id string, fn func(goal *Goal) error) (*Goal, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+
+	goal := s.goals[userID][idx]
+	goal.Milestones = append([]Milestone(nil), goal.Milestones...)
+	goal.Habits = append([]Habit(nil), goal.Habits...)
+	goal.CheckIns = append([]CheckIn(nil), goal.CheckIns...)
+	if err := fn(&goal); err != nil {
+		return nil, err
+	}
+	goal.UpdatedAt = time.Now()
+
+	s.goals[userID][idx] = goal
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (s *service) Import(userID string, list []Goal) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := 0
+	for _, goal := range list {
+		if goal.ID == "" || s.indexOf(userID, goal.ID) != -1 {
+			continue
+		}
+		goal.UserID = userID
+		s.goals[userID] = append(s.goals[userID], goal)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("GoalService: Imported %d goals for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.goals[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.goals, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("GoalService: Purged %d goals for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.goals[userID]), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.goals))
+	for userID := range s.goals {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (s *service) indexOf(userID, id string) int {
+	for i, goal := range s.goals[userID] {
+		if goal.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// persist saves the goals. If that fails the change is undone, so the
+// next save doesn't write out a change the caller was told failed.
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.goals); err != nil {
+		s.reload()
+		return fmt.Errorf("save goals: %w", err)
+	}
+	return nil
+}
+
+// reload puts back the goals as they were last saved. Callers hold the
+// write lock.
+func (s *service) reload() {
+	goals := make(map[string][]Goal)
+	if err := s.store.Load(storeName, &goals); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("GoalService: Failed to undo an unsaved change: %v", err)
+		return
+	}
+	s.goals = goals
+}
+
+func findHabit(habits []Habit, id string) *Habit {
+	for i := range habits {
+		if habits[i].ID == id {
+			return &habits[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package goals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/textutil"
+)
+
+type Action string
+
+const (
+	ActionCreate            Action = "create_goal"
+	ActionAddMilestone      Action = "add_milestone"
+	ActionCompleteMilestone Action = "complete_milestone"
+	ActionAddHabit          Action = "add_habit"
+	ActionCheckIn           Action = "check_in"
+	ActionList              Action = "list"
+)
+
+type HabitSpec struct {
+	Title     string    `json:"title"`
+	Frequency Frequency `json:"frequency"`
+}
+
+// Command is the structured form of free-form goal input. The ID fields
+// reference existing items when the action targets one.
+type Command struct {
+	Action      Action
+	GoalID      string
+	HabitID     string
+	MilestoneID string
+	Title       string
+	Motivation  string
+	Milestones  []string
+	Habits      []HabitSpec
+	Note        string
+}
+
+type Interpreter struct {
+	llmService llm.LLMService
+}
+
+func NewInterpreter(llmService llm.LLMService) *Interpreter {
+	return &Interpreter{llmService: llmService}
+}
+
+func (i *Interpreter) Interpret(input string, existing []Goal) Command {
+	if i.llmService != nil {
+		cmd, err := i.interpretWithLLM(input, existing)
+		if err == nil {
+			return cmd
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("GoalInterpreter: LLM interpretation failed, using fallback: %v", err)
+		}
+	}
+	return interpretFallback(input, existing)
+}
+
+func (i *Interpreter) interpretWithLLM(input string, existing []Goal) (Command, error) {
+	type habitView struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	type goalView struct {
+		ID         string      `json:"id"`
+		Title      string      `json:"title"`
+		Milestones []Milestone `json:"milestones"`
+		Habits     []habitView `json:"habits"`
+	}
+
+	views := make([]goalView, len(existing))
+	for idx, goal := range existing {
+		habits := make([]habitView, len(goal.Habits))
+		for h, habit := range goal.Habits {
+			habits[h] = habitView{ID: habit.ID, Title: habit.Title}
+		}
+		views[idx] = goalView{ID: goal.ID, Title: goal.Title, Milestones: goal.Milestones, Habits: habits}
+	}
+	goalsJSON, _ := json.MarshalIndent(views, "", "  ")
+
+	prompt := fmt.Sprintf(`The user said: "%s"
+
+Their active goals:
+%s
+
+Decide how this input affects their goals and habits. Return a single JSON object:
+{
+  "action": "create_goal" | "add_milestone" | "complete_milestone" | "add_habit" | "check_in" | "list",
+  "goal_id": "id of the existing goal this refers to, empty for create_goal/list",
+  "habit_id": "id of the habit for a check_in, if any",
+  "milestone_id": "id of the milestone for complete_milestone",
+  "title": "goal title for create_goal, milestone or habit title for add_milestone/add_habit",
+  "motivation": "why the user wants this, if stated",
+  "milestones": ["concrete milestones for a new goal"],
+  "habits": [{"title": "habit supporting a new goal", "frequency": "daily" | "weekly"}],
+  "note": "short note for a check_in"
+}`, input, string(goalsJSON))
+
+	response, err := i.llmService.Complete(prompt)
+	if err != nil {
+		return Command{}, err
+	}
+
+	var raw struct {
+		Action      string      `json:"action"`
+		GoalID      string      `json:"goal_id"`
+		HabitID     string      `json:"habit_id"`
+		MilestoneID string      `json:"milestone_id"`
+		Title       string      `json:"title"`
+		Motivation  string      `json:"motivation"`
+		Milestones  []string    `json:"milestones"`
+		Habits      []HabitSpec `json:"habits"`
+		Note        string      `json:"note"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Command{}, err
+	}
+
+	cmd := Command{
+		Action:      Action(raw.Action),
+		GoalID:      raw.GoalID,
+		HabitID:     raw.HabitID,
+		MilestoneID: raw.MilestoneID,
+		Title:       raw.Title,
+		Motivation:  raw.Motivation,
+		Milestones:  raw.Milestones,
+		Habits:      raw.Habits,
+		Note:        raw.Note,
+	}
+	switch cmd.Action {
+	case ActionCreate, ActionList:
+	case ActionAddMilestone, ActionCompleteMilestone, ActionAddHabit, ActionCheckIn:
+		if cmd.GoalID == "" {
+			return Command{}, fmt.Errorf("action %s without goal_id", raw.Action)
+		}
+	default:
+		return Command{}, fmt.Errorf("unknown action %q", raw.Action)
+	}
+	return cmd, nil
+}
+
+var (
+	listGoalPhrases   = []string{"my goals", "list goals", "show goals", "how am i doing"}
+	createGoalPattern = regexp.MustCompile(`(?i)\b(?:i )?(?:want to|would like to|wanna|goal is to|goal:|trying to|need to get|aim to|plan to)\s+(.+)`)
+	becausePattern    = regexp.MustCompile(`(?i)\s+(?:because|so that|since)\s+(.+)$`)
+)
+
+func interpretFallback(input string, existing []Goal) Command {
+	lower := strings.ToLower(strings.TrimSpace(input))
+
+	for _, phrase := range listGoalPhrases {
+		if strings.Contains(lower, phrase) {
+			return Command{Action: ActionList}
+		}
+	}
+
+	if match := createGoalPattern.FindStringSubmatch(input); match != nil {
+		title := match[1]
+		motivation := ""
+		if m := becausePattern.FindStringSubmatch(title); m != nil {
+			motivation = strings.TrimSpace(m[1])
+			title = title[:len(title)-len(m[0])]
+		}
+		title = strings.TrimRight(strings.TrimSpace(title), ".!")
+		return Command{Action: ActionCreate, Title: title, Motivation: motivation}
+	}
+
+	// Anything mentioning an existing goal or habit counts as a check-in
+	keywords := textutil.Keywords(input)
+	var bestGoal, bestHabit string
+	bestScore := 0
+	for _, goal := range existing {
+		if score := textutil.Overlap(keywords, textutil.Keywords(goal.Title)); score > bestScore {
+			bestGoal, bestHabit, bestScore = goal.ID, "", score
+		}
+		for _, habit := range goal.Habits {
+			if score := textutil.Overlap(keywords, textutil.Keywords(habit.Title)); score > 0 && score >= bestScore {
+				bestGoal, bestHabit, bestScore = goal.ID, habit.ID, score
+			}
+		}
+	}
+	if bestScore > 0 {
+		return Command{Action: ActionCheckIn, GoalID: bestGoal, HabitID: bestHabit, Note: strings.TrimSpace(input)}
+	}
+
+	title := strings.TrimRight(strings.TrimSpace(input), ".!")
+	return Command{Action: ActionCreate, Title: title}
+}
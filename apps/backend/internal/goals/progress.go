@@ -0,0 +1,152 @@
+package goals
+
+import (
+	"sort"
+	"time"
+)
+
+type HabitProgress struct {
+	HabitID        string     `json:"habit_id"`
+	Title          string     `json:"title"`
+	Frequency      Frequency  `json:"frequency"`
+	CurrentStreak  int        `json:"current_streak"`
+	LongestStreak  int        `json:"longest_streak"`
+	TotalCheckIns  int        `json:"total_check_ins"`
+	DoneThisPeriod bool       `json:"done_this_period"`
+	LastCheckIn    *time.Time `json:"last_check_in,omitempty"`
+}
+
+type Progress struct {
+	GoalID          string          `json:"goal_id"`
+	Title           string          `json:"title"`
+	Status          Status          `json:"status"`
+	MilestonesDone  int             `json:"milestones_done"`
+	MilestonesTotal int             `json:"milestones_total"`
+	Completion      float64         `json:"completion"`
+	CheckInStreak   int             `json:"check_in_streak"`
+	TotalCheckIns   int             `json:"total_check_ins"`
+	LastCheckIn     *time.Time      `json:"last_check_in,omitempty"`
+	Habits          []HabitProgress `json:"habits"`
+}
+
+// ComputeProgress summarizes milestone completion and habit streaks as of now.
+// Streaks count consecutive days (or weeks for weekly habits) in loc, the
+// user's timezone; the current period does not break a streak until it has
+// passed without a check-in.
+func ComputeProgress(goal Goal, now time.Time, loc *time.Location) Progress {
+	progress := Progress{
+		GoalID:          goal.ID,
+		Title:           goal.Title,
+		Status:          goal.Status,
+		MilestonesTotal: len(goal.Milestones),
+		TotalCheckIns:   len(goal.CheckIns),
+		Habits:          make([]HabitProgress, 0, len(goal.Habits)),
+	}
+
+	for _, milestone := range goal.Milestones {
+		if milestone.Done {
+			progress.MilestonesDone++
+		}
+	}
+	switch {
+	case goal.Status == StatusAchieved:
+		progress.Completion = 1
+	case progress.MilestonesTotal > 0:
+		progress.Completion = float64(progress.MilestonesDone) / float64(progress.MilestonesTotal)
+	}
+
+	var allTimes []time.Time
+	for _, checkIn := range goal.CheckIns {
+		allTimes = append(allTimes, checkIn.At)
+	}
+	progress.CheckInStreak, _ = streaks(allTimes, FrequencyDaily, now, loc)
+	progress.LastCheckIn = latest(allTimes)
+
+	for _, habit := range goal.Habits {
+		var times []time.Time
+		for _, checkIn := range goal.CheckIns {
+			if checkIn.HabitID == habit.ID {
+				times = append(times, checkIn.At)
+			}
+		}
+		current, longest := streaks(times, habit.Frequency, now, loc)
+		habitProgress := HabitProgress{
+			HabitID:       habit.ID,
+			Title:         habit.Title,
+			Frequency:     habit.Frequency,
+			CurrentStreak: current,
+			LongestStreak: longest,
+			TotalCheckIns: len(times),
+			LastCheckIn:   latest(times),
+		}
+		for _, t := range times {
+			if periodIndex(t, habit.Frequency, loc) == periodIndex(now, habit.Frequency, loc) {
+				habitProgress.DoneThisPeriod = true
+				break
+			}
+		}
+		progress.Habits = append(progress.Habits, habitProgress)
+	}
+
+	return progress
+}
+
+// periodIndex numbers days (or Monday-based weeks) since the Unix epoch in loc.
+func periodIndex(t time.Time, frequency Frequency, loc *time.Location) int64 {
+	local := t.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+	if frequency == FrequencyWeekly {
+		// 1970-01-01 was a Thursday; shift so weeks start on Monday
+		return (day + 3) / 7
+	}
+	return day
+}
+
+func streaks(times []time.Time, frequency Frequency, now time.Time, loc *time.Location) (current, longest int) {
+	if len(times) == 0 {
+		return 0, 0
+	}
+
+	seen := make(map[int64]bool)
+	var periods []int64
+	for _, t := range times {
+		p := periodIndex(t, frequency, loc)
+		if !seen[p] {
+			seen[p] = true
+			periods = append(periods, p)
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i] < periods[j] })
+
+	run := 0
+	for i, p := range periods {
+		if i > 0 && p == periods[i-1]+1 {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+
+	p := periodIndex(now, frequency, loc)
+	if !seen[p] {
+		p--
+	}
+	for seen[p] {
+		current++
+		p--
+	}
+	return current, longest
+}
+
+func latest(times []time.Time) *time.Time {
+	var result *time.Time
+	for i := range times {
+		if result == nil || times[i].After(*result) {
+			result = &times[i]
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package goals
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeProgressInUserTimezone(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	at := func(loc *time.Location, day, hour int) time.Time {
+		return time.Date(2024, 5, day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name       string
+		frequency  Frequency
+		checkIns   []time.Time
+		now        time.Time
+		loc        *time.Location
+		wantStreak int
+		wantDone   bool
+	}{
+		{
+			// 16:00 and 18:00 in Los Angeles are 23:00 and 01:00 in UTC, two
+			// days apart
+			name:       "consecutive local days",
+			frequency:  FrequencyDaily,
+			checkIns:   []time.Time{at(losAngeles, 13, 16), at(losAngeles, 14, 18)},
+			now:        at(losAngeles, 14, 19),
+			loc:        losAngeles,
+			wantStreak: 2, wantDone: true,
+		},
+		{
+			name:       "same instants by server days",
+			frequency:  FrequencyDaily,
+			checkIns:   []time.Time{at(losAngeles, 13, 16), at(losAngeles, 14, 18)},
+			now:        at(losAngeles, 14, 19),
+			loc:        time.UTC,
+			wantStreak: 1, wantDone: true,
+		},
+		{
+			name:       "late check-in belongs to the local day",
+			frequency:  FrequencyDaily,
+			checkIns:   []time.Time{at(losAngeles, 14, 23)},
+			now:        at(losAngeles, 15, 8),
+			loc:        losAngeles,
+			wantStreak: 1, wantDone: false,
+		},
+		{
+			// Monday morning in Tokyo is still Sunday in UTC
+			name:       "local week starts on the local monday",
+			frequency:  FrequencyWeekly,
+			checkIns:   []time.Time{at(tokyo, 6, 8)},
+			now:        at(tokyo, 7, 12),
+			loc:        tokyo,
+			wantStreak: 1, wantDone: true,
+		},
+		{
+			name:       "missed local day breaks the streak",
+			frequency:  FrequencyDaily,
+			checkIns:   []time.Time{at(losAngeles, 12, 9), at(losAngeles, 13, 9)},
+			now:        at(losAngeles, 15, 9),
+			loc:        losAngeles,
+			wantStreak: 0, wantDone: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := Goal{ID: "g", Habits: []Habit{{ID: "h", Frequency: tt.frequency}}}
+			for _, checkIn := range tt.checkIns {
+				goal.CheckIns = append(goal.CheckIns, CheckIn{HabitID: "h", At: checkIn.UTC()})
+			}
+
+			habit := ComputeProgress(goal, tt.now.UTC(), tt.loc).Habits[0]
+			if habit.CurrentStreak != tt.wantStreak || habit.DoneThisPeriod != tt.wantDone {
+				t.Fatalf("streak %d, done %v; want %d, %v", habit.CurrentStreak, habit.DoneThisPeriod, tt.wantStreak, tt.wantDone)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a signed inbound request. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + nonce + "." + body)),
+// with the timestamp in unix seconds. Retries that send the same
+// Idempotency-Key get the original response instead of being processed
+// again, including a completed request resent with its nonce unchanged.
+const (
+	HeaderTimestamp      = "X-Soul-Mirror-Timestamp"
+	HeaderNonce          = "X-Soul-Mirror-Nonce"
+	HeaderSignature      = "X-Soul-Mirror-Signature"
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+const (
+	// MaxBodyBytes caps an inbound request body.
+	MaxBodyBytes = 64 << 10
+	// MaxTextLength caps the text of one message, in bytes.
+	MaxTextLength = 10000
+	// DefaultTolerance is how far a timestamp may drift from the server
+	// clock. Nonces are remembered for twice as long.
+	DefaultTolerance = 5 * time.Minute
+	// DefaultSource is used when a payload doesn't name its source.
+	DefaultSource = "webhook"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrInvalid   = errors.New("invalid inbound message")
+	ErrSignature = errors.New("invalid signature")
+	ErrExpired   = errors.New("timestamp outside the allowed window")
+	ErrReplay    = errors.New("nonce already used")
+)
+
+var sourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Payload is the documented body of an inbound webhook call.
+type Payload struct {
+	// UserID is the user the message is filed under.
+	UserID string `json:"user_id"`
+	// Source names the sending app, e.g. "ios-shortcuts"; lowercase letters,
+	// digits, dots, dashes and underscores. Defaults to "webhook".
+	Source string `json:"source,omitempty"`
+	// Text is the thought to process, as if the user had typed it.
+	Text string `json:"text"`
+	// Metadata is logged with the message but not interpreted.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// SessionID continues a conversation from an earlier response; empty
+	// starts a new one.
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// Validate normalizes the payload and checks its fields.
+func (p *Payload) Validate() error {
+	p.UserID = strings.TrimSpace(p.UserID)
+	p.Source = strings.ToLower(strings.TrimSpace(p.Source))
+	p.Text = strings.TrimSpace(p.Text)
+	p.SessionID = strings.TrimSpace(p.SessionID)
+
+	if p.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalid)
+	}
+	if p.Text == "" {
+		return fmt.Errorf("%w: text is required", ErrInvalid)
+	}
+	if len(p.Text) > MaxTextLength {
+		return fmt.Errorf("%w: text is longer than %d bytes", ErrInvalid, MaxTextLength)
+	}
+	if p.Source == "" {
+		p.Source = DefaultSource
+	}
+	if !sourcePattern.MatchString(p.Source) {
+		return fmt.Errorf("%w: source must be 1-64 lowercase letters, digits, '.', '-' or '_'", ErrInvalid)
+	}
+	return nil
+}
+
+// Sign computes the signature header value for a request; clients use the
+// same construction.
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks signatures against one or more shared secrets, so a
+// secret can be rotated without downtime.
+type Verifier struct {
+	secrets   []string
+	tolerance time.Duration
+}
+
+func NewVerifier(secrets []string, tolerance time.Duration) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var cleaned []string
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			cleaned = append(cleaned, secret)
+		}
+	}
+	return &Verifier{secrets: cleaned, tolerance: tolerance}
+}
+
+// Enabled reports whether any secret is configured. Without one the
+// endpoint refuses all calls.
+func (v *Verifier) Enabled() bool {
+	return len(v.secrets) > 0
+}
+
+func (v *Verifier) Tolerance() time.Duration {
+	return v.tolerance
+}
+
+// Verify checks the timestamp window and the signature. Nonce reuse is
+// checked separately by the Ledger, once the signature is known to be good.
+func (v *Verifier) Verify(timestamp, nonce, signature string, body []byte, now time.Time) error {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return fmt.Errorf("%w: timestamp, nonce and signature headers are required", ErrSignature)
+	}
+	if len(nonce) > 128 {
+		return fmt.Errorf("%w: nonce is too long", ErrSignature)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp must be unix seconds", ErrSignature)
+	}
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-v.tolerance)) || signedAt.After(now.Add(v.tolerance)) {
+		return ErrExpired
+	}
+
+	for _, secret := range v.secrets {
+		if hmac.Equal([]byte(Sign(secret, timestamp, nonce, body)), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrSignature
+}
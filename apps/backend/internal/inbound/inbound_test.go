@@ -0,0 +1,85 @@
+package inbound
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"user_id":"alice","text":"hello"}`)
+	stamp := func(at time.Time) string { return strconv.FormatInt(at.Unix(), 10) }
+	verifier := NewVerifier([]string{"new-secret", " old-secret "}, time.Minute)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		nonce     string
+		signature string
+		body      []byte
+		wantErr   error
+	}{
+		{"current secret", stamp(now), "n1", Sign("new-secret", stamp(now), "n1", body), body, nil},
+		{"previous secret", stamp(now), "n1", Sign("old-secret", stamp(now), "n1", body), body, nil},
+		{"within tolerance", stamp(now.Add(-50 * time.Second)), "n1", Sign("new-secret", stamp(now.Add(-50*time.Second)), "n1", body), body, nil},
+		{"unknown secret", stamp(now), "n1", Sign("other", stamp(now), "n1", body), body, ErrSignature},
+		{"body changed", stamp(now), "n1", Sign("new-secret", stamp(now), "n1", body), []byte(`{}`), ErrSignature},
+		{"nonce changed", stamp(now), "n2", Sign("new-secret", stamp(now), "n1", body), body, ErrSignature},
+		{"stale", stamp(now.Add(-2 * time.Minute)), "n1", Sign("new-secret", stamp(now.Add(-2*time.Minute)), "n1", body), body, ErrExpired},
+		{"from the future", stamp(now.Add(2 * time.Minute)), "n1", Sign("new-secret", stamp(now.Add(2*time.Minute)), "n1", body), body, ErrExpired},
+		{"timestamp not in seconds", "yesterday", "n1", Sign("new-secret", "yesterday", "n1", body), body, ErrSignature},
+		{"missing nonce", stamp(now), "", Sign("new-secret", stamp(now), "", body), body, ErrSignature},
+		{"nonce too long", stamp(now), strings.Repeat("n", 129), Sign("new-secret", stamp(now), strings.Repeat("n", 129), body), body, ErrSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(tt.timestamp, tt.nonce, tt.signature, tt.body, now)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if NewVerifier([]string{" ", ""}, 0).Enabled() {
+		t.Fatal("verifier without secrets is enabled")
+	}
+}
+
+func TestPayloadValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    Payload
+		wantSource string
+		wantErr    bool
+	}{
+		{"defaults the source", Payload{UserID: " alice ", Text: " hi "}, DefaultSource, false},
+		{"normalizes the source", Payload{UserID: "alice", Text: "hi", Source: " iOS-Shortcuts "}, "ios-shortcuts", false},
+		{"no user", Payload{Text: "hi"}, "", true},
+		{"no text", Payload{UserID: "alice", Text: "  "}, "", true},
+		{"text too long", Payload{UserID: "alice", Text: strings.Repeat("a", MaxTextLength+1)}, "", true},
+		{"bad source", Payload{UserID: "alice", Text: "hi", Source: "my app"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payload.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("Validate = %v, want ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tt.payload.Source != tt.wantSource || tt.payload.UserID != "alice" || tt.payload.Text != "hi" {
+				t.Fatalf("Validate left %+v", tt.payload)
+			}
+		})
+	}
+}
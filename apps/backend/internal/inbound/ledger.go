@@ -0,0 +1,301 @@
+package inbound
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const (
+	// storeName holds the idempotency records, keyed by user so they can be
+	// encrypted at rest; the nonces are not personal and are kept apart
+	storeName       = "inbound"
+	noncesStoreName = "inbound_nonces"
+)
+
+// idempotencyTTL is how long a response is kept for replay under its key.
+const idempotencyTTL = 24 * time.Hour
+
+var (
+	// ErrInProgress is returned by Begin while a request with the same key
+	// is still being processed.
+	ErrInProgress = errors.New("request with this idempotency key is in progress")
+	// ErrKeyReused is returned by Begin when a key comes back with a
+	// different body.
+	ErrKeyReused = errors.New("idempotency key reused with a different payload")
+)
+
+// Record is the stored outcome of a request made with an idempotency key.
+type Record struct {
+	Key       string          `json:"key"`
+	UserID    string          `json:"user_id"`
+	BodyHash  string          `json:"body_hash"`
+	Done      bool            `json:"done"`
+	Status    int             `json:"status,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// legacyState is how the ledger was stored before records were keyed by
+// user.
+type legacyState struct {
+	Nonces  map[string]time.Time `json:"nonces"`
+	Records map[string]*Record   `json:"records"`
+}
+
+// Ledger remembers nonces and idempotency keys across restarts.
+type Ledger struct {
+	store    storage.Store
+	nonceTTL time.Duration
+	// nonces maps each seen nonce to when it can be forgotten
+	nonces map[string]time.Time
+	// records maps each user to their records by idempotency key
+	records map[string]map[string]*Record
+	mutex   sync.Mutex
+}
+
+// NewLedger keeps nonces for nonceTTL, which must cover the whole window
+// in which a signed request is accepted.
+func NewLedger(store storage.Store, nonceTTL time.Duration) (*Ledger, error) {
+	l := &Ledger{
+		store:    store,
+		nonceTTL: nonceTTL,
+		nonces:   make(map[string]time.Time),
+		records:  make(map[string]map[string]*Record),
+	}
+	migrated, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	// Requests in flight when the process stopped never completed; let
+	// their retries through
+	count := 0
+	for userID, records := range l.records {
+		for key, record := range records {
+			if !record.Done {
+				delete(records, key)
+			}
+		}
+		if len(records) == 0 {
+			delete(l.records, userID)
+		}
+		count += len(records)
+	}
+	if migrated {
+		if err := l.persistNonces(); err != nil {
+			return nil, err
+		}
+		if err := l.persistRecords(); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("InboundLedger: Loaded %d nonces and %d idempotency records", len(l.nonces), count)
+	return l, nil
+}
+
+// load reads the nonces and records, converting the legacy document that
+// held both. It reports whether it did, so they are saved in the new
+// layout.
+func (l *Ledger) load() (bool, error) {
+	var raw map[string]json.RawMessage
+	if err := l.store.Load(storeName, &raw); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return false, fmt.Errorf("load inbound ledger: %w", err)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false, fmt.Errorf("load inbound ledger: %w", err)
+	}
+	if _, legacy := raw["nonces"]; legacy && len(raw) == 2 && raw["records"] != nil {
+		var state legacyState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return false, fmt.Errorf("decode inbound ledger: %w", err)
+		}
+		if state.Nonces != nil {
+			l.nonces = state.Nonces
+		}
+		for _, record := range state.Records {
+			l.add(record)
+		}
+		log.Printf("InboundLedger: Moving %d idempotency records to the per-user layout", len(state.Records))
+		return true, nil
+	}
+
+	if err := json.Unmarshal(data, &l.records); err != nil {
+		return false, fmt.Errorf("decode inbound ledger: %w", err)
+	}
+	if l.records == nil {
+		l.records = make(map[string]map[string]*Record)
+	}
+	if err := l.store.Load(noncesStoreName, &l.nonces); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return false, fmt.Errorf("load inbound nonces: %w", err)
+	}
+	if l.nonces == nil {
+		l.nonces = make(map[string]time.Time)
+	}
+	return false, nil
+}
+
+func (l *Ledger) persistNonces() error {
+	return l.store.Save(noncesStoreName, l.nonces)
+}
+
+func (l *Ledger) persistRecords() error {
+	return l.store.Save(storeName, l.records)
+}
+
+// add stores a record under its user. Callers hold the mutex.
+func (l *Ledger) add(record *Record) {
+	if l.records[record.UserID] == nil {
+		l.records[record.UserID] = make(map[string]*Record)
+	}
+	l.records[record.UserID][record.Key] = record
+}
+
+// remove drops a record. Callers hold the mutex.
+func (l *Ledger) remove(userID, key string) {
+	delete(l.records[userID], key)
+	if len(l.records[userID]) == 0 {
+		delete(l.records, userID)
+	}
+}
+
+// prune drops expired entries. Callers hold the mutex.
+func (l *Ledger) prune(now time.Time) {
+	for nonce, expiresAt := range l.nonces {
+		if now.After(expiresAt) {
+			delete(l.nonces, nonce)
+		}
+	}
+	for userID, records := range l.records {
+		for key, record := range records {
+			if record.Done && now.Sub(record.CreatedAt) > idempotencyTTL {
+				l.remove(userID, key)
+			}
+		}
+	}
+}
+
+// Purge drops the user's idempotency records, which hold the responses
+// sent back to them, returning how many there were.
+func (l *Ledger) Purge(userID string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	count := len(l.records[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(l.records, userID)
+	if err := l.persistRecords(); err != nil {
+		return 0, err
+	}
+	log.Printf("InboundLedger: Purged %d idempotency records for user %s", count, userID)
+	return count, nil
+}
+
+func (l *Ledger) Count(userID string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.records[userID]), nil
+}
+
+// UseNonce records a nonce, failing with ErrReplay if it was seen before.
+func (l *Ledger) UseNonce(nonce string, now time.Time) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.prune(now)
+	if _, seen := l.nonces[nonce]; seen {
+		return ErrReplay
+	}
+	l.nonces[nonce] = now.Add(l.nonceTTL)
+	return l.persistNonces()
+}
+
+// HashBody fingerprints a request body for idempotency checks.
+func HashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin claims an idempotency key. It returns the earlier record when the
+// request already completed, or nil when the caller should process it and
+// then call Complete (or Abandon if processing failed).
+func (l *Ledger) Begin(userID, key, bodyHash string, now time.Time) (*Record, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.prune(now)
+	if record, exists := l.records[userID][key]; exists {
+		if record.BodyHash != bodyHash {
+			return nil, ErrKeyReused
+		}
+		if !record.Done {
+			return nil, ErrInProgress
+		}
+		result := *record
+		return &result, nil
+	}
+
+	l.add(&Record{
+		Key:       key,
+		UserID:    userID,
+		BodyHash:  bodyHash,
+		CreatedAt: now,
+	})
+	return nil, l.persistRecords()
+}
+
+// Completed returns the record of a key whose request with the same body
+// already completed, or nil. Unlike Begin it claims nothing, so it can
+// answer an exact retry whose nonce was already used.
+func (l *Ledger) Completed(userID, key, bodyHash string, now time.Time) *Record {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.prune(now)
+	record, exists := l.records[userID][key]
+	if !exists || !record.Done || record.BodyHash != bodyHash {
+		return nil
+	}
+	result := *record
+	return &result
+}
+
+// Complete stores the response sent for a key so retries get the same one.
+func (l *Ledger) Complete(userID, key string, status int, response any) error {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	record, exists := l.records[userID][key]
+	if !exists {
+		return nil
+	}
+	record.Done = true
+	record.Status = status
+	record.Response = encoded
+	return l.persistRecords()
+}
+
+// Abandon releases a key whose processing failed, so a retry can run.
+func (l *Ledger) Abandon(userID, key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.remove(userID, key)
+	if err := l.persistRecords(); err != nil {
+		log.Printf("InboundLedger: Failed to persist after abandoning key %s: %v", key, err)
+	}
+}
@@ -0,0 +1,163 @@
+package inbound
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+func newLedger(t *testing.T, store storage.Store) *Ledger {
+	t.Helper()
+	ledger, err := NewLedger(store, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ledger
+}
+
+func TestLedgerUseNonce(t *testing.T) {
+	store := storage.NewMemoryStore()
+	ledger := newLedger(t, store)
+	now := time.Now()
+
+	if err := ledger.UseNonce("n1", now); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	tests := []struct {
+		name    string
+		ledger  *Ledger
+		at      time.Time
+		wantErr error
+	}{
+		{"reused", ledger, now.Add(time.Minute), ErrReplay},
+		{"reused after a restart", newLedger(t, store), now.Add(time.Minute), ErrReplay},
+		{"forgotten after the ttl", newLedger(t, store), now.Add(11 * time.Minute), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ledger.UseNonce("n1", tt.at); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UseNonce = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLedgerIdempotency(t *testing.T) {
+	now := time.Now()
+	body, other := HashBody([]byte("a")), HashBody([]byte("b"))
+
+	tests := []struct {
+		name string
+		// complete finishes the first request before the retry
+		complete      bool
+		userID        string
+		bodyHash      string
+		at            time.Time
+		wantErr       error
+		wantRecord    bool
+		wantCompleted bool
+	}{
+		{"retry while in progress", false, "alice", body, now, ErrInProgress, false, false},
+		{"retry after completion", true, "alice", body, now, nil, true, true},
+		{"different body", true, "alice", other, now, ErrKeyReused, false, false},
+		{"same key for another user", true, "bob", body, now, nil, false, false},
+		{"after the record expired", true, "alice", body, now.Add(25 * time.Hour), nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := newLedger(t, storage.NewMemoryStore())
+			if record, err := ledger.Begin("alice", "key", body, now); record != nil || err != nil {
+				t.Fatalf("first Begin = %v, %v", record, err)
+			}
+			if tt.complete {
+				if err := ledger.Complete("alice", "key", http.StatusOK, map[string]string{"response": "done"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			completed := ledger.Completed(tt.userID, "key", tt.bodyHash, tt.at)
+			if (completed != nil) != tt.wantCompleted {
+				t.Fatalf("Completed = %v, want a record %v", completed, tt.wantCompleted)
+			}
+			record, err := ledger.Begin(tt.userID, "key", tt.bodyHash, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin = %v, want %v", err, tt.wantErr)
+			}
+			if (record != nil) != tt.wantRecord {
+				t.Fatalf("Begin = %v, want a record %v", record, tt.wantRecord)
+			}
+			if record != nil && (record.Status != http.StatusOK || string(record.Response) != `{"response":"done"}`) {
+				t.Fatalf("Begin replayed %d %s", record.Status, record.Response)
+			}
+		})
+	}
+}
+
+func TestLedgerAbandonAndRestart(t *testing.T) {
+	store := storage.NewMemoryStore()
+	ledger := newLedger(t, store)
+	now := time.Now()
+	body := HashBody([]byte("a"))
+
+	if _, err := ledger.Begin("alice", "abandoned", body, now); err != nil {
+		t.Fatal(err)
+	}
+	ledger.Abandon("alice", "abandoned")
+	if record, err := ledger.Begin("alice", "abandoned", body, now); record != nil || err != nil {
+		t.Fatalf("Begin after Abandon = %v, %v, want a fresh claim", record, err)
+	}
+
+	// The claim above was still in flight when the process stopped
+	if _, err := ledger.Begin("alice", "done", body, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Complete("alice", "done", http.StatusOK, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	restarted := newLedger(t, store)
+	if record, err := restarted.Begin("alice", "abandoned", body, now); record != nil || err != nil {
+		t.Fatalf("Begin of an interrupted request after restart = %v, %v, want a fresh claim", record, err)
+	}
+	if record, err := restarted.Begin("alice", "done", body, now); record == nil || err != nil {
+		t.Fatalf("Begin of a completed request after restart = %v, %v, want the record", record, err)
+	}
+}
+
+func TestLedgerPurgeAndMigration(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := time.Now()
+	legacy := legacyState{
+		Nonces: map[string]time.Time{"n1": now.Add(time.Minute)},
+		Records: map[string]*Record{
+			"k1": {Key: "k1", UserID: "alice", BodyHash: "h", Done: true, Status: http.StatusOK, Response: []byte(`"a"`), CreatedAt: now},
+			"k2": {Key: "k2", UserID: "bob", BodyHash: "h", Done: true, Status: http.StatusOK, Response: []byte(`"b"`), CreatedAt: now},
+		},
+	}
+	if err := store.Save(storeName, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger := newLedger(t, store)
+	if err := ledger.UseNonce("n1", now); !errors.Is(err, ErrReplay) {
+		t.Fatalf("migrated nonce: %v, want ErrReplay", err)
+	}
+	for _, userID := range []string{"alice", "bob"} {
+		if count, _ := ledger.Count(userID); count != 1 {
+			t.Fatalf("%s has %d records after migration, want 1", userID, count)
+		}
+	}
+
+	if count, err := ledger.Purge("alice"); err != nil || count != 1 {
+		t.Fatalf("Purge = %d, %v, want 1", count, err)
+	}
+	restarted := newLedger(t, store)
+	if count, _ := restarted.Count("alice"); count != 0 {
+		t.Fatalf("alice has %d records after purge, want 0", count)
+	}
+	if record := restarted.Completed("bob", "k2", "h", now); record == nil {
+		t.Fatal("other user's record was lost")
+	}
+}
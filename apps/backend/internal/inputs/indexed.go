@@ -0,0 +1,89 @@
+package inputs
+
+import (
+	"log"
+	"slices"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+)
+
+type indexedInputs struct {
+	InputService
+	index *search.Index
+}
+
+// Indexed keeps what users said searchable. Only the input text is indexed,
+// and not for inputs that were searches themselves, so asking the same
+// question twice does not find the first asking.
+func Indexed(service InputService, index *search.Index) InputService {
+	users, err := service.Users()
+	if err != nil {
+		log.Printf("InputService: Failed to list users for indexing: %v", err)
+	}
+	count := 0
+	for _, userID := range users {
+		err := each(service, userID, func(record *Record) {
+			if searchable(record) {
+				index.Put(document(record))
+				count++
+			}
+		})
+		if err != nil {
+			log.Printf("InputService: Failed to index inputs for user %s: %v", userID, err)
+		}
+	}
+	log.Printf("InputService: Indexed %d inputs for search", count)
+	return &indexedInputs{InputService: service, index: index}
+}
+
+// each calls fn for every input the user has, newest first.
+func each(service InputService, userID string, fn func(record *Record)) error {
+	page, err := service.List(userID, Filter{Limit: MaxLimit})
+	for err == nil && len(page.Inputs) > 0 {
+		for i := range page.Inputs {
+			fn(&page.Inputs[i])
+		}
+		page, err = service.List(userID, Filter{Limit: MaxLimit, Offset: page.Offset + len(page.Inputs)})
+	}
+	return err
+}
+
+func document(record *Record) search.Document {
+	at := record.At
+	return search.Document{Kind: search.KindInput, ID: record.ID, UserID: record.UserID, Text: record.Text, At: &at}
+}
+
+func searchable(record *Record) bool {
+	return !slices.Contains(record.ToolsUsed, search.ToolName)
+}
+
+func (i *indexedInputs) Record(record Record) (*Record, error) {
+	saved, err := i.InputService.Record(record)
+	if err == nil && searchable(saved) {
+		i.index.Put(document(saved))
+	}
+	return saved, err
+}
+
+func (i *indexedInputs) Import(userID string, records []Record) (int, error) {
+	added, err := i.InputService.Import(userID, records)
+	if err == nil && added > 0 {
+		err := each(i.InputService, userID, func(record *Record) {
+			if searchable(record) {
+				i.index.Put(document(record))
+			}
+		})
+		if err != nil {
+			log.Printf("InputService: Failed to index imported inputs for user %s: %v", userID, err)
+		}
+	}
+	return added, err
+}
+
+func (i *indexedInputs) Delete(userID, id string) error {
+	err := i.InputService.Delete(userID, id)
+	if err == nil {
+		i.index.Remove(userID, search.KindInput, id)
+	}
+	return err
+}
@@ -0,0 +1,296 @@
+package inputs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "inputs"
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var (
+	ErrNotFound = errors.New("input not found")
+	ErrInvalid  = errors.New("invalid input filter")
+)
+
+type Status string
+
+const (
+	StatusProcessed Status = "processed"
+	StatusFailed    Status = "failed"
+)
+
+// Item is something created or changed while an input was processed.
+type Item struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Title  string `json:"title,omitempty"`
+	Action string `json:"action"`
+}
+
+// Record is one raw input as it reached the orchestrator, with what came
+// of it.
+type Record struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	At        time.Time `json:"at"`
+	Source    string    `json:"source"`
+	SessionID string    `json:"session_id,omitempty"`
+	Text      string    `json:"text"`
+	// VoiceNoteID references the recording the text was transcribed from.
+	VoiceNoteID    string   `json:"voice_note_id,omitempty"`
+	Status         Status   `json:"status"`
+	Response       string   `json:"response,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	ToolsUsed      []string `json:"tools_used"`
+	Items          []Item   `json:"items"`
+	ProcessingTime string   `json:"processing_time"`
+}
+
+// Filter narrows a listing. Zero values match everything.
+type Filter struct {
+	Source    string
+	SessionID string
+	Status    Status
+	// Query matches text and response, case-insensitively.
+	Query  string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// Page is one page of a listing, newest first.
+type Page struct {
+	Inputs []Record `json:"inputs"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+type InputService interface {
+	Record(record Record) (*Record, error)
+	Get(userID, id string) (*Record, error)
+	List(userID string, filter Filter) (*Page, error)
+	Delete(userID, id string) error
+	// Import adds records from an archive, keeping their IDs and times.
+	// Records the user already has are skipped; it returns how many were
+	// added.
+	Import(userID string, records []Record) (int, error)
+	// Purge deletes the user's whole input log, returning how many records
+	// it held.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type service struct {
+	store  storage.Store
+	inputs map[string][]Record
+	mutex  sync.RWMutex
+}
+
+func NewService(store storage.Store) (InputService, error) {
+	s := &service{
+		store:  store,
+		inputs: make(map[string][]Record),
+	}
+	if err := store.Load(storeName, &s.inputs); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load inputs: %w", err)
+	}
+	log.Printf("InputService: Loaded inputs for %d users", len(s.inputs))
+	return s, nil
+}
+
+func (s *service) persist() error {
+	return s.store.Save(storeName, s.inputs)
+}
+
+func (s *service) Record(record Record) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record.ID = storage.NewID()
+	if record.At.IsZero() {
+		record.At = time.Now()
+	}
+	if record.ToolsUsed == nil {
+		record.ToolsUsed = []string{}
+	}
+	if record.Items == nil {
+		record.Items = []Item{}
+	}
+
+	s.inputs[record.UserID] = append(s.inputs[record.UserID], record)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("InputService: Recorded input %s for user %s from %s (%d items)", record.ID, record.UserID, record.Source, len(record.Items))
+	return &record, nil
+}
+
+func (s *service) Get(userID, id string) (*Record, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, record := range s.inputs[userID] {
+		if record.ID == id {
+			return &record, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *service) List(userID string, filter Filter) (*Page, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalid, MaxLimit)
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalid)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, fmt.Errorf("%w: 'to' is before 'from'", ErrInvalid)
+	}
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+
+	s.mutex.RLock()
+	matches := make([]Record, 0)
+	for _, record := range s.inputs[userID] {
+		if filter.Source != "" && record.Source != filter.Source {
+			continue
+		}
+		if filter.SessionID != "" && record.SessionID != filter.SessionID {
+			continue
+		}
+		if filter.Status != "" && record.Status != filter.Status {
+			continue
+		}
+		if !filter.From.IsZero() && record.At.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !record.At.Before(filter.To) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(record.Text), query) && !strings.Contains(strings.ToLower(record.Response), query) {
+			continue
+		}
+		matches = append(matches, record)
+	}
+	s.mutex.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].At.After(matches[j].At)
+	})
+
+	page := &Page{Inputs: []Record{}, Total: len(matches), Limit: filter.Limit, Offset: filter.Offset}
+	if filter.Offset < len(matches) {
+		end := min(filter.Offset+filter.Limit, len(matches))
+		page.Inputs = matches[filter.Offset:end]
+	}
+	return page, nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := s.inputs[userID]
+	for i, record := range list {
+		if record.ID == id {
+			s.inputs[userID] = append(list[:i], list[i+1:]...)
+			if len(s.inputs[userID]) == 0 {
+				delete(s.inputs, userID)
+			}
+			if err := s.persist(); err != nil {
+				return err
+			}
+			log.Printf("InputService: Deleted input %s for user %s", id, userID)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *service) Import(userID string, records []Record) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing := make(map[string]bool, len(s.inputs[userID]))
+	for _, record := range s.inputs[userID] {
+		existing[record.ID] = true
+	}
+	added := 0
+	for _, record := range records {
+		if record.ID == "" || existing[record.ID] {
+			continue
+		}
+		record.UserID = userID
+		if record.ToolsUsed == nil {
+			record.ToolsUsed = []string{}
+		}
+		if record.Items == nil {
+			record.Items = []Item{}
+		}
+		existing[record.ID] = true
+		s.inputs[userID] = append(s.inputs[userID], record)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("InputService: Imported %d inputs for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.inputs[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.inputs, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("InputService: Purged %d inputs for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.inputs[userID]), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.inputs))
+	for userID := range s.inputs {
+		users = append(users, userID)
+	}
+	return users, nil
+}
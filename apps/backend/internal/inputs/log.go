@@ -0,0 +1,75 @@
+package inputs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type loggedOrchestrator struct {
+	orchestrator.Orchestrator
+	service InputService
+	tracker *Tracker
+}
+
+// Log wraps an orchestrator so every input it processes, whichever channel
+// it came from, is kept as a Record.
+func Log(inner orchestrator.Orchestrator, service InputService, tracker *Tracker) orchestrator.Orchestrator {
+	return &loggedOrchestrator{Orchestrator: inner, service: service, tracker: tracker}
+}
+
+func (l *loggedOrchestrator) ProcessInput(input string) (string, error) {
+	detailed, err := l.ProcessInputDetailed(input)
+	if err != nil {
+		return "", err
+	}
+	return detailed.Result.FinalResponse, nil
+}
+
+func (l *loggedOrchestrator) ProcessInputDetailed(input string) (*types.ProcessResponse, error) {
+	return l.Process(orchestrator.Request{UserID: types.DefaultUserID, Input: input, Source: orchestrator.SourceWeb})
+}
+
+func (l *loggedOrchestrator) Process(req orchestrator.Request) (*types.ProcessResponse, error) {
+	if req.UserID == "" {
+		req.UserID = types.DefaultUserID
+	}
+	start := time.Now()
+	finish := l.tracker.begin(req.UserID)
+	response, err := l.Orchestrator.Process(req)
+	items := finish()
+
+	record := Record{
+		UserID:         req.UserID,
+		At:             start,
+		Source:         req.Source,
+		SessionID:      req.SessionID,
+		Text:           req.Input,
+		VoiceNoteID:    req.VoiceNoteID,
+		Items:          items,
+		ProcessingTime: time.Since(start).String(),
+	}
+	if err != nil {
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	} else {
+		record.Status = StatusProcessed
+		record.SessionID = response.SessionID
+		record.Response = response.Result.FinalResponse
+		for _, execution := range response.Result.ProcessingDetails.ToolExecutions {
+			if execution.Status == "success" {
+				record.ToolsUsed = append(record.ToolsUsed, execution.ToolName)
+			}
+		}
+	}
+
+	saved, saveErr := l.service.Record(record)
+	if saveErr != nil {
+		log.Printf("InputService: Failed to record input for user %s: %v", req.UserID, saveErr)
+	} else if response != nil {
+		response.InputID = saved.ID
+	}
+	return response, err
+}
@@ -0,0 +1,75 @@
+package inputs
+
+import (
+	"log"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/memory"
+)
+
+type rememberedInputs struct {
+	InputService
+	memories memory.MemoryService
+}
+
+// Remembered embeds what users said so the orchestrator can recall it for
+// later inputs. Inputs logged before memory was enabled are embedded when
+// the wrapper is built; ones already remembered are skipped.
+func Remembered(service InputService, memories memory.MemoryService) InputService {
+	users, err := service.Users()
+	if err != nil {
+		log.Printf("InputService: Failed to list users for memory: %v", err)
+	}
+	for _, userID := range users {
+		if err := remember(service, memories, userID); err != nil {
+			log.Printf("InputService: Failed to remember inputs for user %s: %v", userID, err)
+		}
+	}
+	return &rememberedInputs{InputService: service, memories: memories}
+}
+
+// remember embeds every input the user has; ones already remembered are
+// skipped by the memory service.
+func remember(service InputService, memories memory.MemoryService, userID string) error {
+	var items []memory.Item
+	err := each(service, userID, func(record *Record) {
+		items = append(items, memoryItem(record))
+	})
+	if err != nil {
+		return err
+	}
+	return memories.Remember(userID, items...)
+}
+
+func memoryItem(record *Record) memory.Item {
+	return memory.Item{Kind: memory.KindInput, RefID: record.ID, Text: record.Text, At: record.At}
+}
+
+func (r *rememberedInputs) Record(record Record) (*Record, error) {
+	saved, err := r.InputService.Record(record)
+	if err == nil {
+		if err := r.memories.Remember(saved.UserID, memoryItem(saved)); err != nil {
+			log.Printf("InputService: Failed to remember input %s: %v", saved.ID, err)
+		}
+	}
+	return saved, err
+}
+
+func (r *rememberedInputs) Import(userID string, records []Record) (int, error) {
+	added, err := r.InputService.Import(userID, records)
+	if err == nil && added > 0 {
+		if err := remember(r.InputService, r.memories, userID); err != nil {
+			log.Printf("InputService: Failed to remember imported inputs for user %s: %v", userID, err)
+		}
+	}
+	return added, err
+}
+
+func (r *rememberedInputs) Delete(userID, id string) error {
+	err := r.InputService.Delete(userID, id)
+	if err == nil {
+		if err := r.memories.Forget(userID, memory.KindInput, id); err != nil {
+			log.Printf("InputService: Failed to forget input %s: %v", id, err)
+		}
+	}
+	return err
+}
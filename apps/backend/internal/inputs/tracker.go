@@ -0,0 +1,86 @@
+package inputs
+
+import (
+	"sync"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/webhooks"
+)
+
+// Tracker links items to the input being processed. It receives the
+// events the webhooks.Observe* wrappers publish and hands each one to every
+// capture open for that user. Two inputs processed for the same user at
+// the same time both see each other's items.
+type Tracker struct {
+	captures map[string][]*capture
+	mutex    sync.Mutex
+}
+
+type capture struct {
+	items []Item
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{captures: make(map[string][]*capture)}
+}
+
+// begin starts collecting items for userID; the returned function stops
+// and returns them.
+func (t *Tracker) begin(userID string) func() []Item {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	c := &capture{items: []Item{}}
+	t.captures[userID] = append(t.captures[userID], c)
+	return func() []Item {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		open := t.captures[userID]
+		for i, other := range open {
+			if other == c {
+				t.captures[userID] = append(open[:i], open[i+1:]...)
+				break
+			}
+		}
+		if len(t.captures[userID]) == 0 {
+			delete(t.captures, userID)
+		}
+		return c.items
+	}
+}
+
+func (t *Tracker) Publish(userID, event string, data any) error {
+	item, ok := itemFor(event, data)
+	if !ok {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, c := range t.captures[userID] {
+		c.items = append(c.items, item)
+	}
+	return nil
+}
+
+func itemFor(event string, data any) (Item, bool) {
+	switch value := data.(type) {
+	case *tasks.Task:
+		action := "created"
+		if event == webhooks.EventTaskCompleted {
+			action = "completed"
+		}
+		return Item{Type: "task", ID: value.ID, Title: value.Title, Action: action}, true
+	case *notes.Note:
+		return Item{Type: "note", ID: value.ID, Title: value.Title, Action: "created"}, true
+	case *goals.Goal:
+		return Item{Type: "goal", ID: value.ID, Title: value.Title, Action: "created"}, true
+	case *reminders.Reminder:
+		return Item{Type: "reminder", ID: value.ID, Title: value.Message, Action: "created"}, true
+	}
+	return Item{}, false
+}
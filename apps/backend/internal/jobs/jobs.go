@@ -0,0 +1,338 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+const storeName = "jobs"
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrInvalid  = errors.New("invalid job")
+)
+
+// Job is one input processed in the background. Result is set once it
+// succeeds.
+type Job struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Input     string `json:"input"`
+	Source    string `json:"source"`
+	SessionID string `json:"session_id,omitempty"`
+	Status    Status `json:"status"`
+	// Stage and Detail tell how far a running job has got, e.g.
+	// "running_tools" and "2 of 3: tasks".
+	Stage      string                 `json:"stage,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Result     *types.ProcessResponse `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+func (j *Job) finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+type JobService interface {
+	// Enqueue stores a queued job for the input.
+	Enqueue(userID, input, source, sessionID string) (*Job, error)
+	Get(userID, id string) (*Job, error)
+	// List returns the user's jobs, newest first, optionally only those
+	// with the given status.
+	List(userID string, status Status) ([]Job, error)
+
+	// Claim marks the oldest queued job as running and returns it, or nil
+	// when none is queued.
+	Claim() (*Job, error)
+	// Progress records the stage a running job has reached.
+	Progress(userID, id, stage, detail string) error
+	// Finish records the outcome of a running job.
+	Finish(userID, id string, result *types.ProcessResponse, err error) error
+	// Prune drops jobs that finished before the cutoff.
+	Prune(before time.Time) (int, error)
+	// Queued signals when a job is enqueued.
+	Queued() <-chan struct{}
+
+	// Purge deletes the user's jobs, queued ones included.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+}
+
+type service struct {
+	store storage.Store
+	// jobs are keyed by user, which is also what lets the document be
+	// encrypted at rest
+	jobs  map[string][]*Job
+	wake  chan struct{}
+	mutex sync.Mutex
+}
+
+// NewService loads the queue. Jobs that were running when the server
+// stopped are failed rather than run again, since part of their work may
+// already be done; they can be resubmitted.
+func NewService(store storage.Store) (JobService, error) {
+	s := &service{
+		store: store,
+		jobs:  make(map[string][]*Job),
+		wake:  make(chan struct{}, 1),
+	}
+	if err := store.Load(storeName, &s.jobs); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load jobs: %w", err)
+	}
+	if s.jobs == nil {
+		s.jobs = make(map[string][]*Job)
+	}
+
+	queued, interrupted := 0, 0
+	now := time.Now()
+	for _, list := range s.jobs {
+		for _, job := range list {
+			switch job.Status {
+			case StatusQueued:
+				queued++
+			case StatusRunning:
+				job.Status = StatusFailed
+				job.Error = "Interrupted by a server restart, submit it again"
+				job.FinishedAt = &now
+				interrupted++
+			}
+		}
+	}
+	if interrupted > 0 {
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+	}
+	if queued > 0 {
+		s.signal()
+	}
+
+	log.Printf("JobService: Loaded jobs for %d users (%d queued, %d interrupted)", len(s.jobs), queued, interrupted)
+	return s, nil
+}
+
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.jobs); err != nil {
+		return fmt.Errorf("save jobs: %w", err)
+	}
+	return nil
+}
+
+func (s *service) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// find returns the user's job with the given ID. Callers hold the mutex.
+func (s *service) find(userID, id string) *Job {
+	for _, job := range s.jobs[userID] {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+func (s *service) Enqueue(userID, input, source, sessionID string) (*Job, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, fmt.Errorf("%w: input is required", ErrInvalid)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job := &Job{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		Input:     input,
+		Source:    source,
+		SessionID: sessionID,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}
+	s.jobs[userID] = append(s.jobs[userID], job)
+	if err := s.persist(); err != nil {
+		s.jobs[userID] = s.jobs[userID][:len(s.jobs[userID])-1]
+		return nil, err
+	}
+	s.signal()
+
+	log.Printf("JobService: Queued job %s for user %s", job.ID, userID)
+	copied := *job
+	return &copied, nil
+}
+
+func (s *service) Get(userID, id string) (*Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job := s.find(userID, id)
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (s *service) List(userID string, status Status) ([]Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := make([]Job, 0)
+	for _, job := range s.jobs[userID] {
+		if status == "" || job.Status == status {
+			result = append(result, *job)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (s *service) Claim() (*Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var next *Job
+	for _, list := range s.jobs {
+		for _, job := range list {
+			if job.Status == StatusQueued && (next == nil || job.CreatedAt.Before(next.CreatedAt)) {
+				next = job
+			}
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	now := time.Now()
+	next.Status = StatusRunning
+	next.StartedAt = &now
+	if err := s.persist(); err != nil {
+		next.Status = StatusQueued
+		next.StartedAt = nil
+		return nil, err
+	}
+	copied := *next
+	return &copied, nil
+}
+
+func (s *service) Progress(userID, id, stage, detail string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job := s.find(userID, id)
+	if job == nil {
+		return ErrNotFound
+	}
+	// Progress only lives in memory until the job finishes; a restart
+	// fails running jobs anyway
+	job.Stage = stage
+	job.Detail = detail
+	return nil
+}
+
+func (s *service) Finish(userID, id string, result *types.ProcessResponse, err error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job := s.find(userID, id)
+	if job == nil {
+		// Purged while it ran
+		return ErrNotFound
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Stage = ""
+	job.Detail = ""
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusSucceeded
+		job.Result = result
+		if result != nil {
+			job.SessionID = result.SessionID
+		}
+	}
+	return s.persist()
+}
+
+func (s *service) Prune(before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pruned := 0
+	for userID, list := range s.jobs {
+		kept := list[:0]
+		for _, job := range list {
+			if job.finished() && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+				pruned++
+				continue
+			}
+			kept = append(kept, job)
+		}
+		if len(kept) == 0 {
+			delete(s.jobs, userID)
+		} else {
+			s.jobs[userID] = kept
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+	log.Printf("JobService: Pruned %d finished jobs", pruned)
+	return pruned, nil
+}
+
+func (s *service) Queued() <-chan struct{} {
+	return s.wake
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.jobs[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.jobs, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("JobService: Purged %d jobs for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.jobs[userID]), nil
+}
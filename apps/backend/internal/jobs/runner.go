@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+)
+
+// pruneInterval is how often finished jobs past the retention are dropped.
+const pruneInterval = time.Hour
+
+// Runner processes queued jobs in the background with a bounded number of
+// workers.
+type Runner struct {
+	service      JobService
+	orchestrator orchestrator.Orchestrator
+	workers      int
+	retention    time.Duration
+	// slots holds one token per idle worker
+	slots   chan struct{}
+	running sync.WaitGroup
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewRunner runs jobs through orch, at most workers at a time. Finished
+// jobs are kept for retention.
+func NewRunner(service JobService, orch orchestrator.Orchestrator, workers int, retention time.Duration) *Runner {
+	if workers <= 0 {
+		workers = 1
+	}
+	r := &Runner{
+		service:      service,
+		orchestrator: orch,
+		workers:      workers,
+		retention:    retention,
+		slots:        make(chan struct{}, workers),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		r.slots <- struct{}{}
+	}
+	return r
+}
+
+func (r *Runner) Start() {
+	go r.run()
+	log.Printf("JobRunner: Started (%d workers, finished jobs kept for %s)", r.workers, r.retention)
+}
+
+// Stop waits for the jobs already running to finish.
+func (r *Runner) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.done
+		r.running.Wait()
+		log.Printf("JobRunner: Stopped")
+	})
+}
+
+func (r *Runner) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	r.prune()
+	for {
+		r.dispatch()
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.prune()
+		case <-r.service.Queued():
+		}
+	}
+}
+
+// dispatch hands queued jobs to idle workers until the queue is empty. A
+// job is only claimed once a worker is free, so queued jobs never look
+// running while they wait.
+func (r *Runner) dispatch() {
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-r.slots:
+		}
+		job, err := r.service.Claim()
+		if err != nil || job == nil {
+			if err != nil {
+				log.Printf("JobRunner: Failed to claim a job: %v", err)
+			}
+			r.slots <- struct{}{}
+			return
+		}
+		r.running.Add(1)
+		go func() {
+			defer func() {
+				r.slots <- struct{}{}
+				r.running.Done()
+			}()
+			r.process(job)
+		}()
+	}
+}
+
+func (r *Runner) process(job *Job) {
+	start := time.Now()
+	log.Printf("JobRunner: Running job %s for user %s", job.ID, job.UserID)
+
+	response, err := r.orchestrator.Process(orchestrator.Request{
+		UserID:    job.UserID,
+		Input:     job.Input,
+		Source:    job.Source,
+		SessionID: job.SessionID,
+		Progress: func(stage, detail string) {
+			if err := r.service.Progress(job.UserID, job.ID, stage, detail); err != nil && !errors.Is(err, ErrNotFound) {
+				log.Printf("JobRunner: Failed to record progress of job %s: %v", job.ID, err)
+			}
+		},
+	})
+	if finishErr := r.service.Finish(job.UserID, job.ID, response, err); finishErr != nil {
+		if errors.Is(finishErr, ErrNotFound) {
+			log.Printf("JobRunner: Job %s was deleted while it ran", job.ID)
+			return
+		}
+		log.Printf("JobRunner: Failed to record outcome of job %s: %v", job.ID, finishErr)
+		return
+	}
+
+	if err != nil {
+		log.Printf("JobRunner: Job %s failed after %s: %v", job.ID, time.Since(start), err)
+		return
+	}
+	log.Printf("JobRunner: Job %s succeeded in %s", job.ID, time.Since(start))
+}
+
+func (r *Runner) prune() {
+	if r.retention <= 0 {
+		return
+	}
+	if _, err := r.service.Prune(time.Now().Add(-r.retention)); err != nil {
+		log.Printf("JobRunner: Failed to prune finished jobs: %v", err)
+	}
+}
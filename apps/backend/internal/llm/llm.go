@@ -3,6 +3,7 @@ package llm
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -20,11 +21,41 @@ type ToolDescriptor struct {
 type ToolSelection struct {
 	ToolName string
 	Reason   string
+	// Input is the request restated so it stands on its own, with
+	// references to earlier turns resolved. Empty means use the raw input.
+	Input string
 }
 
+// ConversationTurn is one earlier message shown to the LLM for context.
+type ConversationTurn struct {
+	Role    string
+	Content string
+}
+
+// Conversation is what the LLM knows beyond the current input: the recent
+// turns of the session, a summary of the user's profile and earlier
+// memories related to the input.
+type Conversation struct {
+	ProfileSummary string
+	Turns          []ConversationTurn
+	Memories       []string
+}
+
+func (c Conversation) empty() bool {
+	return c.ProfileSummary == "" && len(c.Turns) == 0 && len(c.Memories) == 0
+}
+
+// ErrUnavailable is returned by Complete when no LLM backend is configured.
+// Callers are expected to fall back to their own heuristics.
+var ErrUnavailable = errors.New("LLM unavailable")
+
 type LLMService interface {
 	SelectTools(userInput string, availableTools []ToolDescriptor) ([]ToolSelection, error)
+	// SelectToolsInContext is SelectTools with the conversation so far, so
+	// follow-ups like "and the other one" can be resolved.
+	SelectToolsInContext(userInput string, availableTools []ToolDescriptor, conversation Conversation) ([]ToolSelection, error)
 	ProcessText(input string) (string, error)
+	Complete(prompt string) (string, error)
 }
 
 type service struct {
@@ -46,7 +77,11 @@ func NewService(cfg *config.Config) LLMService {
 }
 
 func (s *service) SelectTools(userInput string, availableTools []ToolDescriptor) ([]ToolSelection, error) {
-	log.Printf("🔍 LLM Tool Selection for: '%s'", userInput)
+	return s.SelectToolsInContext(userInput, availableTools, Conversation{})
+}
+
+func (s *service) SelectToolsInContext(userInput string, availableTools []ToolDescriptor, conversation Conversation) ([]ToolSelection, error) {
+	log.Printf("🔍 LLM Tool Selection for: '%s' (%d earlier turns)", userInput, len(conversation.Turns))
 
 	if !s.config.HasAnthropicKey() {
 		log.Printf("⚠️  No API key - using fallback selection")
@@ -58,7 +93,7 @@ func (s *service) SelectTools(userInput string, availableTools []ToolDescriptor)
 		log.Printf("   • %s: %s", tool.Name, tool.Description)
 	}
 
-	prompt := s.buildToolSelectionPrompt(userInput, availableTools)
+	prompt := s.buildToolSelectionPrompt(userInput, availableTools, conversation)
 	response, err := s.callAnthropic(prompt)
 	if err != nil {
 		log.Printf("❌ Anthropic API error: %v", err)
@@ -106,6 +141,13 @@ func (s *service) ProcessText(input string) (string, error) {
 	return response, nil
 }
 
+func (s *service) Complete(prompt string) (string, error) {
+	if !s.config.HasAnthropicKey() {
+		return "", ErrUnavailable
+	}
+	return s.callAnthropic(prompt)
+}
+
 type anthropicRequest struct {
 	Model     string    `json:"model"`
 	MaxTokens int       `json:"max_tokens"`
@@ -198,10 +240,35 @@ func (s *service) callAnthropic(prompt string) (string, error) {
 	return responseText, nil
 }
 
-func (s *service) buildToolSelectionPrompt(userInput string, tools []ToolDescriptor) string {
+func (s *service) buildToolSelectionPrompt(userInput string, tools []ToolDescriptor, conversation Conversation) string {
 	toolsJSON, _ := json.MarshalIndent(tools, "", "  ")
+
+	var contextSection string
+	if !conversation.empty() {
+		var b strings.Builder
+		if conversation.ProfileSummary != "" {
+			b.WriteString("What we know about the user:\n")
+			b.WriteString(conversation.ProfileSummary)
+			b.WriteString("\n\n")
+		}
+		if len(conversation.Memories) > 0 {
+			b.WriteString("Related things the user said or saved earlier:\n")
+			for _, memory := range conversation.Memories {
+				fmt.Fprintf(&b, "- %s\n", memory)
+			}
+			b.WriteString("\n")
+		}
+		if len(conversation.Turns) > 0 {
+			b.WriteString("Conversation so far (oldest first):\n")
+			for _, turn := range conversation.Turns {
+				fmt.Fprintf(&b, "%s: %s\n", turn.Role, turn.Content)
+			}
+			b.WriteString("\n")
+		}
+		contextSection = b.String()
+	}
 	
-	return fmt.Sprintf(`Given this user input: "%s"
+	return fmt.Sprintf(`%sGiven this user input: "%s"
 
 Select the most appropriate tools from this list:
 %s
@@ -210,7 +277,8 @@ Return a JSON array of tool selections with this format:
 [
   {
     "tool_name": "tool_name",
-    "reason": "explanation for why this tool was selected"
+    "reason": "explanation for why this tool was selected",
+    "input": "the request for this tool, rewritten to stand on its own"
   }
 ]
 
@@ -218,7 +286,8 @@ IMPORTANT:
 - You can select 0-3 tools based on what's most appropriate
 - If no tools are suitable for this input, return an empty array: []
 - Only select tools that would genuinely help process this specific input
-- Don't force a selection if none of the tools are relevant`, userInput, string(toolsJSON))
+- Don't force a selection if none of the tools are relevant
+- If the input refers to earlier messages ("the other one", "that"), resolve the reference in "input"; otherwise "input" may be omitted`, contextSection, userInput, string(toolsJSON))
 }
 
 func (s *service) parseToolSelections(response string) ([]ToolSelection, error) {
@@ -235,6 +304,7 @@ func (s *service) parseToolSelections(response string) ([]ToolSelection, error)
 	var rawSelections []struct {
 		ToolName string `json:"tool_name"`
 		Reason   string `json:"reason"`
+		Input    string `json:"input"`
 	}
 	
 	if err := json.Unmarshal([]byte(jsonStr), &rawSelections); err != nil {
@@ -246,26 +316,38 @@ func (s *service) parseToolSelections(response string) ([]ToolSelection, error)
 		selections[i] = ToolSelection{
 			ToolName: raw.ToolName,
 			Reason:   raw.Reason,
+			Input:    strings.TrimSpace(raw.Input),
 		}
 	}
 	
 	return selections, nil
 }
 
-func (s *service) fallbackToolSelection(userInput string, availableTools []ToolDescriptor) ([]ToolSelection, error) {
-	log.Printf("🔧 Using fallback tool selection")
-	
-	if len(availableTools) == 0 {
-		log.Printf("❌ No tools available for fallback")
-		return []ToolSelection{}, nil
+// DecodeJSON extracts the first JSON object or array from an LLM response,
+// which may be wrapped in prose or code fences, and decodes it into v.
+func DecodeJSON(response string, v any) error {
+	objStart := strings.Index(response, "{")
+	arrStart := strings.Index(response, "[")
+
+	open, closing := "{", "}"
+	if arrStart != -1 && (objStart == -1 || arrStart < objStart) {
+		open, closing = "[", "]"
 	}
-	
-	// Simple fallback: select first tool
-	selection := ToolSelection{
-		ToolName: availableTools[0].Name,
-		Reason:   "Fallback selection - first available tool",
+
+	startIdx := strings.Index(response, open)
+	endIdx := strings.LastIndex(response, closing)
+	if startIdx == -1 || endIdx == -1 || endIdx < startIdx {
+		return fmt.Errorf("no JSON found in response")
 	}
-	
-	log.Printf("✅ Fallback selected: %s - %s", selection.ToolName, selection.Reason)
-	return []ToolSelection{selection}, nil
+
+	return json.Unmarshal([]byte(response[startIdx:endIdx+1]), v)
+}
+
+// fallbackToolSelection runs when the LLM can't choose. Several tools
+// change state (tasks, notes, goals, reminders) and ListTools has no
+// stable order, so guessing could act on input nobody meant as a command;
+// no tools are selected and the input is handled as a reflection.
+func (s *service) fallbackToolSelection(userInput string, availableTools []ToolDescriptor) ([]ToolSelection, error) {
+	log.Printf("🔧 Using fallback tool selection: none of %d tools selected", len(availableTools))
+	return []ToolSelection{}, nil
 }
\ No newline at end of file
@@ -0,0 +1,23 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/config"
+)
+
+func TestSelectToolsWithoutAPIKeySelectsNothing(t *testing.T) {
+	service := NewService(&config.Config{})
+	available := []ToolDescriptor{
+		{Name: "tasks", Description: "Creates and completes tasks"},
+		{Name: "notes", Description: "Captures notes"},
+	}
+
+	selections, err := service.SelectTools("buy milk", available)
+	if err != nil {
+		t.Fatalf("SelectTools: %v", err)
+	}
+	if len(selections) != 0 {
+		t.Fatalf("SelectTools = %v, want no tools", selections)
+	}
+}
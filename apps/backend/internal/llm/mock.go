@@ -47,9 +47,18 @@ func (m *MockLLMService) SelectTools(userInput string, availableTools []ToolDesc
 	return selections, nil
 }
 
+func (m *MockLLMService) SelectToolsInContext(userInput string, availableTools []ToolDescriptor, conversation Conversation) ([]ToolSelection, error) {
+	return m.SelectTools(userInput, availableTools)
+}
+
 func (m *MockLLMService) ProcessText(input string) (string, error) {
 	log.Printf("MockLLMService: Processing text: %s", input)
 	response := "Mock LLM response: " + input
 	log.Printf("MockLLMService: Generated response: %s", response)
 	return response, nil
-}
\ No newline at end of file
+}
+
+func (m *MockLLMService) Complete(prompt string) (string, error) {
+	log.Printf("MockLLMService: Complete requested, reporting unavailable")
+	return "", ErrUnavailable
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"log"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/redact"
+)
+
+// redacted replaces personal data with placeholders before anything is
+// sent to the wrapped service and puts it back into what comes out, so the
+// LLM provider never sees it. Each call gets its own placeholders.
+type redacted struct {
+	LLMService
+	redactor *redact.Redactor
+}
+
+func Redacted(service LLMService, redactor *redact.Redactor) LLMService {
+	return &redacted{LLMService: service, redactor: redactor}
+}
+
+func (r *redacted) SelectTools(userInput string, availableTools []ToolDescriptor) ([]ToolSelection, error) {
+	return r.SelectToolsInContext(userInput, availableTools, Conversation{})
+}
+
+func (r *redacted) SelectToolsInContext(userInput string, availableTools []ToolDescriptor, conversation Conversation) ([]ToolSelection, error) {
+	session := r.redactor.Session()
+	safe := Conversation{ProfileSummary: session.Redact(conversation.ProfileSummary)}
+	for _, turn := range conversation.Turns {
+		safe.Turns = append(safe.Turns, ConversationTurn{Role: turn.Role, Content: session.Redact(turn.Content)})
+	}
+	for _, memory := range conversation.Memories {
+		safe.Memories = append(safe.Memories, session.Redact(memory))
+	}
+	userInput = session.Redact(userInput)
+	logRedactions("tool selection", session)
+
+	selections, err := r.LLMService.SelectToolsInContext(userInput, availableTools, safe)
+	for i := range selections {
+		selections[i].Reason = session.Rehydrate(selections[i].Reason)
+		selections[i].Input = session.Rehydrate(selections[i].Input)
+	}
+	return selections, err
+}
+
+func (r *redacted) ProcessText(input string) (string, error) {
+	session := r.redactor.Session()
+	input = session.Redact(input)
+	logRedactions("text processing", session)
+
+	response, err := r.LLMService.ProcessText(input)
+	return session.Rehydrate(response), err
+}
+
+func (r *redacted) Complete(prompt string) (string, error) {
+	session := r.redactor.Session()
+	prompt = session.Redact(prompt)
+	logRedactions("completion", session)
+
+	response, err := r.LLMService.Complete(prompt)
+	return session.Rehydrate(response), err
+}
+
+func logRedactions(call string, session *redact.Session) {
+	total := 0
+	for _, count := range session.Counts() {
+		total += count
+	}
+	if total > 0 {
+		log.Printf("🔒 Redacted %d pieces of personal data before %s", total, call)
+	}
+}
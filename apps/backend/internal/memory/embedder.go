@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// related the texts are.
+type Embedder interface {
+	Embed(texts []string) ([][]float32, error)
+	// Name identifies the model; vectors from different names are not
+	// comparable.
+	Name() string
+}
+
+// DefaultEmbeddingTimeout bounds a single embeddings request.
+const DefaultEmbeddingTimeout = 30 * time.Second
+
+// Backends accepted by EmbedderConfig.Backend.
+const (
+	BackendNone    = "none"
+	BackendHashing = "hashing"
+	BackendOpenAI  = "openai"
+)
+
+const (
+	DefaultHashingDimensions = 512
+	DefaultOpenAIURL         = "https://api.openai.com/v1/embeddings"
+	DefaultOpenAIModel       = "text-embedding-3-small"
+)
+
+type EmbedderConfig struct {
+	Backend string
+	// URL is an OpenAI-compatible /embeddings endpoint.
+	URL    string
+	APIKey string
+	Model  string
+	// Dimensions sizes hashing vectors; for the OpenAI backend it is passed
+	// on when set, for models that can shorten their output.
+	Dimensions int
+	Timeout    time.Duration
+}
+
+// NewEmbedder builds the configured backend. It returns nil when memory
+// retrieval is disabled; an empty backend selects the offline hashing
+// embedder.
+func NewEmbedder(config EmbedderConfig) (Embedder, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultEmbeddingTimeout
+	}
+
+	switch config.Backend {
+	case BackendNone:
+		return nil, nil
+	case "", BackendHashing:
+		if config.Dimensions <= 0 {
+			config.Dimensions = DefaultHashingDimensions
+		}
+		return NewHashingEmbedder(config.Dimensions), nil
+	case BackendOpenAI:
+		if config.URL == "" {
+			config.URL = DefaultOpenAIURL
+		}
+		if config.Model == "" {
+			config.Model = DefaultOpenAIModel
+		}
+		return NewOpenAIEmbedder(config.URL, config.APIKey, config.Model, config.Dimensions, config.Timeout), nil
+	}
+	return nil, fmt.Errorf("unknown embedder %q", config.Backend)
+}
+
+// hashingEmbedder needs no model or network: words and word pairs are
+// hashed into a fixed number of buckets, weighted by log term frequency.
+// It matches shared vocabulary rather than meaning, so "plants" finds
+// "watering the plant" but not "ficus".
+type hashingEmbedder struct {
+	dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) Embedder {
+	return &hashingEmbedder{dimensions: dimensions}
+}
+
+func (h *hashingEmbedder) Name() string {
+	return fmt.Sprintf("%s-%d", BackendHashing, h.dimensions)
+}
+
+func (h *hashingEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+// bigramWeight scales word pairs relative to single words.
+const bigramWeight = 0.5
+
+func (h *hashingEmbedder) embed(text string) []float32 {
+	counts := make(map[string]float64)
+	terms := search.Terms(text)
+	for i, term := range terms {
+		counts[term]++
+		if i > 0 {
+			counts[terms[i-1]+" "+term] += bigramWeight
+		}
+	}
+
+	vector := make([]float32, h.dimensions)
+	for feature, count := range counts {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+		// The top bit picks a sign so colliding features tend to cancel out
+		// rather than pile up
+		weight := float32(1 + math.Log(count))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(h.dimensions)] += weight
+	}
+	normalize(vector)
+	return vector
+}
+
+func normalize(vector []float32) {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when they
+// differ in length or either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
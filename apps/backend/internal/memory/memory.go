@@ -0,0 +1,370 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const (
+	storeName = "memories"
+	// embedderStoreName keeps the embedder apart from the memories, which
+	// are keyed by user so they can be encrypted at rest
+	embedderStoreName = "memory_embedder"
+)
+
+const (
+	DefaultTopK     = 5
+	DefaultMinScore = 0.15
+	// MaxMemories caps what is kept per user; the oldest go first.
+	MaxMemories = 5000
+)
+
+// Kinds of memory the built-in sources produce.
+const (
+	KindInput    = "input"
+	KindTask     = "task"
+	KindNote     = "note"
+	KindGoal     = "goal"
+	KindReminder = "reminder"
+)
+
+// Item is something worth remembering: what the user said, or an item
+// extracted from it.
+type Item struct {
+	Kind  string
+	RefID string
+	Text  string
+	At    time.Time
+}
+
+// Memory is an Item with its embedding.
+type Memory struct {
+	Kind   string    `json:"kind"`
+	RefID  string    `json:"ref_id"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+	Vector []float32 `json:"vector"`
+}
+
+// Match is a memory recalled for a query.
+type Match struct {
+	Kind  string    `json:"kind"`
+	RefID string    `json:"ref_id"`
+	Text  string    `json:"text"`
+	At    time.Time `json:"at"`
+	Score float64   `json:"score"`
+}
+
+type Config struct {
+	// TopK is how many memories Recall returns at most.
+	TopK int
+	// MinScore is the cosine similarity below which memories are not
+	// considered relevant.
+	MinScore float64
+}
+
+type MemoryService interface {
+	// Remember embeds and stores items, replacing earlier versions with the
+	// same kind and RefID. Unchanged items are not embedded again.
+	Remember(userID string, items ...Item) error
+	Forget(userID, kind, refID string) error
+	// Recall returns the memories most similar to query, best first.
+	Recall(userID, query string) ([]Match, error)
+	// Purge deletes all of the user's memories and their embeddings,
+	// returning how many there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type embedderState struct {
+	// Embedder names the model the vectors came from.
+	Embedder string `json:"embedder"`
+}
+
+// legacyDocument is how memories were stored before they were keyed by
+// user, with the embedder in the same document.
+type legacyDocument struct {
+	Embedder string              `json:"embedder"`
+	Memories map[string][]Memory `json:"memories"`
+}
+
+type service struct {
+	store    storage.Store
+	embedder Embedder
+	config   Config
+	memories map[string][]Memory
+	mutex    sync.RWMutex
+}
+
+// NewService loads stored memories. Vectors made by another embedder are
+// computed again, so switching models only costs one pass.
+func NewService(store storage.Store, embedder Embedder, config Config) (MemoryService, error) {
+	if config.TopK <= 0 {
+		config.TopK = DefaultTopK
+	}
+	if config.MinScore <= 0 {
+		config.MinScore = DefaultMinScore
+	}
+	s := &service{
+		store:    store,
+		embedder: embedder,
+		config:   config,
+		memories: make(map[string][]Memory),
+	}
+	previous, migrated, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != embedder.Name() {
+		if previous != "" {
+			log.Printf("MemoryService: Embedder changed from %s to %s, re-embedding", previous, embedder.Name())
+		}
+		for userID, memories := range s.memories {
+			if err := s.reembed(memories); err != nil {
+				// Vectors that don't match the embedder are skipped by
+				// Recall; Remember fixes them as items change
+				log.Printf("MemoryService: Failed to re-embed memories for user %s: %v", userID, err)
+			}
+		}
+	}
+	if previous != embedder.Name() || migrated {
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+		if err := s.store.Save(embedderStoreName, embedderState{Embedder: embedder.Name()}); err != nil {
+			return nil, fmt.Errorf("save memory embedder: %w", err)
+		}
+	}
+
+	log.Printf("MemoryService: Loaded memories for %d users (embedder: %s)", len(s.memories), embedder.Name())
+	return s, nil
+}
+
+// load reads the memories and returns the embedder their vectors came
+// from, converting the legacy document that held both. It reports whether
+// it did, so the memories are saved in the new layout.
+func (s *service) load() (string, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := s.store.Load(storeName, &raw); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", false, fmt.Errorf("load memories: %w", err)
+	}
+	// A user's memories are a list; only the legacy document holds an
+	// object under "memories"
+	if legacy := bytes.TrimSpace(raw["memories"]); len(legacy) > 0 && legacy[0] == '{' {
+		var doc legacyDocument
+		if err := remarshal(raw, &doc); err != nil {
+			return "", false, fmt.Errorf("load memories: %w", err)
+		}
+		if doc.Memories != nil {
+			s.memories = doc.Memories
+		}
+		log.Printf("MemoryService: Moving memories of %d users to the per-user layout", len(s.memories))
+		return doc.Embedder, true, nil
+	}
+	if err := remarshal(raw, &s.memories); err != nil {
+		return "", false, fmt.Errorf("load memories: %w", err)
+	}
+	if s.memories == nil {
+		s.memories = make(map[string][]Memory)
+	}
+
+	var state embedderState
+	if err := s.store.Load(embedderStoreName, &state); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", false, fmt.Errorf("load memory embedder: %w", err)
+	}
+	return state.Embedder, false, nil
+}
+
+func remarshal(raw map[string]json.RawMessage, v any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *service) reembed(memories []Memory) error {
+	texts := make([]string, len(memories))
+	for i, memory := range memories {
+		texts[i] = memory.Text
+	}
+	vectors, err := s.embedder.Embed(texts)
+	if err != nil {
+		for i := range memories {
+			memories[i].Vector = nil
+		}
+		return err
+	}
+	for i := range memories {
+		memories[i].Vector = vectors[i]
+	}
+	return nil
+}
+
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.memories); err != nil {
+		return fmt.Errorf("save memories: %w", err)
+	}
+	return nil
+}
+
+func (s *service) find(userID, kind, refID string) int {
+	for i, memory := range s.memories[userID] {
+		if memory.Kind == kind && memory.RefID == refID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *service) Remember(userID string, items ...Item) error {
+	// Embedding may call out over the network, so work out what changed
+	// first and embed without holding the lock
+	s.mutex.RLock()
+	var pending []Item
+	for _, item := range items {
+		item.Text = strings.TrimSpace(item.Text)
+		if item.Text == "" {
+			continue
+		}
+		if idx := s.find(userID, item.Kind, item.RefID); idx != -1 {
+			existing := s.memories[userID][idx]
+			if existing.Text == item.Text && len(existing.Vector) > 0 {
+				continue
+			}
+		}
+		pending = append(pending, item)
+	}
+	s.mutex.RUnlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(pending))
+	for i, item := range pending {
+		texts[i] = item.Text
+	}
+	vectors, err := s.embedder.Embed(texts)
+	if err != nil {
+		return fmt.Errorf("embed: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, item := range pending {
+		if item.At.IsZero() {
+			item.At = time.Now()
+		}
+		memory := Memory{Kind: item.Kind, RefID: item.RefID, Text: item.Text, At: item.At, Vector: vectors[i]}
+		if idx := s.find(userID, item.Kind, item.RefID); idx != -1 {
+			s.memories[userID][idx] = memory
+		} else {
+			s.memories[userID] = append(s.memories[userID], memory)
+		}
+	}
+	if list := s.memories[userID]; len(list) > MaxMemories {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].At.Before(list[j].At) })
+		s.memories[userID] = append([]Memory(nil), list[len(list)-MaxMemories:]...)
+	}
+	return s.persist()
+}
+
+func (s *service) Forget(userID, kind, refID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.find(userID, kind, refID)
+	if idx == -1 {
+		return nil
+	}
+	list := s.memories[userID]
+	s.memories[userID] = append(list[:idx], list[idx+1:]...)
+	if len(s.memories[userID]) == 0 {
+		delete(s.memories, userID)
+	}
+	return s.persist()
+}
+
+func (s *service) Recall(userID, query string) ([]Match, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	s.mutex.RLock()
+	empty := len(s.memories[userID]) == 0
+	s.mutex.RUnlock()
+	if empty {
+		return nil, nil
+	}
+
+	vectors, err := s.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var matches []Match
+	for _, memory := range s.memories[userID] {
+		score := cosine(vectors[0], memory.Vector)
+		if score < s.config.MinScore {
+			continue
+		}
+		matches = append(matches, Match{Kind: memory.Kind, RefID: memory.RefID, Text: memory.Text, At: memory.At, Score: score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > s.config.TopK {
+		matches = matches[:s.config.TopK]
+	}
+	return matches, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.memories[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.memories, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("MemoryService: Purged %d memories for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.memories[userID]), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.memories))
+	for userID := range s.memories {
+		users = append(users, userID)
+	}
+	return users, nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"log"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+// Observer remembers the items extracted from inputs. It receives the
+// events the webhooks.Observe* wrappers publish, like the input tracker.
+type Observer struct {
+	service MemoryService
+}
+
+func NewObserver(service MemoryService) *Observer {
+	return &Observer{service: service}
+}
+
+func (o *Observer) Publish(userID, event string, data any) error {
+	item, ok := ItemFor(data)
+	if !ok {
+		return nil
+	}
+	if err := o.service.Remember(userID, item); err != nil {
+		// Failing to remember must not fail the change that was observed
+		log.Printf("MemoryService: Failed to remember %s %s for user %s: %v", item.Kind, item.RefID, userID, err)
+	}
+	return nil
+}
+
+// ItemFor maps an extracted item to what is remembered of it.
+func ItemFor(data any) (Item, bool) {
+	switch value := data.(type) {
+	case *tasks.Task:
+		return Item{Kind: KindTask, RefID: value.ID, Text: value.Title, At: value.CreatedAt}, true
+	case *notes.Note:
+		text := value.Content
+		if !strings.HasPrefix(value.Content, value.Title) {
+			text = value.Title + "\n" + value.Content
+		}
+		return Item{Kind: KindNote, RefID: value.ID, Text: text, At: value.CreatedAt}, true
+	case *goals.Goal:
+		text := value.Title
+		if value.Motivation != "" {
+			text += " — " + value.Motivation
+		}
+		return Item{Kind: KindGoal, RefID: value.ID, Text: text, At: value.CreatedAt}, true
+	case *reminders.Reminder:
+		return Item{Kind: KindReminder, RefID: value.ID, Text: value.Message, At: value.CreatedAt}, true
+	}
+	return Item{}, false
+}
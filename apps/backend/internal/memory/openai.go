@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// openAIBatchSize caps the texts sent in one request.
+const openAIBatchSize = 96
+
+// openAIEmbedder talks to OpenAI's /v1/embeddings or any server exposing
+// the same API (Ollama, LM Studio, vLLM, LiteLLM).
+type openAIEmbedder struct {
+	url        string
+	apiKey     string
+	model      string
+	dimensions int
+	client     *http.Client
+}
+
+func NewOpenAIEmbedder(url, apiKey, model string, dimensions int, timeout time.Duration) Embedder {
+	return &openAIEmbedder{
+		url:        url,
+		apiKey:     apiKey,
+		model:      model,
+		dimensions: dimensions,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *openAIEmbedder) Name() string {
+	if e.dimensions > 0 {
+		return fmt.Sprintf("%s:%s-%d", BackendOpenAI, e.model, e.dimensions)
+	}
+	return BackendOpenAI + ":" + e.model
+}
+
+func (e *openAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		batch, err := e.embedBatch(texts[start:min(start+openAIBatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *openAIEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	request := map[string]any{
+		"model": e.model,
+		"input": texts,
+	}
+	if e.dimensions > 0 {
+		request["dimensions"] = e.dimensions
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("embeddings endpoint: %w", err)
+	}
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("embeddings endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != nil {
+		message := ""
+		if result.Error != nil {
+			message = result.Error.Message
+		}
+		return nil, fmt.Errorf("embeddings endpoint returned %d: %s", resp.StatusCode, message)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings endpoint returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings endpoint returned out-of-range index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
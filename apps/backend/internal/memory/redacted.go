@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"log"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/redact"
+)
+
+// redactedEmbedder replaces personal data with placeholders before texts
+// are sent to the wrapped embedder, so a remote embeddings provider never
+// sees it. Vectors only need the placeholders to line up, so nothing is
+// rehydrated.
+type redactedEmbedder struct {
+	Embedder
+	redactor *redact.Redactor
+}
+
+func Redacted(embedder Embedder, redactor *redact.Redactor) Embedder {
+	return &redactedEmbedder{Embedder: embedder, redactor: redactor}
+}
+
+// Name sets redacted vectors apart, so memories embedded from raw text are
+// embedded again when redaction is turned on and the other way round.
+func (r *redactedEmbedder) Name() string {
+	return r.Embedder.Name() + "+redacted"
+}
+
+func (r *redactedEmbedder) Embed(texts []string) ([][]float32, error) {
+	safe := make([]string, len(texts))
+	total := 0
+	for i, text := range texts {
+		// One session per text, so the same kind of data gets the same
+		// placeholder wherever it appears
+		session := r.redactor.Session()
+		safe[i] = session.Redact(text)
+		for _, count := range session.Counts() {
+			total += count
+		}
+	}
+	if total > 0 {
+		log.Printf("🔒 Redacted %d pieces of personal data before embedding", total)
+	}
+	return r.Embedder.Embed(safe)
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/redact"
+)
+
+// recordingEmbedder keeps the texts it was asked to embed.
+type recordingEmbedder struct {
+	texts []string
+}
+
+func (r *recordingEmbedder) Name() string { return "recording" }
+
+func (r *recordingEmbedder) Embed(texts []string) ([][]float32, error) {
+	r.texts = append(r.texts, texts...)
+	return make([][]float32, len(texts)), nil
+}
+
+func TestRedactedEmbedder(t *testing.T) {
+	inner := &recordingEmbedder{}
+	embedder := Redacted(inner, redact.New(redact.AllKinds, []string{"Anna"}))
+
+	vectors, err := embedder.Embed([]string{"email jane@example.com", "call Anna about jane@example.com", "water the plants"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 {
+		t.Fatalf("got %d vectors, want 3", len(vectors))
+	}
+	want := []string{"email [EMAIL_1]", "call [NAME_1] about [EMAIL_1]", "water the plants"}
+	if !slices.Equal(inner.texts, want) {
+		t.Fatalf("embedder received %q, want %q", inner.texts, want)
+	}
+	if embedder.Name() == inner.Name() {
+		t.Fatal("redacted embedder has the same name as the raw one, so stored vectors would not be recomputed")
+	}
+}
@@ -0,0 +1,183 @@
+package mood
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+)
+
+type Analysis struct {
+	Emotional bool
+	Sentiment float64
+	Energy    float64
+	Emotions  []string
+	Source    string
+}
+
+type Analyzer struct {
+	llmService llm.LLMService
+}
+
+func NewAnalyzer(llmService llm.LLMService) *Analyzer {
+	return &Analyzer{llmService: llmService}
+}
+
+func (a *Analyzer) Analyze(text string) Analysis {
+	if a.llmService != nil {
+		analysis, err := a.analyzeWithLLM(text)
+		if err == nil {
+			return analysis
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("MoodAnalyzer: LLM analysis failed, using lexicon: %v", err)
+		}
+	}
+	return analyzeLexicon(text)
+}
+
+func (a *Analyzer) analyzeWithLLM(text string) (Analysis, error) {
+	prompt := fmt.Sprintf(`Analyze the emotional content of this message the user wrote to their personal journal: "%s"
+
+Return a single JSON object with this format:
+{
+  "emotional": true if the message expresses any feeling or mood, false for neutral requests or facts,
+  "sentiment": number from -1 (very negative) to 1 (very positive),
+  "energy": number from 0 (drained) to 1 (energized),
+  "emotions": ["lowercase emotion labels, e.g. overwhelmed, anxiety, joy, fatigue"]
+}`, text)
+
+	response, err := a.llmService.Complete(prompt)
+	if err != nil {
+		return Analysis{}, err
+	}
+
+	var raw struct {
+		Emotional bool     `json:"emotional"`
+		Sentiment float64  `json:"sentiment"`
+		Energy    float64  `json:"energy"`
+		Emotions  []string `json:"emotions"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Analysis{}, err
+	}
+
+	emotions := make([]string, 0, len(raw.Emotions))
+	for _, emotion := range raw.Emotions {
+		if emotion = strings.ToLower(strings.TrimSpace(emotion)); emotion != "" {
+			emotions = append(emotions, emotion)
+		}
+	}
+
+	return Analysis{
+		Emotional: raw.Emotional,
+		Sentiment: clamp(raw.Sentiment, -1, 1),
+		Energy:    clamp(raw.Energy, 0, 1),
+		Emotions:  emotions,
+		Source:    "llm",
+	}, nil
+}
+
+type lexiconEntry struct {
+	emotion   string
+	sentiment float64
+	energy    float64
+}
+
+// lexicon maps word stems to an emotion label, a sentiment weight and an
+// energy shift relative to neutral (0.5).
+var lexicon = map[string]lexiconEntry{
+	"overwhelm":  {"overwhelmed", -0.7, -0.2},
+	"stress":     {"stress", -0.6, -0.1},
+	"anxious":    {"anxiety", -0.6, 0.1},
+	"anxiety":    {"anxiety", -0.6, 0.1},
+	"worried":    {"anxiety", -0.5, 0},
+	"nervous":    {"anxiety", -0.4, 0.1},
+	"sad":        {"sadness", -0.6, -0.2},
+	"down":       {"sadness", -0.4, -0.2},
+	"depressed":  {"sadness", -0.8, -0.3},
+	"lonely":     {"loneliness", -0.6, -0.2},
+	"angry":      {"anger", -0.7, 0.3},
+	"frustrat":   {"anger", -0.5, 0.1},
+	"annoyed":    {"anger", -0.4, 0.1},
+	"tired":      {"fatigue", -0.3, -0.4},
+	"exhausted":  {"fatigue", -0.5, -0.5},
+	"drained":    {"fatigue", -0.5, -0.5},
+	"burnout":    {"fatigue", -0.7, -0.5},
+	"bored":      {"boredom", -0.2, -0.3},
+	"happy":      {"joy", 0.7, 0.2},
+	"great":      {"joy", 0.6, 0.2},
+	"good":       {"joy", 0.4, 0.1},
+	"excited":    {"excitement", 0.7, 0.4},
+	"motivated":  {"motivation", 0.6, 0.4},
+	"energized":  {"motivation", 0.6, 0.5},
+	"grateful":   {"gratitude", 0.7, 0.1},
+	"thankful":   {"gratitude", 0.7, 0.1},
+	"calm":       {"calm", 0.5, -0.1},
+	"relaxed":    {"calm", 0.5, -0.1},
+	"peaceful":   {"calm", 0.6, -0.1},
+	"proud":      {"pride", 0.7, 0.2},
+	"hopeful":    {"hope", 0.5, 0.1},
+	"love":       {"love", 0.7, 0.2},
+	"feeling":    {"", 0, 0},
+	"struggling": {"struggle", -0.5, -0.2},
+}
+
+var negations = map[string]bool{"not": true, "don't": true, "never": true, "isn't": true, "wasn't": true}
+
+func analyzeLexicon(text string) Analysis {
+	words := strings.Fields(strings.ToLower(text))
+	analysis := Analysis{Energy: 0.5, Source: "lexicon"}
+
+	var sentimentSum float64
+	hits := 0
+	for i, word := range words {
+		word = strings.Trim(word, ".,!?;:\"'()")
+		for stem, entry := range lexicon {
+			// Short stems must match exactly so "down" does not match "download"
+			if word != stem && (len(stem) < 6 || !strings.HasPrefix(word, stem)) {
+				continue
+			}
+			if entry.emotion == "" {
+				analysis.Emotional = true
+				break
+			}
+			sentiment := entry.sentiment
+			if i > 0 && negations[words[i-1]] {
+				sentiment = -sentiment / 2
+			} else if !containsString(analysis.Emotions, entry.emotion) {
+				analysis.Emotions = append(analysis.Emotions, entry.emotion)
+			}
+			sentimentSum += sentiment
+			analysis.Energy += entry.energy
+			hits++
+			break
+		}
+	}
+
+	if hits > 0 {
+		analysis.Emotional = true
+		analysis.Sentiment = clamp(sentimentSum/math.Sqrt(float64(hits)), -1, 1)
+		analysis.Energy = clamp(analysis.Energy, 0, 1)
+	}
+	if analysis.Emotions == nil {
+		analysis.Emotions = []string{}
+	}
+	return analysis
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
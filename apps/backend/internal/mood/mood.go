@@ -0,0 +1,204 @@
+package mood
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "mood"
+
+// Entry is the emotional signal extracted from one input. Sentiment ranges
+// from -1 (very negative) to 1 (very positive), energy from 0 to 1.
+type Entry struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	At        time.Time `json:"at"`
+	Text      string    `json:"text"`
+	Sentiment float64   `json:"sentiment"`
+	Energy    float64   `json:"energy"`
+	Emotions  []string  `json:"emotions"`
+	Source    string    `json:"source"`
+}
+
+type MoodService interface {
+	// Record analyzes text and stores an entry when it carries emotional
+	// signal. It returns nil without error for neutral input.
+	Record(userID, text string, at time.Time) (*Entry, error)
+	Timeline(userID string, from, to time.Time) ([]Entry, error)
+	Aggregate(userID string, period Period, from, to time.Time) ([]Aggregate, error)
+	Trend(userID string, now time.Time) (*Trend, error)
+	// Import adds entries from an archive. Entries the user already has are
+	// skipped; it returns how many were added.
+	Import(userID string, entries []Entry) (int, error)
+	// Purge deletes the user's mood history, returning how many entries it
+	// held.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+}
+
+type service struct {
+	store    storage.Store
+	analyzer *Analyzer
+	entries  map[string][]Entry
+	mutex    sync.RWMutex
+}
+
+func NewService(store storage.Store, analyzer *Analyzer) (MoodService, error) {
+	s := &service{
+		store:    store,
+		analyzer: analyzer,
+		entries:  make(map[string][]Entry),
+	}
+	if err := store.Load(storeName, &s.entries); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load mood entries: %w", err)
+	}
+	log.Printf("MoodService: Loaded mood timelines for %d users", len(s.entries))
+	return s, nil
+}
+
+func (s *service) Record(userID, text string, at time.Time) (*Entry, error) {
+	// Analyze outside the lock; it may call the LLM
+	analysis := s.analyzer.Analyze(text)
+	if !analysis.Emotional {
+		log.Printf("MoodService: No emotional signal in input")
+		return nil, nil
+	}
+
+	entry := Entry{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		At:        at,
+		Text:      text,
+		Sentiment: analysis.Sentiment,
+		Energy:    analysis.Energy,
+		Emotions:  analysis.Emotions,
+		Source:    analysis.Source,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[userID] = append(s.entries[userID], entry)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("MoodService: Recorded mood for user %s (sentiment %.2f, energy %.2f, emotions %v)", userID, entry.Sentiment, entry.Energy, entry.Emotions)
+	return &entry, nil
+}
+
+func (s *service) Import(userID string, entries []Entry) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing := make(map[string]bool, len(s.entries[userID]))
+	for _, entry := range s.entries[userID] {
+		existing[entry.ID] = true
+	}
+	added := 0
+	for _, entry := range entries {
+		if entry.ID == "" || existing[entry.ID] {
+			continue
+		}
+		entry.UserID = userID
+		existing[entry.ID] = true
+		s.entries[userID] = append(s.entries[userID], entry)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("MoodService: Imported %d mood entries for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.entries[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.entries, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("MoodService: Purged %d mood entries for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.entries[userID]), nil
+}
+
+func (s *service) Timeline(userID string, from, to time.Time) ([]Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Entry, 0)
+	for _, entry := range s.entries[userID] {
+		if !from.IsZero() && entry.At.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !entry.At.Before(to) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+	return result, nil
+}
+
+func (s *service) Aggregate(userID string, period Period, from, to time.Time) ([]Aggregate, error) {
+	entries, err := s.Timeline(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(entries, period, to.Location()), nil
+}
+
+func (s *service) Trend(userID string, now time.Time) (*Trend, error) {
+	entries, err := s.Timeline(userID, now.Add(-2*trendWindow), now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	trend := detectTrend(entries, now)
+	return &trend, nil
+}
+
+// persist saves the mood entries. If that fails the change is undone, so
+// the next save doesn't write out a change the caller was told failed.
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.entries); err != nil {
+		s.reload()
+		return fmt.Errorf("save mood entries: %w", err)
+	}
+	return nil
+}
+
+// reload puts back the entries as they were last saved. Callers hold the
+// write lock.
+func (s *service) reload() {
+	entries := make(map[string][]Entry)
+	if err := s.store.Load(storeName, &entries); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("MoodService: Failed to undo an unsaved change: %v", err)
+		return
+	}
+	s.entries = entries
+}
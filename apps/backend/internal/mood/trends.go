@@ -0,0 +1,215 @@
+package mood
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trendWindow is the span compared against the span before it.
+const trendWindow = 7 * 24 * time.Hour
+
+const (
+	// trendThreshold is the change in average sentiment that counts as a trend.
+	trendThreshold = 0.2
+	// recurringThreshold is how often an emotion must appear within the
+	// window to be called out.
+	recurringThreshold = 3
+)
+
+type Period string
+
+const (
+	PeriodDay  Period = "day"
+	PeriodWeek Period = "week"
+)
+
+type Aggregate struct {
+	PeriodStart  time.Time `json:"period_start"`
+	Count        int       `json:"count"`
+	AvgSentiment float64   `json:"avg_sentiment"`
+	AvgEnergy    float64   `json:"avg_energy"`
+	TopEmotions  []string  `json:"top_emotions"`
+}
+
+type Direction string
+
+const (
+	DirectionImproving    Direction = "improving"
+	DirectionDeclining    Direction = "declining"
+	DirectionStable       Direction = "stable"
+	DirectionInsufficient Direction = "insufficient_data"
+)
+
+type Trend struct {
+	Direction         Direction `json:"direction"`
+	CurrentSentiment  float64   `json:"current_sentiment"`
+	PreviousSentiment float64   `json:"previous_sentiment"`
+	Delta             float64   `json:"delta"`
+	CurrentEnergy     float64   `json:"current_energy"`
+	RecurringEmotions []string  `json:"recurring_emotions"`
+	EntriesThisWindow int       `json:"entries_this_window"`
+	EntriesPrevWindow int       `json:"entries_previous_window"`
+	Summary           string    `json:"summary"`
+}
+
+func periodStart(t time.Time, period Period, loc *time.Location) time.Time {
+	local := t.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if period == PeriodWeek {
+		// Weeks start on Monday
+		offset := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -offset)
+	}
+	return start
+}
+
+func aggregate(entries []Entry, period Period, loc *time.Location) []Aggregate {
+	type bucket struct {
+		sentiment, energy float64
+		count             int
+		emotions          map[string]int
+	}
+
+	buckets := make(map[time.Time]*bucket)
+	var starts []time.Time
+	for _, entry := range entries {
+		start := periodStart(entry.At, period, loc)
+		b, exists := buckets[start]
+		if !exists {
+			b = &bucket{emotions: make(map[string]int)}
+			buckets[start] = b
+			starts = append(starts, start)
+		}
+		b.sentiment += entry.Sentiment
+		b.energy += entry.Energy
+		b.count++
+		for _, emotion := range entry.Emotions {
+			b.emotions[emotion]++
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	result := make([]Aggregate, len(starts))
+	for i, start := range starts {
+		b := buckets[start]
+		result[i] = Aggregate{
+			PeriodStart:  start,
+			Count:        b.count,
+			AvgSentiment: round(b.sentiment / float64(b.count)),
+			AvgEnergy:    round(b.energy / float64(b.count)),
+			TopEmotions:  topEmotions(b.emotions, 3, 1),
+		}
+	}
+	return result
+}
+
+func detectTrend(entries []Entry, now time.Time) Trend {
+	windowStart := now.Add(-trendWindow)
+	var current, previous []Entry
+	for _, entry := range entries {
+		if entry.At.Before(windowStart) {
+			previous = append(previous, entry)
+		} else {
+			current = append(current, entry)
+		}
+	}
+
+	trend := Trend{
+		Direction:         DirectionInsufficient,
+		EntriesThisWindow: len(current),
+		EntriesPrevWindow: len(previous),
+		RecurringEmotions: []string{},
+	}
+
+	emotionCounts := make(map[string]int)
+	for _, entry := range current {
+		for _, emotion := range entry.Emotions {
+			emotionCounts[emotion]++
+		}
+	}
+	trend.RecurringEmotions = topEmotions(emotionCounts, 3, recurringThreshold)
+
+	if len(current) > 0 {
+		trend.CurrentSentiment, trend.CurrentEnergy = averages(current)
+	}
+	if len(current) >= 2 && len(previous) >= 2 {
+		trend.PreviousSentiment, _ = averages(previous)
+		trend.Delta = round(trend.CurrentSentiment - trend.PreviousSentiment)
+		switch {
+		case trend.Delta >= trendThreshold:
+			trend.Direction = DirectionImproving
+		case trend.Delta <= -trendThreshold:
+			trend.Direction = DirectionDeclining
+		default:
+			trend.Direction = DirectionStable
+		}
+	}
+
+	trend.Summary = summarize(trend, emotionCounts)
+	return trend
+}
+
+// summarize phrases the notable parts of a trend for use in replies.
+// It is empty when nothing stands out.
+func summarize(trend Trend, emotionCounts map[string]int) string {
+	var parts []string
+	for _, emotion := range trend.RecurringEmotions {
+		parts = append(parts, fmt.Sprintf("you've mentioned %s %d times this week", emotionPhrase(emotion), emotionCounts[emotion]))
+	}
+	switch trend.Direction {
+	case DirectionDeclining:
+		parts = append(parts, "your mood has been lower than the week before")
+	case DirectionImproving:
+		parts = append(parts, "your mood has been better than the week before")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	summary := strings.Join(parts, "; ")
+	return strings.ToUpper(summary[:1]) + summary[1:] + "."
+}
+
+func emotionPhrase(emotion string) string {
+	switch emotion {
+	case "overwhelmed":
+		return "feeling overwhelmed"
+	case "stress", "anxiety", "fatigue", "sadness", "loneliness", "anger":
+		return emotion
+	}
+	return "feeling " + emotion
+}
+
+func averages(entries []Entry) (sentiment, energy float64) {
+	for _, entry := range entries {
+		sentiment += entry.Sentiment
+		energy += entry.Energy
+	}
+	n := float64(len(entries))
+	return round(sentiment / n), round(energy / n)
+}
+
+func topEmotions(counts map[string]int, limit, minCount int) []string {
+	result := make([]string, 0, len(counts))
+	for emotion, count := range counts {
+		if count >= minCount {
+			result = append(result, emotion)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if counts[result[i]] != counts[result[j]] {
+			return counts[result[i]] > counts[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
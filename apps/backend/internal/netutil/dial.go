@@ -0,0 +1,23 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// DenyPrivate is a net.Dialer Control that refuses connections to
+// addresses that aren't publicly routable, so URLs supplied by users can't
+// reach loopback, private or link-local services such as cloud metadata
+// endpoints. It runs after DNS resolution, so hostnames can't sneak past.
+func DenyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
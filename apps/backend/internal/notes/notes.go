@@ -0,0 +1,509 @@
+package notes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "notes"
+
+var (
+	ErrNotFound = errors.New("note not found")
+	ErrInvalid  = errors.New("invalid note")
+)
+
+type Note struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	CollectionID string    `json:"collection_id,omitempty"`
+	Tags         []string  `json:"tags"`
+	RelatedIDs   []string  `json:"related_ids"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Collection groups related notes into a project, e.g. "Creative projects".
+type Collection struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	NoteCount   int       `json:"note_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Filter struct {
+	CollectionID string
+	Tag          string
+}
+
+type NoteService interface {
+	Create(userID string, note Note) (*Note, error)
+	Get(userID, id string) (*Note, error)
+	List(userID string, filter Filter) ([]Note, error)
+	Delete(userID, id string) error
+	Link(userID, id, relatedID string) error
+	Collections(userID string) ([]Collection, error)
+	GetCollection(userID, id string) (*Collection, error)
+	EnsureCollection(userID, name, description string) (*Collection, error)
+	// Import adds notes and collections from an archive, keeping their IDs.
+	// Collections are matched to the user's by name, links to notes that
+	// don't exist are dropped and notes the user already has are skipped.
+	// It returns how many notes were added.
+	Import(userID string, notes []Note, collections []Collection) (int, error)
+	// Purge deletes all of the user's notes and collections, returning how
+	// many there were together.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type userNotes struct {
+	Notes       []Note       `json:"notes"`
+	Collections []Collection `json:"collections"`
+}
+
+type service struct {
+	store storage.Store
+	users map[string]*userNotes
+	mutex sync.RWMutex
+}
+
+func NewService(store storage.Store) (NoteService, error) {
+	s := &service{
+		store: store,
+		users: make(map[string]*userNotes),
+	}
+	if err := store.Load(storeName, &s.users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load notes: %w", err)
+	}
+	log.Printf("NoteService: Loaded notes for %d users", len(s.users))
+	return s, nil
+}
+
+func (s *service) user(userID string) *userNotes {
+	data, exists := s.users[userID]
+	if !exists {
+		data = &userNotes{}
+		s.users[userID] = data
+	}
+	return data
+}
+
+func (s *service) Create(userID string, note Note) (*Note, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	note.Content = strings.TrimSpace(note.Content)
+	if note.Content == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalid)
+	}
+
+	data := s.user(userID)
+	if note.CollectionID != "" && findCollection(data.Collections, note.CollectionID) == -1 {
+		return nil, fmt.Errorf("%w: unknown collection %s", ErrInvalid, note.CollectionID)
+	}
+
+	now := time.Now()
+	note.ID = storage.NewID()
+	note.UserID = userID
+	note.Tags = normalizeTags(note.Tags)
+	note.CreatedAt = now
+	note.UpdatedAt = now
+	if note.Title == "" {
+		note.Title = defaultTitle(note.Content)
+	}
+
+	// Keep only links to notes that exist, and make them bidirectional
+	related := make([]string, 0, len(note.RelatedIDs))
+	for _, relatedID := range note.RelatedIDs {
+		idx := findNote(data.Notes, relatedID)
+		if idx == -1 || containsString(related, relatedID) {
+			continue
+		}
+		related = append(related, relatedID)
+		data.Notes[idx].RelatedIDs = appendUnique(data.Notes[idx].RelatedIDs, note.ID)
+	}
+	note.RelatedIDs = related
+
+	data.Notes = append(data.Notes, note)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("NoteService: Created note %s for user %s (collection: %s, related: %d)", note.ID, userID, note.CollectionID, len(related))
+	return &note, nil
+}
+
+func (s *service) Get(userID, id string) (*Note, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, exists := s.users[userID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	idx := findNote(data.Notes, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	note := data.Notes[idx]
+	return &note, nil
+}
+
+func (s *service) List(userID string, filter Filter) ([]Note, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Note, 0)
+	data, exists := s.users[userID]
+	if !exists {
+		return result, nil
+	}
+
+	tag := strings.ToLower(strings.TrimSpace(filter.Tag))
+	for _, note := range data.Notes {
+		if filter.CollectionID != "" && note.CollectionID != filter.CollectionID {
+			continue
+		}
+		if tag != "" && !containsString(note.Tags, tag) {
+			continue
+		}
+		result = append(result, note)
+	}
+
+	// Newest first
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, exists := s.users[userID]
+	if !exists {
+		return ErrNotFound
+	}
+	idx := findNote(data.Notes, id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	data.Notes = append(data.Notes[:idx], data.Notes[idx+1:]...)
+	for i := range data.Notes {
+		data.Notes[i].RelatedIDs = removeString(data.Notes[i].RelatedIDs, id)
+	}
+	if err := s.persist(); err != nil {
+		return err
+	}
+
+	log.Printf("NoteService: Deleted note %s for user %s", id, userID)
+	return nil
+}
+
+func (s *service) Link(userID, id, relatedID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if id == relatedID {
+		return fmt.Errorf("%w: a note cannot be linked to itself", ErrInvalid)
+	}
+	data, exists := s.users[userID]
+	if !exists {
+		return ErrNotFound
+	}
+	a, b := findNote(data.Notes, id), findNote(data.Notes, relatedID)
+	if a == -1 || b == -1 {
+		return ErrNotFound
+	}
+
+	data.Notes[a].RelatedIDs = appendUnique(data.Notes[a].RelatedIDs, relatedID)
+	data.Notes[b].RelatedIDs = appendUnique(data.Notes[b].RelatedIDs, id)
+	return s.persist()
+}
+
+func (s *service) Collections(userID string) ([]Collection, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Collection, 0)
+	data, exists := s.users[userID]
+	if !exists {
+		return result, nil
+	}
+	for _, collection := range data.Collections {
+		collection.NoteCount = countNotes(data.Notes, collection.ID)
+		result = append(result, collection)
+	}
+	return result, nil
+}
+
+func (s *service) GetCollection(userID, id string) (*Collection, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, exists := s.users[userID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	idx := findCollection(data.Collections, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	collection := data.Collections[idx]
+	collection.NoteCount = countNotes(data.Notes, collection.ID)
+	return &collection, nil
+}
+
+// EnsureCollection returns the user's collection with the given name
+// (case-insensitive), creating it if it does not exist yet.
+func (s *service) EnsureCollection(userID, name, description string) (*Collection, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: collection name is required", ErrInvalid)
+	}
+
+	data := s.user(userID)
+	for _, collection := range data.Collections {
+		if strings.EqualFold(collection.Name, name) {
+			collection.NoteCount = countNotes(data.Notes, collection.ID)
+			return &collection, nil
+		}
+	}
+
+	collection := Collection{
+		ID:          storage.NewID(),
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		CreatedAt:   time.Now(),
+	}
+	data.Collections = append(data.Collections, collection)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("NoteService: Created collection '%s' for user %s", name, userID)
+	return &collection, nil
+}
+
+func (s *service) Import(userID string, list []Note, collections []Collection) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data := s.user(userID)
+	collectionIDs := make(map[string]string, len(collections))
+	for _, collection := range collections {
+		if collection.ID == "" {
+			continue
+		}
+		if idx := findCollection(data.Collections, collection.ID); idx != -1 {
+			collectionIDs[collection.ID] = collection.ID
+			continue
+		}
+		matched := false
+		for _, existing := range data.Collections {
+			if strings.EqualFold(existing.Name, collection.Name) {
+				collectionIDs[collection.ID] = existing.ID
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			collection.UserID = userID
+			collection.NoteCount = 0
+			data.Collections = append(data.Collections, collection)
+			collectionIDs[collection.ID] = collection.ID
+		}
+	}
+
+	var imported []string
+	for _, note := range list {
+		if note.ID == "" || findNote(data.Notes, note.ID) != -1 {
+			continue
+		}
+		note.UserID = userID
+		note.Tags = normalizeTags(note.Tags)
+		note.CollectionID = collectionIDs[note.CollectionID]
+		data.Notes = append(data.Notes, note)
+		imported = append(imported, note.ID)
+	}
+
+	// Links are resolved once every note is in, in both directions
+	for _, id := range imported {
+		idx := findNote(data.Notes, id)
+		related := make([]string, 0, len(data.Notes[idx].RelatedIDs))
+		for _, relatedID := range data.Notes[idx].RelatedIDs {
+			other := findNote(data.Notes, relatedID)
+			if other == -1 || other == idx || containsString(related, relatedID) {
+				continue
+			}
+			related = append(related, relatedID)
+			data.Notes[other].RelatedIDs = appendUnique(data.Notes[other].RelatedIDs, id)
+		}
+		data.Notes[idx].RelatedIDs = related
+	}
+
+	if len(imported) == 0 && len(collections) == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("NoteService: Imported %d notes for user %s", len(imported), userID)
+	return len(imported), nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := s.count(userID)
+	if _, exists := s.users[userID]; !exists {
+		return 0, nil
+	}
+	delete(s.users, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("NoteService: Purged %d notes and collections for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.count(userID), nil
+}
+
+func (s *service) count(userID string) int {
+	data, exists := s.users[userID]
+	if !exists {
+		return 0
+	}
+	return len(data.Notes) + len(data.Collections)
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.users))
+	for userID, data := range s.users {
+		if len(data.Notes) > 0 {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+// persist saves the notes. If that fails the change is undone, so the
+// next save doesn't write out a change the caller was told failed.
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.users); err != nil {
+		s.reload()
+		return fmt.Errorf("save notes: %w", err)
+	}
+	return nil
+}
+
+// reload puts back the notes as they were last saved. Callers hold the
+// write lock.
+func (s *service) reload() {
+	users := make(map[string]*userNotes)
+	if err := s.store.Load(storeName, &users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("NoteService: Failed to undo an unsaved change: %v", err)
+		return
+	}
+	s.users = users
+}
+
+func defaultTitle(content string) string {
+	words := strings.Fields(content)
+	if len(words) > 8 {
+		return strings.Join(words[:8], " ") + "…"
+	}
+	return strings.Join(words, " ")
+}
+
+func findNote(notes []Note, id string) int {
+	for i, note := range notes {
+		if note.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func findCollection(collections []Collection, id string) int {
+	for i, collection := range collections {
+		if collection.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func countNotes(notes []Note, collectionID string) int {
+	count := 0
+	for _, note := range notes {
+		if note.CollectionID == collectionID {
+			count++
+		}
+	}
+	return count
+}
+
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+		if tag != "" {
+			result = appendUnique(result, tag)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsString(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func removeString(values []string, value string) []string {
+	result := values[:0]
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,234 @@
+package notes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/textutil"
+)
+
+const (
+	// maxRelated caps how many existing notes a new note is linked to.
+	maxRelated = 3
+	// maxContextNotes caps how many recent notes are shown to the LLM.
+	maxContextNotes = 30
+)
+
+// Placement is where the organizer decided a new note belongs.
+type Placement struct {
+	Title                 string
+	Collection            string
+	CollectionDescription string
+	Tags                  []string
+	RelatedIDs            []string
+}
+
+// Organizer decides which collection a note belongs to and which existing
+// notes it relates to, using the LLM when available and keyword overlap otherwise.
+type Organizer struct {
+	service    NoteService
+	llmService llm.LLMService
+}
+
+func NewOrganizer(service NoteService, llmService llm.LLMService) *Organizer {
+	return &Organizer{
+		service:    service,
+		llmService: llmService,
+	}
+}
+
+// Capture organizes content and stores it as a new note in the chosen
+// collection, linked to the related notes.
+func (o *Organizer) Capture(userID, content string) (*Note, *Collection, error) {
+	placement, err := o.Place(userID, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.File(userID, content, placement)
+}
+
+// Place decides where content belongs among the user's notes without
+// storing anything.
+func (o *Organizer) Place(userID, content string) (Placement, error) {
+	collections, err := o.service.Collections(userID)
+	if err != nil {
+		return Placement{}, err
+	}
+	existing, err := o.service.List(userID, Filter{})
+	if err != nil {
+		return Placement{}, err
+	}
+	return o.Organize(content, collections, existing), nil
+}
+
+// File stores content as a new note where placement says it belongs.
+func (o *Organizer) File(userID, content string, placement Placement) (*Note, *Collection, error) {
+	collection, err := o.service.EnsureCollection(userID, placement.Collection, placement.CollectionDescription)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	note, err := o.service.Create(userID, Note{
+		Title:        placement.Title,
+		Content:      content,
+		CollectionID: collection.ID,
+		Tags:         placement.Tags,
+		RelatedIDs:   placement.RelatedIDs,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return note, collection, nil
+}
+
+func (o *Organizer) Organize(content string, collections []Collection, existing []Note) Placement {
+	if len(existing) > maxContextNotes {
+		existing = existing[:maxContextNotes]
+	}
+
+	if o.llmService != nil {
+		placement, err := o.organizeWithLLM(content, collections, existing)
+		if err == nil {
+			return placement
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("NoteOrganizer: LLM organization failed, using fallback: %v", err)
+		}
+	}
+	return organizeFallback(content, collections, existing)
+}
+
+func (o *Organizer) organizeWithLLM(content string, collections []Collection, existing []Note) (Placement, error) {
+	type collectionView struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	type noteView struct {
+		ID         string `json:"id"`
+		Title      string `json:"title"`
+		Collection string `json:"collection"`
+	}
+
+	names := make(map[string]string)
+	collectionViews := make([]collectionView, len(collections))
+	for i, c := range collections {
+		names[c.ID] = c.Name
+		collectionViews[i] = collectionView{Name: c.Name, Description: c.Description}
+	}
+	noteViews := make([]noteView, len(existing))
+	for i, n := range existing {
+		noteViews[i] = noteView{ID: n.ID, Title: n.Title, Collection: names[n.CollectionID]}
+	}
+
+	collectionsJSON, _ := json.MarshalIndent(collectionViews, "", "  ")
+	notesJSON, _ := json.MarshalIndent(noteViews, "", "  ")
+
+	prompt := fmt.Sprintf(`The user captured this note or idea: "%s"
+
+Their existing project collections:
+%s
+
+Their recent notes:
+%s
+
+Decide where the note belongs. Reuse an existing collection when it fits,
+otherwise propose a short new collection name (e.g. "Creative projects").
+
+Return a single JSON object with this format:
+{
+  "title": "short title for the note",
+  "collection": "collection name",
+  "collection_description": "one sentence, only needed for a new collection",
+  "tags": ["lowercase", "tags"],
+  "related_ids": ["ids of up to %d clearly related existing notes"]
+}`, content, string(collectionsJSON), string(notesJSON), maxRelated)
+
+	response, err := o.llmService.Complete(prompt)
+	if err != nil {
+		return Placement{}, err
+	}
+
+	var raw struct {
+		Title                 string   `json:"title"`
+		Collection            string   `json:"collection"`
+		CollectionDescription string   `json:"collection_description"`
+		Tags                  []string `json:"tags"`
+		RelatedIDs            []string `json:"related_ids"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Placement{}, err
+	}
+	if strings.TrimSpace(raw.Collection) == "" {
+		return Placement{}, fmt.Errorf("no collection in response")
+	}
+	if len(raw.RelatedIDs) > maxRelated {
+		raw.RelatedIDs = raw.RelatedIDs[:maxRelated]
+	}
+
+	return Placement{
+		Title:                 raw.Title,
+		Collection:            raw.Collection,
+		CollectionDescription: raw.CollectionDescription,
+		Tags:                  raw.Tags,
+		RelatedIDs:            raw.RelatedIDs,
+	}, nil
+}
+
+func organizeFallback(content string, collections []Collection, existing []Note) Placement {
+	keywords := textutil.Keywords(content)
+	placement := Placement{
+		Title: defaultTitle(content),
+		Tags:  extractHashTags(content),
+	}
+
+	// Relate notes that share at least two keywords
+	collectionVotes := make(map[string]int)
+	for _, note := range existing {
+		score := textutil.Overlap(keywords, textutil.Keywords(note.Title+" "+note.Content))
+		if score >= 2 && len(placement.RelatedIDs) < maxRelated {
+			placement.RelatedIDs = append(placement.RelatedIDs, note.ID)
+			if note.CollectionID != "" {
+				collectionVotes[note.CollectionID] += score
+			}
+		}
+	}
+
+	// Prefer the collection most related notes live in, then a name match
+	bestID, bestVotes := "", 0
+	for _, collection := range collections {
+		votes := collectionVotes[collection.ID] + 2*textutil.Overlap(keywords, textutil.Keywords(collection.Name+" "+collection.Description))
+		if votes > bestVotes {
+			bestID, bestVotes = collection.ID, votes
+		}
+	}
+	for _, collection := range collections {
+		if collection.ID == bestID {
+			placement.Collection = collection.Name
+			return placement
+		}
+	}
+
+	switch {
+	case len(placement.Tags) > 0:
+		placement.Collection = strings.ToUpper(placement.Tags[0][:1]) + placement.Tags[0][1:]
+	default:
+		placement.Collection = "Inbox"
+		placement.CollectionDescription = "Ideas that have not been grouped yet"
+	}
+	return placement
+}
+
+var hashTagPattern = regexp.MustCompile(`#(\w[\w-]*)`)
+
+func extractHashTags(content string) []string {
+	var tags []string
+	for _, match := range hashTagPattern.FindAllStringSubmatch(content, -1) {
+		tags = appendUnique(tags, strings.ToLower(match[1]))
+	}
+	return tags
+}
@@ -21,6 +21,10 @@ func (m *MockOrchestrator) ProcessInput(input string) (string, error) {
 	return response, nil
 }
 
+func (m *MockOrchestrator) Process(req Request) (*types.ProcessResponse, error) {
+	return m.ProcessInputDetailed(req.Input)
+}
+
 func (m *MockOrchestrator) ProcessInputDetailed(input string) (*types.ProcessResponse, error) {
 	log.Printf("MockOrchestrator: Processing detailed input: %s", input)
 	response := fmt.Sprintf("Mock processed: %s", input)
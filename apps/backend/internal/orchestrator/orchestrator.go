@@ -1,32 +1,118 @@
 package orchestrator
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/memory"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/redact"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/sessions"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/tools"
 )
 
 type Orchestrator interface {
 	ProcessInput(input string) (string, error)
 	ProcessInputDetailed(input string) (*types.ProcessResponse, error)
+	// Process handles input on behalf of a specific user and channel.
+	// ProcessInput and ProcessInputDetailed act on the default user.
+	Process(req Request) (*types.ProcessResponse, error)
 }
 
+// Request is one piece of user input and where it came from.
+type Request struct {
+	UserID string
+	Input  string
+	// Source names the channel the input arrived on, e.g. "web", "voice"
+	// or "telegram".
+	Source string
+	// SessionID continues an existing conversation. When empty a new
+	// session is started; its ID is returned in the response.
+	SessionID string
+	// VoiceNoteID is set when Input was transcribed from a voice note.
+	VoiceNoteID string
+	// Progress, when set, is told as processing moves from one stage to
+	// the next. It must not block.
+	Progress func(stage, detail string)
+}
+
+// Processing stages reported to Request.Progress.
+const (
+	StageContext   = "loading_context"
+	StageSelection = "selecting_tools"
+	StageTools     = "running_tools"
+	StageProfile   = "updating_profile"
+	StageMood      = "tracking_mood"
+)
+
+func (r Request) progress(stage, detail string) {
+	if r.Progress != nil {
+		r.Progress(stage, detail)
+	}
+}
+
+// ErrSessionNotFound is returned when Request.SessionID names no session of
+// the user.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Input sources used by the built-in channels.
+const (
+	SourceWeb      = "web"
+	SourceVoice    = "voice"
+	SourceTelegram = "telegram"
+)
+
+const (
+	// calendarLookahead is how far ahead upcoming events are reported.
+	calendarLookahead = 24 * time.Hour
+	// maxUpcomingEvents caps the events included in a response.
+	maxUpcomingEvents = 3
+	// stressfulEventWindow is how soon an event must start to be mentioned
+	// when the input sounds negative.
+	stressfulEventWindow = 4 * time.Hour
+	// contextTurns and contextChars bound the earlier turns passed to the
+	// LLM with each input.
+	contextTurns = 10
+	contextChars = 4000
+	// profileSummaryChars caps the profile excerpt passed to the LLM.
+	profileSummaryChars = 1500
+)
+
 type orchestrator struct {
-	toolService    tools.ToolService
-	profileService profile.ProfileService
-	llmService     llm.LLMService
+	toolService     tools.ToolService
+	profileService  profile.ProfileService
+	llmService      llm.LLMService
+	moodService     mood.MoodService
+	calendarService calendar.CalendarService
+	sessionService  sessions.SessionService
+	memoryService   memory.MemoryService
+	// redactor is nil when PII redaction is off
+	redactor *redact.Redactor
+	executor *tools.Executor
 }
 
-func New(toolService tools.ToolService, profileService profile.ProfileService, llmService llm.LLMService) Orchestrator {
+func New(toolService tools.ToolService, profileService profile.ProfileService, llmService llm.LLMService, moodService mood.MoodService, calendarService calendar.CalendarService, sessionService sessions.SessionService, memoryService memory.MemoryService, redactor *redact.Redactor, executor *tools.Executor) Orchestrator {
+	if executor == nil {
+		executor = tools.NewExecutor(toolService, tools.DefaultExecutorConfig)
+	}
 	return &orchestrator{
-		toolService:    toolService,
-		profileService: profileService,
-		llmService:     llmService,
+		toolService:     toolService,
+		profileService:  profileService,
+		llmService:      llmService,
+		moodService:     moodService,
+		calendarService: calendarService,
+		sessionService:  sessionService,
+		memoryService:   memoryService,
+		redactor:        redactor,
+		executor:        executor,
 	}
 }
 
@@ -39,8 +125,27 @@ func (o *orchestrator) ProcessInput(input string) (string, error) {
 }
 
 func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessResponse, error) {
+	return o.Process(Request{UserID: types.DefaultUserID, Input: input, Source: SourceWeb})
+}
+
+func (o *orchestrator) Process(req Request) (*types.ProcessResponse, error) {
 	startTime := time.Now()
-	log.Printf("Orchestrator: Processing input: %s", input)
+	input := req.Input
+	userID := req.UserID
+	if userID == "" {
+		userID = types.DefaultUserID
+	}
+	log.Printf("Orchestrator: Processing input from %s for user %s: %s", req.Source, userID, input)
+
+	req.progress(StageContext, "")
+	sessionID, conversation, err := o.loadConversation(userID, req.SessionID, input)
+	if err != nil {
+		return nil, err
+	}
+	recalled := o.recallMemories(userID, input)
+	for _, match := range recalled {
+		conversation.Memories = append(conversation.Memories, fmt.Sprintf("[%s, %s] %s", match.Kind, match.At.Format("Jan 2, 2006"), match.Text))
+	}
 
 	// Get available tools and convert to descriptors for LLM
 	toolsList := o.toolService.ListTools()
@@ -53,8 +158,9 @@ func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessRespons
 	}
 
 	// Let LLM select the best tools for this input
+	req.progress(StageSelection, fmt.Sprintf("%d tools available", len(toolDescriptors)))
 	llmStart := time.Now()
-	toolSelections, err := o.llmService.SelectTools(input, toolDescriptors)
+	toolSelections, err := o.llmService.SelectToolsInContext(input, toolDescriptors, conversation)
 	llmDuration := time.Since(llmStart)
 	if err != nil {
 		return nil, fmt.Errorf("tool selection failed: %w", err)
@@ -71,56 +177,32 @@ func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessRespons
 
 	var combinedResponse string
 	var toolExecutions []types.ToolExecution
+	var toolGraph *types.ToolGraph
 
 	if len(toolSelections) == 0 {
 		log.Printf("Orchestrator: No tools needed - processing as reflection")
 		combinedResponse = fmt.Sprintf("Acknowledged: %s", input)
 	} else {
-		// Execute all selected tools and combine responses
-		var allResponses []string
-		for _, selection := range toolSelections {
+		// Execute the selected tools, independent ones concurrently and the
+		// rest after the tools they depend on, and combine responses
+		calls := make([]tools.Call, len(toolSelections))
+		for i, selection := range toolSelections {
 			log.Printf("Orchestrator: Executing tool '%s' - Reason: %s", selection.ToolName, selection.Reason)
-			
-			toolStart := time.Now()
-			tool := o.toolService.GetTool(selection.ToolName)
-			if tool == nil {
-				log.Printf("Warning: Tool '%s' not found, skipping", selection.ToolName)
-				toolExecutions = append(toolExecutions, types.ToolExecution{
-					ToolName:      selection.ToolName,
-					Input:         input,
-					Output:        "",
-					ExecutionTime: time.Since(toolStart).String(),
-					Status:        "skipped",
-					Error:         "Tool not found",
-				})
-				continue
+			calls[i] = tools.Call{Tool: selection.ToolName, Input: input}
+			if selection.Input != "" {
+				calls[i].Input = selection.Input
 			}
-			
-			toolResponse, err := tool.Execute(input)
-			toolDuration := time.Since(toolStart)
-			
-			if err != nil {
-				log.Printf("Warning: Tool '%s' execution failed: %v", selection.ToolName, err)
-				toolExecutions = append(toolExecutions, types.ToolExecution{
-					ToolName:      selection.ToolName,
-					Input:         input,
-					Output:        "",
-					ExecutionTime: toolDuration.String(),
-					Status:        "error",
-					Error:         err.Error(),
-				})
-				continue
+		}
+		graph := o.executor.BuildGraph(calls)
+		toolExecutions, toolGraph = o.executor.RunGraph(userID, graph, func(i int) {
+			req.progress(StageTools, fmt.Sprintf("%d of %d: %s", i+1, len(calls), calls[i].Tool))
+		})
+
+		var allResponses []string
+		for _, execution := range toolExecutions {
+			if execution.Status == types.ToolStatusSuccess {
+				allResponses = append(allResponses, fmt.Sprintf("%s: %s", execution.ToolName, execution.Output))
 			}
-			
-			toolExecutions = append(toolExecutions, types.ToolExecution{
-				ToolName:      selection.ToolName,
-				Input:         input,
-				Output:        toolResponse,
-				ExecutionTime: toolDuration.String(),
-				Status:        "success",
-			})
-			
-			allResponses = append(allResponses, fmt.Sprintf("%s: %s", selection.ToolName, toolResponse))
 		}
 
 		if len(allResponses) == 0 {
@@ -136,22 +218,41 @@ func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessRespons
 	}
 
 	// Let ProfileService analyze and learn from the input
+	req.progress(StageProfile, "")
 	profileStart := time.Now()
-	profileLengthBefore := len(o.getProfileSafely())
-	err = o.profileService.ProcessInput(input)
+	profileLengthBefore := len(o.getProfileSafely(userID))
+	err = o.profileService.ProcessInput(userID, input)
 	profileDuration := time.Since(profileStart)
 	profileSuccess := err == nil
-	profileLengthAfter := len(o.getProfileSafely())
+	profileLengthAfter := len(o.getProfileSafely(userID))
 	
 	if err != nil {
 		log.Printf("Warning: Failed to process input for profile: %v", err)
 	}
 
+	// Track the emotional signal and mention notable trends in the reply
+	req.progress(StageMood, "")
+	moodDetails := o.trackMood(userID, input)
+	if moodDetails != nil && moodDetails.TrendSummary != "" {
+		combinedResponse = fmt.Sprintf("%s\n\n%s", combinedResponse, moodDetails.TrendSummary)
+	}
+
+	// Surface what's coming up, and point at the next event when the input
+	// sounds stressed
+	upcoming := o.upcomingEvents(userID)
+	if moodDetails != nil && moodDetails.Sentiment < 0 && len(upcoming) > 0 && time.Until(upcoming[0].Start) <= stressfulEventWindow {
+		next := upcoming[0]
+		combinedResponse = fmt.Sprintf("%s\n\nYour next event is \"%s\" at %s — it might be worth taking a short break before it.", combinedResponse, next.Summary, next.Start.Format("3:04 PM"))
+	}
+
+	o.recordTurns(userID, sessionID, req.Source, input, combinedResponse)
+
 	totalDuration := time.Since(startTime)
 	log.Printf("Orchestrator: Generated response: %s", combinedResponse)
 
 	response := &types.ProcessResponse{
-		Input: input,
+		Input:     input,
+		SessionID: sessionID,
 		Result: types.ProcessResult{
 			FinalResponse: combinedResponse,
 			ProcessingDetails: types.ProcessingDetails{
@@ -169,6 +270,15 @@ func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessRespons
 					ProcessingTime:      profileDuration.String(),
 					Success:             profileSuccess,
 				},
+				Mood:           moodDetails,
+				UpcomingEvents: upcoming,
+				Conversation: &types.ConversationInfo{
+					TurnsInContext:      len(conversation.Turns),
+					ProfileSummaryChars: len(conversation.ProfileSummary),
+					Memories:            recalled,
+				},
+				Redactions: o.countRedactions(input, conversation),
+				ToolGraph:  toolGraph,
 			},
 			Metadata: types.ProcessMetadata{
 				TotalProcessingTime: totalDuration.String(),
@@ -183,8 +293,179 @@ func (o *orchestrator) ProcessInputDetailed(input string) (*types.ProcessRespons
 	return response, nil
 }
 
-func (o *orchestrator) getProfileSafely() string {
-	profile, err := o.profileService.Get()
+// countRedactions reports the personal data that redaction kept out of the
+// tool selection call. Nil when redaction is off.
+func (o *orchestrator) countRedactions(input string, conversation llm.Conversation) *types.RedactionInfo {
+	if o.redactor == nil {
+		return nil
+	}
+	texts := append([]string{input, conversation.ProfileSummary}, conversation.Memories...)
+	for _, turn := range conversation.Turns {
+		texts = append(texts, turn.Content)
+	}
+	info := &types.RedactionInfo{Counts: make(map[string]int)}
+	for kind, count := range o.redactor.Count(texts...) {
+		info.Counts[string(kind)] = count
+		info.Total += count
+	}
+	return info
+}
+
+// loadConversation resolves the session for a request, starting a new one
+// when none is given, and gathers the context passed to the LLM.
+func (o *orchestrator) loadConversation(userID, sessionID, input string) (string, llm.Conversation, error) {
+	conversation := llm.Conversation{
+		ProfileSummary: profileSummary(o.getProfileSafely(userID), profileSummaryChars),
+	}
+	if o.sessionService == nil {
+		return "", conversation, nil
+	}
+
+	if sessionID == "" {
+		session, err := o.sessionService.Create(userID, input)
+		if err != nil {
+			log.Printf("Warning: Failed to start session: %v", err)
+			return "", conversation, nil
+		}
+		return session.ID, conversation, nil
+	}
+
+	turns, err := o.sessionService.Recent(userID, sessionID, contextTurns, contextChars)
+	if errors.Is(err, sessions.ErrNotFound) {
+		return "", conversation, ErrSessionNotFound
+	}
+	if err != nil {
+		return "", conversation, fmt.Errorf("load session: %w", err)
+	}
+	for _, turn := range turns {
+		conversation.Turns = append(conversation.Turns, llm.ConversationTurn{
+			Role:    string(turn.Role),
+			Content: turn.Content,
+		})
+	}
+	return sessionID, conversation, nil
+}
+
+// recallMemories looks up earlier inputs and items similar to the input, so
+// the LLM sees relevant history without the whole profile.
+func (o *orchestrator) recallMemories(userID, input string) []types.RecalledMemory {
+	if o.memoryService == nil {
+		return nil
+	}
+	matches, err := o.memoryService.Recall(userID, input)
+	if err != nil {
+		log.Printf("Warning: Failed to recall memories: %v", err)
+		return nil
+	}
+	recalled := make([]types.RecalledMemory, len(matches))
+	for i, match := range matches {
+		recalled[i] = types.RecalledMemory{
+			Kind:  match.Kind,
+			RefID: match.RefID,
+			Text:  match.Text,
+			At:    match.At,
+			Score: math.Round(match.Score*1000) / 1000,
+		}
+	}
+	return recalled
+}
+
+func (o *orchestrator) recordTurns(userID, sessionID, source, input, response string) {
+	if o.sessionService == nil || sessionID == "" {
+		return
+	}
+	_, err := o.sessionService.Append(userID, sessionID,
+		sessions.Turn{Role: sessions.RoleUser, Content: input, Source: source},
+		sessions.Turn{Role: sessions.RoleAssistant, Content: response},
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to record turns in session %s: %v", sessionID, err)
+	}
+}
+
+// profileSummary keeps the most recent profile lines that fit in limit
+// characters, without the heading; the profile grows at the bottom.
+func profileSummary(profile string, limit int) string {
+	lines := strings.Split(strings.TrimSpace(profile), "\n")
+	var kept []string
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line == "User Profile" || strings.Trim(line, "=") == "" {
+			continue
+		}
+		if size+len(line)+1 > limit {
+			break
+		}
+		kept = append([]string{line}, kept...)
+		size += len(line) + 1
+	}
+	return strings.Join(kept, "\n")
+}
+
+func (o *orchestrator) trackMood(userID, input string) *types.MoodDetails {
+	if o.moodService == nil {
+		return nil
+	}
+
+	now := time.Now()
+	entry, err := o.moodService.Record(userID, input, now)
+	if err != nil {
+		log.Printf("Warning: Failed to record mood: %v", err)
+		return nil
+	}
+	if entry == nil {
+		return nil
+	}
+
+	details := &types.MoodDetails{
+		Sentiment: entry.Sentiment,
+		Energy:    entry.Energy,
+		Emotions:  entry.Emotions,
+	}
+
+	trend, err := o.moodService.Trend(userID, now)
+	if err != nil {
+		log.Printf("Warning: Failed to compute mood trend: %v", err)
+		return details
+	}
+	details.Trend = string(trend.Direction)
+	details.TrendSummary = trend.Summary
+	return details
+}
+
+func (o *orchestrator) upcomingEvents(userID string) []types.EventSummary {
+	if o.calendarService == nil {
+		return nil
+	}
+
+	now := time.Now()
+	occurrences, err := o.calendarService.Upcoming(userID, now, now.Add(calendarLookahead))
+	if err != nil {
+		log.Printf("Warning: Failed to get upcoming events: %v", err)
+		return nil
+	}
+
+	var events []types.EventSummary
+	for _, occurrence := range occurrences {
+		if occurrence.AllDay || !occurrence.Start.After(now) {
+			continue
+		}
+		events = append(events, types.EventSummary{
+			Summary:  occurrence.Summary,
+			Start:    occurrence.Start,
+			End:      occurrence.End,
+			Location: occurrence.Location,
+		})
+		if len(events) == maxUpcomingEvents {
+			break
+		}
+	}
+	return events
+}
+
+func (o *orchestrator) getProfileSafely(userID string) string {
+	profile, err := o.profileService.Get(userID)
 	if err != nil {
 		return ""
 	}
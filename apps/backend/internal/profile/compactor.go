@@ -0,0 +1,254 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+)
+
+const (
+	DefaultMaxChars  = 6000
+	DefaultMaxTokens = 1500
+	// minDuplicateLength keeps short entries like "Tired" from being
+	// treated as contained in longer ones.
+	minDuplicateLength = 12
+)
+
+type CompactorConfig struct {
+	// MaxChars and MaxTokens are the sizes past which a profile is
+	// compacted. Compaction aims for half of them.
+	MaxChars  int
+	MaxTokens int
+	// Interval is how often profiles are checked in the background.
+	Interval time.Duration
+}
+
+// Compactor keeps profiles within model context limits by having the LLM
+// merge duplicates and archive stale entries. Without an LLM it merges
+// exact and contained duplicates and archives the oldest entries.
+type Compactor struct {
+	service    ProfileService
+	llmService llm.LLMService
+	config     CompactorConfig
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func NewCompactor(service ProfileService, llmService llm.LLMService, config CompactorConfig) *Compactor {
+	if config.MaxChars <= 0 {
+		config.MaxChars = DefaultMaxChars
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = DefaultMaxTokens
+	}
+	return &Compactor{service: service, llmService: llmService, config: config}
+}
+
+// estimateTokens approximates a token count at four characters per token.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// NeedsCompaction reports whether a profile is past either threshold.
+func (c *Compactor) NeedsCompaction(profile string) bool {
+	return len(profile) > c.config.MaxChars || estimateTokens(profile) > c.config.MaxTokens
+}
+
+func (c *Compactor) targetChars() int {
+	return min(c.config.MaxChars, c.config.MaxTokens*4) / 2
+}
+
+// Compact consolidates the user's profile if it is past a threshold, or
+// regardless when force is set. It returns nil when nothing was changed.
+func (c *Compactor) Compact(userID string, force bool) (*Event, error) {
+	previous, err := c.service.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !force && !c.NeedsCompaction(previous) {
+		return nil, nil
+	}
+	entries := Entries(previous)
+	if len(entries) < 2 {
+		return nil, nil
+	}
+
+	event, kept, err := c.compactWithLLM(entries)
+	if err != nil {
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("ProfileCompactor: LLM compaction failed for user %s, using heuristics: %v", userID, err)
+		}
+		event, kept = c.compactHeuristically(entries)
+	}
+	if len(kept) == len(entries) {
+		return nil, nil
+	}
+
+	event.Type = EventCompacted
+	event.EntriesBefore = len(entries)
+	event.EntriesAfter = len(kept)
+	return c.service.Rewrite(userID, previous, Format(kept), event)
+}
+
+func (c *Compactor) compactWithLLM(entries []string) (Event, []string, error) {
+	if c.llmService == nil {
+		return Event{}, nil, llm.ErrUnavailable
+	}
+
+	var numbered strings.Builder
+	for i, entry := range entries {
+		fmt.Fprintf(&numbered, "%d. %s\n", i+1, entry)
+	}
+	prompt := fmt.Sprintf(`These are the entries of a user's profile, learned from what they told their personal journal, oldest first:
+
+%s
+The profile is getting too long. Consolidate it to at most %d characters:
+- merge entries that say the same thing, keeping the most recent details
+- combine related facts into one entry
+- archive facts that are stale or superseded by newer entries
+- keep everything still true and useful about the user; don't invent anything
+
+Return a single JSON object with this format:
+{
+  "entries": ["the consolidated profile entries, oldest first"],
+  "archived": [numbers of the original entries that are stale and should not be carried over],
+  "summary": "one sentence on what was merged and archived"
+}`, numbered.String(), c.targetChars())
+
+	response, err := c.llmService.Complete(prompt)
+	if err != nil {
+		return Event{}, nil, err
+	}
+
+	var raw struct {
+		Entries  []string `json:"entries"`
+		Archived []int    `json:"archived"`
+		Summary  string   `json:"summary"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Event{}, nil, err
+	}
+
+	kept := make([]string, 0, len(raw.Entries))
+	for _, entry := range raw.Entries {
+		entry = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(entry), "•"))
+		if entry != "" {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == 0 {
+		return Event{}, nil, fmt.Errorf("compaction returned no entries")
+	}
+	if len(Format(kept)) >= len(Format(entries)) {
+		return Event{}, nil, fmt.Errorf("compaction did not shrink the profile")
+	}
+
+	event := Event{Summary: strings.TrimSpace(raw.Summary), Archived: []string{}}
+	for _, number := range raw.Archived {
+		if number >= 1 && number <= len(entries) {
+			event.Archived = append(event.Archived, entries[number-1])
+		}
+	}
+	if event.Summary == "" {
+		event.Summary = fmt.Sprintf("Consolidated %d entries into %d", len(entries), len(kept))
+	}
+	return event, kept, nil
+}
+
+// compactHeuristically drops entries repeated later on, verbatim or as part
+// of a longer entry, then archives the oldest until the profile fits.
+func (c *Compactor) compactHeuristically(entries []string) (Event, []string) {
+	normalized := make([]string, len(entries))
+	for i, entry := range entries {
+		normalized[i] = normalizeEntry(entry)
+	}
+
+	var kept []string
+	merged := 0
+	for i, entry := range entries {
+		duplicate := false
+		for j := i + 1; j < len(entries) && !duplicate; j++ {
+			duplicate = normalized[i] == normalized[j] ||
+				(len(normalized[i]) >= minDuplicateLength && strings.Contains(normalized[j], normalized[i]))
+		}
+		// An earlier entry contained in a later one is superseded; a later
+		// one contained in an earlier one adds nothing
+		for j := 0; j < i && !duplicate; j++ {
+			duplicate = len(normalized[i]) >= minDuplicateLength && normalized[i] != normalized[j] && strings.Contains(normalized[j], normalized[i])
+		}
+		if duplicate {
+			merged++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	event := Event{Archived: []string{}}
+	for len(kept) > 1 && len(Format(kept)) > c.targetChars() {
+		event.Archived = append(event.Archived, kept[0])
+		kept = kept[1:]
+	}
+	event.Summary = fmt.Sprintf("Merged %d duplicate entries and archived %d older entries", merged, len(event.Archived))
+	return event, kept
+}
+
+func normalizeEntry(entry string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(entry), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// RunOnce compacts every profile past a threshold.
+func (c *Compactor) RunOnce() {
+	users, err := c.service.Users()
+	if err != nil {
+		log.Printf("ProfileCompactor: Failed to list users: %v", err)
+		return
+	}
+	for _, userID := range users {
+		event, err := c.Compact(userID, false)
+		if err != nil {
+			log.Printf("ProfileCompactor: Failed to compact profile for user %s: %v", userID, err)
+			continue
+		}
+		if event != nil {
+			log.Printf("ProfileCompactor: Compacted profile for user %s: %s", userID, event.Summary)
+		}
+	}
+}
+
+func (c *Compactor) Start() {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	log.Printf("ProfileCompactor: Checking profiles every %s (max %d chars, %d tokens)", c.config.Interval, c.config.MaxChars, c.config.MaxTokens)
+
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.config.Interval)
+		defer ticker.Stop()
+
+		c.RunOnce()
+		for {
+			select {
+			case <-ticker.C:
+				c.RunOnce()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (c *Compactor) Stop() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
@@ -14,13 +14,49 @@ func NewMockService() ProfileService {
 	}
 }
 
-func (m *MockProfileService) Get() (string, error) {
+func (m *MockProfileService) Get(userID string) (string, error) {
 	log.Printf("MockProfileService: Getting profile")
 	return m.profile, nil
 }
 
-func (m *MockProfileService) ProcessInput(input string) error {
+func (m *MockProfileService) ProcessInput(userID, input string) error {
 	log.Printf("MockProfileService: Processing input: %s", input)
 	m.profile += "• " + input + "\n"
 	return nil
-}
\ No newline at end of file
+}
+
+func (m *MockProfileService) Rewrite(userID, previous, content string, event Event) (*Event, error) {
+	log.Printf("MockProfileService: Rewriting profile (%s)", event.Type)
+	m.profile = content
+	event.UserID = userID
+	return &event, nil
+}
+
+func (m *MockProfileService) History(userID string) ([]Event, error) {
+	return []Event{}, nil
+}
+
+func (m *MockProfileService) Import(userID, profile string, history []Event) (int, error) {
+	log.Printf("MockProfileService: Importing profile")
+	added := 0
+	for _, entry := range Entries(profile) {
+		m.profile += "• " + entry + "\n"
+		added++
+	}
+	return added, nil
+}
+
+func (m *MockProfileService) Purge(userID string) (int, error) {
+	log.Printf("MockProfileService: Purging profile")
+	count := len(Entries(m.profile))
+	m.profile = "Mock Profile\n============\n\n"
+	return count, nil
+}
+
+func (m *MockProfileService) Count(userID string) (int, error) {
+	return len(Entries(m.profile)), nil
+}
+
+func (m *MockProfileService) Users() ([]string, error) {
+	return []string{"default"}, nil
+}
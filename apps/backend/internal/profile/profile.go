@@ -1,44 +1,311 @@
 package profile
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
 )
 
+const emptyProfile = "User Profile\n===========\n\n"
+
+const storeName = "profiles"
+
+// maxHistory caps the events kept per user; the oldest go first.
+const maxHistory = 100
+
+// ErrConflict is returned by Rewrite when the profile changed in a way
+// other than new entries being appended since it was read.
+var ErrConflict = errors.New("profile changed during rewrite")
+
+type EventType string
+
+const (
+	EventCompacted EventType = "compacted"
+)
+
+// Event records a change to the profile other than an appended entry.
+type Event struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	Type          EventType `json:"type"`
+	At            time.Time `json:"at"`
+	Summary       string    `json:"summary"`
+	CharsBefore   int       `json:"chars_before"`
+	CharsAfter    int       `json:"chars_after"`
+	EntriesBefore int       `json:"entries_before"`
+	EntriesAfter  int       `json:"entries_after"`
+	// Archived holds entries dropped from the profile, so nothing learned
+	// is lost outright.
+	Archived []string `json:"archived"`
+	// Previous is the full profile before the change.
+	Previous string `json:"previous"`
+}
+
 type ProfileService interface {
-	Get() (string, error)
-	ProcessInput(input string) error
+	Get(userID string) (string, error)
+	ProcessInput(userID, input string) error
+	// Rewrite replaces the profile read as previous with content and
+	// records event. Entries appended since previous was read are kept.
+	Rewrite(userID, previous, content string, event Event) (*Event, error)
+	// History returns the user's profile events, newest first.
+	History(userID string) ([]Event, error)
+	// Import merges an archived profile: entries the profile lacks are
+	// appended and events not in its history are added. It returns how
+	// many entries were added.
+	Import(userID, profile string, history []Event) (int, error)
+	// Purge deletes the user's profile and its history, returning how many
+	// entries and events there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type userProfile struct {
+	Profile string  `json:"profile"`
+	History []Event `json:"history"`
 }
 
 type service struct {
-	profile string
-	mutex   sync.RWMutex
+	store    storage.Store
+	profiles map[string]*userProfile
+	mutex    sync.RWMutex
+}
+
+func NewService(store storage.Store) (ProfileService, error) {
+	s := &service{
+		store:    store,
+		profiles: make(map[string]*userProfile),
+	}
+	if err := store.Load(storeName, &s.profiles); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load profiles: %w", err)
+	}
+	log.Printf("ProfileService: Loaded profiles for %d users", len(s.profiles))
+	return s, nil
 }
 
-func NewService() ProfileService {
-	return &service{
-		profile: "User Profile\n===========\n\n",
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.profiles); err != nil {
+		return fmt.Errorf("save profiles: %w", err)
 	}
+	return nil
 }
 
-func (s *service) Get() (string, error) {
+func (s *service) Get(userID string) (string, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	log.Printf("ProfileService: Retrieved profile")
-	return s.profile, nil
+	log.Printf("ProfileService: Retrieved profile for user %s", userID)
+	data, exists := s.profiles[userID]
+	if !exists {
+		return emptyProfile, nil
+	}
+	return data.Profile, nil
 }
 
-func (s *service) ProcessInput(input string) error {
+func (s *service) ProcessInput(userID, input string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	log.Printf("ProfileService: Processing input: %s", input)
+	log.Printf("ProfileService: Processing input for user %s: %s", userID, input)
+
+	data, exists := s.profiles[userID]
+	if !exists {
+		data = &userProfile{Profile: emptyProfile}
+		s.profiles[userID] = data
+	}
 
 	// Simply append input to profile with timestamp-like marker
-	s.profile += fmt.Sprintf("• %s\n", input)
+	data.Profile += fmt.Sprintf("• %s\n", input)
+	if err := s.persist(); err != nil {
+		return err
+	}
 
 	log.Printf("ProfileService: Added input to profile")
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *service) Rewrite(userID, previous, content string, event Event) (*Event, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, exists := s.profiles[userID]
+	if !exists {
+		data = &userProfile{Profile: emptyProfile}
+		s.profiles[userID] = data
+	}
+	if data.Profile != previous {
+		if !strings.HasPrefix(data.Profile, previous) {
+			return nil, ErrConflict
+		}
+		content += data.Profile[len(previous):]
+	}
+
+	event.ID = storage.NewID()
+	event.UserID = userID
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	event.Previous = previous
+	event.CharsBefore = len(data.Profile)
+	event.CharsAfter = len(content)
+	if event.Archived == nil {
+		event.Archived = []string{}
+	}
+
+	data.Profile = content
+	data.History = append(data.History, event)
+	if len(data.History) > maxHistory {
+		data.History = append([]Event(nil), data.History[len(data.History)-maxHistory:]...)
+	}
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("ProfileService: Rewrote profile for user %s (%s, %d → %d chars)", userID, event.Type, event.CharsBefore, event.CharsAfter)
+	return &event, nil
+}
+
+func (s *service) History(userID string) ([]Event, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	events := make([]Event, 0)
+	if data, exists := s.profiles[userID]; exists {
+		for i := len(data.History) - 1; i >= 0; i-- {
+			events = append(events, data.History[i])
+		}
+	}
+	return events, nil
+}
+
+func (s *service) Import(userID, profile string, history []Event) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, exists := s.profiles[userID]
+	if !exists {
+		data = &userProfile{Profile: emptyProfile}
+	}
+
+	known := make(map[string]bool)
+	for _, entry := range Entries(data.Profile) {
+		known[entry] = true
+	}
+	added := 0
+	var b strings.Builder
+	b.WriteString(data.Profile)
+	for _, entry := range Entries(profile) {
+		if !known[entry] {
+			known[entry] = true
+			fmt.Fprintf(&b, "• %s\n", entry)
+			added++
+		}
+	}
+
+	events := make(map[string]bool, len(data.History))
+	for _, event := range data.History {
+		events[event.ID] = true
+	}
+	importedEvents := 0
+	for _, event := range history {
+		if event.ID == "" || events[event.ID] {
+			continue
+		}
+		event.UserID = userID
+		events[event.ID] = true
+		data.History = append(data.History, event)
+		importedEvents++
+	}
+	if added == 0 && importedEvents == 0 {
+		return 0, nil
+	}
+	sort.SliceStable(data.History, func(i, j int) bool {
+		return data.History[i].At.Before(data.History[j].At)
+	})
+	if len(data.History) > maxHistory {
+		data.History = append([]Event(nil), data.History[len(data.History)-maxHistory:]...)
+	}
+
+	data.Profile = b.String()
+	s.profiles[userID] = data
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("ProfileService: Imported %d entries and %d events for user %s", added, importedEvents, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := s.count(userID)
+	if _, exists := s.profiles[userID]; !exists {
+		return 0, nil
+	}
+	delete(s.profiles, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("ProfileService: Purged profile for user %s (%d entries and events)", userID, count)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.count(userID), nil
+}
+
+func (s *service) count(userID string) int {
+	data, exists := s.profiles[userID]
+	if !exists {
+		return 0
+	}
+	// A profile with no entries yet still counts as kept
+	return max(len(Entries(data.Profile)), 1) + len(data.History)
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.profiles))
+	for userID := range s.profiles {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+// Entries returns the bullet entries of a profile, without the heading.
+func Entries(profile string) []string {
+	var entries []string
+	for _, line := range strings.Split(profile, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "•") {
+			continue
+		}
+		if entry := strings.TrimSpace(strings.TrimPrefix(line, "•")); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Format renders entries as a profile.
+func Format(entries []string) string {
+	var b strings.Builder
+	b.WriteString(emptyProfile)
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "• %s\n", entry)
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers, after the IETF RateLimit header fields draft.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Headers lists the headers the middleware sets, for CORS.
+var Headers = []string{HeaderLimit, HeaderRemaining, HeaderReset, HeaderPolicy, HeaderRetryAfter}
+
+// Key identifies who a request counts against: the client IP. API keys
+// and user IDs are not authenticated, so a client could claim a fresh one
+// on every request and never run out; they can only be used for limits
+// once they are verified.
+func Key(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware counts each request against limiter under key(c) and rejects
+// it with 429 once the bucket is empty. Paths in exempt are let through
+// uncounted. A later limiter on the same route overwrites the headers, so
+// they describe the most specific limit.
+func Middleware(limiter *Limiter, key func(*gin.Context) string, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		who := key(c)
+		decision := limiter.Allow(who)
+		c.Header(HeaderLimit, strconv.Itoa(decision.Limit))
+		c.Header(HeaderRemaining, strconv.Itoa(decision.Remaining))
+		c.Header(HeaderReset, strconv.Itoa(seconds(decision.Reset)))
+		c.Header(HeaderPolicy, limiter.Policy())
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := seconds(decision.RetryAfter)
+		log.Printf("RateLimit: %s limit exceeded by %s on %s %s", limiter.Name(), who, c.Request.Method, c.Request.URL.Path)
+		c.Header(HeaderRetryAfter, strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       fmt.Sprintf("Rate limit exceeded, try again in %d seconds", retryAfter),
+			"retry_after": retryAfter,
+		})
+	}
+}
+
+// seconds rounds up, so clients waiting that long are never early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter(t *testing.T, limiter *Limiter) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(Middleware(limiter, Key, "/health"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.GET("/process", ok)
+	return router
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		// headers vary per request, as a client dodging the limit would
+		headers    func(i int) map[string]string
+		remoteAddr func(i int) string
+		wantLast   int
+	}{
+		{
+			name:     "limited per client",
+			path:     "/process",
+			wantLast: http.StatusTooManyRequests,
+		},
+		{
+			name: "fresh api keys don't escape the limit",
+			path: "/process",
+			headers: func(i int) map[string]string {
+				return map[string]string{"X-API-Key": string(rune('a' + i))}
+			},
+			wantLast: http.StatusTooManyRequests,
+		},
+		{
+			name: "fresh bearer tokens and user ids don't escape the limit",
+			path: "/process?user_id=u",
+			headers: func(i int) map[string]string {
+				return map[string]string{"Authorization": "Bearer " + string(rune('a'+i)), "X-User-ID": string(rune('a' + i))}
+			},
+			wantLast: http.StatusTooManyRequests,
+		},
+		{
+			name: "forwarded addresses from untrusted clients are ignored",
+			path: "/process",
+			headers: func(i int) map[string]string {
+				return map[string]string{"X-Forwarded-For": "198.51.100." + string(rune('1'+i))}
+			},
+			wantLast: http.StatusTooManyRequests,
+		},
+		{
+			name:     "exempt path",
+			path:     "/health",
+			wantLast: http.StatusOK,
+		},
+		{
+			name: "different clients",
+			path: "/process",
+			remoteAddr: func(i int) string {
+				return "192.0.2." + string(rune('1'+i)) + ":1234"
+			},
+			wantLast: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter(t, New("test", Limit{Requests: 2, Window: time.Minute}))
+			var last *httptest.ResponseRecorder
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				if tt.remoteAddr != nil {
+					req.RemoteAddr = tt.remoteAddr(i)
+				}
+				if tt.headers != nil {
+					for name, value := range tt.headers(i) {
+						req.Header.Set(name, value)
+					}
+				}
+				last = httptest.NewRecorder()
+				router.ServeHTTP(last, req)
+			}
+			if last.Code != tt.wantLast {
+				t.Fatalf("third request got %d, want %d", last.Code, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	router := newRouter(t, New("test", Limit{Requests: 1, Window: time.Minute}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/process", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	allowed := send()
+	if got := allowed.Header().Get(HeaderLimit); got != "1" {
+		t.Fatalf("%s = %q, want 1", HeaderLimit, got)
+	}
+	if got := allowed.Header().Get(HeaderRemaining); got != "0" {
+		t.Fatalf("%s = %q, want 0", HeaderRemaining, got)
+	}
+	if got := allowed.Header().Get(HeaderPolicy); got != "1;w=60;burst=1" {
+		t.Fatalf("%s = %q", HeaderPolicy, got)
+	}
+	if allowed.Header().Get(HeaderRetryAfter) != "" {
+		t.Fatal("Retry-After set on an allowed request")
+	}
+
+	denied := send()
+	if denied.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", denied.Code)
+	}
+	if got := denied.Header().Get(HeaderRetryAfter); got != "60" {
+		t.Fatalf("%s = %q, want 60", HeaderRetryAfter, got)
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled are dropped.
+const sweepInterval = time.Minute
+
+// Limit allows Requests per Window on average, with bursts of up to Burst
+// requests. Burst defaults to Requests.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+// Decision is the outcome of one request against a limiter.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed; zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps a token bucket per key. Buckets start full, refill at
+// Requests per Window and are forgotten once full again, so idle keys cost
+// nothing.
+type Limiter struct {
+	name string
+	// rate is tokens per second
+	rate      float64
+	burst     float64
+	limit     Limit
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+func New(name string, limit Limit) *Limiter {
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Requests
+	}
+	return &Limiter{
+		name:      name,
+		rate:      float64(limit.Requests) / limit.Window.Seconds(),
+		burst:     float64(limit.Burst),
+		limit:     limit,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *Limiter) Name() string {
+	return l.name
+}
+
+// Policy describes the limit in the format of the RateLimit-Policy header.
+func (l *Limiter) Policy() string {
+	return fmt.Sprintf("%d;w=%d;burst=%d", l.limit.Requests, int(l.limit.Window.Seconds()), l.limit.Burst)
+}
+
+// Allow takes a token from key's bucket if there is one.
+func (l *Limiter) Allow(key string) Decision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	decision := Decision{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = l.wait(1 - b.tokens)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = l.wait(l.burst - b.tokens)
+	return decision
+}
+
+// wait is how long it takes to refill the given number of tokens.
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that are full by now. Callers hold the mutex.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
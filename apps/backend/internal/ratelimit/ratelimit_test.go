@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         Limit
+		requests      int
+		wantAllowed   int
+		wantRemaining int
+	}{
+		{"within burst", Limit{Requests: 10, Window: time.Minute, Burst: 5}, 3, 3, 2},
+		{"exactly the burst", Limit{Requests: 10, Window: time.Minute, Burst: 5}, 5, 5, 0},
+		{"past the burst", Limit{Requests: 10, Window: time.Minute, Burst: 5}, 8, 5, 0},
+		{"burst defaults to requests", Limit{Requests: 4, Window: time.Minute}, 6, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New("test", tt.limit)
+			allowed := 0
+			var last Decision
+			for i := 0; i < tt.requests; i++ {
+				last = limiter.Allow("ip:192.0.2.1")
+				if last.Allowed {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Fatalf("allowed %d of %d requests, want %d", allowed, tt.requests, tt.wantAllowed)
+			}
+			if last.Remaining != tt.wantRemaining {
+				t.Fatalf("Remaining = %d, want %d", last.Remaining, tt.wantRemaining)
+			}
+			if last.Allowed != (last.RetryAfter == 0) {
+				t.Fatalf("Allowed = %v with RetryAfter %s", last.Allowed, last.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestLimiterKeysHaveSeparateBuckets(t *testing.T) {
+	limiter := New("test", Limit{Requests: 1, Window: time.Minute})
+	if !limiter.Allow("ip:192.0.2.1").Allowed {
+		t.Fatal("first request denied")
+	}
+	if limiter.Allow("ip:192.0.2.1").Allowed {
+		t.Fatal("second request from the same key allowed")
+	}
+	if !limiter.Allow("ip:192.0.2.2").Allowed {
+		t.Fatal("another key shares the bucket")
+	}
+}
+
+func TestLimiterRefills(t *testing.T) {
+	limiter := New("test", Limit{Requests: 10, Window: 100 * time.Millisecond, Burst: 1})
+	limiter.Allow("k")
+	denied := limiter.Allow("k")
+	if denied.Allowed {
+		t.Fatal("request past the burst allowed")
+	}
+	if denied.RetryAfter <= 0 || denied.RetryAfter > 10*time.Millisecond {
+		t.Fatalf("RetryAfter = %s, want up to 10ms", denied.RetryAfter)
+	}
+
+	time.Sleep(denied.RetryAfter + 5*time.Millisecond)
+	if !limiter.Allow("k").Allowed {
+		t.Fatal("request denied after the bucket refilled")
+	}
+}
+
+func TestLimiterPolicy(t *testing.T) {
+	limiter := New("test", Limit{Requests: 120, Window: time.Minute, Burst: 60})
+	if got, want := limiter.Policy(), "120;w=60;burst=60"; got != want {
+		t.Fatalf("Policy = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,315 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kind is a category of personal data.
+type Kind string
+
+const (
+	KindEmail   Kind = "email"
+	KindCard    Kind = "card"
+	KindIBAN    Kind = "iban"
+	KindPhone   Kind = "phone"
+	KindIP      Kind = "ip"
+	KindAddress Kind = "address"
+	// KindName covers the terms of the dictionary: names of friends and
+	// family, places and anything else the user wants kept private.
+	KindName Kind = "name"
+)
+
+// AllKinds lists the pattern rules in the order they are applied. Rules
+// that match longer spans come first, so a card number or an IP address
+// isn't taken for a phone number. Dictionary terms are looked for right
+// after emails, so a name isn't found inside an address.
+var AllKinds = []Kind{KindEmail, KindIBAN, KindCard, KindIP, KindPhone, KindAddress}
+
+type rule struct {
+	kind    Kind
+	pattern *regexp.Regexp
+	// valid filters out matches the pattern can't tell apart, e.g. dates
+	// that look like phone numbers. Nil accepts every match.
+	valid func(match string) bool
+}
+
+var rules = map[Kind]rule{
+	KindEmail: {
+		kind:    KindEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	KindIBAN: {
+		kind:    KindIBAN,
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+	},
+	KindCard: {
+		kind:    KindCard,
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:   luhn,
+	},
+	KindPhone: {
+		kind:    KindPhone,
+		pattern: regexp.MustCompile(`\+?\(?\d[\d ().-]{6,18}\d`),
+		valid:   phone,
+	},
+	KindIP: {
+		kind:    KindIP,
+		pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		valid:   ipv4,
+	},
+	KindAddress: {
+		kind: KindAddress,
+		pattern: regexp.MustCompile(`\b\d{1,5}[a-z]?,? (?:[A-Z][A-Za-z'.-]* ){1,3}(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way|Place|Pl|Square|Sq|Terrace|Close)\b\.?` +
+			`|\b[A-ZÄÖÜ][a-zäöüß]+(?:straße|strasse|str\.|weg|platz|gasse|allee|ring|damm) \d{1,4}[a-z]?\b`),
+	},
+}
+
+// ParseKinds checks a list of rule names, e.g. from configuration.
+func ParseKinds(names []string) ([]Kind, error) {
+	var kinds []Kind
+	for _, name := range names {
+		kind := Kind(strings.ToLower(strings.TrimSpace(name)))
+		if _, exists := rules[kind]; !exists {
+			return nil, fmt.Errorf("unknown redaction rule %q", name)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// Redactor finds personal data in text with pattern rules and a dictionary
+// of terms. It holds no state between calls; each Session keeps its own
+// placeholders.
+type Redactor struct {
+	rules []rule
+}
+
+// New builds a redactor applying the given pattern rules, in the order of
+// AllKinds, and matching the dictionary terms as whole words regardless of
+// case.
+func New(kinds []Kind, dictionary []string) *Redactor {
+	r := &Redactor{}
+	enabled := make(map[Kind]bool)
+	for _, kind := range kinds {
+		enabled[kind] = true
+	}
+	var terms []string
+	for _, term := range dictionary {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, regexp.QuoteMeta(term))
+		}
+	}
+	// Longer terms first, so "Anna Maria" wins over "Anna"
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	for _, kind := range AllKinds {
+		if enabled[kind] {
+			r.rules = append(r.rules, rules[kind])
+		}
+		if kind == KindEmail && len(terms) > 0 {
+			r.rules = append(r.rules, rule{
+				kind:    KindName,
+				pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(terms, "|") + `)\b`),
+			})
+		}
+	}
+	return r
+}
+
+// Kinds lists the rules in use, including KindName when there is a
+// dictionary.
+func (r *Redactor) Kinds() []Kind {
+	var kinds []Kind
+	for _, rule := range r.rules {
+		kinds = append(kinds, rule.kind)
+	}
+	return kinds
+}
+
+// Session returns a fresh set of placeholders. Use one session for
+// everything sent in a single LLM call, so the same value always gets the
+// same placeholder and the response can be rehydrated.
+func (r *Redactor) Session() *Session {
+	return &Session{
+		redactor:     r,
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[Kind]int),
+		next:         make(map[Kind]int),
+	}
+}
+
+// Count returns how many pieces of personal data of each kind the texts
+// contain, as a session redacting them would report.
+func (r *Redactor) Count(texts ...string) map[Kind]int {
+	session := r.Session()
+	for _, text := range texts {
+		session.Redact(text)
+	}
+	return session.Counts()
+}
+
+// Session replaces personal data with placeholders like [EMAIL_1] and puts
+// it back into text that uses them.
+type Session struct {
+	redactor *Redactor
+	// placeholders maps a normalized value to its placeholder and values
+	// maps the placeholder back to the value as first seen
+	placeholders map[string]string
+	values       map[string]string
+	counts       map[Kind]int
+	next         map[Kind]int
+	mutex        sync.Mutex
+}
+
+// Redact returns text with every match replaced by its placeholder.
+func (s *Session) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, rule := range s.redactor.rules {
+		text = s.replace(text, rule)
+	}
+	return text
+}
+
+var placeholderPattern = regexp.MustCompile(`\[[A-Z]+_\d+\]`)
+
+// replace applies one rule, leaving the placeholders of earlier rules
+// alone. Callers hold the mutex.
+func (s *Session) replace(text string, rule rule) string {
+	taken := placeholderPattern.FindAllStringIndex(text, -1)
+	var b strings.Builder
+	last := 0
+	for _, match := range rule.pattern.FindAllStringIndex(text, -1) {
+		value := text[match[0]:match[1]]
+		if overlaps(match, taken) || rule.valid != nil && !rule.valid(value) {
+			continue
+		}
+		b.WriteString(text[last:match[0]])
+		b.WriteString(s.placeholder(rule.kind, value))
+		last = match[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func overlaps(span []int, spans [][]int) bool {
+	for _, other := range spans {
+		if span[0] < other[1] && other[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder returns the placeholder of value, assigning the next one of
+// its kind when it is new. Callers hold the mutex.
+func (s *Session) placeholder(kind Kind, value string) string {
+	s.counts[kind]++
+	key := string(kind) + ":" + strings.ToLower(value)
+	if placeholder, exists := s.placeholders[key]; exists {
+		return placeholder
+	}
+	s.next[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(string(kind)), s.next[kind])
+	s.placeholders[key] = placeholder
+	s.values[placeholder] = value
+	return placeholder
+}
+
+// Rehydrate puts the original values back in place of the placeholders.
+func (s *Session) Rehydrate(text string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.values) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(s.values))
+	for placeholder, value := range s.values {
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Counts returns how many matches of each kind were redacted, repeats
+// included.
+func (s *Session) Counts() map[Kind]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[Kind]int, len(s.counts))
+	for kind, count := range s.counts {
+		counts[kind] = count
+	}
+	return counts
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhn accepts card numbers with a valid check digit.
+func luhn(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}[-./]\d{1,2}[-./]\d{1,2}|^\d{1,2}[-./]\d{1,2}[-./]\d{2,4}$`)
+
+// phone accepts numbers with enough digits to be dialed, leaving out dates,
+// times and amounts.
+func phone(match string) bool {
+	if datePattern.MatchString(strings.TrimSpace(match)) {
+		return false
+	}
+	count := len(digits(match))
+	if strings.HasPrefix(match, "+") {
+		return count >= 7 && count <= 15
+	}
+	return count >= 9 && count <= 15
+}
+
+func ipv4(match string) bool {
+	for _, part := range strings.Split(match, ".") {
+		if len(part) > 1 && part[0] == '0' {
+			return false
+		}
+		if value, err := strconv.Atoi(part); err != nil || value > 255 {
+			return false
+		}
+	}
+	return true
+}
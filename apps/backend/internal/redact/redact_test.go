@@ -0,0 +1,71 @@
+package redact
+
+import (
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	redactor := New(AllKinds, []string{"Anna", "Anna Maria"})
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"email", "write to jane.doe@example.com", "write to [EMAIL_1]"},
+		{"card with valid check digit", "card 4111 1111 1111 1111 please", "card [CARD_1] please"},
+		{"card with invalid check digit", "order 4111 1111 1111 1112", "order 4111 1111 1111 1112"},
+		{"iban", "pay DE89 3704 0044 0532 0130 00 today", "pay [IBAN_1] today"},
+		{"international phone", "call +49 30 1234567", "call [PHONE_1]"},
+		{"date is not a phone", "due 2024-03-15", "due 2024-03-15"},
+		{"ip", "server at 192.168.1.20", "server at [IP_1]"},
+		{"invalid ip", "version 1.2.3.400", "version 1.2.3.400"},
+		{"street address", "I live at 12 Baker Street", "I live at [ADDRESS_1]"},
+		{"german address", "Treffpunkt Hauptstraße 5", "Treffpunkt [ADDRESS_1]"},
+		{"dictionary term regardless of case", "lunch with anna", "lunch with [NAME_1]"},
+		{"longer term wins", "Anna Maria called", "[NAME_1] called"},
+		{"term inside a word", "Annabelle called", "Annabelle called"},
+		{"repeated value keeps its placeholder", "a@b.io then a@b.io and c@d.io", "[EMAIL_1] then [EMAIL_1] and [EMAIL_2]"},
+		{"nothing to redact", "water the plants", "water the plants"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := redactor.Session()
+			got := session.Redact(tt.input)
+			if got != tt.want {
+				t.Fatalf("Redact(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if back := session.Rehydrate(got); back != tt.input {
+				t.Fatalf("Rehydrate(%q) = %q, want %q", got, back, tt.input)
+			}
+		})
+	}
+}
+
+func TestRedactOnlyEnabledRules(t *testing.T) {
+	redactor := New([]Kind{KindEmail}, nil)
+	input := "jane@example.com, +49 30 1234567"
+	if got, want := redactor.Session().Redact(input), "[EMAIL_1], +49 30 1234567"; got != want {
+		t.Fatalf("Redact = %q, want %q", got, want)
+	}
+	counts := redactor.Count(input, input)
+	if counts[KindEmail] != 2 || counts[KindPhone] != 0 {
+		t.Fatalf("Count = %v, want 2 emails and no phones", counts)
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	tests := []struct {
+		names   []string
+		wantErr bool
+	}{
+		{[]string{"email", " Phone "}, false},
+		{nil, false},
+		{[]string{"email", "ssn"}, true},
+	}
+	for _, tt := range tests {
+		_, err := ParseKinds(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseKinds(%q) error = %v, want error %v", tt.names, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package reminders
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionList   Action = "list"
+	ActionCancel Action = "cancel"
+)
+
+// Command is the structured form of a natural-language reminder request.
+// Ref identifies an existing reminder by (part of) its message for cancel.
+type Command struct {
+	Action     Action
+	Message    string
+	At         time.Time
+	Recurrence *Recurrence
+	Ref        string
+}
+
+type Parser struct {
+	llmService llm.LLMService
+}
+
+func NewParser(llmService llm.LLMService) *Parser {
+	return &Parser{llmService: llmService}
+}
+
+// Parse interprets input relative to now in the user's location.
+func (p *Parser) Parse(input string, now time.Time, loc *time.Location) (Command, error) {
+	return p.ParseWithSchedule(input, "", now, loc)
+}
+
+// ParseWithSchedule is Parse with the user's upcoming events, so requests
+// like "remind me before the dentist" can be timed. Only the LLM uses the
+// schedule.
+func (p *Parser) ParseWithSchedule(input, schedule string, now time.Time, loc *time.Location) (Command, error) {
+	if p.llmService != nil {
+		cmd, err := p.parseWithLLM(input, schedule, now, loc)
+		if err == nil {
+			return cmd, nil
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("ReminderParser: LLM parsing failed, using fallback: %v", err)
+		}
+	}
+	return parseFallback(input, now, loc)
+}
+
+func (p *Parser) parseWithLLM(input, schedule string, now time.Time, loc *time.Location) (Command, error) {
+	var scheduleSection string
+	if schedule != "" {
+		scheduleSection = fmt.Sprintf("The user's calendar:\n%s\n\n", schedule)
+	}
+	prompt := fmt.Sprintf(`The current time is %s (timezone %s).
+%sInterpret this reminder request from the user: "%s"
+
+Return a single JSON object with this format:
+{
+  "action": "create" | "list" | "cancel",
+  "message": "what to remind the user about, for create",
+  "at": "RFC3339 time with offset when the reminder should first fire, for create",
+  "recurrence": {"frequency": "hourly" | "daily" | "weekdays" | "weekly" | "monthly", "interval": 1} or null,
+  "reminder_ref": "words identifying an existing reminder for cancel, otherwise empty"
+}
+If the user asks to be reminded before an event, fire 15 minutes before it.`, now.In(loc).Format(time.RFC3339), loc, scheduleSection, input)
+
+	response, err := p.llmService.Complete(prompt)
+	if err != nil {
+		return Command{}, err
+	}
+
+	var raw struct {
+		Action      string      `json:"action"`
+		Message     string      `json:"message"`
+		At          string      `json:"at"`
+		Recurrence  *Recurrence `json:"recurrence"`
+		ReminderRef string      `json:"reminder_ref"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Command{}, err
+	}
+
+	cmd := Command{
+		Action:     Action(raw.Action),
+		Message:    strings.TrimSpace(raw.Message),
+		Recurrence: raw.Recurrence,
+		Ref:        raw.ReminderRef,
+	}
+	switch cmd.Action {
+	case ActionList, ActionCancel:
+		return cmd, nil
+	case ActionCreate:
+	default:
+		return Command{}, fmt.Errorf("unknown action %q", raw.Action)
+	}
+
+	at, err := time.Parse(time.RFC3339, raw.At)
+	if err != nil {
+		return Command{}, fmt.Errorf("invalid reminder time %q: %w", raw.At, err)
+	}
+	cmd.At = at
+	if cmd.Recurrence != nil && cmd.Recurrence.Frequency == "" {
+		cmd.Recurrence = nil
+	}
+	return cmd, nil
+}
+
+var (
+	listPattern   = regexp.MustCompile(`\b(list|show|what are)\b.*\breminders?\b|\bmy reminders\b`)
+	cancelPattern = regexp.MustCompile(`\b(cancel|delete|remove|stop|forget)\b`)
+	leadInPattern = regexp.MustCompile(`(?i)^(?:please )?(?:remind me|reminder|set a reminder|ping me|nudge me)(?: (?:to|that|about|of|for))?\b[\s:,]*`)
+	refFiller     = regexp.MustCompile(`\b(cancel|delete|remove|stop|forget|the|my|reminder|reminders|about|to|for|reminding me)\b`)
+)
+
+func parseFallback(input string, now time.Time, loc *time.Location) (Command, error) {
+	lower := strings.ToLower(strings.TrimSpace(input))
+
+	if cancelPattern.MatchString(lower) && strings.Contains(lower, "remind") {
+		ref := whitespacePattern.ReplaceAllString(refFiller.ReplaceAllString(lower, " "), " ")
+		return Command{Action: ActionCancel, Ref: strings.TrimSpace(ref)}, nil
+	}
+	if listPattern.MatchString(lower) {
+		return Command{Action: ActionList}, nil
+	}
+
+	when, err := ParseWhen(input, now, loc)
+	if err != nil {
+		return Command{}, err
+	}
+	message := strings.TrimSpace(leadInPattern.ReplaceAllString(when.Rest, ""))
+	message = strings.TrimSpace(strings.TrimPrefix(message, "before "))
+	message = strings.Trim(message, " ,.;:")
+	if message == "" {
+		message = "Reminder"
+	}
+	return Command{
+		Action:     ActionCreate,
+		Message:    message,
+		At:         when.At,
+		Recurrence: when.Recurrence,
+	}, nil
+}
+
+// FindByRef returns the scheduled reminder whose message best matches ref,
+// or nil.
+func FindByRef(reminders []Reminder, ref string) *Reminder {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil
+	}
+
+	var best *Reminder
+	bestScore := 0
+	for i := range reminders {
+		reminder := &reminders[i]
+		if reminder.Status != StatusScheduled {
+			continue
+		}
+		message := strings.ToLower(reminder.Message)
+		if strings.Contains(message, ref) {
+			return reminder
+		}
+		score := 0
+		for _, word := range strings.Fields(ref) {
+			if len(word) > 2 && strings.Contains(message, word) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = reminder, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,106 @@
+package reminders
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyHourly   Frequency = "hourly"
+	FrequencyDaily    Frequency = "daily"
+	FrequencyWeekdays Frequency = "weekdays"
+	FrequencyWeekly   Frequency = "weekly"
+	FrequencyMonthly  Frequency = "monthly"
+)
+
+// Recurrence repeats a reminder every Interval units of Frequency, keeping
+// the wall-clock time of the first occurrence in the reminder's timezone.
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	Interval  int       `json:"interval"`
+}
+
+func ParseFrequency(s string) (Frequency, bool) {
+	switch Frequency(strings.ToLower(strings.TrimSpace(s))) {
+	case FrequencyHourly:
+		return FrequencyHourly, true
+	case FrequencyDaily:
+		return FrequencyDaily, true
+	case FrequencyWeekdays:
+		return FrequencyWeekdays, true
+	case FrequencyWeekly:
+		return FrequencyWeekly, true
+	case FrequencyMonthly:
+		return FrequencyMonthly, true
+	}
+	return "", false
+}
+
+func (r *Recurrence) normalize() (*Recurrence, error) {
+	frequency, ok := ParseFrequency(string(r.Frequency))
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown recurrence %q", ErrInvalid, r.Frequency)
+	}
+	interval := r.Interval
+	if interval <= 0 || frequency == FrequencyWeekdays {
+		interval = 1
+	}
+	return &Recurrence{Frequency: frequency, Interval: interval}, nil
+}
+
+// NextAfter returns the first occurrence after now, stepping from prev.
+// Occurrences missed while the server was down are skipped rather than
+// fired in a burst.
+func (r *Recurrence) NextAfter(prev, now time.Time, loc *time.Location) time.Time {
+	next := prev.In(loc)
+	for !next.After(now) {
+		next = r.step(next)
+	}
+	return next
+}
+
+func (r *Recurrence) step(t time.Time) time.Time {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	switch r.Frequency {
+	case FrequencyHourly:
+		return t.Add(time.Duration(interval) * time.Hour)
+	case FrequencyWeekdays:
+		t = t.AddDate(0, 0, 1)
+		for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t
+	case FrequencyWeekly:
+		return t.AddDate(0, 0, 7*interval)
+	case FrequencyMonthly:
+		return t.AddDate(0, interval, 0)
+	default:
+		return t.AddDate(0, 0, interval)
+	}
+}
+
+// Describe phrases the recurrence for replies, e.g. "every 2 weeks".
+func (r *Recurrence) Describe() string {
+	if r == nil {
+		return ""
+	}
+	if r.Frequency == FrequencyWeekdays {
+		return "every weekday"
+	}
+	unit := map[Frequency]string{
+		FrequencyHourly:  "hour",
+		FrequencyDaily:   "day",
+		FrequencyWeekly:  "week",
+		FrequencyMonthly: "month",
+	}[r.Frequency]
+	if r.Interval > 1 {
+		return fmt.Sprintf("every %d %ss", r.Interval, unit)
+	}
+	return "every " + unit
+}
@@ -0,0 +1,415 @@
+package reminders
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "reminders"
+
+// pastGrace is how far in the past a one-off reminder may be created, so a
+// time computed just before the request still counts as now.
+const pastGrace = time.Minute
+
+var (
+	ErrNotFound = errors.New("reminder not found")
+	ErrInvalid  = errors.New("invalid reminder")
+)
+
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusFired     Status = "fired"
+	StatusCancelled Status = "cancelled"
+	// StatusMissed marks a one-off reminder whose time passed while it was
+	// not scheduled here, such as one restored from an older export.
+	StatusMissed Status = "missed"
+)
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusScheduled, StatusFired, StatusCancelled, StatusMissed:
+		return true
+	}
+	return false
+}
+
+// Reminder fires Message at FireAt. Recurring reminders stay scheduled and
+// move FireAt to the next occurrence each time they fire.
+type Reminder struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	Message     string      `json:"message"`
+	FireAt      time.Time   `json:"fire_at"`
+	Timezone    string      `json:"timezone"`
+	Recurrence  *Recurrence `json:"recurrence,omitempty"`
+	Status      Status      `json:"status"`
+	FireCount   int         `json:"fire_count"`
+	LastFiredAt *time.Time  `json:"last_fired_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type ReminderService interface {
+	Create(userID string, reminder Reminder) (*Reminder, error)
+	Get(userID, id string) (*Reminder, error)
+	List(userID string, status Status) ([]Reminder, error)
+	Cancel(userID, id string) (*Reminder, error)
+	// Due returns scheduled reminders whose fire time has passed.
+	Due(now time.Time) ([]Reminder, error)
+	// Fire records that a reminder went off and schedules its next
+	// occurrence, if any.
+	Fire(id string, at time.Time) (*Reminder, error)
+	// Import adds reminders from an archive, keeping their IDs and
+	// schedules, and adopts timezone if the user has none of their own.
+	// Reminders the user already has are skipped; it returns how many were
+	// added.
+	Import(userID, timezone string, reminders []Reminder) (int, error)
+	// Purge deletes the user's reminders and timezone, returning how many
+	// reminders there were.
+	Purge(userID string) (int, error)
+	// Count returns how many reminders the user has, plus one for a
+	// timezone of their own.
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+	Location(userID string) (*time.Location, error)
+	SetTimezone(userID, name string) (*time.Location, error)
+}
+
+type userReminders struct {
+	Timezone  string     `json:"timezone,omitempty"`
+	Reminders []Reminder `json:"reminders"`
+}
+
+type service struct {
+	store           storage.Store
+	defaultLocation *time.Location
+	users           map[string]*userReminders
+	mutex           sync.RWMutex
+}
+
+// NewService loads persisted reminders. Users without a timezone of their
+// own are interpreted in defaultLocation.
+func NewService(store storage.Store, defaultLocation *time.Location) (ReminderService, error) {
+	s := &service{
+		store:           store,
+		defaultLocation: defaultLocation,
+		users:           make(map[string]*userReminders),
+	}
+	if err := store.Load(storeName, &s.users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load reminders: %w", err)
+	}
+	log.Printf("ReminderService: Loaded reminders for %d users", len(s.users))
+	return s, nil
+}
+
+func (s *service) Create(userID string, reminder Reminder) (*Reminder, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reminder.Message = strings.TrimSpace(reminder.Message)
+	if reminder.Message == "" {
+		return nil, fmt.Errorf("%w: message is required", ErrInvalid)
+	}
+	if reminder.FireAt.IsZero() {
+		return nil, fmt.Errorf("%w: fire time is required", ErrInvalid)
+	}
+	now := time.Now()
+	if reminder.Recurrence == nil && reminder.FireAt.Before(now.Add(-pastGrace)) {
+		return nil, fmt.Errorf("%w: fire time %s has already passed", ErrInvalid, reminder.FireAt.Format(time.RFC3339))
+	}
+	if reminder.Recurrence != nil {
+		recurrence, err := reminder.Recurrence.normalize()
+		if err != nil {
+			return nil, err
+		}
+		reminder.Recurrence = recurrence
+	}
+
+	user := s.user(userID)
+	if reminder.Timezone == "" {
+		reminder.Timezone = s.locationOf(user).String()
+	} else if _, err := time.LoadLocation(reminder.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalid, reminder.Timezone)
+	}
+	if reminder.Recurrence != nil && reminder.FireAt.Before(now) {
+		// A series that started in the past begins at its next occurrence
+		loc, _ := time.LoadLocation(reminder.Timezone)
+		reminder.FireAt = reminder.Recurrence.NextAfter(reminder.FireAt, now, loc)
+	}
+
+	reminder.ID = storage.NewID()
+	reminder.UserID = userID
+	reminder.Status = StatusScheduled
+	reminder.FireCount = 0
+	reminder.LastFiredAt = nil
+	reminder.CreatedAt = now
+
+	user.Reminders = append(user.Reminders, reminder)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("ReminderService: Scheduled reminder %s for user %s at %s", reminder.ID, userID, reminder.FireAt.Format(time.RFC3339))
+	return &reminder, nil
+}
+
+func (s *service) Get(userID, id string) (*Reminder, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	for _, reminder := range user.Reminders {
+		if reminder.ID == id {
+			return &reminder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *service) List(userID string, status Status) ([]Reminder, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Reminder, 0)
+	if user, exists := s.users[userID]; exists {
+		for _, reminder := range user.Reminders {
+			if status == "" || reminder.Status == status {
+				result = append(result, reminder)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].FireAt.Before(result[j].FireAt)
+	})
+	return result, nil
+}
+
+func (s *service) Cancel(userID, id string) (*Reminder, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reminder := s.find(userID, id)
+	if reminder == nil {
+		return nil, ErrNotFound
+	}
+	reminder.Status = StatusCancelled
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("ReminderService: Cancelled reminder %s for user %s", id, userID)
+	result := *reminder
+	return &result, nil
+}
+
+func (s *service) Due(now time.Time) ([]Reminder, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var due []Reminder
+	for _, user := range s.users {
+		for _, reminder := range user.Reminders {
+			if reminder.Status == StatusScheduled && !reminder.FireAt.After(now) {
+				due = append(due, reminder)
+			}
+		}
+	}
+	return due, nil
+}
+
+func (s *service) Fire(id string, at time.Time) (*Reminder, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for userID := range s.users {
+		reminder := s.find(userID, id)
+		if reminder == nil {
+			continue
+		}
+
+		reminder.FireCount++
+		reminder.LastFiredAt = &at
+		if reminder.Recurrence == nil {
+			reminder.Status = StatusFired
+		} else {
+			loc, err := time.LoadLocation(reminder.Timezone)
+			if err != nil {
+				loc = s.defaultLocation
+			}
+			reminder.FireAt = reminder.Recurrence.NextAfter(reminder.FireAt, at, loc)
+		}
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+		result := *reminder
+		return &result, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *service) Import(userID, timezone string, list []Reminder) (int, error) {
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return 0, fmt.Errorf("%w: unknown timezone %q", ErrInvalid, timezone)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user := s.user(userID)
+	changed := false
+	if user.Timezone == "" && timezone != "" {
+		user.Timezone = timezone
+		changed = true
+	}
+	now := time.Now()
+	added := 0
+	for _, reminder := range list {
+		if reminder.ID == "" || s.find(userID, reminder.ID) != nil {
+			continue
+		}
+		reminder.UserID = userID
+		// Times that passed since the export are not fired all at once:
+		// one-off reminders are missed and series resume at their next
+		// occurrence
+		if reminder.Status == StatusScheduled && reminder.FireAt.Before(now) {
+			if reminder.Recurrence == nil {
+				reminder.Status = StatusMissed
+			} else {
+				loc, err := time.LoadLocation(reminder.Timezone)
+				if err != nil {
+					loc = s.locationOf(user)
+				}
+				reminder.FireAt = reminder.Recurrence.NextAfter(reminder.FireAt, now, loc)
+			}
+		}
+		user.Reminders = append(user.Reminders, reminder)
+		added++
+	}
+	if added == 0 && !changed {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("ReminderService: Imported %d reminders for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.users))
+	for userID := range s.users {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (s *service) Location(userID string) (*time.Location, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.locationOf(s.users[userID]), nil
+}
+
+func (s *service) SetTimezone(userID, name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(strings.TrimSpace(name))
+	if err != nil || strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalid, name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.user(userID).Timezone = loc.String()
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	log.Printf("ReminderService: Set timezone for user %s to %s", userID, loc)
+	return loc, nil
+}
+
+func (s *service) locationOf(user *userReminders) *time.Location {
+	if user != nil && user.Timezone != "" {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc
+		}
+	}
+	return s.defaultLocation
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return 0, nil
+	}
+	delete(s.users, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("ReminderService: Purged %d reminders for user %s", len(user.Reminders), userID)
+	return len(user.Reminders), nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return 0, nil
+	}
+	count := len(user.Reminders)
+	if user.Timezone != "" {
+		count++
+	}
+	return count, nil
+}
+
+func (s *service) user(userID string) *userReminders {
+	user, exists := s.users[userID]
+	if !exists {
+		user = &userReminders{}
+		s.users[userID] = user
+	}
+	return user
+}
+
+func (s *service) find(userID, id string) *Reminder {
+	user, exists := s.users[userID]
+	if !exists {
+		return nil
+	}
+	for i := range user.Reminders {
+		if user.Reminders[i].ID == id {
+			return &user.Reminders[i]
+		}
+	}
+	return nil
+}
+
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.users); err != nil {
+		return fmt.Errorf("save reminders: %w", err)
+	}
+	return nil
+}
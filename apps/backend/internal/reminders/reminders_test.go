@@ -0,0 +1,96 @@
+package reminders
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+func newService(t *testing.T) ReminderService {
+	t.Helper()
+	service, err := NewService(storage.NewMemoryStore(), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestCreateFireTimes(t *testing.T) {
+	now := time.Now()
+	daily := &Recurrence{Frequency: FrequencyDaily, Interval: 1}
+
+	tests := []struct {
+		name       string
+		fireAt     time.Time
+		recurrence *Recurrence
+		wantErr    error
+		// wantAfter is false when FireAt is kept as given
+		wantAfter bool
+	}{
+		{"one-off in the future", now.Add(time.Hour), nil, nil, false},
+		{"one-off just computed", now.Add(-time.Second), nil, nil, false},
+		{"one-off in the past", now.Add(-time.Hour), nil, ErrInvalid, false},
+		{"series started in the past", now.Add(-50 * time.Hour), daily, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder, err := newService(t).Create("alice", Reminder{Message: "stretch", FireAt: tt.fireAt, Recurrence: tt.recurrence})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantAfter {
+				if !reminder.FireAt.After(now) || reminder.FireAt.After(now.Add(24*time.Hour)) {
+					t.Fatalf("FireAt = %s, want the next occurrence after %s", reminder.FireAt, now)
+				}
+			} else if !reminder.FireAt.Equal(tt.fireAt) {
+				t.Fatalf("FireAt = %s, want %s", reminder.FireAt, tt.fireAt)
+			}
+		})
+	}
+}
+
+func TestImportSettlesPastReminders(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-72*time.Hour), now.Add(time.Hour)
+	daily := &Recurrence{Frequency: FrequencyDaily, Interval: 1}
+
+	tests := []struct {
+		name       string
+		reminder   Reminder
+		wantStatus Status
+	}{
+		{"past one-off", Reminder{FireAt: past, Status: StatusScheduled}, StatusMissed},
+		{"past series", Reminder{FireAt: past, Status: StatusScheduled, Recurrence: daily, Timezone: "UTC"}, StatusScheduled},
+		{"future one-off", Reminder{FireAt: future, Status: StatusScheduled}, StatusScheduled},
+		{"already fired", Reminder{FireAt: past, Status: StatusFired}, StatusFired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newService(t)
+			tt.reminder.ID, tt.reminder.Message = "r1", "stretch"
+			if added, err := service.Import("alice", "", []Reminder{tt.reminder}); added != 1 || err != nil {
+				t.Fatalf("Import = %d, %v", added, err)
+			}
+
+			reminder, err := service.Get("alice", "r1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reminder.Status != tt.wantStatus {
+				t.Fatalf("Status = %s, want %s", reminder.Status, tt.wantStatus)
+			}
+			if tt.reminder.Recurrence != nil && !reminder.FireAt.After(now) {
+				t.Fatalf("FireAt = %s, want the next occurrence", reminder.FireAt)
+			}
+			// Nothing imported fires at once
+			if due, _ := service.Due(now); len(due) != 0 {
+				t.Fatalf("Due after Import = %v, want none", due)
+			}
+		})
+	}
+}
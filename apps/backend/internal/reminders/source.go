@@ -0,0 +1,47 @@
+package reminders
+
+import (
+	"log"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+)
+
+// ReasonReminder marks nudges that come from a user-set reminder.
+const ReasonReminder = "reminder"
+
+// Source hands due reminders to the scheduler as nudges so they go out
+// through the same delivery channels.
+type Source struct {
+	service ReminderService
+}
+
+func NewSource(service ReminderService) *Source {
+	return &Source{service: service}
+}
+
+func (s *Source) Name() string {
+	return "reminders"
+}
+
+func (s *Source) Collect(now time.Time) ([]scheduler.Nudge, error) {
+	due, err := s.service.Due(now)
+	if err != nil {
+		return nil, err
+	}
+
+	nudges := make([]scheduler.Nudge, 0, len(due))
+	for _, reminder := range due {
+		if _, err := s.service.Fire(reminder.ID, now); err != nil {
+			log.Printf("ReminderSource: Failed to fire reminder %s: %v", reminder.ID, err)
+			continue
+		}
+		nudges = append(nudges, scheduler.Nudge{
+			UserID:    reminder.UserID,
+			Message:   "Reminder: " + reminder.Message,
+			Reason:    ReasonReminder,
+			DeliverAt: reminder.FireAt,
+		})
+	}
+	return nudges, nil
+}
@@ -0,0 +1,410 @@
+package reminders
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultHour is used when a day is given without a time of day.
+	defaultHour = 9
+	// defaultLead is how long before an event "remind me before ..." fires.
+	defaultLead = 15 * time.Minute
+)
+
+// When is a point in time, and optionally a recurrence, read from text.
+// Rest is the text with the recognized time phrases removed.
+type When struct {
+	At         time.Time
+	Recurrence *Recurrence
+	Rest       string
+}
+
+var (
+	weekdayNames = map[string]time.Weekday{
+		"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+		"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	}
+	monthNames = map[string]time.Month{
+		"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "may": time.May, "jun": time.June,
+		"jul": time.July, "aug": time.August, "sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+	}
+	numberWords = map[string]int{
+		"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+		"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "fifteen": 15,
+		"twenty": 20, "thirty": 30, "forty-five": 45,
+	}
+	partOfDayHours = map[string]int{"morning": 8, "afternoon": 14, "evening": 19, "night": 21, "tonight": 20}
+
+	weekdayGroup = `(monday|tuesday|wednesday|thursday|friday|saturday|sunday)`
+	monthGroup   = `(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?`
+	numberGroup  = `(\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen|twenty|thirty|forty-five)`
+
+	everyNPattern       = regexp.MustCompile(`\bevery ` + numberGroup + ` (hour|day|week|month)s?\b`)
+	everyOtherPattern   = regexp.MustCompile(`\bevery other (hour|day|week|month)\b`)
+	everyWeekdayPattern = regexp.MustCompile(`\b(?:every (?:weekday|work ?day)|on weekdays)s?\b`)
+	everyDayNamePattern = regexp.MustCompile(`\bevery ` + weekdayGroup + `s?\b`)
+	everyPartPattern    = regexp.MustCompile(`\bevery (morning|afternoon|evening|night)\b`)
+	everyUnitPattern    = regexp.MustCompile(`\b(?:every|each) (hour|day|week|month)\b`)
+	adverbPattern       = regexp.MustCompile(`(?:^remind me (hourly|daily|weekly|monthly)\b|\b(hourly|daily|weekly|monthly)$)`)
+
+	leadPattern       = regexp.MustCompile(`\b` + numberGroup + ` (minute|min|hour)s? (?:before|ahead of)\b`)
+	beforePattern     = regexp.MustCompile(`\bbefore (?:my|the|our|his|her|their)\b`)
+	inHalfPattern     = regexp.MustCompile(`\bin half an? hour\b`)
+	inPattern         = regexp.MustCompile(`\bin ` + numberGroup + ` (minute|min|hour|hr|day|week|month)s?\b`)
+	relativeDayPat    = regexp.MustCompile(`\b(day after tomorrow|tomorrow|today|tonight)\b`)
+	nextUnitPattern   = regexp.MustCompile(`\bnext (week|month)\b`)
+	dayNamePattern    = regexp.MustCompile(`\b(?:on |next |this |coming )?` + weekdayGroup + `\b`)
+	isoDatePattern    = regexp.MustCompile(`\b(?:on )?(\d{4})-(\d{2})-(\d{2})\b`)
+	monthDayPattern   = regexp.MustCompile(`\b(?:on )?` + monthGroup + ` (\d{1,2})(?:st|nd|rd|th)?\b`)
+	dayMonthPattern   = regexp.MustCompile(`\b(?:on )?(?:the )?(\d{1,2})(?:st|nd|rd|th)? (?:of )?` + monthGroup + `\b`)
+	noonPattern       = regexp.MustCompile(`\b(?:at )?(noon|midnight)\b`)
+	meridiemPattern   = regexp.MustCompile(`\b(?:at |@ ?)?(\d{1,2})(?::(\d{2}))? ?(am|pm|a\.m\.|p\.m\.)`)
+	atTimePattern     = regexp.MustCompile(`\b(?:at|@) ?(\d{1,2})(?::(\d{2}))?\b`)
+	clockPattern      = regexp.MustCompile(`\b(\d{1,2}):(\d{2})\b`)
+	partOfDayPattern  = regexp.MustCompile(`\b(?:in the |this )?(morning|afternoon|evening)\b`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ParseWhen reads a reminder time from natural-language text such as "in 20
+// minutes", "tomorrow at 3", "before my meeting friday at 10am" or "every
+// weekday at 8:30", interpreting wall-clock times in loc. It fails with
+// ErrInvalid when the text holds no recognizable time, a time of day or
+// date that doesn't exist such as "at 25:00" or "feb 30", or a one-off time
+// that has already passed.
+func ParseWhen(text string, now time.Time, loc *time.Location) (When, error) {
+	p := &whenParser{text: strings.ToLower(text), original: text, now: now.In(loc), loc: loc}
+	if len(p.text) != len(text) {
+		p.original = p.text
+	}
+	p.parse()
+	if p.err != nil {
+		return When{Rest: strings.TrimSpace(text)}, p.err
+	}
+	if !p.found {
+		return When{Rest: strings.TrimSpace(text)}, fmt.Errorf("%w: could not find a time in %q", ErrInvalid, text)
+	}
+	at := p.resolve()
+	if p.recurrence == nil && at.Before(p.now) {
+		return When{Rest: strings.TrimSpace(text)}, fmt.Errorf("%w: %s is in the past", ErrInvalid, at.Format("2006-01-02 15:04"))
+	}
+	return When{At: at, Recurrence: p.recurrence, Rest: p.rest()}, nil
+}
+
+// whenParser matches against the lowercased text and blanks out the same
+// byte ranges in the original, so the leftover message keeps its casing.
+type whenParser struct {
+	text     string
+	original string
+	now      time.Time
+	loc      *time.Location
+
+	found      bool
+	recurrence *Recurrence
+
+	relative   time.Duration
+	hasDate    bool
+	date       time.Time
+	weekday    *time.Weekday
+	hasTime    bool
+	hour, min  int
+	partOfDay  string
+	lead       time.Duration
+	leadBefore bool
+	err        error
+}
+
+// take finds pattern in the remaining text, blanks out the match and returns
+// its submatches.
+func (p *whenParser) take(pattern *regexp.Regexp) []string {
+	loc := pattern.FindStringSubmatchIndex(p.text)
+	if loc == nil {
+		return nil
+	}
+	match := make([]string, len(loc)/2)
+	for i := range match {
+		if loc[2*i] >= 0 {
+			match[i] = p.text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	blank := strings.Repeat(" ", loc[1]-loc[0])
+	p.text = p.text[:loc[0]] + blank + p.text[loc[1]:]
+	p.original = p.original[:loc[0]] + blank + p.original[loc[1]:]
+	p.found = true
+	return match
+}
+
+func (p *whenParser) parse() {
+	p.parseRecurrence()
+
+	if m := p.take(leadPattern); m != nil {
+		n := parseNumber(m[1])
+		p.lead = time.Duration(n) * time.Minute
+		if strings.HasPrefix(m[2], "hour") {
+			p.lead = time.Duration(n) * time.Hour
+		}
+		p.leadBefore = true
+	} else if beforePattern.MatchString(p.text) {
+		p.lead = defaultLead
+		p.leadBefore = true
+	}
+
+	if p.take(inHalfPattern) != nil {
+		p.relative = 30 * time.Minute
+	} else if m := p.take(inPattern); m != nil {
+		n := parseNumber(m[1])
+		switch m[2] {
+		case "minute", "min":
+			p.relative = time.Duration(n) * time.Minute
+		case "hour", "hr":
+			p.relative = time.Duration(n) * time.Hour
+		case "day":
+			p.date, p.hasDate = p.now.AddDate(0, 0, n), true
+		case "week":
+			p.date, p.hasDate = p.now.AddDate(0, 0, 7*n), true
+		case "month":
+			p.date, p.hasDate = p.now.AddDate(0, n, 0), true
+		}
+	}
+
+	p.parseDate()
+	p.parseTime()
+}
+
+func (p *whenParser) parseRecurrence() {
+	if m := p.take(everyNPattern); m != nil {
+		p.recurrence = &Recurrence{Frequency: unitFrequency(m[2]), Interval: parseNumber(m[1])}
+	} else if m := p.take(everyOtherPattern); m != nil {
+		p.recurrence = &Recurrence{Frequency: unitFrequency(m[1]), Interval: 2}
+	} else if p.take(everyWeekdayPattern) != nil {
+		p.recurrence = &Recurrence{Frequency: FrequencyWeekdays, Interval: 1}
+	} else if m := p.take(everyDayNamePattern); m != nil {
+		day := weekdayNames[m[1]]
+		p.weekday = &day
+		p.recurrence = &Recurrence{Frequency: FrequencyWeekly, Interval: 1}
+	} else if m := p.take(everyPartPattern); m != nil {
+		p.partOfDay = m[1]
+		p.recurrence = &Recurrence{Frequency: FrequencyDaily, Interval: 1}
+	} else if m := p.take(everyUnitPattern); m != nil {
+		p.recurrence = &Recurrence{Frequency: unitFrequency(m[1]), Interval: 1}
+	} else {
+		p.parseAdverb()
+	}
+}
+
+// parseAdverb handles "remind me daily ..." and "... weekly", but not
+// adverbs mid-sentence where they are usually part of the message
+// ("the daily standup").
+func (p *whenParser) parseAdverb() {
+	prefix := p.original[:min(len("remind me"), len(p.original))]
+	m := p.take(adverbPattern)
+	if m == nil {
+		return
+	}
+	p.recurrence = &Recurrence{Frequency: Frequency(m[1] + m[2]), Interval: 1}
+	if m[1] != "" {
+		// Put "remind me" back so the message can still be extracted.
+		p.text = "remind me" + p.text[len(prefix):]
+		p.original = prefix + p.original[len(prefix):]
+	}
+}
+
+func (p *whenParser) parseDate() {
+	if p.hasDate {
+		return
+	}
+	today := p.now
+
+	if m := p.take(relativeDayPat); m != nil {
+		p.hasDate = true
+		switch m[1] {
+		case "today":
+			p.date = today
+		case "tonight":
+			p.date = today
+			if p.partOfDay == "" {
+				p.partOfDay = "tonight"
+			}
+		case "tomorrow":
+			p.date = today.AddDate(0, 0, 1)
+		case "day after tomorrow":
+			p.date = today.AddDate(0, 0, 2)
+		}
+		return
+	}
+	if m := p.take(nextUnitPattern); m != nil {
+		p.hasDate = true
+		if m[1] == "week" {
+			p.date = today.AddDate(0, 0, 7)
+		} else {
+			p.date = today.AddDate(0, 1, 0)
+		}
+		return
+	}
+	if m := p.take(isoDatePattern); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month < 1 || month > 12 || day < 1 || day > daysIn(time.Month(month), year) {
+			p.err = fmt.Errorf("%w: %s is not a date", ErrInvalid, strings.TrimSpace(m[0]))
+			return
+		}
+		p.date, p.hasDate = time.Date(year, time.Month(month), day, 0, 0, 0, 0, p.loc), true
+		return
+	}
+	if m := p.take(monthDayPattern); m != nil {
+		day, _ := strconv.Atoi(m[2])
+		p.upcomingDate(monthNames[m[1][:3]], day, m[0])
+		return
+	}
+	if m := p.take(dayMonthPattern); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		p.upcomingDate(monthNames[m[2][:3]], day, m[0])
+		return
+	}
+	if m := p.take(dayNamePattern); m != nil {
+		day := weekdayNames[m[1]]
+		p.weekday = &day
+	}
+}
+
+func (p *whenParser) parseTime() {
+	if m := p.take(noonPattern); m != nil {
+		p.hasTime = true
+		if m[1] == "noon" {
+			p.hour = 12
+		}
+		return
+	}
+	if m := p.take(meridiemPattern); m != nil {
+		p.hasTime = true
+		p.hour, _ = strconv.Atoi(m[1])
+		p.min, _ = strconv.Atoi(m[2])
+		if p.hour < 1 || p.hour > 12 || p.min > 59 {
+			p.err = fmt.Errorf("%w: %s is not a time of day", ErrInvalid, strings.TrimSpace(m[0]))
+			return
+		}
+		p.hour %= 12
+		if strings.HasPrefix(m[3], "p") {
+			p.hour += 12
+		}
+		return
+	}
+	m := p.take(atTimePattern)
+	if m == nil {
+		m = p.take(clockPattern)
+	}
+	if m != nil {
+		p.hasTime = true
+		p.hour, _ = strconv.Atoi(m[1])
+		p.min, _ = strconv.Atoi(m[2])
+		if p.hour > 23 || p.min > 59 {
+			p.err = fmt.Errorf("%w: %s is not a time of day", ErrInvalid, strings.TrimSpace(m[0]))
+			return
+		}
+		if p.hour >= 1 && p.hour <= 7 {
+			// "at 3" almost always means the afternoon
+			p.hour += 12
+		}
+		return
+	}
+	if m := p.take(partOfDayPattern); m != nil && p.partOfDay == "" {
+		p.partOfDay = m[1]
+	}
+}
+
+// upcomingDate sets the date to the next month/day on or after today, or
+// fails when no year has that day, as with "feb 30".
+func (p *whenParser) upcomingDate(month time.Month, day int, match string) {
+	// 2000 is a leap year, so February allows the 29th
+	if day < 1 || day > daysIn(month, 2000) {
+		p.err = fmt.Errorf("%w: %s is not a date", ErrInvalid, strings.TrimSpace(match))
+		return
+	}
+	today := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.loc)
+	for year := p.now.Year(); ; year++ {
+		// February 29th waits for the next leap year
+		if day > daysIn(month, year) {
+			continue
+		}
+		if date := time.Date(year, month, day, 0, 0, 0, 0, p.loc); !date.Before(today) {
+			p.date, p.hasDate = date, true
+			return
+		}
+	}
+}
+
+// daysIn returns the number of days in month of year.
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func (p *whenParser) resolve() time.Time {
+	if p.relative > 0 && !p.hasDate && !p.hasTime {
+		return p.now.Add(p.relative)
+	}
+
+	hour, min := defaultHour, 0
+	switch {
+	case p.hasTime:
+		hour, min = p.hour, p.min
+	case p.partOfDay != "":
+		hour = partOfDayHours[p.partOfDay]
+	case !p.hasDate && p.weekday == nil && p.recurrence != nil:
+		// "every 2 hours" starts from now
+		if p.recurrence.Frequency == FrequencyHourly {
+			return p.now.Add(time.Duration(p.recurrence.Interval) * time.Hour)
+		}
+	}
+
+	base := p.now
+	if p.hasDate {
+		base = p.date.In(p.loc)
+	}
+	at := time.Date(base.Year(), base.Month(), base.Day(), hour, min, 0, 0, p.loc)
+
+	if p.weekday != nil {
+		for at.Weekday() != *p.weekday || !at.After(p.now) {
+			at = at.AddDate(0, 0, 1)
+		}
+	} else if !p.hasDate && !at.After(p.now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	if p.recurrence != nil && p.recurrence.Frequency == FrequencyWeekdays {
+		for at.Weekday() == time.Saturday || at.Weekday() == time.Sunday {
+			at = at.AddDate(0, 0, 1)
+		}
+	}
+
+	if p.leadBefore {
+		at = at.Add(-p.lead)
+	}
+	return at
+}
+
+func (p *whenParser) rest() string {
+	rest := whitespacePattern.ReplaceAllString(p.original, " ")
+	return strings.Trim(rest, " ,.;")
+}
+
+func parseNumber(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return numberWords[s]
+}
+
+func unitFrequency(unit string) Frequency {
+	switch unit {
+	case "hour":
+		return FrequencyHourly
+	case "week":
+		return FrequencyWeekly
+	case "month":
+		return FrequencyMonthly
+	}
+	return FrequencyDaily
+}
@@ -0,0 +1,90 @@
+package reminders
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	// Wednesday
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, berlin)
+
+	tests := []struct {
+		text           string
+		want           string
+		wantRecurrence Frequency
+		wantRest       string
+	}{
+		{"in 20 minutes call mom", "2024-05-15 10:50", "", "call mom"},
+		{"in half an hour", "2024-05-15 11:00", "", ""},
+		{"tomorrow at 3 water the plants", "2024-05-16 15:00", "", "water the plants"},
+		{"today at 23:59", "2024-05-15 23:59", "", ""},
+		{"at 0:00", "2024-05-16 00:00", "", ""},
+		{"at 9", "2024-05-16 09:00", "", ""},
+		{"friday at 10am", "2024-05-17 10:00", "", ""},
+		{"12am on 2024-06-01", "2024-06-01 00:00", "", ""},
+		{"at 12:30pm", "2024-05-15 12:30", "", ""},
+		{"noon", "2024-05-15 12:00", "", ""},
+		{"15 minutes before my meeting friday at 10am", "2024-05-17 09:45", "", "my meeting"},
+		{"every weekday at 8:30 stand up", "2024-05-16 08:30", FrequencyWeekdays, "stand up"},
+		{"every monday morning", "2024-05-20 08:00", FrequencyWeekly, ""},
+		{"on june 3rd pay rent", "2024-06-03 09:00", "", "pay rent"},
+		{"feb 29 check the boiler", "2028-02-29 09:00", "", "check the boiler"},
+		{"may 15 at 18:00", "2024-05-15 18:00", "", ""},
+		{"Call Anna tonight", "2024-05-15 20:00", "", "Call Anna"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			when, err := ParseWhen(tt.text, now, berlin)
+			if err != nil {
+				t.Fatalf("ParseWhen: %v", err)
+			}
+			if got := when.At.In(berlin).Format("2006-01-02 15:04"); got != tt.want {
+				t.Fatalf("At = %s, want %s", got, tt.want)
+			}
+			var frequency Frequency
+			if when.Recurrence != nil {
+				frequency = when.Recurrence.Frequency
+			}
+			if frequency != tt.wantRecurrence {
+				t.Fatalf("recurrence = %q, want %q", frequency, tt.wantRecurrence)
+			}
+			if when.Rest != tt.wantRest {
+				t.Fatalf("Rest = %q, want %q", when.Rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseWhenRejectsInvalidTimes(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+	for _, text := range []string{
+		"at 25:00",
+		"at 24",
+		"at 9:60",
+		"tomorrow 23:75",
+		"13pm",
+		"at 0am",
+		"at 11:61 pm",
+		"call mom",
+		"",
+		"remind me feb 30 to pay rent",
+		"on the 31st of april",
+		"2024-02-30 at 9",
+		"2024-13-01",
+		"2024-05-01 at 9",
+		"today at 9",
+	} {
+		t.Run(text, func(t *testing.T) {
+			when, err := ParseWhen(text, now, time.UTC)
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("ParseWhen(%q) = %s, %v, want ErrInvalid", text, when.At, err)
+			}
+		})
+	}
+}
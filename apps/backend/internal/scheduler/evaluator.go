@@ -0,0 +1,327 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/mood"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+const (
+	// maxProfileChars caps how much of the profile is sent to the LLM.
+	maxProfileChars = 2000
+	// maxContextTasks caps how many open tasks are sent to the LLM.
+	maxContextTasks = 10
+	// dueSoonWindow is how far ahead a task counts as due soon.
+	dueSoonWindow = 3 * time.Hour
+	// streakReminderHour is the local hour after which an unfinished daily
+	// habit with a running streak triggers a reminder.
+	streakReminderHour = 17
+	// eventLookahead is how far ahead calendar events are considered.
+	eventLookahead = 24 * time.Hour
+	// eventLeadWindow is how soon an event must start to be nudged about.
+	eventLeadWindow = time.Hour
+	// maxContextEvents caps how many upcoming events are sent to the LLM.
+	maxContextEvents = 5
+)
+
+// Decision is the evaluator's verdict on whether a user should be nudged now.
+type Decision struct {
+	Nudge     bool
+	Message   string
+	Reason    string
+	DeliverIn time.Duration
+}
+
+// Services holds what the evaluator looks at. Mood, Calendar and Locator
+// are optional.
+type Services struct {
+	LLM      llm.LLMService
+	Tasks    tasks.TaskService
+	Goals    goals.GoalService
+	Profile  profile.ProfileService
+	Mood     mood.MoodService
+	Calendar calendar.CalendarService
+	Locator  calendar.Locator
+}
+
+// Evaluator gathers a user's tasks, goals, mood, calendar and profile and
+// decides whether a nudge is warranted, asking the LLM when available.
+type Evaluator struct {
+	llmService      llm.LLMService
+	taskService     tasks.TaskService
+	goalService     goals.GoalService
+	profileService  profile.ProfileService
+	moodService     mood.MoodService
+	calendarService calendar.CalendarService
+	locator         calendar.Locator
+}
+
+func NewEvaluator(services Services) *Evaluator {
+	return &Evaluator{
+		llmService:      services.LLM,
+		taskService:     services.Tasks,
+		goalService:     services.Goals,
+		profileService:  services.Profile,
+		moodService:     services.Mood,
+		calendarService: services.Calendar,
+		locator:         services.Locator,
+	}
+}
+
+type userContext struct {
+	now         time.Time
+	loc         *time.Location
+	events      []calendar.Occurrence
+	openTasks   []tasks.Task
+	activeGoals []goals.Goal
+	progress    []goals.Progress
+	profile     string
+	trend       *mood.Trend
+	recent      []Nudge
+}
+
+func (e *Evaluator) Evaluate(userID string, now time.Time, recent []Nudge) (Decision, error) {
+	ctx, err := e.gather(userID, now, recent)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if e.llmService != nil {
+		decision, err := e.evaluateWithLLM(ctx)
+		if err == nil {
+			return decision, nil
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("NudgeEvaluator: LLM evaluation failed, using rules: %v", err)
+		}
+	}
+	return evaluateRules(ctx), nil
+}
+
+func (e *Evaluator) gather(userID string, now time.Time, recent []Nudge) (*userContext, error) {
+	ctx := &userContext{now: now, loc: time.Local, recent: recent}
+
+	var err error
+	if e.locator != nil {
+		if ctx.loc, err = e.locator.Location(userID); err != nil {
+			return nil, fmt.Errorf("get timezone: %w", err)
+		}
+		ctx.now = now.In(ctx.loc)
+	}
+
+	if ctx.openTasks, err = e.taskService.List(userID, tasks.Filter{Status: tasks.StatusOpen}); err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	if len(ctx.openTasks) > maxContextTasks {
+		ctx.openTasks = ctx.openTasks[:maxContextTasks]
+	}
+
+	if ctx.activeGoals, err = e.goalService.List(userID, goals.StatusActive); err != nil {
+		return nil, fmt.Errorf("list goals: %w", err)
+	}
+	for _, goal := range ctx.activeGoals {
+		ctx.progress = append(ctx.progress, goals.ComputeProgress(goal, now, ctx.loc))
+	}
+
+	if ctx.profile, err = e.profileService.Get(userID); err != nil {
+		return nil, fmt.Errorf("get profile: %w", err)
+	}
+	if len(ctx.profile) > maxProfileChars {
+		ctx.profile = ctx.profile[len(ctx.profile)-maxProfileChars:]
+	}
+
+	if e.moodService != nil {
+		if ctx.trend, err = e.moodService.Trend(userID, now); err != nil {
+			log.Printf("NudgeEvaluator: Failed to get mood trend for %s: %v", userID, err)
+		}
+	}
+
+	if e.calendarService != nil {
+		if ctx.events, err = e.calendarService.Upcoming(userID, now, now.Add(eventLookahead)); err != nil {
+			log.Printf("NudgeEvaluator: Failed to get calendar for %s: %v", userID, err)
+		}
+		if len(ctx.events) > maxContextEvents {
+			ctx.events = ctx.events[:maxContextEvents]
+		}
+	}
+	return ctx, nil
+}
+
+func (e *Evaluator) evaluateWithLLM(ctx *userContext) (Decision, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Current time: %s\n\n", ctx.now.Format("Monday, January 2, 2006 at 3:04 PM MST"))
+
+	b.WriteString("Open tasks:\n")
+	for _, task := range ctx.openTasks {
+		due := "no due date"
+		if task.DueAt != nil {
+			due = "due " + task.DueAt.In(ctx.loc).Format(time.RFC3339)
+		}
+		fmt.Fprintf(&b, "- %s [%s, %s]\n", task.Title, task.Priority, due)
+	}
+
+	b.WriteString("\nActive goals:\n")
+	for i, goal := range ctx.activeGoals {
+		p := ctx.progress[i]
+		fmt.Fprintf(&b, "- %s (motivation: %s; %d/%d milestones)\n", goal.Title, goal.Motivation, p.MilestonesDone, p.MilestonesTotal)
+		for _, habit := range p.Habits {
+			fmt.Fprintf(&b, "  habit %s: %d-%s streak, done this period: %v\n", habit.Title, habit.CurrentStreak, habit.Frequency, habit.DoneThisPeriod)
+		}
+	}
+
+	b.WriteString("\nUpcoming calendar events:\n")
+	for _, event := range ctx.events {
+		fmt.Fprintf(&b, "- %s\n", calendar.Describe(event, ctx.loc))
+	}
+
+	if ctx.trend != nil {
+		fmt.Fprintf(&b, "\nMood trend: %s (average sentiment %.2f). %s\n", ctx.trend.Direction, ctx.trend.CurrentSentiment, ctx.trend.Summary)
+	}
+
+	b.WriteString("\nRecent nudges already sent:\n")
+	for _, nudge := range ctx.recent {
+		fmt.Fprintf(&b, "- %s: %s\n", nudge.CreatedAt.Format(time.RFC3339), nudge.Message)
+	}
+
+	prompt := fmt.Sprintf(`You are a supportive personal assistant deciding whether to proactively nudge the user right now.
+Only nudge when it would genuinely help: an upcoming deadline or meeting, a habit streak at risk, or a goal that fits their current state.
+Do not repeat recent nudges. Keep messages short, warm and specific.
+
+%s
+User profile:
+%s
+
+Return a single JSON object:
+{
+  "nudge": true or false,
+  "message": "the message to send, empty if no nudge",
+  "reason": "short machine-readable reason, e.g. task_due, habit_streak, mood_support",
+  "deliver_in_minutes": minutes from now to deliver (0 for immediately)
+}`, b.String(), ctx.profile)
+
+	response, err := e.llmService.Complete(prompt)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	var raw struct {
+		Nudge            bool   `json:"nudge"`
+		Message          string `json:"message"`
+		Reason           string `json:"reason"`
+		DeliverInMinutes int    `json:"deliver_in_minutes"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Decision{}, err
+	}
+	if raw.Nudge && strings.TrimSpace(raw.Message) == "" {
+		return Decision{}, fmt.Errorf("nudge without message")
+	}
+	if raw.DeliverInMinutes < 0 {
+		raw.DeliverInMinutes = 0
+	}
+
+	return Decision{
+		Nudge:     raw.Nudge,
+		Message:   strings.TrimSpace(raw.Message),
+		Reason:    raw.Reason,
+		DeliverIn: time.Duration(raw.DeliverInMinutes) * time.Minute,
+	}, nil
+}
+
+// evaluateRules is the offline fallback: imminent events first, then
+// deadlines, habit streaks at risk, and goal reminders when mood is low.
+func evaluateRules(ctx *userContext) Decision {
+	var candidates []Decision
+	lowMood := ctx.trend != nil && ctx.trend.CurrentSentiment < 0 &&
+		(ctx.trend.Direction == mood.DirectionDeclining || len(ctx.trend.RecurringEmotions) > 0)
+
+	for _, event := range ctx.events {
+		if event.AllDay || !event.Start.After(ctx.now) || event.Start.Sub(ctx.now) > eventLeadWindow {
+			continue
+		}
+		message := fmt.Sprintf("Coming up at %s: %s.", event.Start.In(ctx.loc).Format("3:04 PM"), event.Summary)
+		if habit := supportingHabit(ctx.activeGoals); lowMood && habit != "" {
+			message = fmt.Sprintf("\"%s\" starts at %s. It's been a heavy stretch — a few minutes of %s beforehand might help.", event.Summary, event.Start.In(ctx.loc).Format("3:04 PM"), habit)
+		}
+		candidates = append(candidates, Decision{Nudge: true, Message: message, Reason: "event_soon"})
+	}
+
+	for _, task := range ctx.openTasks {
+		if task.DueAt == nil {
+			continue
+		}
+		switch {
+		case task.DueAt.Before(ctx.now):
+			candidates = append(candidates, Decision{
+				Nudge:   true,
+				Message: fmt.Sprintf("\"%s\" was due %s. Want to tackle it or reschedule?", task.Title, task.DueAt.In(ctx.loc).Format("Mon 3:04 PM")),
+				Reason:  "task_overdue",
+			})
+		case task.DueAt.Sub(ctx.now) <= dueSoonWindow:
+			candidates = append(candidates, Decision{
+				Nudge:   true,
+				Message: fmt.Sprintf("Heads up: \"%s\" is due at %s.", task.Title, task.DueAt.In(ctx.loc).Format("3:04 PM")),
+				Reason:  "task_due",
+			})
+		}
+	}
+
+	if ctx.now.Hour() >= streakReminderHour {
+		for _, p := range ctx.progress {
+			for _, habit := range p.Habits {
+				if habit.Frequency == goals.FrequencyDaily && habit.CurrentStreak > 0 && !habit.DoneThisPeriod {
+					candidates = append(candidates, Decision{
+						Nudge:   true,
+						Message: fmt.Sprintf("You're on a %d-day streak with \"%s\" — a few minutes today keeps it going.", habit.CurrentStreak, habit.Title),
+						Reason:  "habit_streak",
+					})
+				}
+			}
+		}
+	}
+
+	if lowMood && len(ctx.activeGoals) > 0 {
+		goal := ctx.activeGoals[0]
+		message := fmt.Sprintf("It sounds like a heavy stretch. Remember your goal \"%s\"", goal.Title)
+		if goal.Motivation != "" {
+			message += " — " + goal.Motivation
+		}
+		candidates = append(candidates, Decision{Nudge: true, Message: message + ".", Reason: "mood_support"})
+	}
+
+	for _, candidate := range candidates {
+		if !recentlySent(ctx.recent, candidate.Message) {
+			return candidate
+		}
+	}
+	return Decision{Nudge: false, Reason: "nothing_actionable"}
+}
+
+// supportingHabit returns the first habit of the user's active goals, e.g.
+// "meditate 10 minutes", or "" when there is none.
+func supportingHabit(activeGoals []goals.Goal) string {
+	for _, goal := range activeGoals {
+		if len(goal.Habits) > 0 {
+			return goal.Habits[0].Title
+		}
+	}
+	return ""
+}
+
+func recentlySent(recent []Nudge, message string) bool {
+	for _, nudge := range recent {
+		if nudge.Message == message {
+			return true
+		}
+	}
+	return false
+}
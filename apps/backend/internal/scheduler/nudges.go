@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const nudgesStoreName = "nudges"
+
+var ErrNotFound = errors.New("nudge not found")
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusDismissed Status = "dismissed"
+)
+
+// Nudge is a proactive message queued for a user.
+type Nudge struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Message     string     `json:"message"`
+	Reason      string     `json:"reason"`
+	Status      Status     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliverAt   time.Time  `json:"deliver_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	Channels    []string   `json:"channels,omitempty"`
+}
+
+type NudgeQueue interface {
+	Enqueue(nudge Nudge) (*Nudge, error)
+	List(userID string, status Status) ([]Nudge, error)
+	// Due returns pending nudges whose delivery time has passed.
+	Due(now time.Time) ([]Nudge, error)
+	MarkDelivered(id string, channels []string, at time.Time) error
+	Dismiss(userID, id string) error
+	// Purge deletes the user's nudges, pending or not, returning how many
+	// there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+}
+
+type queue struct {
+	store  storage.Store
+	nudges []Nudge
+	mutex  sync.RWMutex
+}
+
+func NewQueue(store storage.Store) (NudgeQueue, error) {
+	q := &queue{store: store}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	log.Printf("NudgeQueue: Loaded %d nudges", len(q.nudges))
+	return q, nil
+}
+
+func (q *queue) Enqueue(nudge Nudge) (*Nudge, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	nudge.ID = storage.NewID()
+	nudge.Status = StatusPending
+	nudge.CreatedAt = now
+	if nudge.DeliverAt.IsZero() {
+		nudge.DeliverAt = now
+	}
+
+	q.nudges = append(q.nudges, nudge)
+	if err := q.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("NudgeQueue: Queued nudge %s for user %s at %s", nudge.ID, nudge.UserID, nudge.DeliverAt.Format(time.RFC3339))
+	return &nudge, nil
+}
+
+func (q *queue) List(userID string, status Status) ([]Nudge, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	result := make([]Nudge, 0)
+	for _, nudge := range q.nudges {
+		if nudge.UserID != userID || (status != "" && nudge.Status != status) {
+			continue
+		}
+		result = append(result, nudge)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].DeliverAt.After(result[j].DeliverAt)
+	})
+	return result, nil
+}
+
+func (q *queue) Due(now time.Time) ([]Nudge, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	var due []Nudge
+	for _, nudge := range q.nudges {
+		if nudge.Status == StatusPending && !nudge.DeliverAt.After(now) {
+			due = append(due, nudge)
+		}
+	}
+	return due, nil
+}
+
+func (q *queue) MarkDelivered(id string, channels []string, at time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i := range q.nudges {
+		if q.nudges[i].ID == id {
+			q.nudges[i].Status = StatusDelivered
+			q.nudges[i].DeliveredAt = &at
+			q.nudges[i].Channels = channels
+			return q.persist()
+		}
+	}
+	return ErrNotFound
+}
+
+func (q *queue) Dismiss(userID, id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i := range q.nudges {
+		if q.nudges[i].ID == id && q.nudges[i].UserID == userID {
+			q.nudges[i].Status = StatusDismissed
+			return q.persist()
+		}
+	}
+	return ErrNotFound
+}
+
+func (q *queue) Purge(userID string) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	kept := q.nudges[:0]
+	for _, nudge := range q.nudges {
+		if nudge.UserID != userID {
+			kept = append(kept, nudge)
+		}
+	}
+	count := len(q.nudges) - len(kept)
+	q.nudges = kept
+	if count == 0 {
+		return 0, nil
+	}
+	if err := q.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("NudgeQueue: Purged %d nudges for user %s", count, userID)
+	return count, nil
+}
+
+func (q *queue) Count(userID string) (int, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	count := 0
+	for _, nudge := range q.nudges {
+		if nudge.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// load reads the nudges, which are kept per user so the document can be
+// encrypted. A plain list saved before that is read as it is and saved per
+// user on the next change.
+func (q *queue) load() error {
+	var raw json.RawMessage
+	if err := q.store.Load(nudgesStoreName, &raw); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("load nudges: %w", err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &q.nudges); err != nil {
+			return fmt.Errorf("decode nudges: %w", err)
+		}
+		return nil
+	}
+
+	var users map[string][]Nudge
+	if err := json.Unmarshal(raw, &users); err != nil {
+		return fmt.Errorf("decode nudges: %w", err)
+	}
+	for _, nudges := range users {
+		q.nudges = append(q.nudges, nudges...)
+	}
+	sort.SliceStable(q.nudges, func(i, j int) bool {
+		return q.nudges[i].CreatedAt.Before(q.nudges[j].CreatedAt)
+	})
+	return nil
+}
+
+func (q *queue) persist() error {
+	users := make(map[string][]Nudge)
+	for _, nudge := range q.nudges {
+		users[nudge.UserID] = append(users[nudge.UserID], nudge)
+	}
+	if err := q.store.Save(nudgesStoreName, users); err != nil {
+		return fmt.Errorf("save nudges: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const stateStoreName = "scheduler"
+
+// recentWindow is how far back already-queued nudges are shown to the
+// evaluator so it does not repeat itself.
+const recentWindow = 24 * time.Hour
+
+// ErrNoRoute is returned by a Deliverer that has no destination for the
+// nudge's user, e.g. a chat channel the user never connected.
+var ErrNoRoute = errors.New("no delivery route for user")
+
+// Deliverer sends a due nudge to the user over some channel.
+type Deliverer interface {
+	Name() string
+	Deliver(nudge Nudge) error
+}
+
+// Source produces nudges that are due by now from outside the evaluator,
+// e.g. reminders the user set explicitly.
+type Source interface {
+	Name() string
+	Collect(now time.Time) ([]Nudge, error)
+}
+
+// UserLister reports the users a service holds data for.
+type UserLister interface {
+	Users() ([]string, error)
+}
+
+type Config struct {
+	// Interval is how often users are evaluated for nudges.
+	Interval time.Duration
+	// DispatchInterval is how often sources are collected and due nudges
+	// are delivered.
+	DispatchInterval time.Duration
+	// MinGap is the minimum time between two nudges for the same user.
+	MinGap time.Duration
+}
+
+// userState is persisted so evaluation pacing survives restarts.
+type userState struct {
+	LastEvaluatedAt  time.Time `json:"last_evaluated_at"`
+	NextEvaluationAt time.Time `json:"next_evaluation_at"`
+	LastNudgeAt      time.Time `json:"last_nudge_at"`
+}
+
+// Scheduler periodically evaluates every known user, queues nudges the
+// evaluator asks for and hands due nudges to the registered deliverers.
+type Scheduler struct {
+	config     Config
+	store      storage.Store
+	queue      NudgeQueue
+	evaluator  *Evaluator
+	users      []UserLister
+	deliverers []Deliverer
+	sources    []Source
+	state      map[string]*userState
+	mutex      sync.Mutex
+	// dispatching keeps runs of dispatch from the loop and EvaluateNow
+	// apart
+	dispatching sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func New(config Config, store storage.Store, queue NudgeQueue, evaluator *Evaluator, users ...UserLister) (*Scheduler, error) {
+	s := &Scheduler{
+		config:    config,
+		store:     store,
+		queue:     queue,
+		evaluator: evaluator,
+		users:     users,
+		state:     make(map[string]*userState),
+	}
+	if err := store.Load(stateStoreName, &s.state); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load scheduler state: %w", err)
+	}
+	return s, nil
+}
+
+// AddDeliverer registers a delivery channel. Nudges stay pending until at
+// least one deliverer accepts them.
+func (s *Scheduler) AddDeliverer(deliverer Deliverer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.deliverers = append(s.deliverers, deliverer)
+	log.Printf("Scheduler: Registered deliverer %s", deliverer.Name())
+}
+
+// AddSource registers a producer of time-based nudges.
+func (s *Scheduler) AddSource(source Source) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources = append(s.sources, source)
+	log.Printf("Scheduler: Registered source %s", source.Name())
+}
+
+// Start runs the scheduler loop in the background until Stop is called.
+func (s *Scheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	log.Printf("Scheduler: Starting with interval %s (dispatch every %s)", s.config.Interval, s.config.DispatchInterval)
+
+	go func() {
+		defer close(s.done)
+		evaluateTicker := time.NewTicker(s.config.Interval)
+		defer evaluateTicker.Stop()
+		dispatchTicker := time.NewTicker(s.config.DispatchInterval)
+		defer dispatchTicker.Stop()
+
+		s.RunOnce(time.Now())
+		for {
+			select {
+			case <-evaluateTicker.C:
+				s.RunOnce(time.Now())
+			case <-dispatchTicker.C:
+				s.dispatch(time.Now())
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	log.Printf("Scheduler: Stopped")
+}
+
+// RunOnce evaluates all users that are due and dispatches due nudges.
+func (s *Scheduler) RunOnce(now time.Time) {
+	for _, userID := range s.knownUsers() {
+		if !s.dueForEvaluation(userID, now) {
+			continue
+		}
+		if _, err := s.evaluate(userID, now); err != nil {
+			log.Printf("Scheduler: Failed to evaluate user %s: %v", userID, err)
+		}
+	}
+	s.dispatch(now)
+}
+
+// EvaluateNow evaluates a single user immediately, ignoring pacing, and
+// dispatches anything that became due. It returns the queued nudge, if any.
+func (s *Scheduler) EvaluateNow(userID string) (*Nudge, error) {
+	now := time.Now()
+	nudge, err := s.evaluate(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	s.dispatch(now)
+	return nudge, nil
+}
+
+func (s *Scheduler) evaluate(userID string, now time.Time) (*Nudge, error) {
+	queued, err := s.queue.List(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	var recent []Nudge
+	for _, nudge := range queued {
+		if nudge.Status != StatusDismissed && now.Sub(nudge.CreatedAt) <= recentWindow {
+			recent = append(recent, nudge)
+		}
+	}
+
+	decision, err := s.evaluator.Evaluate(userID, now, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	var nudge *Nudge
+	if decision.Nudge {
+		nudge, err = s.queue.Enqueue(Nudge{
+			UserID:    userID,
+			Message:   decision.Message,
+			Reason:    decision.Reason,
+			DeliverAt: now.Add(decision.DeliverIn),
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Printf("Scheduler: No nudge for user %s (%s)", userID, decision.Reason)
+	}
+
+	if err := s.recordEvaluation(userID, now, nudge != nil); err != nil {
+		return nil, err
+	}
+	return nudge, nil
+}
+
+// dispatch collects the sources and delivers the nudges that are due. Runs
+// never overlap: the queue only learns a nudge was delivered after every
+// deliverer had it, so a second run in between would send it again.
+func (s *Scheduler) dispatch(now time.Time) {
+	s.dispatching.Lock()
+	defer s.dispatching.Unlock()
+
+	s.collect(now)
+
+	due, err := s.queue.Due(now)
+	if err != nil {
+		log.Printf("Scheduler: Failed to load due nudges: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	deliverers := append([]Deliverer(nil), s.deliverers...)
+	s.mutex.Unlock()
+	if len(deliverers) == 0 {
+		return
+	}
+
+	for _, nudge := range due {
+		var channels []string
+		for _, deliverer := range deliverers {
+			err := deliverer.Deliver(nudge)
+			switch {
+			case err == nil:
+				channels = append(channels, deliverer.Name())
+			case errors.Is(err, ErrNoRoute):
+			default:
+				log.Printf("Scheduler: %s failed to deliver nudge %s: %v", deliverer.Name(), nudge.ID, err)
+			}
+		}
+		if len(channels) == 0 {
+			continue
+		}
+		if err := s.queue.MarkDelivered(nudge.ID, channels, now); err != nil {
+			log.Printf("Scheduler: Failed to mark nudge %s delivered: %v", nudge.ID, err)
+		}
+	}
+}
+
+// collect queues the nudges that sources report as due.
+func (s *Scheduler) collect(now time.Time) {
+	s.mutex.Lock()
+	sources := append([]Source(nil), s.sources...)
+	s.mutex.Unlock()
+
+	for _, source := range sources {
+		nudges, err := source.Collect(now)
+		if err != nil {
+			log.Printf("Scheduler: Failed to collect from %s: %v", source.Name(), err)
+			continue
+		}
+		for _, nudge := range nudges {
+			if _, err := s.queue.Enqueue(nudge); err != nil {
+				log.Printf("Scheduler: Failed to queue nudge from %s: %v", source.Name(), err)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) knownUsers() []string {
+	seen := make(map[string]bool)
+	var users []string
+	for _, lister := range s.users {
+		ids, err := lister.Users()
+		if err != nil {
+			log.Printf("Scheduler: Failed to list users: %v", err)
+			continue
+		}
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		}
+	}
+	return users
+}
+
+func (s *Scheduler) dueForEvaluation(userID string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, exists := s.state[userID]
+	return !exists || !now.Before(state.NextEvaluationAt)
+}
+
+// Purge forgets when the user was last evaluated and nudged, returning 1
+// if that was known.
+func (s *Scheduler) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.state[userID]; !exists {
+		return 0, nil
+	}
+	delete(s.state, userID)
+	if err := s.store.Save(stateStoreName, s.state); err != nil {
+		return 0, fmt.Errorf("save scheduler state: %w", err)
+	}
+	return 1, nil
+}
+
+func (s *Scheduler) Count(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.state[userID]; exists {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// recordEvaluation schedules the user's next evaluation one interval out,
+// but never sooner than MinGap after their last nudge.
+func (s *Scheduler) recordEvaluation(userID string, now time.Time, nudged bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, exists := s.state[userID]
+	if !exists {
+		state = &userState{}
+		s.state[userID] = state
+	}
+	state.LastEvaluatedAt = now
+	if nudged {
+		state.LastNudgeAt = now
+	}
+	state.NextEvaluationAt = now.Add(s.config.Interval)
+	if gapEnd := state.LastNudgeAt.Add(s.config.MinGap); gapEnd.After(state.NextEvaluationAt) {
+		state.NextEvaluationAt = gapEnd
+	}
+
+	if err := s.store.Save(stateStoreName, s.state); err != nil {
+		return fmt.Errorf("save scheduler state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+// The Observe* wrappers index what a domain service already holds and keep
+// the index current as items change through it.
+
+func TaskDocument(task *tasks.Task) Document {
+	text := task.Title
+	if len(task.Tags) > 0 {
+		text += "\n" + strings.Join(task.Tags, " ")
+	}
+	at := task.CreatedAt
+	return Document{Kind: KindTask, ID: task.ID, UserID: task.UserID, Text: text, At: &at}
+}
+
+func NoteDocument(note *notes.Note) Document {
+	text := note.Content
+	if len(note.Tags) > 0 {
+		text += "\n" + strings.Join(note.Tags, " ")
+	}
+	at := note.CreatedAt
+	return Document{Kind: KindNote, ID: note.ID, UserID: note.UserID, Title: note.Title, Text: text, At: &at}
+}
+
+// ProfileDocuments turns each entry of a profile into its own document.
+func ProfileDocuments(userID, profile string) []Document {
+	var docs []Document
+	for i, line := range strings.Split(profile, "\n") {
+		entry := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "•"))
+		if entry == "" || i < 2 {
+			// The first two lines are the "User Profile" header
+			continue
+		}
+		docs = append(docs, Document{Kind: KindProfile, ID: fmt.Sprintf("line-%d", i), UserID: userID, Text: entry})
+	}
+	return docs
+}
+
+type observedTasks struct {
+	tasks.TaskService
+	index *Index
+}
+
+func ObserveTasks(service tasks.TaskService, index *Index) tasks.TaskService {
+	users, err := service.Users()
+	if err != nil {
+		log.Printf("Search: Failed to list task users: %v", err)
+	}
+	count := 0
+	for _, userID := range users {
+		list, err := service.List(userID, tasks.Filter{})
+		if err != nil {
+			log.Printf("Search: Failed to index tasks for user %s: %v", userID, err)
+			continue
+		}
+		for _, task := range list {
+			index.Put(TaskDocument(&task))
+			count++
+		}
+	}
+	log.Printf("Search: Indexed %d tasks", count)
+	return &observedTasks{TaskService: service, index: index}
+}
+
+func (o *observedTasks) Create(userID string, task tasks.Task) (*tasks.Task, error) {
+	created, err := o.TaskService.Create(userID, task)
+	if err == nil {
+		o.index.Put(TaskDocument(created))
+	}
+	return created, err
+}
+
+func (o *observedTasks) Update(userID, id string, update tasks.Update) (*tasks.Task, error) {
+	updated, err := o.TaskService.Update(userID, id, update)
+	if err == nil {
+		o.index.Put(TaskDocument(updated))
+	}
+	return updated, err
+}
+
+func (o *observedTasks) Import(userID string, list []tasks.Task) (int, error) {
+	added, err := o.TaskService.Import(userID, list)
+	if err == nil && added > 0 {
+		o.reindex(userID)
+	}
+	return added, err
+}
+
+func (o *observedTasks) reindex(userID string) {
+	list, err := o.TaskService.List(userID, tasks.Filter{})
+	if err != nil {
+		log.Printf("Search: Failed to index tasks for user %s: %v", userID, err)
+		return
+	}
+	for _, task := range list {
+		o.index.Put(TaskDocument(&task))
+	}
+}
+
+func (o *observedTasks) Delete(userID, id string) error {
+	err := o.TaskService.Delete(userID, id)
+	if err == nil {
+		o.index.Remove(userID, KindTask, id)
+	}
+	return err
+}
+
+type observedNotes struct {
+	notes.NoteService
+	index *Index
+}
+
+func ObserveNotes(service notes.NoteService, index *Index) notes.NoteService {
+	users, err := service.Users()
+	if err != nil {
+		log.Printf("Search: Failed to list note users: %v", err)
+	}
+	count := 0
+	for _, userID := range users {
+		list, err := service.List(userID, notes.Filter{})
+		if err != nil {
+			log.Printf("Search: Failed to index notes for user %s: %v", userID, err)
+			continue
+		}
+		for _, note := range list {
+			index.Put(NoteDocument(&note))
+			count++
+		}
+	}
+	log.Printf("Search: Indexed %d notes", count)
+	return &observedNotes{NoteService: service, index: index}
+}
+
+func (o *observedNotes) Create(userID string, note notes.Note) (*notes.Note, error) {
+	created, err := o.NoteService.Create(userID, note)
+	if err == nil {
+		o.index.Put(NoteDocument(created))
+	}
+	return created, err
+}
+
+func (o *observedNotes) Import(userID string, list []notes.Note, collections []notes.Collection) (int, error) {
+	added, err := o.NoteService.Import(userID, list, collections)
+	if err == nil && added > 0 {
+		current, err := o.NoteService.List(userID, notes.Filter{})
+		if err != nil {
+			log.Printf("Search: Failed to index notes for user %s: %v", userID, err)
+			return added, nil
+		}
+		for _, note := range current {
+			o.index.Put(NoteDocument(&note))
+		}
+	}
+	return added, err
+}
+
+func (o *observedNotes) Delete(userID, id string) error {
+	err := o.NoteService.Delete(userID, id)
+	if err == nil {
+		o.index.Remove(userID, KindNote, id)
+	}
+	return err
+}
+
+type observedProfile struct {
+	profile.ProfileService
+	index *Index
+}
+
+func ObserveProfile(service profile.ProfileService, index *Index) profile.ProfileService {
+	o := &observedProfile{ProfileService: service, index: index}
+	users, err := service.Users()
+	if err != nil {
+		log.Printf("Search: Failed to list profile users: %v", err)
+	}
+	for _, userID := range users {
+		o.reindex(userID)
+	}
+	return o
+}
+
+func (o *observedProfile) ProcessInput(userID, input string) error {
+	if err := o.ProfileService.ProcessInput(userID, input); err != nil {
+		return err
+	}
+	o.reindex(userID)
+	return nil
+}
+
+func (o *observedProfile) Rewrite(userID, previous, content string, event profile.Event) (*profile.Event, error) {
+	recorded, err := o.ProfileService.Rewrite(userID, previous, content, event)
+	if err == nil {
+		o.reindex(userID)
+	}
+	return recorded, err
+}
+
+func (o *observedProfile) Import(userID, text string, history []profile.Event) (int, error) {
+	added, err := o.ProfileService.Import(userID, text, history)
+	if err == nil && added > 0 {
+		o.reindex(userID)
+	}
+	return added, err
+}
+
+func (o *observedProfile) reindex(userID string) {
+	current, err := o.ProfileService.Get(userID)
+	if err != nil {
+		log.Printf("Search: Failed to read profile for user %s: %v", userID, err)
+		return
+	}
+	o.index.ReplaceKind(userID, KindProfile, ProfileDocuments(userID, current))
+}
@@ -0,0 +1,417 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is the type of thing a document was indexed from.
+type Kind string
+
+const (
+	KindInput   Kind = "input"
+	KindNote    Kind = "note"
+	KindTask    Kind = "task"
+	KindProfile Kind = "profile"
+)
+
+// Kinds lists every searchable kind.
+var Kinds = []Kind{KindInput, KindNote, KindTask, KindProfile}
+
+func ParseKind(s string) (Kind, bool) {
+	for _, kind := range Kinds {
+		if string(kind) == strings.ToLower(strings.TrimSpace(s)) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// ToolName is the name the search tool registers under.
+const ToolName = "search"
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// snippetRadius is how many characters of context a snippet shows on
+	// each side of the first match.
+	snippetRadius = 80
+	// BM25 parameters
+	termSaturation = 1.2
+	lengthNorm     = 0.75
+	// phraseBoost multiplies the score of documents containing the query
+	// as typed.
+	phraseBoost = 1.5
+)
+
+var ErrInvalid = errors.New("invalid search query")
+
+// Document is one searchable piece of user data.
+type Document struct {
+	Kind   Kind
+	ID     string
+	UserID string
+	Title  string
+	Text   string
+	// At is when the underlying item was created, if it has such a time.
+	At *time.Time
+}
+
+type Query struct {
+	Text string
+	// Kinds restricts the search; empty means all kinds.
+	Kinds []Kind
+	Limit int
+}
+
+type Result struct {
+	Kind    Kind       `json:"kind"`
+	ID      string     `json:"id"`
+	Title   string     `json:"title,omitempty"`
+	Snippet string     `json:"snippet"`
+	At      *time.Time `json:"at,omitempty"`
+	Score   float64    `json:"score"`
+}
+
+// Index is an in-memory inverted index per user. It is rebuilt from the
+// domain services at startup and kept current by the Observe* wrappers, so
+// it needs no storage of its own.
+type Index struct {
+	users map[string]*userIndex
+	mutex sync.RWMutex
+}
+
+type userIndex struct {
+	docs map[string]*indexedDoc
+	// postings maps a term to the documents containing it and how often.
+	postings    map[string]map[string]int
+	totalLength int
+}
+
+type indexedDoc struct {
+	Document
+	terms  map[string]int
+	length int
+}
+
+func NewIndex() *Index {
+	return &Index{users: make(map[string]*userIndex)}
+}
+
+func docKey(kind Kind, id string) string {
+	return string(kind) + ":" + id
+}
+
+// Put adds a document, replacing any earlier version of it.
+func (x *Index) Put(doc Document) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.put(doc)
+}
+
+func (x *Index) put(doc Document) {
+	user := x.users[doc.UserID]
+	if user == nil {
+		user = &userIndex{docs: make(map[string]*indexedDoc), postings: make(map[string]map[string]int)}
+		x.users[doc.UserID] = user
+	}
+	key := docKey(doc.Kind, doc.ID)
+	user.remove(key)
+
+	terms := make(map[string]int)
+	length := 0
+	// Title words count twice
+	for _, term := range append(Terms(doc.Title), Terms(doc.Title+" "+doc.Text)...) {
+		terms[term]++
+		length++
+	}
+	if length == 0 {
+		return
+	}
+	user.docs[key] = &indexedDoc{Document: doc, terms: terms, length: length}
+	user.totalLength += length
+	for term, count := range terms {
+		if user.postings[term] == nil {
+			user.postings[term] = make(map[string]int)
+		}
+		user.postings[term][key] = count
+	}
+}
+
+// Remove drops a document, if indexed.
+func (x *Index) Remove(userID string, kind Kind, id string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if user := x.users[userID]; user != nil {
+		user.remove(docKey(kind, id))
+	}
+}
+
+// ReplaceKind swaps all of a user's documents of one kind for docs.
+func (x *Index) ReplaceKind(userID string, kind Kind, docs []Document) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if user := x.users[userID]; user != nil {
+		for key, doc := range user.docs {
+			if doc.Kind == kind {
+				user.remove(key)
+			}
+		}
+	}
+	for _, doc := range docs {
+		x.put(doc)
+	}
+}
+
+// Purge drops everything indexed for a user, returning how many documents
+// there were.
+func (x *Index) Purge(userID string) (int, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	count := 0
+	if user, exists := x.users[userID]; exists {
+		count = len(user.docs)
+	}
+	delete(x.users, userID)
+	return count, nil
+}
+
+// Count returns how many documents are indexed for a user.
+func (x *Index) Count(userID string) (int, error) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	if user, exists := x.users[userID]; exists {
+		return len(user.docs), nil
+	}
+	return 0, nil
+}
+
+func (u *userIndex) remove(key string) {
+	doc, exists := u.docs[key]
+	if !exists {
+		return
+	}
+	for term := range doc.terms {
+		delete(u.postings[term], key)
+		if len(u.postings[term]) == 0 {
+			delete(u.postings, term)
+		}
+	}
+	u.totalLength -= doc.length
+	delete(u.docs, key)
+}
+
+// Search ranks the user's documents against the query with BM25.
+func (x *Index) Search(userID string, query Query) ([]Result, error) {
+	terms := queryTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query has no searchable words", ErrInvalid)
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalid, MaxLimit)
+	}
+	kinds := make(map[Kind]bool)
+	for _, kind := range query.Kinds {
+		kinds[kind] = true
+	}
+	phrase := strings.ToLower(strings.TrimSpace(query.Text))
+
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	user := x.users[userID]
+	results := make([]Result, 0)
+	if user == nil || len(user.docs) == 0 {
+		return results, nil
+	}
+
+	total := float64(len(user.docs))
+	avgLength := float64(user.totalLength) / total
+	scores := make(map[string]float64)
+	for _, term := range terms {
+		postings := user.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+		for key, count := range postings {
+			doc := user.docs[key]
+			if len(kinds) > 0 && !kinds[doc.Kind] {
+				continue
+			}
+			tf := float64(count)
+			norm := termSaturation * (1 - lengthNorm + lengthNorm*float64(doc.length)/avgLength)
+			scores[key] += idf * tf * (termSaturation + 1) / (tf + norm)
+		}
+	}
+
+	for key, score := range scores {
+		doc := user.docs[key]
+		if len(terms) > 1 && strings.Contains(strings.ToLower(doc.Title+" "+doc.Text), phrase) {
+			score *= phraseBoost
+		}
+		results = append(results, Result{
+			Kind:    doc.Kind,
+			ID:      doc.ID,
+			Title:   doc.Title,
+			Snippet: snippet(doc.Text, terms),
+			At:      doc.At,
+			Score:   math.Round(score*1000) / 1000,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return docKey(results[i].Kind, results[i].ID) < docKey(results[j].Kind, results[j].ID)
+	})
+	if len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
+
+// stopWords are function words, too common to be worth indexing. Content
+// words such as "idea" or "today" stay searchable.
+var stopWords = map[string]bool{
+	// articles and conjunctions
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "if": true, "so": true,
+	"than": true, "then": true,
+	// pronouns
+	"i": true, "me": true, "my": true, "mine": true, "we": true, "us": true, "our": true,
+	"you": true, "your": true, "he": true, "him": true, "his": true, "she": true, "her": true,
+	"it": true, "its": true, "they": true, "them": true, "their": true, "this": true, "that": true,
+	"these": true, "those": true,
+	// prepositions
+	"at": true, "by": true, "for": true, "from": true, "in": true, "into": true, "of": true,
+	"on": true, "to": true, "with": true,
+	// auxiliaries
+	"am": true, "is": true, "are": true, "was": true, "were": true, "be": true, "been": true,
+	"being": true, "do": true, "has": true, "have": true, "had": true, "will": true, "would": true,
+	"can": true, "could": true,
+}
+
+// questionWords are dropped from queries only, so "when did I mention
+// plant care?" searches for "plant care".
+var questionWords = map[string]bool{
+	"what": true, "when": true, "where": true, "which": true, "who": true, "why": true, "how": true,
+	"did": true, "does": true, "ever": true, "about": true, "mention": true, "mentioned": true,
+	"say": true, "said": true, "talk": true, "talked": true, "write": true, "wrote": true,
+	"search": true, "find": true, "anything": true, "something": true, "last": true, "time": true,
+}
+
+func queryTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words(text) {
+		if questionWords[word] || stopWords[word] {
+			continue
+		}
+		term := stem(word)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Terms splits text into the lowercased, stemmed words the index uses,
+// without stop words.
+func Terms(text string) []string {
+	var terms []string
+	for _, word := range words(text) {
+		if !stopWords[word] {
+			terms = append(terms, stem(word))
+		}
+	}
+	return terms
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// stem strips common English suffixes so "plants" finds "plant" and
+// "watering" finds "watered". It is deliberately crude.
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		return word[:len(word)-3]
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// snippet cuts the text around the first word matching a query term.
+func snippet(text string, terms []string) string {
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	lower := strings.ToLower(text)
+	match := -1
+	start := -1
+	for i, r := range lower {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start == -1 {
+			start = i
+		}
+		if !isWord && start != -1 {
+			if wanted[stem(lower[start:i])] {
+				match = start
+				break
+			}
+			start = -1
+		}
+	}
+	if match == -1 && start != -1 && wanted[stem(lower[start:])] {
+		match = start
+	}
+	if match == -1 {
+		match = 0
+	}
+	if len(lower) != len(text) {
+		// Lowercasing changed byte offsets; fall back to the beginning
+		match = 0
+	}
+
+	from := max(0, match-snippetRadius)
+	to := min(len(text), match+snippetRadius)
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	result := strings.Join(strings.Fields(text[from:to]), " ")
+	if from > 0 {
+		result = "…" + result
+	}
+	if to < len(text) {
+		result += "…"
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func newIndex(docs ...Document) *Index {
+	index := NewIndex()
+	for _, doc := range docs {
+		if doc.UserID == "" {
+			doc.UserID = "alice"
+		}
+		index.Put(doc)
+	}
+	return index
+}
+
+func ids(results []Result) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	index := newIndex(
+		Document{Kind: KindInput, ID: "idea", Text: "Had this cool app idea today: a journal that talks back"},
+		Document{Kind: KindNote, ID: "plants", Title: "Plant care", Text: "Watering the fern twice a week"},
+		Document{Kind: KindTask, ID: "water", Title: "Water the plants"},
+		Document{Kind: KindInput, ID: "phrase", Text: "Morning run by the river"},
+		Document{Kind: KindInput, ID: "scrambled", Text: "Run in the morning by the river"},
+		Document{Kind: KindInput, ID: "bob", UserID: "bob", Text: "My cool idea"},
+	)
+
+	tests := []struct {
+		name    string
+		query   Query
+		want    []string
+		wantErr error
+	}{
+		{"content words", Query{Text: "cool idea"}, []string{"idea"}, nil},
+		{"question words dropped", Query{Text: "when did I mention my app idea?"}, []string{"idea"}, nil},
+		{"singular finds plural", Query{Text: "plant"}, []string{"water", "plants"}, nil},
+		{"stems match across forms", Query{Text: "watered"}, []string{"water", "plants"}, nil},
+		{"kind filter", Query{Text: "plants", Kinds: []Kind{KindTask}}, []string{"water"}, nil},
+		{"exact phrase ranks first", Query{Text: "morning run"}, []string{"phrase", "scrambled"}, nil},
+		{"limit", Query{Text: "river", Limit: 1}, []string{"phrase"}, nil},
+		{"no match", Query{Text: "taxes"}, nil, nil},
+		{"only function words", Query{Text: "what was it"}, nil, ErrInvalid},
+		{"limit too large", Query{Text: "idea", Limit: MaxLimit + 1}, nil, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := index.Search("alice", tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Search = %v, want %v", err, tt.wantErr)
+			}
+			if got := ids(results); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("Search = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchScoring(t *testing.T) {
+	index := newIndex(
+		Document{Kind: KindInput, ID: "phrase", Text: "Morning run by the river"},
+		Document{Kind: KindInput, ID: "scrambled", Text: "Run in the morning by the river"},
+		Document{Kind: KindInput, ID: "short", Text: "Budget review"},
+		Document{Kind: KindInput, ID: "long", Text: "Budget review after a long meeting about hiring, offices and travel"},
+		Document{Kind: KindInput, ID: "twice", Text: "Budget again, the budget never balances"},
+	)
+
+	results, err := index.Search("alice", Query{Text: "morning run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ratio := results[0].Score / results[1].Score; ratio < phraseBoost-0.01 || ratio > phraseBoost+0.01 {
+		t.Fatalf("phrase scores %v, want a ratio of %v", results, phraseBoost)
+	}
+
+	results, err = index.Search("alice", Query{Text: "budget"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Repeats raise the score and longer documents dilute it
+	if got := strings.Join(ids(results), ","); got != "twice,short,long" {
+		t.Fatalf("Search = %s, want twice,short,long", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	// Three-byte runes put the snippet bounds in the middle of a rune
+	padding := strings.Repeat("€", 100)
+	tests := []struct {
+		name      string
+		text      string
+		terms     []string
+		want      string
+		wantStart bool
+		wantEnd   bool
+	}{
+		{"short text", "Called mom about the trip", []string{"trip"}, "Called mom about the trip", false, false},
+		{"around the match", padding + " target " + padding, []string{"target"}, "target", true, true},
+		{"stemmed match", "We kept watering it", []string{"water"}, "We kept watering it", false, false},
+		{"no match starts at the beginning", "target " + padding + padding, []string{"missing"}, "target", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snippet(tt.text, tt.terms)
+			if !utf8.ValidString(got) {
+				t.Fatalf("snippet %q is not valid UTF-8", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("snippet %q does not contain %q", got, tt.want)
+			}
+			if strings.HasPrefix(got, "…") != tt.wantStart || strings.HasSuffix(got, "…") != tt.wantEnd {
+				t.Fatalf("snippet %q, want cut at start %v and end %v", got, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestReplaceKind(t *testing.T) {
+	index := newIndex(
+		Document{Kind: KindNote, ID: "n1", Text: "Old garden plan"},
+		Document{Kind: KindNote, ID: "n2", Text: "Old garden budget"},
+		Document{Kind: KindTask, ID: "t1", Text: "Buy garden gloves"},
+		Document{Kind: KindNote, ID: "n1", UserID: "bob", Text: "Bob's garden"},
+	)
+	index.ReplaceKind("alice", KindNote, []Document{{Kind: KindNote, ID: "n3", UserID: "alice", Text: "New garden layout"}})
+
+	results, err := index.Search("alice", Query{Text: "garden"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ids(results), ","); got != "n3,t1" && got != "t1,n3" {
+		t.Fatalf("Search after ReplaceKind = %s, want n3 and t1", got)
+	}
+	if results, _ := index.Search("alice", Query{Text: "old"}); len(results) != 0 {
+		t.Fatalf("replaced notes still found: %v", ids(results))
+	}
+	if count, _ := index.Count("bob"); count != 1 {
+		t.Fatalf("bob has %d documents, want 1", count)
+	}
+}
@@ -6,9 +6,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/kirillsobolev/soul-mirror/backend/internal/api"
-	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
-	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
-	"github.com/kirillsobolev/soul-mirror/backend/internal/tools"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/ratelimit"
 )
 
 type Server struct {
@@ -16,10 +14,13 @@ type Server struct {
 	port     string
 	logger   *slog.Logger
 	router   *gin.Engine
+	// llmLimit throttles the endpoints that make LLM calls; nil when rate
+	// limiting is off
+	llmLimit gin.HandlerFunc
 }
 
-func New(orch orchestrator.Orchestrator, profileService profile.ProfileService, toolService tools.ToolService, logger *slog.Logger, environment, port string) *Server {
-	handlers := api.NewHandlers(orch, profileService, toolService, logger, environment)
+func New(deps api.Dependencies, logger *slog.Logger, environment, port string) *Server {
+	handlers := api.NewHandlers(deps, logger, environment)
 	
 	// Set Gin mode based on environment
 	if environment == "production" {
@@ -36,7 +37,8 @@ func New(orch orchestrator.Orchestrator, profileService profile.ProfileService,
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-API-Key", "X-User-ID", "accept", "origin", "Cache-Control", "X-Requested-With"}
+	config.ExposeHeaders = append([]string{api.SessionHeader, "Location"}, ratelimit.Headers...)
 	router.Use(cors.New(config))
 	
 	return &Server{
@@ -47,12 +49,38 @@ func New(orch orchestrator.Orchestrator, profileService profile.ProfileService,
 	}
 }
 
+// TrustProxies takes the client IP from X-Forwarded-For and X-Real-IP only
+// on requests coming from the given addresses or CIDR ranges. With none,
+// the headers are ignored, so clients can't pick their own IP and escape
+// the rate limits. Call it before Start.
+func (s *Server) TrustProxies(proxies []string) error {
+	return s.router.SetTrustedProxies(proxies)
+}
+
+// UseRateLimits throttles every request with general, and the endpoints
+// that make LLM calls with llm as well. Call it before Start.
+func (s *Server) UseRateLimits(general, llm *ratelimit.Limiter) {
+	// Health checks are exempt, and Telegram updates all come from
+	// Telegram's servers, so limiting them by IP would throttle every user
+	s.router.Use(ratelimit.Middleware(general, ratelimit.Key, "/health", "/api/telegram/webhook"))
+	s.llmLimit = ratelimit.Middleware(llm, ratelimit.Key)
+}
+
+// llm puts the LLM rate limit in front of handler when there is one.
+func (s *Server) llm(handler gin.HandlerFunc) []gin.HandlerFunc {
+	if s.llmLimit == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{s.llmLimit, handler}
+}
+
 func (s *Server) setupRoutes() {
 	// Health endpoint
 	s.router.GET("/health", s.handlers.HealthHandler)
 	
 	// Main endpoints
-	s.router.GET("/process", s.handlers.ProcessHandler)
+	s.router.GET("/process", s.llm(s.handlers.ProcessHandler)...)
+	s.router.POST("/process", s.llm(s.handlers.ProcessAsyncHandler)...)
 	s.router.GET("/profile", s.handlers.ProfileHandler)
 	
 	// API endpoints
@@ -60,6 +88,109 @@ func (s *Server) setupRoutes() {
 	{
 		api.GET("/tools", s.handlers.ToolsHandler)
 		api.GET("/status", s.handlers.StatusHandler)
+		api.GET("/profile", s.handlers.StructuredProfileHandler)
+		api.GET("/profile/history", s.handlers.ProfileHistoryHandler)
+		api.POST("/profile/compact", s.llm(s.handlers.CompactProfileHandler)...)
+
+		api.GET("/tasks", s.handlers.ListTasksHandler)
+		api.POST("/tasks", s.handlers.CreateTaskHandler)
+		api.GET("/tasks/:id", s.handlers.GetTaskHandler)
+		api.PUT("/tasks/:id", s.handlers.UpdateTaskHandler)
+		api.POST("/tasks/:id/complete", s.handlers.CompleteTaskHandler)
+		api.DELETE("/tasks/:id", s.handlers.DeleteTaskHandler)
+
+		api.GET("/notes", s.handlers.ListNotesHandler)
+		api.POST("/notes", s.llm(s.handlers.CreateNoteHandler)...)
+		api.GET("/notes/collections", s.handlers.ListCollectionsHandler)
+		api.GET("/notes/collections/:id", s.handlers.GetCollectionHandler)
+		api.GET("/notes/:id", s.handlers.GetNoteHandler)
+		api.POST("/notes/:id/links", s.handlers.LinkNoteHandler)
+		api.DELETE("/notes/:id", s.handlers.DeleteNoteHandler)
+
+		api.GET("/goals", s.handlers.ListGoalsHandler)
+		api.POST("/goals", s.handlers.CreateGoalHandler)
+		api.GET("/goals/progress", s.handlers.GoalsProgressHandler)
+		api.GET("/goals/:id", s.handlers.GetGoalHandler)
+		api.PUT("/goals/:id", s.handlers.UpdateGoalHandler)
+		api.DELETE("/goals/:id", s.handlers.DeleteGoalHandler)
+		api.GET("/goals/:id/progress", s.handlers.GoalProgressHandler)
+		api.POST("/goals/:id/milestones", s.handlers.AddMilestoneHandler)
+		api.POST("/goals/:id/milestones/:milestoneId/complete", s.handlers.CompleteMilestoneHandler)
+		api.POST("/goals/:id/habits", s.handlers.AddHabitHandler)
+		api.POST("/goals/:id/checkins", s.handlers.CheckInHandler)
+
+		api.GET("/mood", s.handlers.MoodTimelineHandler)
+		api.POST("/mood", s.llm(s.handlers.JournalHandler)...)
+		api.GET("/mood/aggregates", s.handlers.MoodAggregatesHandler)
+		api.GET("/mood/trend", s.handlers.MoodTrendHandler)
+
+		api.GET("/reminders", s.handlers.ListRemindersHandler)
+		api.POST("/reminders", s.llm(s.handlers.CreateReminderHandler)...)
+		api.GET("/reminders/timezone", s.handlers.GetTimezoneHandler)
+		api.PUT("/reminders/timezone", s.handlers.SetTimezoneHandler)
+		api.GET("/reminders/:id", s.handlers.GetReminderHandler)
+		api.DELETE("/reminders/:id", s.handlers.CancelReminderHandler)
+
+		api.GET("/calendar/events", s.handlers.UpcomingEventsHandler)
+		api.GET("/calendar/sources", s.handlers.ListCalendarSourcesHandler)
+		api.POST("/calendar/import", s.handlers.ImportCalendarHandler)
+		api.POST("/calendar/subscriptions", s.handlers.SubscribeCalendarHandler)
+		api.POST("/calendar/sources/:id/refresh", s.handlers.RefreshCalendarSourceHandler)
+		api.DELETE("/calendar/sources/:id", s.handlers.DeleteCalendarSourceHandler)
+
+		api.POST("/voice", s.llm(s.handlers.ProcessVoiceHandler)...)
+		api.GET("/voice-notes", s.handlers.ListVoiceNotesHandler)
+		api.GET("/voice-notes/:id", s.handlers.GetVoiceNoteHandler)
+		api.GET("/voice-notes/:id/audio", s.handlers.VoiceNoteAudioHandler)
+		api.DELETE("/voice-notes/:id", s.handlers.DeleteVoiceNoteHandler)
+
+		api.POST("/inbound", s.llm(s.handlers.InboundWebhookHandler)...)
+
+		api.GET("/webhooks", s.handlers.ListWebhooksHandler)
+		api.POST("/webhooks", s.handlers.RegisterWebhookHandler)
+		api.GET("/webhooks/:id", s.handlers.GetWebhookHandler)
+		api.PUT("/webhooks/:id", s.handlers.UpdateWebhookHandler)
+		api.DELETE("/webhooks/:id", s.handlers.DeleteWebhookHandler)
+		api.POST("/webhooks/:id/ping", s.handlers.PingWebhookHandler)
+		api.GET("/webhooks/:id/deliveries", s.handlers.WebhookDeliveriesHandler)
+
+		api.GET("/inputs", s.handlers.ListInputsHandler)
+		api.GET("/inputs/:id", s.handlers.GetInputHandler)
+		api.DELETE("/inputs/:id", s.handlers.DeleteInputHandler)
+
+		api.GET("/search", s.handlers.SearchHandler)
+
+		api.GET("/export", s.handlers.ExportHandler)
+		api.POST("/import", s.handlers.ImportHandler)
+
+		api.DELETE("/account", s.handlers.DeleteAccountHandler)
+		api.GET("/account/deletions", s.handlers.ListDeletionsHandler)
+		api.GET("/account/verify", s.handlers.VerifyDeletionHandler)
+
+		api.GET("/encryption", s.handlers.EncryptionStatusHandler)
+		api.POST("/encryption/rotate", s.handlers.RotateDataKeyHandler)
+
+		api.GET("/jobs", s.handlers.ListJobsHandler)
+		api.GET("/jobs/:id", s.handlers.GetJobHandler)
+
+		api.GET("/sessions", s.handlers.ListSessionsHandler)
+		api.POST("/sessions", s.handlers.CreateSessionHandler)
+		api.GET("/sessions/:id", s.handlers.GetSessionHandler)
+		api.DELETE("/sessions/:id", s.handlers.DeleteSessionHandler)
+
+		api.GET("/email/settings", s.handlers.GetEmailSettingsHandler)
+		api.PUT("/email/settings", s.handlers.UpdateEmailSettingsHandler)
+		api.GET("/email/digest", s.handlers.PreviewDigestHandler)
+		api.POST("/email/digest", s.handlers.SendDigestHandler)
+
+		api.POST("/telegram/webhook", s.handlers.TelegramWebhookHandler)
+		api.POST("/telegram/link-code", s.handlers.TelegramLinkCodeHandler)
+		api.GET("/telegram/chats", s.handlers.ListTelegramChatsHandler)
+		api.DELETE("/telegram/chats/:chatId", s.handlers.UnlinkTelegramChatHandler)
+
+		api.GET("/nudges", s.handlers.ListNudgesHandler)
+		api.POST("/nudges/evaluate", s.llm(s.handlers.EvaluateNudgesHandler)...)
+		api.POST("/nudges/:id/dismiss", s.handlers.DismissNudgeHandler)
 	}
 }
 
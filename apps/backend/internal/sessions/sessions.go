@@ -0,0 +1,319 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "sessions"
+
+const (
+	// MaxStoredTurns caps the turns kept per session; older ones are
+	// dropped first.
+	MaxStoredTurns = 200
+	// maxTitleLength caps the title derived from the first message.
+	maxTitleLength = 60
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrInvalid  = errors.New("invalid session")
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Turn is one message in a conversation.
+type Turn struct {
+	Role    Role      `json:"role"`
+	Content string    `json:"content"`
+	Source  string    `json:"source,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Session is a conversation thread. Inputs sent with the same session ID
+// see the turns before them.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	TurnCount int       `json:"turn_count"`
+	Turns     []Turn    `json:"turns,omitempty"`
+}
+
+type SessionService interface {
+	Create(userID, title string) (*Session, error)
+	// Get returns a session with all of its stored turns.
+	Get(userID, id string) (*Session, error)
+	// List returns the user's sessions, most recently active first, without
+	// their turns.
+	List(userID string) ([]Session, error)
+	// Append adds turns to a session, naming it after the first user turn
+	// if it has no title yet.
+	Append(userID, id string, turns ...Turn) (*Session, error)
+	// Recent returns up to limit of the latest turns, oldest first, while
+	// their combined content stays within maxChars.
+	Recent(userID, id string, limit, maxChars int) ([]Turn, error)
+	Delete(userID, id string) error
+	// Import adds sessions from an archive, keeping their IDs and turns.
+	// Sessions the user already has are skipped; it returns how many were
+	// added.
+	Import(userID string, sessions []Session) (int, error)
+	// Purge deletes all of the user's sessions and their turns, returning
+	// how many sessions there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type service struct {
+	store    storage.Store
+	sessions map[string][]*Session
+	mutex    sync.RWMutex
+}
+
+func NewService(store storage.Store) (SessionService, error) {
+	s := &service{
+		store:    store,
+		sessions: make(map[string][]*Session),
+	}
+	if err := store.Load(storeName, &s.sessions); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load sessions: %w", err)
+	}
+	log.Printf("SessionService: Loaded sessions for %d users", len(s.sessions))
+	return s, nil
+}
+
+func (s *service) persist() error {
+	return s.store.Save(storeName, s.sessions)
+}
+
+func (s *service) find(userID, id string) *Session {
+	for _, session := range s.sessions[userID] {
+		if session.ID == id {
+			return session
+		}
+	}
+	return nil
+}
+
+func (s *service) Create(userID, title string) (*Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	session := &Session{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		Title:     truncate(strings.TrimSpace(title), maxTitleLength),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.sessions[userID] = append(s.sessions[userID], session)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("SessionService: Created session %s for user %s", session.ID, userID)
+	return session.copy(true), nil
+}
+
+func (s *service) Get(userID, id string) (*Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	session := s.find(userID, id)
+	if session == nil {
+		return nil, ErrNotFound
+	}
+	return session.copy(true), nil
+}
+
+func (s *service) List(userID string) ([]Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Session, 0, len(s.sessions[userID]))
+	for _, session := range s.sessions[userID] {
+		result = append(result, *session.copy(false))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].UpdatedAt.After(result[j].UpdatedAt)
+	})
+	return result, nil
+}
+
+func (s *service) Append(userID, id string, turns ...Turn) (*Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session := s.find(userID, id)
+	if session == nil {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	for _, turn := range turns {
+		turn.Content = strings.TrimSpace(turn.Content)
+		if turn.Content == "" {
+			continue
+		}
+		if turn.Role != RoleUser && turn.Role != RoleAssistant {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalid, turn.Role)
+		}
+		if turn.At.IsZero() {
+			turn.At = now
+		}
+		if session.Title == "" && turn.Role == RoleUser {
+			session.Title = truncate(turn.Content, maxTitleLength)
+		}
+		session.Turns = append(session.Turns, turn)
+		session.TurnCount++
+	}
+	if len(session.Turns) > MaxStoredTurns {
+		session.Turns = append([]Turn(nil), session.Turns[len(session.Turns)-MaxStoredTurns:]...)
+	}
+	session.UpdatedAt = now
+
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	return session.copy(false), nil
+}
+
+func (s *service) Recent(userID, id string, limit, maxChars int) ([]Turn, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	session := s.find(userID, id)
+	if session == nil {
+		return nil, ErrNotFound
+	}
+
+	start := len(session.Turns)
+	chars := 0
+	for start > 0 && len(session.Turns)-start < limit {
+		chars += len(session.Turns[start-1].Content)
+		if maxChars > 0 && chars > maxChars && start < len(session.Turns) {
+			break
+		}
+		start--
+	}
+	return append([]Turn(nil), session.Turns[start:]...), nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := s.sessions[userID]
+	for i, session := range list {
+		if session.ID == id {
+			s.sessions[userID] = append(list[:i], list[i+1:]...)
+			if len(s.sessions[userID]) == 0 {
+				delete(s.sessions, userID)
+			}
+			if err := s.persist(); err != nil {
+				return err
+			}
+			log.Printf("SessionService: Deleted session %s for user %s", id, userID)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *service) Import(userID string, list []Session) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := 0
+	for _, session := range list {
+		if session.ID == "" || s.find(userID, session.ID) != nil {
+			continue
+		}
+		imported := session.copy(true)
+		imported.UserID = userID
+		if len(imported.Turns) > MaxStoredTurns {
+			imported.Turns = imported.Turns[len(imported.Turns)-MaxStoredTurns:]
+		}
+		if imported.TurnCount < len(imported.Turns) {
+			imported.TurnCount = len(imported.Turns)
+		}
+		s.sessions[userID] = append(s.sessions[userID], imported)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("SessionService: Imported %d sessions for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.sessions[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.sessions, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("SessionService: Purged %d sessions for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.sessions[userID]), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.sessions))
+	for userID := range s.sessions {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (session *Session) copy(withTurns bool) *Session {
+	result := *session
+	result.Turns = nil
+	if withTurns {
+		result.Turns = append([]Turn{}, session.Turns...)
+	}
+	return &result
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
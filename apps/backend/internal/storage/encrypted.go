@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// envelopeFormat versions the layout of encrypted documents.
+const envelopeFormat = 1
+
+// envelope is how an encrypted document is stored: each user's part of it
+// sealed with their own data key.
+type envelope struct {
+	Encrypted int                    `json:"encrypted"`
+	Users     map[string]sealedValue `json:"users"`
+}
+
+type sealedValue struct {
+	// Key is the version of the user's data key it is sealed with.
+	Key  int    `json:"key"`
+	Data []byte `json:"data"`
+}
+
+// EncryptedStore seals the named documents with per-user data keys before
+// they reach the wrapped store, and opens them again on load. The
+// documents must be JSON objects keyed by user ID, which is how the domain
+// services keep their state. Other documents pass through unchanged.
+//
+// Documents written before encryption was enabled load as they are and are
+// encrypted the next time they are saved.
+type EncryptedStore struct {
+	inner   Store
+	keyring *Keyring
+	names   map[string]bool
+	owners  []BlobOwner
+	// mutex keeps rotation from interleaving with saves
+	mutex sync.Mutex
+	// rotating serializes rotations, which reseal blobs without holding
+	// mutex
+	rotating sync.Mutex
+}
+
+// BlobSealer encrypts data kept outside the store, such as files, with
+// the owning user's data key.
+type BlobSealer interface {
+	SealBlob(userID, name string, plain []byte) ([]byte, error)
+	// OpenBlob decrypts what SealBlob returned. Data that was never sealed
+	// is returned as it is.
+	OpenBlob(userID, name string, data []byte) ([]byte, error)
+}
+
+// BlobOwner keeps blobs sealed with a BlobSealer. Reseal seals the user's
+// blobs again with their current data key; it is called when the key is
+// rotated, before the older versions are destroyed.
+type BlobOwner interface {
+	Reseal(userID string) error
+}
+
+func NewEncryptedStore(inner Store, keyring *Keyring, names ...string) *EncryptedStore {
+	s := &EncryptedStore{inner: inner, keyring: keyring, names: make(map[string]bool)}
+	for _, name := range names {
+		s.names[name] = true
+	}
+	return s
+}
+
+// Documents lists the names of the documents that are encrypted.
+func (s *EncryptedStore) Documents() []string {
+	names := make([]string, 0, len(s.names))
+	for name := range s.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *EncryptedStore) Keyring() *Keyring {
+	return s.keyring
+}
+
+// AddBlobOwner registers a holder of sealed blobs to reseal on rotation.
+func (s *EncryptedStore) AddBlobOwner(owner BlobOwner) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.owners = append(s.owners, owner)
+}
+
+func sealContext(name, userID string) []byte {
+	return []byte(name + "\x00" + userID)
+}
+
+func (s *EncryptedStore) Load(name string, v any) error {
+	if !s.names[name] {
+		return s.inner.Load(name, v)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	users, encrypted, err := s.load(name)
+	var unkeyed *unkeyedError
+	if errors.As(err, &unkeyed) {
+		// Written in a layout that predates keying by user; the service
+		// converts it and it is encrypted on the next save
+		log.Printf("EncryptedStore: Document %s is not keyed by user yet, loading it as it is", name)
+		if err := json.Unmarshal(unkeyed.data, v); err != nil {
+			return fmt.Errorf("decode %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		log.Printf("EncryptedStore: Document %s is not encrypted yet, it will be on the next save", name)
+	}
+	if users == nil {
+		users = make(map[string]json.RawMessage)
+	}
+	data, err := json.Marshal(users)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// unkeyedError is returned by load for a document that is not a JSON
+// object, such as a list saved before encryption was enabled.
+type unkeyedError struct {
+	name string
+	data json.RawMessage
+}
+
+func (e *unkeyedError) Error() string {
+	return fmt.Sprintf("document %s is not keyed by user", e.name)
+}
+
+// load reads a document and opens each user's part of it. It reports
+// whether the document was encrypted. Callers hold the mutex.
+func (s *EncryptedStore) load(name string) (map[string]json.RawMessage, bool, error) {
+	var data json.RawMessage
+	if err := s.inner.Load(name, &data); err != nil {
+		return nil, false, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, &unkeyedError{name: name, data: data}
+	}
+	if !isEnvelope(raw) {
+		return raw, false, nil
+	}
+	var sealed map[string]sealedValue
+	if err := json.Unmarshal(raw["users"], &sealed); err != nil {
+		return nil, false, fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	users := make(map[string]json.RawMessage, len(sealed))
+	for userID, value := range sealed {
+		key, err := s.keyring.key(userID, value.Key)
+		if errors.Is(err, ErrKeyNotFound) {
+			// The key was destroyed with the account; what it sealed is
+			// unreadable and dropped on the next save
+			log.Printf("EncryptedStore: Skipping data of user %s in %s: %v", userID, name, err)
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, false, err
+		}
+		plain, err := open(aead, value.Data, sealContext(name, userID))
+		if err != nil {
+			return nil, false, fmt.Errorf("decrypt %s for user %s: %w", name, userID, err)
+		}
+		users[userID] = plain
+	}
+	return users, true, nil
+}
+
+// isEnvelope tells an encrypted document from a plaintext one keyed by
+// user ID.
+func isEnvelope(raw map[string]json.RawMessage) bool {
+	if len(raw) != 2 || raw["users"] == nil {
+		return false
+	}
+	var format int
+	return json.Unmarshal(raw["encrypted"], &format) == nil && format == envelopeFormat
+}
+
+func (s *EncryptedStore) Save(name string, v any) error {
+	if !s.names[name] {
+		return s.inner.Save(name, v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	var users map[string]json.RawMessage
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("encrypt %s: document is not keyed by user: %w", name, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.save(name, users)
+}
+
+// save seals each user's part with their current data key. Callers hold
+// the mutex.
+func (s *EncryptedStore) save(name string, users map[string]json.RawMessage) error {
+	sealed := envelope{Encrypted: envelopeFormat, Users: make(map[string]sealedValue, len(users))}
+	for userID, plain := range users {
+		version, key, err := s.keyring.current(userID)
+		if err != nil {
+			return fmt.Errorf("encrypt %s for user %s: %w", name, userID, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return err
+		}
+		data, err := seal(aead, plain, sealContext(name, userID))
+		if err != nil {
+			return fmt.Errorf("encrypt %s for user %s: %w", name, userID, err)
+		}
+		sealed.Users[userID] = sealedValue{Key: version, Data: data}
+	}
+	return s.inner.Save(name, sealed)
+}
+
+// blobMagic starts every sealed blob, followed by the version of the data
+// key as a big-endian uint32 and the ciphertext.
+var blobMagic = []byte("SMENC\x01")
+
+// IsSealedBlob reports whether data was produced by SealBlob.
+func IsSealedBlob(data []byte) bool {
+	return bytes.HasPrefix(data, blobMagic)
+}
+
+func (s *EncryptedStore) SealBlob(userID, name string, plain []byte) ([]byte, error) {
+	version, key, err := s.keyring.current(userID)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt %s for user %s: %w", name, userID, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := binary.BigEndian.AppendUint32(append([]byte(nil), blobMagic...), uint32(version))
+	sealed, err := seal(aead, plain, sealContext(name, userID))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt %s for user %s: %w", name, userID, err)
+	}
+	return append(header, sealed...), nil
+}
+
+func (s *EncryptedStore) OpenBlob(userID, name string, data []byte) ([]byte, error) {
+	if !IsSealedBlob(data) {
+		return data, nil
+	}
+	data = data[len(blobMagic):]
+	if len(data) < 4 {
+		return nil, fmt.Errorf("decrypt %s for user %s: blob too short", name, userID)
+	}
+	key, err := s.keyring.key(userID, int(binary.BigEndian.Uint32(data)))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := open(aead, data[4:], sealContext(name, userID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s for user %s: %w", name, userID, err)
+	}
+	return plain, nil
+}
+
+func (s *EncryptedStore) Delete(name string) error {
+	return s.inner.Delete(name)
+}
+
+// Rotate gives the user a new data key, re-encrypts their part of every
+// encrypted document and their blobs with it and destroys the old
+// versions.
+func (s *EncryptedStore) Rotate(userID string) (*DataKey, error) {
+	s.rotating.Lock()
+	defer s.rotating.Unlock()
+
+	dataKey, owners, err := s.rotateDocuments(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Owners save documents of their own while they hold their locks, so
+	// they are called without holding mutex
+	for _, owner := range owners {
+		if err := owner.Reseal(userID); err != nil {
+			return nil, fmt.Errorf("re-encrypt blobs: %w", err)
+		}
+	}
+	if err := s.keyring.retire(userID, dataKey.Version); err != nil {
+		return nil, err
+	}
+
+	log.Printf("EncryptedStore: Rotated data key of user %s to version %d", userID, dataKey.Version)
+	dataKey.Wrapped = nil
+	return &dataKey, nil
+}
+
+// rotateDocuments adds a data key version and seals the user's part of
+// every encrypted document with it. It returns the key and the blob owners
+// to reseal.
+func (s *EncryptedStore) rotateDocuments(userID string) (DataKey, []BlobOwner, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dataKey, _, err := s.keyring.rotate(userID)
+	if err != nil {
+		return DataKey{}, nil, err
+	}
+	// Every user's part is sealed again on save; only the rotated user's
+	// key changes
+	for _, name := range s.Documents() {
+		users, _, err := s.load(name)
+		var unkeyed *unkeyedError
+		if errors.Is(err, ErrNotFound) || errors.As(err, &unkeyed) {
+			continue
+		}
+		if err != nil {
+			return DataKey{}, nil, fmt.Errorf("re-encrypt %s: %w", name, err)
+		}
+		if _, exists := users[userID]; !exists {
+			continue
+		}
+		if err := s.save(name, users); err != nil {
+			return DataKey{}, nil, fmt.Errorf("re-encrypt %s: %w", name, err)
+		}
+	}
+	return dataKey, append([]BlobOwner(nil), s.owners...), nil
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type note struct {
+	Text string `json:"text"`
+}
+
+func newEncryptedStore(t *testing.T, names ...string) (*EncryptedStore, *MemoryStore) {
+	t.Helper()
+	inner := NewMemoryStore().(*MemoryStore)
+	keyring, err := NewKeyring(inner, newMasterKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEncryptedStore(inner, keyring, names...), inner
+}
+
+func raw(m *MemoryStore, name string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return string(m.docs[name])
+}
+
+func TestEncryptedStoreSealsNamedDocuments(t *testing.T) {
+	store, inner := newEncryptedStore(t, "notes")
+	doc := map[string][]note{
+		"alice": {{Text: "the spare key is under the mat"}},
+		"bob":   {{Text: "dentist on friday"}},
+	}
+
+	tests := []struct {
+		name       string
+		document   string
+		wantSealed bool
+	}{
+		{"named document", "notes", true},
+		{"other document", "scheduler", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Save(tt.document, doc); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			stored := raw(inner, tt.document)
+			if sealed := !strings.Contains(stored, "spare key"); sealed != tt.wantSealed {
+				t.Fatalf("stored document %s, want sealed %v", stored, tt.wantSealed)
+			}
+
+			var loaded map[string][]note
+			if err := store.Load(tt.document, &loaded); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if loaded["alice"][0].Text != doc["alice"][0].Text || loaded["bob"][0].Text != doc["bob"][0].Text {
+				t.Fatalf("Load = %v, want %v", loaded, doc)
+			}
+		})
+	}
+}
+
+func TestEncryptedStoreBindsUsersToTheirParts(t *testing.T) {
+	store, inner := newEncryptedStore(t, "notes")
+	if err := store.Save("notes", map[string][]note{"alice": {{Text: "a"}}, "bob": {{Text: "b"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving alice's sealed part over to bob must not make it readable as
+	// bob's
+	var env envelope
+	if err := json.Unmarshal([]byte(raw(inner, "notes")), &env); err != nil {
+		t.Fatal(err)
+	}
+	env.Users["bob"] = env.Users["alice"]
+	if err := inner.Save("notes", env); err != nil {
+		t.Fatal(err)
+	}
+	var loaded map[string][]note
+	if err := store.Load("notes", &loaded); err == nil {
+		t.Fatal("Load accepted a part sealed for another user")
+	}
+}
+
+func TestEncryptedStoreLoadsLegacyPlaintext(t *testing.T) {
+	store, inner := newEncryptedStore(t, "notes")
+	if err := inner.Save("notes", map[string][]note{"alice": {{Text: "written before encryption"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded map[string][]note
+	if err := store.Load("notes", &loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded["alice"][0].Text != "written before encryption" {
+		t.Fatalf("Load = %v", loaded)
+	}
+	if err := store.Save("notes", loaded); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(raw(inner, "notes"), "written before encryption") {
+		t.Fatal("legacy document is still plaintext after saving")
+	}
+}
+
+func TestEncryptedStoreRejectsDocumentsNotKeyedByUser(t *testing.T) {
+	store, _ := newEncryptedStore(t, "memories")
+	err := store.Save("memories", []note{{Text: "a list, not an object"}})
+	if err == nil {
+		t.Fatal("Save accepted a document that is not keyed by user")
+	}
+}
+
+func TestEncryptedStoreSkipsPurgedUsers(t *testing.T) {
+	store, _ := newEncryptedStore(t, "notes")
+	if err := store.Save("notes", map[string][]note{"alice": {{Text: "a"}}, "bob": {{Text: "b"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Keyring().Purge("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded map[string][]note
+	if err := store.Load("notes", &loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, exists := loaded["alice"]; exists {
+		t.Fatal("data sealed with a destroyed key was opened")
+	}
+	if len(loaded["bob"]) != 1 {
+		t.Fatalf("other user's data was lost: %v", loaded)
+	}
+}
+
+type blobs struct {
+	store *EncryptedStore
+	data  map[string][]byte
+}
+
+func (b *blobs) Reseal(userID string) error {
+	plain, err := b.store.OpenBlob(userID, "audio", b.data[userID])
+	if err != nil {
+		return err
+	}
+	b.data[userID], err = b.store.SealBlob(userID, "audio", plain)
+	return err
+}
+
+func TestEncryptedStoreRotate(t *testing.T) {
+	store, inner := newEncryptedStore(t, "notes", "inputs")
+	if err := store.Save("notes", map[string][]note{"alice": {{Text: "a"}}, "bob": {{Text: "b"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("inputs", map[string][]note{"bob": {{Text: "only bob"}}}); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := store.SealBlob("alice", "audio", []byte("recording"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := &blobs{store: store, data: map[string][]byte{"alice": sealed}}
+	store.AddBlobOwner(owner)
+
+	dataKey, err := store.Rotate("alice")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if dataKey.Version != 2 || dataKey.Wrapped != nil {
+		t.Fatalf("Rotate = version %d with wrapped key %v, want version 2 without it", dataKey.Version, dataKey.Wrapped)
+	}
+	if keys := store.Keyring().Keys("alice"); len(keys) != 1 || keys[0].Version != 2 {
+		t.Fatalf("keys after rotation = %v, want only version 2", keys)
+	}
+
+	var env envelope
+	if err := json.Unmarshal([]byte(raw(inner, "notes")), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Users["alice"].Key != 2 || env.Users["bob"].Key != 1 {
+		t.Fatalf("notes sealed with versions %d and %d, want 2 for alice and 1 for bob", env.Users["alice"].Key, env.Users["bob"].Key)
+	}
+	var loaded map[string][]note
+	if err := store.Load("notes", &loaded); err != nil || loaded["alice"][0].Text != "a" {
+		t.Fatalf("Load after rotation = %v, %v", loaded, err)
+	}
+	plain, err := store.OpenBlob("alice", "audio", owner.data["alice"])
+	if err != nil || string(plain) != "recording" {
+		t.Fatalf("OpenBlob after rotation = %q, %v", plain, err)
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	store, _ := newEncryptedStore(t)
+	sealed, err := store.SealBlob("alice", "audio", []byte("recording"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealedBlob(sealed) || bytes.Contains(sealed, []byte("recording")) {
+		t.Fatal("SealBlob did not encrypt the data")
+	}
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		userID  string
+		blob    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"sealed", "alice", "audio", sealed, "recording", false},
+		{"plaintext passes through", "alice", "audio", []byte("legacy"), "legacy", false},
+		{"other user", "bob", "audio", sealed, "", true},
+		{"other blob", "alice", "video", sealed, "", true},
+		{"tampered", "alice", "audio", tampered, "", true},
+		{"truncated", "alice", "audio", sealed[:len(blobMagic)+2], "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.OpenBlob(tt.userID, tt.blob, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("OpenBlob = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenBlob: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("OpenBlob = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.Keyring().Purge("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.OpenBlob("alice", "audio", sealed); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("OpenBlob after purge: %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestEncryptedStoreLoadsUnkeyedDocuments(t *testing.T) {
+	store, inner := newEncryptedStore(t, "nudges")
+	// Saved as a list before the document was keyed by user
+	if err := inner.Save("nudges", []note{{Text: "drink water"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded []note
+	if err := store.Load("nudges", &loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Text != "drink water" {
+		t.Fatalf("Load = %+v", loaded)
+	}
+	if _, err := store.Rotate("alice"); err != nil {
+		t.Fatalf("Rotate with an unkeyed document: %v", err)
+	}
+	if err := store.Save("nudges", map[string][]note{"alice": loaded}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(raw(inner, "nudges"), "drink water") {
+		t.Fatal("document saved keyed by user was not sealed")
+	}
+}
@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keysStoreName = "keys"
+
+// ErrKeyNotFound is returned when a user's data key doesn't exist, e.g.
+// because it was purged along with their account.
+var ErrKeyNotFound = errors.New("data key not found")
+
+// DataKey is one version of a user's data key, wrapped by a master key.
+type DataKey struct {
+	Version int `json:"version"`
+	// MasterKeyID identifies the master key that wrapped it.
+	MasterKeyID string    `json:"master_key_id"`
+	Wrapped     []byte    `json:"wrapped,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Keyring holds per-user data keys, stored wrapped by the master key.
+// Unwrapped keys only ever live in memory.
+type Keyring struct {
+	store     Store
+	masterID  string
+	master    cipher.AEAD
+	keys      map[string][]DataKey
+	unwrapped map[string]map[int][]byte
+	mutex     sync.Mutex
+}
+
+// ParseKey decodes a 256-bit key given as base64 or hex.
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(encoded); err == nil && len(key) == 32 {
+			return key, nil
+		}
+	}
+	return nil, errors.New("key must be 32 bytes, base64 or hex encoded")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyID names a master key without revealing it.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+}
+
+func wrapContext(userID string, version int) []byte {
+	return []byte(fmt.Sprintf("data-key:%s:%d", userID, version))
+}
+
+// NewKeyring loads the data keys wrapped by master. Keys still wrapped by
+// one of the previous master keys are rewrapped with master, which is how
+// the master key is rotated: set the new key and list the old one as
+// previous until the next start.
+func NewKeyring(store Store, master string, previous ...string) (*Keyring, error) {
+	masterKey, err := ParseKey(master)
+	if err != nil {
+		return nil, fmt.Errorf("master key: %w", err)
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{
+		store:     store,
+		masterID:  keyID(masterKey),
+		master:    aead,
+		keys:      make(map[string][]DataKey),
+		unwrapped: make(map[string]map[int][]byte),
+	}
+	olds := make(map[string]cipher.AEAD)
+	for i, encoded := range previous {
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("previous master key %d: %w", i+1, err)
+		}
+		if olds[keyID(key)], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := store.Load(keysStoreName, &k.keys); err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("load data keys: %w", err)
+	}
+	if k.keys == nil {
+		k.keys = make(map[string][]DataKey)
+	}
+
+	rewrapped := 0
+	for userID, versions := range k.keys {
+		for i, dataKey := range versions {
+			if dataKey.MasterKeyID == k.masterID {
+				continue
+			}
+			old, exists := olds[dataKey.MasterKeyID]
+			if !exists {
+				return nil, fmt.Errorf("data key %d of user %s is wrapped by unknown master key %s", dataKey.Version, userID, dataKey.MasterKeyID)
+			}
+			plain, err := open(old, dataKey.Wrapped, wrapContext(userID, dataKey.Version))
+			if err != nil {
+				return nil, fmt.Errorf("unwrap data key %d of user %s: %w", dataKey.Version, userID, err)
+			}
+			if versions[i].Wrapped, err = seal(k.master, plain, wrapContext(userID, dataKey.Version)); err != nil {
+				return nil, err
+			}
+			versions[i].MasterKeyID = k.masterID
+			rewrapped++
+		}
+	}
+	if rewrapped > 0 {
+		if err := k.persist(); err != nil {
+			return nil, err
+		}
+		log.Printf("Keyring: Rewrapped %d data keys with master key %s", rewrapped, k.masterID)
+	}
+
+	log.Printf("Keyring: Loaded data keys for %d users (master key %s)", len(k.keys), k.masterID)
+	return k, nil
+}
+
+func (k *Keyring) persist() error {
+	if err := k.store.Save(keysStoreName, k.keys); err != nil {
+		return fmt.Errorf("save data keys: %w", err)
+	}
+	return nil
+}
+
+// MasterKeyID identifies the master key in use.
+func (k *Keyring) MasterKeyID() string {
+	return k.masterID
+}
+
+// current returns the user's latest data key, creating the first one.
+func (k *Keyring) current(userID string) (int, []byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	versions := k.keys[userID]
+	if len(versions) == 0 {
+		dataKey, plain, err := k.add(userID, 1)
+		if err != nil {
+			return 0, nil, err
+		}
+		return dataKey.Version, plain, nil
+	}
+	latest := versions[len(versions)-1].Version
+	plain, err := k.unwrap(userID, latest)
+	return latest, plain, err
+}
+
+// key returns a specific version of the user's data key.
+func (k *Keyring) key(userID string, version int) ([]byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.unwrap(userID, version)
+}
+
+// unwrap returns a data key, from memory if it was unwrapped before.
+// Callers hold the mutex.
+func (k *Keyring) unwrap(userID string, version int) ([]byte, error) {
+	if plain, exists := k.unwrapped[userID][version]; exists {
+		return plain, nil
+	}
+	for _, dataKey := range k.keys[userID] {
+		if dataKey.Version != version {
+			continue
+		}
+		plain, err := open(k.master, dataKey.Wrapped, wrapContext(userID, version))
+		if err != nil {
+			return nil, fmt.Errorf("unwrap data key %d of user %s: %w", version, userID, err)
+		}
+		k.cache(userID, version, plain)
+		return plain, nil
+	}
+	return nil, fmt.Errorf("%w: version %d of user %s", ErrKeyNotFound, version, userID)
+}
+
+func (k *Keyring) cache(userID string, version int, plain []byte) {
+	if k.unwrapped[userID] == nil {
+		k.unwrapped[userID] = make(map[int][]byte)
+	}
+	k.unwrapped[userID][version] = plain
+}
+
+// add generates and stores a new data key version. Callers hold the mutex.
+func (k *Keyring) add(userID string, version int) (DataKey, []byte, error) {
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return DataKey{}, nil, err
+	}
+	wrapped, err := seal(k.master, plain, wrapContext(userID, version))
+	if err != nil {
+		return DataKey{}, nil, err
+	}
+	dataKey := DataKey{Version: version, MasterKeyID: k.masterID, Wrapped: wrapped, CreatedAt: time.Now()}
+	k.keys[userID] = append(k.keys[userID], dataKey)
+	if err := k.persist(); err != nil {
+		k.keys[userID] = k.keys[userID][:len(k.keys[userID])-1]
+		return DataKey{}, nil, err
+	}
+	k.cache(userID, version, plain)
+	return dataKey, plain, nil
+}
+
+// rotate adds a new version of the user's data key. Older versions stay
+// until retire is called, so data sealed with them can still be opened.
+func (k *Keyring) rotate(userID string) (DataKey, []byte, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	version := 1
+	if versions := k.keys[userID]; len(versions) > 0 {
+		version = versions[len(versions)-1].Version + 1
+	}
+	return k.add(userID, version)
+}
+
+// retire drops the user's data key versions older than keep.
+func (k *Keyring) retire(userID string, keep int) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	var kept []DataKey
+	for _, dataKey := range k.keys[userID] {
+		if dataKey.Version >= keep {
+			kept = append(kept, dataKey)
+		} else {
+			delete(k.unwrapped[userID], dataKey.Version)
+		}
+	}
+	k.keys[userID] = kept
+	return k.persist()
+}
+
+// Keys describes the user's data key versions, without the keys.
+func (k *Keyring) Keys(userID string) []DataKey {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	result := make([]DataKey, 0, len(k.keys[userID]))
+	for _, dataKey := range k.keys[userID] {
+		dataKey.Wrapped = nil
+		result = append(result, dataKey)
+	}
+	return result
+}
+
+// Purge destroys the user's data keys, so anything still sealed with them
+// can never be read again.
+func (k *Keyring) Purge(userID string) (int, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	count := len(k.keys[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(k.keys, userID)
+	delete(k.unwrapped, userID)
+	if err := k.persist(); err != nil {
+		return 0, err
+	}
+	log.Printf("Keyring: Destroyed %d data keys of user %s", count, userID)
+	return count, nil
+}
+
+func (k *Keyring) Count(userID string) (int, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return len(k.keys[userID]), nil
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func newMasterKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xab}, 32)
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"base64", base64.StdEncoding.EncodeToString(key), false},
+		{"raw base64", base64.RawStdEncoding.EncodeToString(key), false},
+		{"url base64", base64.URLEncoding.EncodeToString(key), false},
+		{"hex", hex.EncodeToString(key), false},
+		{"surrounding whitespace", " " + hex.EncodeToString(key) + "\n", false},
+		{"too short", base64.StdEncoding.EncodeToString(key[:16]), true},
+		{"not encoded", "correct horse battery staple", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseKey(%q) succeeded, want an error", tt.encoded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKey(%q): %v", tt.encoded, err)
+			}
+			if !bytes.Equal(got, key) {
+				t.Fatalf("ParseKey(%q) = %x, want %x", tt.encoded, got, key)
+			}
+		})
+	}
+}
+
+func TestKeyringRewrapsWithNewMasterKey(t *testing.T) {
+	store := NewMemoryStore()
+	oldMaster, newMaster := newMasterKey(t), newMasterKey(t)
+
+	keyring, err := NewKeyring(store, oldMaster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, original, err := keyring.current("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		master   string
+		previous []string
+		wantErr  bool
+	}{
+		{"unknown master key", newMaster, nil, true},
+		{"new master with the old one as previous", newMaster, []string{oldMaster}, false},
+		// The keys were rewrapped by the start before
+		{"new master alone", newMaster, nil, false},
+		{"old master after rewrapping", oldMaster, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(store, tt.master, tt.previous...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewKeyring succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeyring: %v", err)
+			}
+			got, err := keyring.key("alice", 1)
+			if err != nil {
+				t.Fatalf("key: %v", err)
+			}
+			if !bytes.Equal(got, original) {
+				t.Fatal("data key changed when it was rewrapped")
+			}
+			for _, dataKey := range keyring.Keys("alice") {
+				if dataKey.MasterKeyID != keyring.MasterKeyID() {
+					t.Fatalf("data key %d is wrapped by %s, want %s", dataKey.Version, dataKey.MasterKeyID, keyring.MasterKeyID())
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringRotateAndRetire(t *testing.T) {
+	keyring, err := NewKeyring(NewMemoryStore(), newMasterKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, first, err := keyring.current("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Fatalf("first data key has version %d, want 1", version)
+	}
+
+	rotated, second, err := keyring.rotate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Version != 2 || bytes.Equal(first, second) {
+		t.Fatalf("rotate returned version %d, want a new key with version 2", rotated.Version)
+	}
+	if version, _, _ := keyring.current("alice"); version != 2 {
+		t.Fatalf("current version is %d after rotating, want 2", version)
+	}
+	if _, err := keyring.key("alice", 1); err != nil {
+		t.Fatalf("old version is gone before it was retired: %v", err)
+	}
+
+	if err := keyring.retire("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.key("alice", 1); err == nil {
+		t.Fatal("retired version can still be used")
+	}
+	if count, _ := keyring.Count("alice"); count != 1 {
+		t.Fatalf("%d data keys left after retiring, want 1", count)
+	}
+}
+
+func TestKeyringPurge(t *testing.T) {
+	store := NewMemoryStore()
+	master := newMasterKey(t)
+	keyring, err := NewKeyring(store, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []string{"alice", "bob"} {
+		if _, _, err := keyring.current(userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, err := keyring.Purge("alice"); err != nil || count != 1 {
+		t.Fatalf("Purge = %d, %v, want 1, nil", count, err)
+	}
+	reloaded, err := NewKeyring(store, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.key("alice", 1); err == nil {
+		t.Fatal("purged data key survived a reload")
+	}
+	if _, err := reloaded.key("bob", 1); err != nil {
+		t.Fatalf("other user's data key was lost: %v", err)
+	}
+}
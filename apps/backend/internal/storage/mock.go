@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+type MemoryStore struct {
+	docs  map[string][]byte
+	mutex sync.Mutex
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		docs: make(map[string][]byte),
+	}
+}
+
+func (m *MemoryStore) Load(name string, v any) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data, exists := m.docs[name]
+	if !exists {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (m *MemoryStore) Save(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.docs[name] = data
+	return nil
+}
+
+func (m *MemoryStore) Delete(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.docs, name)
+	return nil
+}
@@ -0,0 +1,276 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+)
+
+type Action string
+
+const (
+	ActionCreate       Action = "create"
+	ActionComplete     Action = "complete"
+	ActionList         Action = "list"
+	ActionReprioritize Action = "reprioritize"
+)
+
+// Command is the structured form of a natural-language task request.
+// Ref identifies an existing task by (part of) its title for complete and
+// reprioritize actions.
+type Command struct {
+	Action   Action
+	Title    string
+	Ref      string
+	DueAt    *time.Time
+	Tags     []string
+	Priority Priority
+}
+
+type Parser struct {
+	llmService llm.LLMService
+}
+
+func NewParser(llmService llm.LLMService) *Parser {
+	return &Parser{llmService: llmService}
+}
+
+func (p *Parser) Parse(input string, now time.Time) Command {
+	if p.llmService != nil {
+		cmd, err := p.parseWithLLM(input, now)
+		if err == nil {
+			return cmd
+		}
+		if !errors.Is(err, llm.ErrUnavailable) {
+			log.Printf("TaskParser: LLM parsing failed, using fallback: %v", err)
+		}
+	}
+	return parseFallback(input, now)
+}
+
+func (p *Parser) parseWithLLM(input string, now time.Time) (Command, error) {
+	prompt := fmt.Sprintf(`The current time is %s.
+Interpret this task request from the user: "%s"
+
+Return a single JSON object with this format:
+{
+  "action": "create" | "complete" | "list" | "reprioritize",
+  "title": "short task title for create, otherwise empty",
+  "task_ref": "words identifying an existing task for complete/reprioritize, otherwise empty",
+  "due": "RFC3339 due date/time if one was mentioned, otherwise empty",
+  "tags": ["lowercase", "tags"],
+  "priority": "low" | "medium" | "high" | ""
+}`, now.Format(time.RFC3339), input)
+
+	response, err := p.llmService.Complete(prompt)
+	if err != nil {
+		return Command{}, err
+	}
+
+	var raw struct {
+		Action   string   `json:"action"`
+		Title    string   `json:"title"`
+		TaskRef  string   `json:"task_ref"`
+		Due      string   `json:"due"`
+		Tags     []string `json:"tags"`
+		Priority string   `json:"priority"`
+	}
+	if err := llm.DecodeJSON(response, &raw); err != nil {
+		return Command{}, err
+	}
+
+	cmd := Command{
+		Action: Action(raw.Action),
+		Title:  raw.Title,
+		Ref:    raw.TaskRef,
+		Tags:   raw.Tags,
+	}
+	switch cmd.Action {
+	case ActionCreate, ActionComplete, ActionList, ActionReprioritize:
+	default:
+		return Command{}, fmt.Errorf("unknown action %q", raw.Action)
+	}
+	if priority, ok := ParsePriority(raw.Priority); ok {
+		cmd.Priority = priority
+	}
+	if raw.Due != "" {
+		due, err := time.Parse(time.RFC3339, raw.Due)
+		if err != nil {
+			return Command{}, fmt.Errorf("invalid due date %q: %w", raw.Due, err)
+		}
+		cmd.DueAt = &due
+	}
+	return cmd, nil
+}
+
+var (
+	tagPattern        = regexp.MustCompile(`#(\w[\w-]*)`)
+	inDaysPattern     = regexp.MustCompile(`\bin (\d+) days?\b`)
+	listPhrases       = []string{"list tasks", "list my tasks", "show my tasks", "what are my tasks", "what's on my", "todo list", "to-do list"}
+	completeWords     = []string{"done with", "finished", "completed", "complete", "done", "mark"}
+	priorityWords     = []string{"reprioritize", "prioritize", "priority"}
+	reprioritizeVerbs = []string{"set", "make", "change", "bump", "raise", "lower"}
+	highPattern       = regexp.MustCompile(`\b(urgent|asap|high|important)\b`)
+	lowPattern        = regexp.MustCompile(`\b(low|someday|whenever)\b`)
+	createPrefixes    = []string{"remind me to", "i need to", "i have to", "i should", "add task", "new task", "todo:", "todo", "task:"}
+)
+
+func parseFallback(input string, now time.Time) Command {
+	lower := strings.ToLower(strings.TrimSpace(input))
+	tags := extractTags(input)
+	priority := priorityFromText(lower)
+
+	if priority != "" && isReprioritize(lower) {
+		ref := stripWords(lower, append(priorityWords, reprioritizeVerbs...))
+		ref = stripWords(ref, []string{"high", "low", "medium", "urgent", "to", "of", "the", "as"})
+		return Command{Action: ActionReprioritize, Ref: ref, Priority: priority}
+	}
+
+	for _, phrase := range listPhrases {
+		if strings.Contains(lower, phrase) {
+			return Command{Action: ActionList, Tags: tags}
+		}
+	}
+
+	for _, word := range completeWords {
+		if strings.HasPrefix(lower, word+" ") || strings.HasSuffix(lower, " "+word) {
+			return Command{Action: ActionComplete, Ref: stripWords(lower, append(completeWords, "as", "task", "i", "i'm", "with", "the"))}
+		}
+	}
+
+	title := tagPattern.ReplaceAllString(input, "")
+	for _, prefix := range createPrefixes {
+		if strings.HasPrefix(strings.ToLower(title), prefix) {
+			title = title[len(prefix):]
+			break
+		}
+	}
+
+	return Command{
+		Action:   ActionCreate,
+		Title:    strings.Join(strings.Fields(title), " "),
+		DueAt:    dueFromText(lower, now),
+		Tags:     tags,
+		Priority: priority,
+	}
+}
+
+func extractTags(input string) []string {
+	var tags []string
+	for _, match := range tagPattern.FindAllStringSubmatch(input, -1) {
+		tags = append(tags, strings.ToLower(match[1]))
+	}
+	return tags
+}
+
+func isReprioritize(lower string) bool {
+	if strings.Contains(lower, "prioritize") {
+		return true
+	}
+	if !strings.Contains(lower, "priority") {
+		return false
+	}
+	for _, verb := range reprioritizeVerbs {
+		if strings.HasPrefix(lower, verb+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func priorityFromText(lower string) Priority {
+	switch {
+	case highPattern.MatchString(lower):
+		return PriorityHigh
+	case lowPattern.MatchString(lower):
+		return PriorityLow
+	case strings.Contains(lower, "medium"):
+		return PriorityMedium
+	}
+	return ""
+}
+
+func dueFromText(lower string, now time.Time) *time.Time {
+	endOfDay := func(t time.Time) *time.Time {
+		due := time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 0, 0, t.Location())
+		return &due
+	}
+
+	switch {
+	case strings.Contains(lower, "today"), strings.Contains(lower, "tonight"):
+		return endOfDay(now)
+	case strings.Contains(lower, "tomorrow"):
+		return endOfDay(now.AddDate(0, 0, 1))
+	case strings.Contains(lower, "next week"):
+		return endOfDay(now.AddDate(0, 0, 7))
+	}
+
+	if match := inDaysPattern.FindStringSubmatch(lower); match != nil {
+		days, _ := strconv.Atoi(match[1])
+		return endOfDay(now.AddDate(0, 0, days))
+	}
+
+	for offset := 1; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		name := strings.ToLower(day.Weekday().String())
+		if strings.Contains(lower, "on "+name) || strings.Contains(lower, "by "+name) {
+			return endOfDay(day)
+		}
+	}
+	return nil
+}
+
+func stripWords(lower string, words []string) string {
+	fields := strings.Fields(lower)
+	skip := make(map[string]bool)
+	for _, word := range words {
+		for _, part := range strings.Fields(word) {
+			skip[part] = true
+		}
+	}
+
+	kept := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !skip[strings.Trim(field, ".,!?:")] {
+			kept = append(kept, field)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// FindByRef returns the open task whose title best matches ref, or nil.
+func FindByRef(tasks []Task, ref string) *Task {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil
+	}
+
+	var best *Task
+	bestScore := 0
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Status != StatusOpen {
+			continue
+		}
+		title := strings.ToLower(task.Title)
+		if strings.Contains(title, ref) {
+			return task
+		}
+		score := 0
+		for _, word := range strings.Fields(ref) {
+			if len(word) > 2 && strings.Contains(title, word) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = task, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,391 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "tasks"
+
+var (
+	ErrNotFound = errors.New("task not found")
+	ErrInvalid  = errors.New("invalid task")
+)
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
+func ParsePriority(s string) (Priority, bool) {
+	switch Priority(strings.ToLower(strings.TrimSpace(s))) {
+	case PriorityLow:
+		return PriorityLow, true
+	case PriorityMedium:
+		return PriorityMedium, true
+	case PriorityHigh:
+		return PriorityHigh, true
+	}
+	return "", false
+}
+
+func (p Priority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 2
+	case PriorityMedium:
+		return 1
+	}
+	return 0
+}
+
+type Status string
+
+const (
+	StatusOpen Status = "open"
+	StatusDone Status = "done"
+)
+
+type Task struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Title       string     `json:"title"`
+	Priority    Priority   `json:"priority"`
+	Status      Status     `json:"status"`
+	Tags        []string   `json:"tags"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type Filter struct {
+	Status Status
+	Tag    string
+}
+
+// Update describes a partial change; nil fields are left untouched.
+type Update struct {
+	Title    *string
+	Priority *Priority
+	DueAt    *time.Time
+	Tags     []string
+}
+
+type TaskService interface {
+	Create(userID string, task Task) (*Task, error)
+	Get(userID, id string) (*Task, error)
+	List(userID string, filter Filter) ([]Task, error)
+	Update(userID, id string, update Update) (*Task, error)
+	Complete(userID, id string) (*Task, error)
+	Delete(userID, id string) error
+	// Import adds tasks from an archive, keeping their IDs and times. Tasks
+	// the user already has are skipped; it returns how many were added.
+	Import(userID string, tasks []Task) (int, error)
+	// Purge deletes all of the user's tasks, returning how many there were.
+	Purge(userID string) (int, error)
+	// Count returns how many tasks the user has.
+	Count(userID string) (int, error)
+	Users() ([]string, error)
+}
+
+type service struct {
+	store storage.Store
+	tasks map[string][]Task
+	mutex sync.RWMutex
+}
+
+func NewService(store storage.Store) (TaskService, error) {
+	s := &service{
+		store: store,
+		tasks: make(map[string][]Task),
+	}
+	if err := store.Load(storeName, &s.tasks); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load tasks: %w", err)
+	}
+	log.Printf("TaskService: Loaded tasks for %d users", len(s.tasks))
+	return s, nil
+}
+
+func (s *service) Create(userID string, task Task) (*Task, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+
+	now := time.Now()
+	task.ID = storage.NewID()
+	task.UserID = userID
+	task.Status = StatusOpen
+	task.Tags = normalizeTags(task.Tags)
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.CompletedAt = nil
+	if task.Priority == "" {
+		task.Priority = PriorityMedium
+	}
+
+	s.tasks[userID] = append(s.tasks[userID], task)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("TaskService: Created task %s for user %s: %s", task.ID, userID, task.Title)
+	return &task, nil
+}
+
+func (s *service) Get(userID, id string) (*Task, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	task := s.tasks[userID][idx]
+	return &task, nil
+}
+
+func (s *service) List(userID string, filter Filter) ([]Task, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tag := strings.ToLower(strings.TrimSpace(filter.Tag))
+	result := make([]Task, 0)
+	for _, task := range s.tasks[userID] {
+		if filter.Status != "" && task.Status != filter.Status {
+			continue
+		}
+		if tag != "" && !containsTag(task.Tags, tag) {
+			continue
+		}
+		result = append(result, task)
+	}
+
+	// Open tasks first, then by priority, then soonest due, then oldest
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Status != b.Status {
+			return a.Status == StatusOpen
+		}
+		if a.Priority.rank() != b.Priority.rank() {
+			return a.Priority.rank() > b.Priority.rank()
+		}
+		if (a.DueAt == nil) != (b.DueAt == nil) {
+			return a.DueAt != nil
+		}
+		if a.DueAt != nil && !a.DueAt.Equal(*b.DueAt) {
+			return a.DueAt.Before(*b.DueAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	return result, nil
+}
+
+func (s *service) Update(userID, id string, update Update) (*Task, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+
+	task := &s.tasks[userID][idx]
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: title is required", ErrInvalid)
+		}
+		task.Title = title
+	}
+	if update.Priority != nil {
+		task.Priority = *update.Priority
+	}
+	if update.DueAt != nil {
+		due := *update.DueAt
+		task.DueAt = &due
+	}
+	if update.Tags != nil {
+		task.Tags = normalizeTags(update.Tags)
+	}
+	task.UpdatedAt = time.Now()
+
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	updated := *task
+	log.Printf("TaskService: Updated task %s for user %s", id, userID)
+	return &updated, nil
+}
+
+func (s *service) Complete(userID, id string) (*Task, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+
+	task := &s.tasks[userID][idx]
+	if task.Status != StatusDone {
+		now := time.Now()
+		task.Status = StatusDone
+		task.CompletedAt = &now
+		task.UpdatedAt = now
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+	}
+
+	completed := *task
+	log.Printf("TaskService: Completed task %s for user %s", id, userID)
+	return &completed, nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.indexOf(userID, id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	userTasks := s.tasks[userID]
+	s.tasks[userID] = append(userTasks[:idx], userTasks[idx+1:]...)
+	if err := s.persist(); err != nil {
+		return err
+	}
+
+	log.Printf("TaskService: Deleted task %s for user %s", id, userID)
+	return nil
+}
+
+func (s *service) Import(userID string, list []Task) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := 0
+	for _, task := range list {
+		if task.ID == "" || s.indexOf(userID, task.ID) != -1 {
+			continue
+		}
+		task.UserID = userID
+		task.Tags = normalizeTags(task.Tags)
+		s.tasks[userID] = append(s.tasks[userID], task)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("TaskService: Imported %d tasks for user %s", added, userID)
+	return added, nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.tasks[userID])
+	if count == 0 {
+		return 0, nil
+	}
+	delete(s.tasks, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("TaskService: Purged %d tasks for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.tasks[userID]), nil
+}
+
+func (s *service) Users() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	users := make([]string, 0, len(s.tasks))
+	for userID := range s.tasks {
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+func (s *service) indexOf(userID, id string) int {
+	for i, task := range s.tasks[userID] {
+		if task.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// persist saves the tasks. If that fails the change is undone, so the
+// next save doesn't write out a change the caller was told failed.
+func (s *service) persist() error {
+	if err := s.store.Save(storeName, s.tasks); err != nil {
+		s.reload()
+		return fmt.Errorf("save tasks: %w", err)
+	}
+	return nil
+}
+
+// reload puts back the tasks as they were last saved. Callers hold the
+// write lock.
+func (s *service) reload() {
+	tasks := make(map[string][]Task)
+	if err := s.store.Load(storeName, &tasks); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("TaskService: Failed to undo an unsaved change: %v", err)
+		return
+	}
+	s.tasks = tasks
+}
+
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
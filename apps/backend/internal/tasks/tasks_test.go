@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+var errDiskFull = errors.New("disk full")
+
+// failingStore fails saves while failing is set.
+type failingStore struct {
+	storage.Store
+	failing bool
+}
+
+func (f *failingStore) Save(name string, v any) error {
+	if f.failing {
+		return errDiskFull
+	}
+	return f.Store.Save(name, v)
+}
+
+func TestFailedSaveChangesNothing(t *testing.T) {
+	title := "Renamed"
+	tests := []struct {
+		name   string
+		change func(s TaskService, id string) error
+	}{
+		{"create", func(s TaskService, id string) error {
+			_, err := s.Create("alice", Task{Title: "Another"})
+			return err
+		}},
+		{"update", func(s TaskService, id string) error {
+			_, err := s.Update("alice", id, Update{Title: &title})
+			return err
+		}},
+		{"complete", func(s TaskService, id string) error {
+			_, err := s.Complete("alice", id)
+			return err
+		}},
+		{"delete", func(s TaskService, id string) error {
+			return s.Delete("alice", id)
+		}},
+		{"purge", func(s TaskService, id string) error {
+			_, err := s.Purge("alice")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &failingStore{Store: storage.NewMemoryStore()}
+			service, err := NewService(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task, err := service.Create("alice", Task{Title: "Call the bank"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store.failing = true
+			if err := tt.change(service, task.ID); !errors.Is(err, errDiskFull) {
+				t.Fatalf("change = %v, want %v", err, errDiskFull)
+			}
+			list, _ := service.List("alice", Filter{})
+			if len(list) != 1 || list[0].Title != task.Title || list[0].Status != StatusOpen {
+				t.Fatalf("tasks after a failed save = %+v, want the saved task only", list)
+			}
+
+			// The next save doesn't carry the failed change along
+			store.failing = false
+			if _, err := service.Create("bob", Task{Title: "Water plants"}); err != nil {
+				t.Fatal(err)
+			}
+			restarted, err := NewService(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count, _ := restarted.Count("alice"); count != 1 {
+				t.Fatalf("alice has %d saved tasks, want 1", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/orchestrator"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/voice"
+)
+
+// Update delivery modes.
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
+const (
+	// DefaultPollTimeout is how long one getUpdates call waits for messages.
+	DefaultPollTimeout = 30 * time.Second
+	// maxPollBackoff caps the wait between failed polls.
+	maxPollBackoff = time.Minute
+	// webhookWorkers is how many webhook updates are handled at once, and
+	// webhookQueueSize how many more may wait for a worker.
+	webhookWorkers   = 4
+	webhookQueueSize = 64
+)
+
+// ErrBusy is returned by Enqueue when the queue of webhook updates is full.
+// Telegram retries updates that are not acknowledged.
+var ErrBusy = errors.New("too many telegram updates waiting")
+
+const helpText = `Send me whatever is on your mind — text or a voice message — and I'll file it into tasks, notes, goals and reminders and keep your profile up to date. Nudges will show up here too.
+
+Send /new to start a fresh conversation. To use this chat with an existing Soul Mirror account, get a link code from the app and send /start <code>.`
+
+type Config struct {
+	Token      string
+	APIBaseURL string
+	Mode       string
+	// WebhookURL is the public address Telegram posts updates to in webhook
+	// mode; WebhookSecret is echoed back in X-Telegram-Bot-Api-Secret-Token.
+	WebhookURL    string
+	WebhookSecret string
+	PollTimeout   time.Duration
+}
+
+// Bot connects a Telegram bot to the orchestrator. It also delivers nudges
+// to linked chats as a scheduler.Deliverer.
+type Bot struct {
+	config       Config
+	client       *Client
+	chats        *Chats
+	orchestrator orchestrator.Orchestrator
+	voiceService voice.VoiceService
+	cancel       context.CancelFunc
+	done         chan struct{}
+	// updates queues webhook updates for the workers. enqueuing keeps
+	// accepting an update and queueing it together; closed is set by Stop
+	updates   chan Update
+	enqueuing sync.Mutex
+	closed    bool
+	workers   sync.WaitGroup
+}
+
+// New builds a bot. voiceService may be nil, in which case voice messages
+// are politely declined.
+func New(config Config, store storage.Store, orch orchestrator.Orchestrator, voiceService voice.VoiceService) (*Bot, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("telegram bot token is required")
+	}
+	switch config.Mode {
+	case "":
+		config.Mode = ModePolling
+	case ModePolling:
+	case ModeWebhook:
+		if config.WebhookURL == "" || config.WebhookSecret == "" {
+			return nil, fmt.Errorf("telegram webhook mode needs a webhook URL and secret")
+		}
+	default:
+		return nil, fmt.Errorf("unknown telegram mode %q", config.Mode)
+	}
+	if config.PollTimeout <= 0 {
+		config.PollTimeout = DefaultPollTimeout
+	}
+
+	chats, err := NewChats(store)
+	if err != nil {
+		return nil, err
+	}
+	return &Bot{
+		config:       config,
+		client:       NewClient(config.APIBaseURL, config.Token),
+		chats:        chats,
+		orchestrator: orch,
+		voiceService: voiceService,
+	}, nil
+}
+
+func (b *Bot) Mode() string {
+	return b.config.Mode
+}
+
+func (b *Bot) Chats() *Chats {
+	return b.chats
+}
+
+// Start registers the webhook, or starts long polling in the background.
+func (b *Bot) Start() error {
+	if b.config.Mode == ModeWebhook {
+		if err := b.client.SetWebhook(b.config.WebhookURL, b.config.WebhookSecret); err != nil {
+			return err
+		}
+		b.updates = make(chan Update, webhookQueueSize)
+		for range webhookWorkers {
+			b.workers.Add(1)
+			go func() {
+				defer b.workers.Done()
+				for update := range b.updates {
+					b.handle(update)
+				}
+			}()
+		}
+		log.Printf("TelegramBot: Webhook registered at %s", b.config.WebhookURL)
+		return nil
+	}
+
+	// getUpdates is refused while a webhook is set
+	if err := b.client.DeleteWebhook(); err != nil {
+		log.Printf("TelegramBot: Failed to clear webhook: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	go b.poll(ctx)
+	log.Printf("TelegramBot: Long polling started (timeout %s)", b.config.PollTimeout)
+	return nil
+}
+
+func (b *Bot) Stop() {
+	if b.updates != nil {
+		b.enqueuing.Lock()
+		b.closed = true
+		close(b.updates)
+		b.enqueuing.Unlock()
+		// Updates already acknowledged are handled before stopping
+		b.workers.Wait()
+		log.Printf("TelegramBot: Stopped")
+		return
+	}
+	if b.cancel == nil {
+		return
+	}
+	b.cancel()
+	<-b.done
+	log.Printf("TelegramBot: Stopped")
+}
+
+func (b *Bot) poll(ctx context.Context) {
+	defer close(b.done)
+
+	backoff := time.Second
+	for {
+		updates, err := b.client.GetUpdates(ctx, b.chats.Offset(), b.config.PollTimeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("TelegramBot: Polling failed, retrying in %s: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxPollBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		for _, update := range updates {
+			b.HandleUpdate(update)
+		}
+	}
+}
+
+// VerifySecret checks the secret token Telegram sends with webhook calls.
+func (b *Bot) VerifySecret(token string) bool {
+	return b.config.WebhookSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(b.config.WebhookSecret)) == 1
+}
+
+// HandleUpdate processes one update, skipping ones already handled.
+func (b *Bot) HandleUpdate(update Update) {
+	if !b.accept(update) {
+		return
+	}
+	b.handle(update)
+}
+
+// Enqueue hands a webhook update to the workers, skipping ones already
+// handled or queued. It fails with ErrBusy when the queue is full, so the
+// update is left for Telegram to send again.
+func (b *Bot) Enqueue(update Update) error {
+	b.enqueuing.Lock()
+	defer b.enqueuing.Unlock()
+
+	if b.updates == nil || b.closed {
+		return ErrBusy
+	}
+	// Only enqueuers send, so there is room for the update once it is
+	// accepted
+	if len(b.updates) == cap(b.updates) {
+		log.Printf("TelegramBot: Queue full, leaving update %d for Telegram to retry", update.UpdateID)
+		return ErrBusy
+	}
+	if b.accept(update) {
+		b.updates <- update
+	}
+	return nil
+}
+
+// accept records the update, reporting false if it was handled before.
+func (b *Bot) accept(update Update) bool {
+	fresh, err := b.chats.Accept(update.UpdateID)
+	if err != nil {
+		log.Printf("TelegramBot: Failed to record update %d: %v", update.UpdateID, err)
+	}
+	if !fresh {
+		log.Printf("TelegramBot: Skipping update %d, already handled", update.UpdateID)
+	}
+	return fresh
+}
+
+func (b *Bot) handle(update Update) {
+	message := update.Message
+	if message == nil || (message.From != nil && message.From.IsBot) {
+		return
+	}
+
+	if reply := b.handleMessage(message); reply != "" {
+		b.send(message.Chat.ID, reply)
+	}
+}
+
+func (b *Bot) handleMessage(message *Message) string {
+	text := strings.TrimSpace(message.Text)
+	if strings.HasPrefix(text, "/") {
+		return b.handleCommand(message.Chat, text)
+	}
+
+	userID, err := b.chats.UserFor(message.Chat)
+	if err != nil {
+		log.Printf("TelegramBot: Failed to resolve user for chat %d: %v", message.Chat.ID, err)
+		return "Something went wrong on my side. Please try again in a bit."
+	}
+
+	prefix := ""
+	voiceNoteID := ""
+	if clip := voiceClip(message); clip != nil {
+		note, reply := b.transcribe(userID, clip)
+		if note == nil {
+			return reply
+		}
+		text = note.Transcript
+		voiceNoteID = note.ID
+		prefix = fmt.Sprintf("🎙 \"%s\"\n\n", note.Transcript)
+	}
+	if text == "" {
+		text = strings.TrimSpace(message.Caption)
+	}
+	if text == "" {
+		return "I can read text and listen to voice messages — send me one of those."
+	}
+
+	if err := b.client.SendChatAction(message.Chat.ID, "typing"); err != nil {
+		log.Printf("TelegramBot: Failed to send typing action: %v", err)
+	}
+	log.Printf("TelegramBot: Processing message from chat %d for user %s", message.Chat.ID, userID)
+	request := orchestrator.Request{
+		UserID:      userID,
+		Input:       text,
+		Source:      orchestrator.SourceTelegram,
+		SessionID:   b.chats.Session(message.Chat.ID),
+		VoiceNoteID: voiceNoteID,
+	}
+	response, err := b.orchestrator.Process(request)
+	if errors.Is(err, orchestrator.ErrSessionNotFound) {
+		// The session was deleted through the API; carry on in a new one
+		request.SessionID = ""
+		response, err = b.orchestrator.Process(request)
+	}
+	if err != nil {
+		log.Printf("TelegramBot: Processing failed for chat %d: %v", message.Chat.ID, err)
+		return "Sorry, I couldn't process that. Please try again."
+	}
+	if err := b.chats.SetSession(message.Chat.ID, response.SessionID); err != nil {
+		log.Printf("TelegramBot: Failed to remember session for chat %d: %v", message.Chat.ID, err)
+	}
+	return prefix + response.Result.FinalResponse
+}
+
+func (b *Bot) handleCommand(chat ChatInfo, text string) string {
+	command, argument, _ := strings.Cut(text, " ")
+	// Commands in groups arrive as /start@botname
+	command, _, _ = strings.Cut(command, "@")
+	argument = strings.TrimSpace(argument)
+
+	switch command {
+	case "/start":
+		if argument == "" {
+			if _, err := b.chats.UserFor(chat); err != nil {
+				log.Printf("TelegramBot: Failed to register chat %d: %v", chat.ID, err)
+			}
+			return "Hi! " + helpText
+		}
+		userID, ok, err := b.chats.Claim(chat, argument)
+		if err != nil {
+			log.Printf("TelegramBot: Failed to link chat %d: %v", chat.ID, err)
+			return "Something went wrong linking this chat. Please try again."
+		}
+		if !ok {
+			return "That link code is unknown or has expired. Get a new one from the app and send /start <code> again."
+		}
+		return fmt.Sprintf("This chat is now linked to %s. Send me whatever is on your mind.", userID)
+	case "/new":
+		if err := b.chats.SetSession(chat.ID, ""); err != nil {
+			log.Printf("TelegramBot: Failed to reset session for chat %d: %v", chat.ID, err)
+			return "Something went wrong on my side. Please try again in a bit."
+		}
+		return "Starting a fresh conversation."
+	case "/help":
+		return helpText
+	}
+	return "I don't know that command. " + helpText
+}
+
+func voiceClip(message *Message) *Voice {
+	if message.Voice != nil {
+		return message.Voice
+	}
+	return message.Audio
+}
+
+// transcribe downloads and transcribes a voice message, returning either
+// the transcribed note or a reply explaining why there is none.
+func (b *Bot) transcribe(userID string, clip *Voice) (*voice.Note, string) {
+	if b.voiceService == nil || !b.voiceService.CanTranscribe() {
+		return nil, "Voice messages aren't set up here yet — please send text instead."
+	}
+
+	file, err := b.client.GetFile(clip.FileID)
+	if err != nil {
+		log.Printf("TelegramBot: Failed to resolve voice file: %v", err)
+		return nil, "I couldn't download that voice message. Please try again."
+	}
+	data, err := b.client.DownloadFile(file.FilePath)
+	if err != nil {
+		log.Printf("TelegramBot: Failed to download voice file: %v", err)
+		return nil, "I couldn't download that voice message. Please try again."
+	}
+
+	name := clip.FileName
+	if name == "" {
+		name = "telegram-voice.ogg"
+	}
+	note, err := b.voiceService.Save(userID, name, bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, voice.ErrInvalid) {
+			return nil, "I can't read that audio format. Voice messages, m4a, wav and mp3 work."
+		}
+		log.Printf("TelegramBot: Failed to save voice note: %v", err)
+		return nil, "Something went wrong saving that voice message."
+	}
+	note, err = b.voiceService.Transcribe(userID, note.ID)
+	if err != nil {
+		log.Printf("TelegramBot: Failed to transcribe voice note: %v", err)
+		return nil, "I couldn't transcribe that voice message. Please try again or send text."
+	}
+	if note.Transcript == "" {
+		return nil, "I couldn't hear any words in that voice message."
+	}
+	return note, ""
+}
+
+func (b *Bot) send(chatID int64, text string) error {
+	for _, chunk := range splitMessage(text) {
+		if err := b.client.SendMessage(chatID, chunk); err != nil {
+			log.Printf("TelegramBot: Failed to send message to chat %d: %v", chatID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bot) Name() string {
+	return "telegram"
+}
+
+// Deliver sends a nudge to every chat linked to its user.
+func (b *Bot) Deliver(nudge scheduler.Nudge) error {
+	chats := b.chats.ForUser(nudge.UserID)
+	if len(chats) == 0 {
+		return scheduler.ErrNoRoute
+	}
+
+	var delivered int
+	var lastErr error
+	for _, chat := range chats {
+		if err := b.send(chat.ChatID, nudge.Message); err != nil {
+			lastErr = err
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return lastErr
+	}
+	log.Printf("TelegramBot: Delivered nudge %s to %d chats", nudge.ID, delivered)
+	return nil
+}
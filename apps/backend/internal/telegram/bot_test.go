@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+func TestBotEnqueue(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer api.Close()
+
+	bot, err := New(Config{
+		Token:         "token",
+		APIBaseURL:    api.URL,
+		Mode:          ModeWebhook,
+		WebhookURL:    "https://example.com/api/telegram/webhook",
+		WebhookSecret: "secret",
+	}, storage.NewMemoryStore(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Enqueue(Update{UpdateID: 1}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Enqueue before Start = %v, want ErrBusy", err)
+	}
+	if err := bot.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates without a message are accepted and dropped by the workers
+	for _, updateID := range []int64{1, 1, 3, 2, 3} {
+		if err := bot.Enqueue(Update{UpdateID: updateID}); err != nil {
+			t.Fatalf("Enqueue(%d): %v", updateID, err)
+		}
+	}
+	bot.Stop()
+
+	for _, updateID := range []int64{1, 2, 3} {
+		if fresh, _ := bot.Chats().Accept(updateID); fresh {
+			t.Fatalf("update %d was not recorded", updateID)
+		}
+	}
+	if err := bot.Enqueue(Update{UpdateID: 4}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Enqueue after Stop = %v, want ErrBusy", err)
+	}
+}
@@ -0,0 +1,281 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "telegram"
+
+// linkCodeTTL is how long a code from the API stays valid for /start.
+const linkCodeTTL = 15 * time.Minute
+
+var ErrNotFound = errors.New("telegram chat not found")
+
+// Chat maps a Telegram chat to the Soul Mirror user it talks for.
+type Chat struct {
+	ChatID   int64     `json:"chat_id"`
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username,omitempty"`
+	Title    string    `json:"title,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+	// SessionID is the conversation the chat's messages continue.
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// LinkCode lets an existing user claim a chat by sending /start <code>.
+type LinkCode struct {
+	Code      string    `json:"code"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// seenWindow is how many recent update IDs are remembered. Webhook calls
+// are handled concurrently, so an update can be accepted after a later one.
+const seenWindow = 1000
+
+type chatState struct {
+	// Offset is the next update ID to fetch. Seen holds the most recent
+	// update IDs accepted; they and anything older than the window have
+	// been handled, so restarts and webhook retries don't replay messages.
+	Offset int64            `json:"offset"`
+	Seen   []int64          `json:"seen,omitempty"`
+	Chats  map[string]*Chat `json:"chats"`
+}
+
+// Chats is the persistent chat directory. Chats that write before being
+// linked get a user of their own, "telegram-<chat id>".
+type Chats struct {
+	store storage.Store
+	state chatState
+	codes map[string]LinkCode
+	// floor is the offset saved before Seen was kept; everything below it
+	// was handled in order
+	floor int64
+	mutex sync.Mutex
+}
+
+func NewChats(store storage.Store) (*Chats, error) {
+	c := &Chats{
+		store: store,
+		state: chatState{Chats: make(map[string]*Chat)},
+		codes: make(map[string]LinkCode),
+	}
+	if err := store.Load(storeName, &c.state); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load telegram chats: %w", err)
+	}
+	if c.state.Chats == nil {
+		c.state.Chats = make(map[string]*Chat)
+	}
+	if len(c.state.Seen) == 0 {
+		c.floor = c.state.Offset
+	}
+	log.Printf("TelegramChats: Loaded %d chats", len(c.state.Chats))
+	return c, nil
+}
+
+func (c *Chats) persist() error {
+	return c.store.Save(storeName, c.state)
+}
+
+func chatKey(chatID int64) string {
+	return strconv.FormatInt(chatID, 10)
+}
+
+// UserFor returns the user a chat talks for, registering the chat under a
+// fresh user the first time it's seen.
+func (c *Chats) UserFor(info ChatInfo) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if chat, exists := c.state.Chats[chatKey(info.ID)]; exists {
+		return chat.UserID, nil
+	}
+	chat := newChat(info, "telegram-"+chatKey(info.ID))
+	c.state.Chats[chatKey(info.ID)] = chat
+	if err := c.persist(); err != nil {
+		return "", err
+	}
+	log.Printf("TelegramChats: Registered chat %d as user %s", info.ID, chat.UserID)
+	return chat.UserID, nil
+}
+
+func newChat(info ChatInfo, userID string) *Chat {
+	title := info.Title
+	if title == "" {
+		title = info.FirstName
+	}
+	return &Chat{
+		ChatID:   info.ID,
+		UserID:   userID,
+		Username: info.Username,
+		Title:    title,
+		LinkedAt: time.Now(),
+	}
+}
+
+// NewLinkCode issues a one-time code that links the chat it's sent from to
+// userID.
+func (c *Chats) NewLinkCode(userID string) (LinkCode, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return LinkCode{}, err
+	}
+	code := LinkCode{
+		Code:      base32.StdEncoding.EncodeToString(raw),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(linkCodeTTL),
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.codes[code.Code] = code
+	return code, nil
+}
+
+// Claim links a chat to the user behind code. It reports false for unknown
+// or expired codes.
+func (c *Chats) Claim(info ChatInfo, code string) (string, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	link, exists := c.codes[code]
+	delete(c.codes, code)
+	if !exists || time.Now().After(link.ExpiresAt) {
+		return "", false, nil
+	}
+
+	c.state.Chats[chatKey(info.ID)] = newChat(info, link.UserID)
+	if err := c.persist(); err != nil {
+		return "", false, err
+	}
+	log.Printf("TelegramChats: Linked chat %d to user %s", info.ID, link.UserID)
+	return link.UserID, true, nil
+}
+
+// ForUser lists the chats linked to userID.
+func (c *Chats) ForUser(userID string) []Chat {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := make([]Chat, 0)
+	for _, chat := range c.state.Chats {
+		if chat.UserID == userID {
+			result = append(result, *chat)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LinkedAt.Before(result[j].LinkedAt)
+	})
+	return result
+}
+
+// Unlink forgets a chat of userID's. The chat gets a fresh user if it
+// writes again.
+func (c *Chats) Unlink(userID string, chatID int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	chat, exists := c.state.Chats[chatKey(chatID)]
+	if !exists || chat.UserID != userID {
+		return ErrNotFound
+	}
+	delete(c.state.Chats, chatKey(chatID))
+	if err := c.persist(); err != nil {
+		return err
+	}
+	log.Printf("TelegramChats: Unlinked chat %d from user %s", chatID, userID)
+	return nil
+}
+
+// Purge unlinks every chat of userID's and drops their link codes,
+// returning how many chats there were.
+func (c *Chats) Purge(userID string) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for code, link := range c.codes {
+		if link.UserID == userID {
+			delete(c.codes, code)
+		}
+	}
+	count := 0
+	for key, chat := range c.state.Chats {
+		if chat.UserID == userID {
+			delete(c.state.Chats, key)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if err := c.persist(); err != nil {
+		return 0, err
+	}
+	log.Printf("TelegramChats: Unlinked %d chats from user %s", count, userID)
+	return count, nil
+}
+
+// Count returns how many chats are linked to userID.
+func (c *Chats) Count(userID string) (int, error) {
+	return len(c.ForUser(userID)), nil
+}
+
+// Accept records an update as handled, reporting false if it already was.
+func (c *Chats) Accept(updateID int64) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if updateID < c.floor || updateID < c.state.Offset-seenWindow || slices.Contains(c.state.Seen, updateID) {
+		return false, nil
+	}
+	c.state.Seen = append(c.state.Seen, updateID)
+	if len(c.state.Seen) > seenWindow {
+		c.state.Seen = slices.Clone(c.state.Seen[len(c.state.Seen)-seenWindow:])
+	}
+	c.state.Offset = max(c.state.Offset, updateID+1)
+	return true, c.persist()
+}
+
+func (c *Chats) Offset() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.state.Offset
+}
+
+// Session returns the conversation session a chat continues, if any.
+func (c *Chats) Session(chatID int64) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if chat, exists := c.state.Chats[chatKey(chatID)]; exists {
+		return chat.SessionID
+	}
+	return ""
+}
+
+// SetSession records the session a chat continues; an empty ID makes the
+// next message start a new one.
+func (c *Chats) SetSession(chatID int64, sessionID string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	chat, exists := c.state.Chats[chatKey(chatID)]
+	if !exists || chat.SessionID == sessionID {
+		return nil
+	}
+	chat.SessionID = sessionID
+	return c.persist()
+}
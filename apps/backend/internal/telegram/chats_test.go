@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+func TestChatsAccept(t *testing.T) {
+	store := storage.NewMemoryStore()
+	chats, err := NewChats(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, updateID := range []int64{10, 12} {
+		if fresh, err := chats.Accept(updateID); !fresh || err != nil {
+			t.Fatalf("Accept(%d) = %v, %v", updateID, fresh, err)
+		}
+	}
+	restarted, err := NewChats(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		updateID  int64
+		wantFresh bool
+	}{
+		{"retry", 12, false},
+		{"arrived after a later one", 11, true},
+		{"retry of the late one", 11, false},
+		{"next", 13, true},
+		{"older than the window", 12 - seenWindow, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fresh, err := restarted.Accept(tt.updateID); fresh != tt.wantFresh || err != nil {
+				t.Fatalf("Accept(%d) = %v, %v, want %v", tt.updateID, fresh, err, tt.wantFresh)
+			}
+		})
+	}
+	if offset := restarted.Offset(); offset != 14 {
+		t.Fatalf("Offset = %d, want 14", offset)
+	}
+}
+
+func TestChatsAcceptLegacyOffset(t *testing.T) {
+	store := storage.NewMemoryStore()
+	// Saved before recent update IDs were kept: everything below the offset
+	// was handled
+	if err := store.Save(storeName, chatState{Offset: 50, Chats: map[string]*Chat{}}); err != nil {
+		t.Fatal(err)
+	}
+	chats, err := NewChats(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh, _ := chats.Accept(49); fresh {
+		t.Fatal("update below the saved offset was accepted")
+	}
+	if fresh, _ := chats.Accept(50); !fresh {
+		t.Fatal("update at the saved offset was refused")
+	}
+}
@@ -0,0 +1,65 @@
+package textutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	wordPattern = regexp.MustCompile(`[a-z0-9']+`)
+	stopWords   = map[string]bool{
+		"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "about": true,
+		"for": true, "with": true, "this": true, "that": true, "had": true, "have": true,
+		"idea": true, "cool": true, "some": true, "my": true, "i": true, "to": true, "of": true,
+		"in": true, "on": true, "is": true, "it": true, "be": true, "maybe": true, "should": true,
+		"want": true, "was": true, "were": true, "today": true, "just": true, "again": true,
+		"did": true, "get": true, "got": true, "more": true, "really": true, "very": true,
+	}
+)
+
+// Keywords returns the lowercase, de-duplicated content words of text.
+func Keywords(text string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if len(word) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		result = append(result, word)
+	}
+	return result
+}
+
+// Overlap counts the words of a that also appear in b. Words also match on a
+// common stem, so "meditate" matches "meditated" and "meditation".
+func Overlap(a, b []string) int {
+	count := 0
+	for _, x := range a {
+		for _, y := range b {
+			if Related(x, y) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Related reports whether two lowercase words are equal or share a stem of
+// at least five letters (or four when one word is the stem itself).
+func Related(x, y string) bool {
+	if x == y {
+		return true
+	}
+	p := sharedPrefix(x, y)
+	return p >= 5 || (p >= 4 && (p == len(x) || p == len(y)))
+}
+
+func sharedPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
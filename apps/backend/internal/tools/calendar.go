@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type calendarTool struct {
+	service calendar.CalendarService
+	locator calendar.Locator
+}
+
+func NewCalendarTool(service calendar.CalendarService, locator calendar.Locator) Tool {
+	return &calendarTool{
+		service: service,
+		locator: locator,
+	}
+}
+
+func (t *calendarTool) Name() string {
+	return "calendar"
+}
+
+func (t *calendarTool) Description() string {
+	return "Looks up the user's imported calendar: what's on today, tomorrow, this week or a given weekday, and when their next meeting is. Use when the user asks about their schedule or upcoming events."
+}
+
+func (t *calendarTool) Spec() Spec {
+	return Spec{Outputs: []string{DataSchedule}}
+}
+
+func (t *calendarTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *calendarTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	loc := time.UTC
+	if t.locator != nil {
+		userLoc, err := t.locator.Location(userID)
+		if err != nil {
+			return "", err
+		}
+		loc = userLoc
+	}
+
+	span := calendar.ParseRange(input, time.Now(), loc)
+	log.Printf("CalendarTool: Looking up %s for input: %s", span.Label, input)
+
+	occurrences, err := t.service.Upcoming(userID, span.From, span.To)
+	if err != nil {
+		return "", err
+	}
+	if len(occurrences) == 0 {
+		if span.NextOnly {
+			return "Nothing on your calendar in the next 30 days", nil
+		}
+		return fmt.Sprintf("Nothing on your calendar for %s", span.Label), nil
+	}
+	if span.NextOnly {
+		return "Next up: " + calendar.Describe(occurrences[0], loc), nil
+	}
+
+	lines := make([]string, len(occurrences))
+	for i, occurrence := range occurrences {
+		lines[i] = "- " + calendar.Describe(occurrence, loc)
+	}
+	return fmt.Sprintf("%d events for %s:\n%s", len(occurrences), span.Label, strings.Join(lines, "\n")), nil
+}
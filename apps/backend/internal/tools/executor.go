@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// ExecutorConfig bounds tool execution.
+type ExecutorConfig struct {
+	// Workers is how many tools run at once.
+	Workers int
+	// Timeout is how long a single tool may take.
+	Timeout time.Duration
+}
+
+var DefaultExecutorConfig = ExecutorConfig{
+	Workers: 4,
+	Timeout: 30 * time.Second,
+}
+
+// Call is one tool to run and the input to run it with.
+type Call struct {
+	Tool  string
+	Input string
+	// Upstream is what the tools this one depends on produced.
+	Upstream []Upstream
+}
+
+// ErrAbandoned is returned by Commit once the executor has given up on the
+// call, so the tool must not change anything.
+var ErrAbandoned = errors.New("tool call abandoned")
+
+// gate records whether a running call has started changing state or has
+// been given up on; whichever happens first wins.
+type gate struct {
+	mutex     sync.Mutex
+	committed bool
+	abandoned bool
+}
+
+type gateKey struct{}
+
+// Commit is called by a tool right before it changes state. It fails once
+// the call has passed its deadline, so a tool that timed out leaves nothing
+// behind. Once it succeeds the executor waits for the tool and reports what
+// it did, instead of a timeout for a change that went through.
+func Commit(ctx context.Context) error {
+	g, ok := ctx.Value(gateKey{}).(*gate)
+	if !ok {
+		return ctx.Err()
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.abandoned {
+		return ErrAbandoned
+	}
+	if err := ctx.Err(); err != nil {
+		g.abandoned = true
+		return err
+	}
+	g.committed = true
+	return nil
+}
+
+// abandon gives up on the call unless it has already committed, and
+// reports whether it did.
+func (g *gate) abandon() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.committed {
+		return false
+	}
+	g.abandoned = true
+	return true
+}
+
+// Executor runs tools concurrently. A tool that panics or runs past the
+// timeout fails on its own without affecting the others. Tools get a
+// context that is cancelled at the timeout, and those that change state
+// call Commit first, so a call that timed out has no side effects.
+type Executor struct {
+	service ToolService
+	config  ExecutorConfig
+}
+
+func NewExecutor(service ToolService, config ExecutorConfig) *Executor {
+	if config.Workers <= 0 {
+		config.Workers = DefaultExecutorConfig.Workers
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultExecutorConfig.Timeout
+	}
+	return &Executor{service: service, config: config}
+}
+
+// Run executes the calls on behalf of userID and returns one execution per
+// call, in the order of calls whatever order they finish in. started, when
+// set, is called as each call begins and must be safe for concurrent use.
+func (e *Executor) Run(userID string, calls []Call, started func(index int)) []types.ToolExecution {
+	executions := make([]types.ToolExecution, len(calls))
+	slots := make(chan struct{}, e.config.Workers)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if started != nil {
+				started(i)
+			}
+			executions[i] = e.execute(userID, call)
+		}()
+	}
+	wg.Wait()
+	return executions
+}
+
+type outcome struct {
+	output string
+	err    error
+}
+
+func (e *Executor) execute(userID string, call Call) types.ToolExecution {
+	start := time.Now()
+	execution := types.ToolExecution{ToolName: call.Tool, Input: call.Input}
+
+	tool := e.service.GetTool(call.Tool)
+	if tool == nil {
+		log.Printf("Warning: Tool '%s' not found, skipping", call.Tool)
+		execution.ExecutionTime = time.Since(start).String()
+		execution.Status = types.ToolStatusSkipped
+		execution.Error = "Tool not found"
+		return execution
+	}
+
+	g := &gate{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), gateKey{}, g), e.config.Timeout)
+	defer cancel()
+
+	// Buffered, so a tool that times out can still deliver and exit
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Warning: Tool '%s' panicked: %v\n%s", call.Tool, r, debug.Stack())
+				done <- outcome{err: fmt.Errorf("tool panicked: %v", r)}
+			}
+		}()
+		output, err := RunWith(ctx, tool, userID, call.Input, call.Upstream)
+		done <- outcome{output: output, err: err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		if g.abandon() {
+			log.Printf("Warning: Tool '%s' timed out after %s", call.Tool, e.config.Timeout)
+			execution.ExecutionTime = time.Since(start).String()
+			execution.Status = types.ToolStatusTimeout
+			execution.Error = fmt.Sprintf("Timed out after %s", e.config.Timeout)
+			return execution
+		}
+		// The tool is already changing state, so the change is reported
+		// rather than hidden behind a timeout
+		log.Printf("Warning: Tool '%s' passed the %s timeout while committing, waiting for it", call.Tool, e.config.Timeout)
+		result = <-done
+	}
+
+	execution.ExecutionTime = time.Since(start).String()
+	if result.err != nil {
+		log.Printf("Warning: Tool '%s' execution failed: %v", call.Tool, result.err)
+		execution.Status = types.ToolStatusError
+		execution.Error = result.err.Error()
+		return execution
+	}
+	execution.Status = types.ToolStatusSuccess
+	execution.Output = result.output
+	return execution
+}
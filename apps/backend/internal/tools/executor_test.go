@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// fakeTool runs fn for a user, or echoes its input.
+type fakeTool struct {
+	name string
+	spec Spec
+	fn   func(ctx context.Context, input string, upstream []Upstream) (string, error)
+}
+
+func (t *fakeTool) Name() string        { return t.name }
+func (t *fakeTool) Description() string { return t.name }
+func (t *fakeTool) Spec() Spec          { return t.spec }
+
+func (t *fakeTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *fakeTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	return t.ExecuteWith(ctx, userID, input, nil)
+}
+
+func (t *fakeTool) ExecuteWith(ctx context.Context, userID, input string, upstream []Upstream) (string, error) {
+	if t.fn == nil {
+		return input, nil
+	}
+	return t.fn(ctx, input, upstream)
+}
+
+func newExecutor(timeout time.Duration, tools ...Tool) *Executor {
+	service := &toolService{tools: make(map[string]Tool)}
+	for _, tool := range tools {
+		service.RegisterTool(tool)
+	}
+	return NewExecutor(service, ExecutorConfig{Workers: 2, Timeout: timeout})
+}
+
+func TestExecutorRun(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	tests := []struct {
+		name       string
+		tool       *fakeTool
+		wantStatus string
+		wantOutput string
+	}{
+		{"success", &fakeTool{}, types.ToolStatusSuccess, "input"},
+		{"error", &fakeTool{fn: func(context.Context, string, []Upstream) (string, error) {
+			return "", errors.New("broken")
+		}}, types.ToolStatusError, ""},
+		{"panic", &fakeTool{fn: func(context.Context, string, []Upstream) (string, error) {
+			panic("boom")
+		}}, types.ToolStatusError, ""},
+		{"ignores the deadline", &fakeTool{fn: func(context.Context, string, []Upstream) (string, error) {
+			time.Sleep(4 * timeout)
+			return "late", nil
+		}}, types.ToolStatusTimeout, ""},
+		{"committed before the deadline", &fakeTool{fn: func(ctx context.Context, _ string, _ []Upstream) (string, error) {
+			if err := Commit(ctx); err != nil {
+				return "", err
+			}
+			time.Sleep(2 * timeout)
+			return "written", nil
+		}}, types.ToolStatusSuccess, "written"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tool.name = "fake"
+			executor := newExecutor(timeout, tt.tool)
+			executions := executor.Run("alice", []Call{{Tool: "fake", Input: "input"}}, nil)
+			if got := executions[0]; got.Status != tt.wantStatus || got.Output != tt.wantOutput {
+				t.Fatalf("execution = %s %q (%s), want %s %q", got.Status, got.Output, got.Error, tt.wantStatus, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestExecutorTimedOutToolChangesNothing(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	var mutex sync.Mutex
+	var written []string
+	result := make(chan error, 1)
+	slow := &fakeTool{name: "slow", fn: func(ctx context.Context, input string, _ []Upstream) (string, error) {
+		// Works past the deadline without watching ctx, then tries to write
+		time.Sleep(3 * timeout)
+		err := Commit(ctx)
+		if err == nil {
+			mutex.Lock()
+			written = append(written, input)
+			mutex.Unlock()
+		}
+		result <- err
+		return input, err
+	}}
+
+	executions := newExecutor(timeout, slow).Run("alice", []Call{{Tool: "slow", Input: "buy milk"}}, nil)
+	if executions[0].Status != types.ToolStatusTimeout {
+		t.Fatalf("status = %s, want timeout", executions[0].Status)
+	}
+	if err := <-result; err == nil {
+		t.Fatal("Commit succeeded after the call timed out")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(written) != 0 {
+		t.Fatalf("timed out tool wrote %v", written)
+	}
+}
+
+func TestExecutorKeepsCallOrder(t *testing.T) {
+	delays := map[string]time.Duration{"a": 30 * time.Millisecond, "b": 0, "c": 10 * time.Millisecond}
+	var tools []Tool
+	var calls []Call
+	for _, name := range []string{"a", "b", "c"} {
+		delay := delays[name]
+		tools = append(tools, &fakeTool{name: name, fn: func(_ context.Context, input string, _ []Upstream) (string, error) {
+			time.Sleep(delay)
+			return input, nil
+		}})
+		calls = append(calls, Call{Tool: name, Input: name})
+	}
+	calls = append(calls, Call{Tool: "missing"})
+
+	executions := newExecutor(time.Second, tools...).Run("alice", calls, nil)
+	for i, name := range []string{"a", "b", "c"} {
+		if executions[i].ToolName != name || executions[i].Output != name {
+			t.Fatalf("execution %d = %s %q, want %s", i, executions[i].ToolName, executions[i].Output, name)
+		}
+	}
+	if executions[3].Status != types.ToolStatusSkipped {
+		t.Fatalf("unknown tool status = %s, want skipped", executions[3].Status)
+	}
+}
+
+func TestCommitWithoutExecutor(t *testing.T) {
+	if err := Commit(context.Background()); err != nil {
+		t.Fatalf("Commit outside the executor: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Commit(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Commit with a cancelled context = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type goalsTool struct {
+	service     goals.GoalService
+	interpreter *goals.Interpreter
+	locator     calendar.Locator
+}
+
+func NewGoalsTool(service goals.GoalService, interpreter *goals.Interpreter, locator calendar.Locator) Tool {
+	return &goalsTool{
+		service:     service,
+		interpreter: interpreter,
+		locator:     locator,
+	}
+}
+
+func (t *goalsTool) Name() string {
+	return "goals"
+}
+
+func (t *goalsTool) Description() string {
+	return "Tracks personal goals and the habits that support them: creates goals with motivation and milestones, adds habits, records check-ins and reports streaks. Use when the user states something they want to improve or reports progress on it."
+}
+
+func (t *goalsTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *goalsTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	loc := time.UTC
+	if t.locator != nil {
+		userLoc, err := t.locator.Location(userID)
+		if err != nil {
+			return "", err
+		}
+		loc = userLoc
+	}
+
+	active, err := t.service.List(userID, goals.StatusActive)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := t.interpreter.Interpret(input, active)
+	log.Printf("GoalsTool: Interpreted action '%s' from input: %s", cmd.Action, input)
+
+	switch cmd.Action {
+	case goals.ActionCreate:
+		goal := goals.Goal{Title: cmd.Title, Motivation: cmd.Motivation}
+		for _, title := range cmd.Milestones {
+			goal.Milestones = append(goal.Milestones, goals.Milestone{Title: title})
+		}
+		for _, habit := range cmd.Habits {
+			goal.Habits = append(goal.Habits, goals.Habit{Title: habit.Title, Frequency: habit.Frequency})
+		}
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		created, err := t.service.Create(userID, goal)
+		if err != nil {
+			return "", err
+		}
+		response := fmt.Sprintf("New goal: %s", created.Title)
+		if len(created.Milestones) > 0 || len(created.Habits) > 0 {
+			response += fmt.Sprintf(" (%d milestones, %d habits)", len(created.Milestones), len(created.Habits))
+		}
+		return response, nil
+
+	case goals.ActionAddMilestone:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		goal, err := t.service.AddMilestone(userID, cmd.GoalID, cmd.Title)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added milestone \"%s\" to %s", cmd.Title, goal.Title), nil
+
+	case goals.ActionCompleteMilestone:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		goal, err := t.service.CompleteMilestone(userID, cmd.GoalID, cmd.MilestoneID)
+		if err != nil {
+			return "", err
+		}
+		progress := goals.ComputeProgress(*goal, time.Now(), loc)
+		return fmt.Sprintf("Milestone done for %s (%d/%d)", goal.Title, progress.MilestonesDone, progress.MilestonesTotal), nil
+
+	case goals.ActionAddHabit:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		goal, err := t.service.AddHabit(userID, cmd.GoalID, cmd.Title, goals.FrequencyDaily)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added habit \"%s\" to %s", cmd.Title, goal.Title), nil
+
+	case goals.ActionCheckIn:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		goal, err := t.service.CheckIn(userID, cmd.GoalID, goals.CheckIn{HabitID: cmd.HabitID, Note: cmd.Note})
+		if err != nil {
+			return "", err
+		}
+		progress := goals.ComputeProgress(*goal, time.Now(), loc)
+		for _, habit := range progress.Habits {
+			if habit.HabitID == cmd.HabitID {
+				return fmt.Sprintf("Checked in on %s: %d-%s streak", habit.Title, habit.CurrentStreak, streakUnit(habit.Frequency)), nil
+			}
+		}
+		return fmt.Sprintf("Checked in on %s: %d-day streak", goal.Title, progress.CheckInStreak), nil
+
+	case goals.ActionList:
+		if len(active) == 0 {
+			return "No active goals", nil
+		}
+		lines := make([]string, len(active))
+		for i, goal := range active {
+			progress := goals.ComputeProgress(goal, time.Now(), loc)
+			lines[i] = fmt.Sprintf("- %s (%d/%d milestones, %d-day check-in streak)", goal.Title, progress.MilestonesDone, progress.MilestonesTotal, progress.CheckInStreak)
+		}
+		return "Your goals:\n" + strings.Join(lines, "\n"), nil
+	}
+
+	return "", fmt.Errorf("unsupported goal action %q", cmd.Action)
+}
+
+func streakUnit(frequency goals.Frequency) string {
+	if frequency == goals.FrequencyWeekly {
+		return "week"
+	}
+	return "day"
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// Kinds of data tools pass to one another.
+const (
+	// DataSchedule is the user's upcoming calendar events.
+	DataSchedule = "schedule"
+)
+
+// Spec declares how a tool relates to others selected for the same input.
+type Spec struct {
+	// Inputs are kinds of data the tool makes use of when another selected
+	// tool produces them.
+	Inputs []string
+	// Outputs are kinds of data the tool produces.
+	Outputs []string
+	// DependsOn names tools that must run first when they are selected too,
+	// whether or not they pass anything along.
+	DependsOn []string
+}
+
+// DeclaredTool is implemented by tools that depend on or feed others.
+// Tools without a Spec are independent.
+type DeclaredTool interface {
+	Tool
+	Spec() Spec
+}
+
+// Upstream is the output of a tool that ran before, with the kinds of data
+// it provides to the tool it is passed to.
+type Upstream struct {
+	Tool   string
+	Data   []string
+	Output string
+}
+
+// ChainedTool is implemented by tools that use the output of the tools they
+// depend on.
+type ChainedTool interface {
+	Tool
+	ExecuteWith(ctx context.Context, userID, input string, upstream []Upstream) (string, error)
+}
+
+func SpecOf(tool Tool) Spec {
+	if declared, ok := tool.(DeclaredTool); ok {
+		return declared.Spec()
+	}
+	return Spec{}
+}
+
+// RunWith executes tool on behalf of userID with the outputs of the tools
+// it depends on. Tools that can't use them just run.
+func RunWith(ctx context.Context, tool Tool, userID, input string, upstream []Upstream) (string, error) {
+	if chained, ok := tool.(ChainedTool); ok && len(upstream) > 0 {
+		return chained.ExecuteWith(ctx, userID, input, upstream)
+	}
+	return Run(ctx, tool, userID, input)
+}
+
+// Graph orders calls by their tools' dependencies. Levels run one after
+// the other; the calls within a level are independent.
+type Graph struct {
+	calls  []Call
+	edges  []types.ToolEdge
+	levels [][]int
+}
+
+// BuildGraph links call j after call i when j's tool depends on i's by
+// name or uses data i's tool outputs. Dependencies between calls of the
+// same tool are ignored, and cycles are broken in favour of the earlier
+// call, so every call runs exactly once.
+func (e *Executor) BuildGraph(calls []Call) *Graph {
+	specs := make([]Spec, len(calls))
+	for i, call := range calls {
+		if tool := e.service.GetTool(call.Tool); tool != nil {
+			specs[i] = SpecOf(tool)
+		}
+	}
+
+	g := &Graph{calls: calls}
+	for to := range calls {
+		for from := range calls {
+			if from == to || calls[from].Tool == calls[to].Tool {
+				continue
+			}
+			var data []string
+			for _, kind := range specs[to].Inputs {
+				if slices.Contains(specs[from].Outputs, kind) {
+					data = append(data, kind)
+				}
+			}
+			if len(data) > 0 || slices.Contains(specs[to].DependsOn, calls[from].Tool) {
+				g.edges = append(g.edges, types.ToolEdge{From: from, To: to, Data: data})
+			}
+		}
+	}
+
+	// Kahn's algorithm, one level at a time
+	placed := make([]bool, len(calls))
+	for remaining := len(calls); remaining > 0; {
+		var level []int
+		for i := range calls {
+			if !placed[i] && g.ready(i, placed) {
+				level = append(level, i)
+			}
+		}
+		if len(level) == 0 {
+			// A cycle: run the earliest waiting call and forget the
+			// dependencies that would have held it back
+			first := slices.Index(placed, false)
+			log.Printf("Warning: Tools form a dependency cycle, running '%s' first", calls[first].Tool)
+			g.edges = slices.DeleteFunc(g.edges, func(edge types.ToolEdge) bool {
+				return edge.To == first && !placed[edge.From]
+			})
+			level = []int{first}
+		}
+		for _, i := range level {
+			placed[i] = true
+		}
+		remaining -= len(level)
+		g.levels = append(g.levels, level)
+	}
+	return g
+}
+
+// ready reports whether every call i depends on has been placed.
+func (g *Graph) ready(i int, placed []bool) bool {
+	for _, edge := range g.edges {
+		if edge.To == i && !placed[edge.From] {
+			return false
+		}
+	}
+	return true
+}
+
+// RunGraph executes the graph level by level, passing each call the
+// successful outputs of the calls it depends on. Executions are returned
+// in the order of the calls, with the graph as it ran.
+func (e *Executor) RunGraph(userID string, g *Graph, started func(index int)) ([]types.ToolExecution, *types.ToolGraph) {
+	executions := make([]types.ToolExecution, len(g.calls))
+	nodes := make([]types.ToolNode, len(g.calls))
+	for depth, level := range g.levels {
+		calls := make([]Call, len(level))
+		for n, i := range level {
+			calls[n] = g.calls[i]
+			calls[n].Upstream = g.upstream(i, executions)
+		}
+		results := e.Run(userID, calls, func(n int) {
+			if started != nil {
+				started(level[n])
+			}
+		})
+		for n, i := range level {
+			executions[i] = results[n]
+			nodes[i] = types.ToolNode{Index: i, ToolName: g.calls[i].Tool, Level: depth, Status: results[n].Status}
+		}
+	}
+
+	edges := make([]types.ToolEdge, len(g.edges))
+	for n, edge := range g.edges {
+		edge.Passed = len(edge.Data) > 0 && executions[edge.From].Status == types.ToolStatusSuccess
+		edges[n] = edge
+	}
+	return executions, &types.ToolGraph{Nodes: nodes, Edges: edges, Levels: g.levels}
+}
+
+// upstream collects what the calls i depends on produced for it. Failed
+// calls pass nothing, and i runs without their output.
+func (g *Graph) upstream(i int, executions []types.ToolExecution) []Upstream {
+	var upstream []Upstream
+	for _, edge := range g.edges {
+		if edge.To != i || len(edge.Data) == 0 || executions[edge.From].Status != types.ToolStatusSuccess {
+			continue
+		}
+		upstream = append(upstream, Upstream{Tool: g.calls[edge.From].Tool, Data: edge.Data, Output: executions[edge.From].Output})
+	}
+	return upstream
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+func TestBuildGraph(t *testing.T) {
+	tools := []Tool{
+		&fakeTool{name: "calendar", spec: Spec{Outputs: []string{DataSchedule}}},
+		&fakeTool{name: "reminders", spec: Spec{Inputs: []string{DataSchedule}}},
+		&fakeTool{name: "after-reminders", spec: Spec{DependsOn: []string{"reminders"}}},
+		&fakeTool{name: "ping", spec: Spec{DependsOn: []string{"pong"}}},
+		&fakeTool{name: "pong", spec: Spec{DependsOn: []string{"ping"}}},
+		&fakeTool{name: "time"},
+	}
+	executor := newExecutor(time.Second, tools...)
+
+	tests := []struct {
+		name       string
+		calls      []string
+		wantLevels [][]int
+	}{
+		{"independent", []string{"time", "calendar"}, [][]int{{0, 1}}},
+		{"data dependency", []string{"reminders", "calendar"}, [][]int{{1}, {0}}},
+		{"chain", []string{"after-reminders", "reminders", "calendar"}, [][]int{{2}, {1}, {0}}},
+		{"dependency not selected", []string{"reminders", "time"}, [][]int{{0, 1}}},
+		{"same tool twice", []string{"reminders", "reminders"}, [][]int{{0, 1}}},
+		{"cycle runs the earlier call first", []string{"pong", "ping", "time"}, [][]int{{2}, {0}, {1}}},
+		{"unknown tool", []string{"missing", "calendar"}, [][]int{{0, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]Call, len(tt.calls))
+			for i, name := range tt.calls {
+				calls[i] = Call{Tool: name}
+			}
+			g := executor.BuildGraph(calls)
+			if !reflect.DeepEqual(g.levels, tt.wantLevels) {
+				t.Fatalf("levels = %v, want %v", g.levels, tt.wantLevels)
+			}
+		})
+	}
+}
+
+func TestRunGraphPassesUpstream(t *testing.T) {
+	upstreamOf := func(_ context.Context, input string, upstream []Upstream) (string, error) {
+		var outputs []string
+		for _, up := range upstream {
+			outputs = append(outputs, up.Tool+"="+up.Output)
+		}
+		return input + "[" + strings.Join(outputs, ",") + "]", nil
+	}
+	failing := func(context.Context, string, []Upstream) (string, error) {
+		return "", errors.New("unavailable")
+	}
+
+	tests := []struct {
+		name       string
+		calendar   func(context.Context, string, []Upstream) (string, error)
+		wantOutput string
+		wantPassed bool
+	}{
+		{"successful output is passed", nil, "remind[calendar=dentist at 3]", true},
+		{"failed output is not", failing, "remind[]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newExecutor(time.Second,
+				&fakeTool{name: "calendar", spec: Spec{Outputs: []string{DataSchedule}}, fn: tt.calendar},
+				&fakeTool{name: "reminders", spec: Spec{Inputs: []string{DataSchedule}}, fn: upstreamOf},
+			)
+			g := executor.BuildGraph([]Call{{Tool: "reminders", Input: "remind"}, {Tool: "calendar", Input: "dentist at 3"}})
+
+			executions, graph := executor.RunGraph("alice", g, nil)
+			if executions[0].Output != tt.wantOutput {
+				t.Fatalf("reminders output = %q, want %q", executions[0].Output, tt.wantOutput)
+			}
+			if len(graph.Edges) != 1 || graph.Edges[0].Passed != tt.wantPassed {
+				t.Fatalf("edges = %+v, want one with passed %v", graph.Edges, tt.wantPassed)
+			}
+			if graph.Nodes[1].Level != 0 || graph.Nodes[0].Level != 1 {
+				t.Fatalf("nodes = %+v, want calendar on level 0 and reminders on level 1", graph.Nodes)
+			}
+			if tt.calendar == nil && executions[1].Status != types.ToolStatusSuccess {
+				t.Fatalf("calendar status = %s", executions[1].Status)
+			}
+		})
+	}
+}
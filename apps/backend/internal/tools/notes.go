@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type notesTool struct {
+	service   notes.NoteService
+	organizer *notes.Organizer
+}
+
+func NewNotesTool(service notes.NoteService, organizer *notes.Organizer) Tool {
+	return &notesTool{
+		service:   service,
+		organizer: organizer,
+	}
+}
+
+func (t *notesTool) Name() string {
+	return "notes"
+}
+
+func (t *notesTool) Description() string {
+	return "Captures ideas and notes, files them into project collections (e.g. creative projects) and links them to related notes. Use when the user shares an idea, thought or something worth remembering."
+}
+
+func (t *notesTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *notesTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+
+	if isCollectionsQuery(input) {
+		return t.listCollections(userID)
+	}
+
+	// Placing the note can take a while with the LLM; nothing is stored
+	// until it is done
+	placement, err := t.organizer.Place(userID, input)
+	if err != nil {
+		return "", err
+	}
+	if err := Commit(ctx); err != nil {
+		return "", err
+	}
+	note, collection, err := t.organizer.File(userID, input, placement)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("NotesTool: Captured note %s into collection '%s'", note.ID, collection.Name)
+
+	response := fmt.Sprintf("Saved \"%s\" to %s", note.Title, collection.Name)
+	if len(note.RelatedIDs) > 0 {
+		titles := make([]string, 0, len(note.RelatedIDs))
+		for _, id := range note.RelatedIDs {
+			if related, err := t.service.Get(userID, id); err == nil {
+				titles = append(titles, related.Title)
+			}
+		}
+		response += fmt.Sprintf(" (related: %s)", strings.Join(titles, "; "))
+	}
+	return response, nil
+}
+
+func (t *notesTool) listCollections(userID string) (string, error) {
+	collections, err := t.service.Collections(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(collections) == 0 {
+		return "No note collections yet", nil
+	}
+
+	lines := make([]string, len(collections))
+	for i, collection := range collections {
+		lines[i] = fmt.Sprintf("- %s (%d notes)", collection.Name, collection.NoteCount)
+	}
+	return "Your collections:\n" + strings.Join(lines, "\n"), nil
+}
+
+func isCollectionsQuery(input string) bool {
+	lower := strings.ToLower(input)
+	for _, phrase := range []string{"show my notes", "list my notes", "show my ideas", "list my ideas", "my collections", "my projects"} {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
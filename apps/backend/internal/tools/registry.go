@@ -1,8 +1,17 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"log"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/calendar"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/llm"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
 )
 
 type Tool interface {
@@ -11,23 +20,74 @@ type Tool interface {
 	Description() string
 }
 
+// UserTool is implemented by tools that keep per-user state. Execute acts
+// on the default user; ExecuteFor acts on the given one. Tools that change
+// state call Commit with ctx right before they do.
+type UserTool interface {
+	Tool
+	ExecuteFor(ctx context.Context, userID, input string) (string, error)
+}
+
+// Run executes tool on behalf of userID. Stateless tools ignore the user.
+func Run(ctx context.Context, tool Tool, userID, input string) (string, error) {
+	if userTool, ok := tool.(UserTool); ok {
+		return userTool.ExecuteFor(ctx, userID, input)
+	}
+	return tool.Execute(input)
+}
+
 type ToolService interface {
 	GetTool(name string) Tool
 	RegisterTool(tool Tool)
 	ListTools() []Tool
 }
 
+// Services holds the domain services that stateful tools are built on.
+// Tools whose service is nil are not registered.
+type Services struct {
+	LLM       llm.LLMService
+	Tasks     tasks.TaskService
+	Notes     notes.NoteService
+	Goals     goals.GoalService
+	Reminders reminders.ReminderService
+	Calendar  calendar.CalendarService
+	Search    *search.Index
+}
+
 type toolService struct {
 	tools map[string]Tool
 }
 
-func NewToolService() ToolService {
+func NewToolService(services Services) ToolService {
 	s := &toolService{
 		tools: make(map[string]Tool),
 	}
-	
+
 	s.RegisterTool(&EchoTool{})
 	s.RegisterTool(NewTimeTool())
+	if services.Tasks != nil {
+		s.RegisterTool(NewTasksTool(services.Tasks, tasks.NewParser(services.LLM)))
+	}
+	if services.Notes != nil {
+		s.RegisterTool(NewNotesTool(services.Notes, notes.NewOrganizer(services.Notes, services.LLM)))
+	}
+	// The user's timezone is kept with their reminders
+	var locator calendar.Locator
+	if services.Reminders != nil {
+		locator = services.Reminders
+	}
+	if services.Goals != nil {
+		s.RegisterTool(NewGoalsTool(services.Goals, goals.NewInterpreter(services.LLM), locator))
+	}
+	if services.Reminders != nil {
+		s.RegisterTool(NewRemindersTool(services.Reminders, reminders.NewParser(services.LLM)))
+	}
+	if services.Calendar != nil {
+		s.RegisterTool(NewCalendarTool(services.Calendar, locator))
+	}
+	if services.Search != nil {
+		s.RegisterTool(NewSearchTool(services.Search))
+	}
 	return s
 }
 
@@ -68,4 +128,4 @@ func (t *EchoTool) Name() string {
 
 func (t *EchoTool) Description() string {
 	return "Echoes back the input with a prefix. Useful for testing and simple responses."
-}
\ No newline at end of file
+}
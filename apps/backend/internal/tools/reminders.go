@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type remindersTool struct {
+	service reminders.ReminderService
+	parser  *reminders.Parser
+}
+
+func NewRemindersTool(service reminders.ReminderService, parser *reminders.Parser) Tool {
+	return &remindersTool{
+		service: service,
+		parser:  parser,
+	}
+}
+
+func (t *remindersTool) Name() string {
+	return "reminders"
+}
+
+func (t *remindersTool) Description() string {
+	return "Sets, lists and cancels reminders. Understands relative and absolute times (\"in 20 minutes\", \"tomorrow at 3\", \"before my meeting friday at 10am\") and recurrences (\"every weekday at 8:30\"). Use when the user asks to be reminded of something at a particular time. When the time is relative to a calendar event, select calendar too so the event can be looked up."
+}
+
+// Spec has reminders run after calendar, so reminders relative to events
+// can be timed against the user's schedule.
+func (t *remindersTool) Spec() Spec {
+	return Spec{Inputs: []string{DataSchedule}}
+}
+
+func (t *remindersTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *remindersTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	return t.ExecuteWith(ctx, userID, input, nil)
+}
+
+func (t *remindersTool) ExecuteWith(ctx context.Context, userID, input string, upstream []Upstream) (string, error) {
+	loc, err := t.service.Location(userID)
+	if err != nil {
+		return "", err
+	}
+
+	var schedule []string
+	for _, up := range upstream {
+		if slices.Contains(up.Data, DataSchedule) {
+			schedule = append(schedule, up.Output)
+		}
+	}
+	cmd, err := t.parser.ParseWithSchedule(input, strings.Join(schedule, "\n"), time.Now(), loc)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("RemindersTool: Parsed action '%s' from input: %s", cmd.Action, input)
+
+	switch cmd.Action {
+	case reminders.ActionCreate:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		reminder, err := t.service.Create(userID, reminders.Reminder{
+			Message:    cmd.Message,
+			FireAt:     cmd.At,
+			Recurrence: cmd.Recurrence,
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Reminder set: " + formatReminder(*reminder, loc), nil
+
+	case reminders.ActionList:
+		scheduled, err := t.service.List(userID, reminders.StatusScheduled)
+		if err != nil {
+			return "", err
+		}
+		if len(scheduled) == 0 {
+			return "No upcoming reminders", nil
+		}
+		lines := make([]string, len(scheduled))
+		for i, reminder := range scheduled {
+			lines[i] = fmt.Sprintf("%d. %s", i+1, formatReminder(reminder, loc))
+		}
+		return fmt.Sprintf("%d upcoming reminders:\n%s", len(scheduled), strings.Join(lines, "\n")), nil
+
+	case reminders.ActionCancel:
+		scheduled, err := t.service.List(userID, reminders.StatusScheduled)
+		if err != nil {
+			return "", err
+		}
+		match := reminders.FindByRef(scheduled, cmd.Ref)
+		if match == nil {
+			return "", fmt.Errorf("no reminder matches %q", cmd.Ref)
+		}
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		if _, err := t.service.Cancel(userID, match.ID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Cancelled reminder: %s", match.Message), nil
+	}
+
+	return "", fmt.Errorf("unsupported reminder action %q", cmd.Action)
+}
+
+func formatReminder(reminder reminders.Reminder, loc *time.Location) string {
+	result := fmt.Sprintf("%s — %s", reminder.Message, reminder.FireAt.In(loc).Format("Mon Jan 2, 3:04 PM MST"))
+	if reminder.Recurrence != nil {
+		result += " (" + reminder.Recurrence.Describe() + ")"
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/search"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+// searchResultLimit caps how many matches the tool reports back.
+const searchResultLimit = 5
+
+type searchTool struct {
+	index *search.Index
+}
+
+func NewSearchTool(index *search.Index) Tool {
+	return &searchTool{index: index}
+}
+
+func (t *searchTool) Name() string {
+	return search.ToolName
+}
+
+func (t *searchTool) Description() string {
+	return "Searches everything the user has said before, their notes, tasks and profile. Use when the user asks when they mentioned something, what they said about a topic, or to recall an earlier idea."
+}
+
+func (t *searchTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *searchTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	results, err := t.index.Search(userID, search.Query{Text: input, Limit: searchResultLimit})
+	if errors.Is(err, search.ErrInvalid) {
+		return "Nothing to search for — tell me what to look for", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Printf("SearchTool: %d matches for: %s", len(results), input)
+
+	if len(results) == 0 {
+		return "No matches found", nil
+	}
+	lines := make([]string, len(results))
+	for i, result := range results {
+		lines[i] = "• " + formatSearchResult(result)
+	}
+	return fmt.Sprintf("Found %d matches:\n%s", len(results), strings.Join(lines, "\n")), nil
+}
+
+func formatSearchResult(result search.Result) string {
+	label := string(result.Kind)
+	if result.At != nil {
+		label += ", " + result.At.Format("Jan 2, 2006")
+	}
+	text := result.Snippet
+	if result.Title != "" && !strings.HasPrefix(text, result.Title) {
+		text = result.Title + ": " + text
+	}
+	return fmt.Sprintf("[%s] %s", label, text)
+}
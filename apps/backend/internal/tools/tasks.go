@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/types"
+)
+
+type tasksTool struct {
+	service tasks.TaskService
+	parser  *tasks.Parser
+}
+
+func NewTasksTool(service tasks.TaskService, parser *tasks.Parser) Tool {
+	return &tasksTool{
+		service: service,
+		parser:  parser,
+	}
+}
+
+func (t *tasksTool) Name() string {
+	return "tasks"
+}
+
+func (t *tasksTool) Description() string {
+	return "Manages the user's task list: creates tasks (with due dates, tags and priority), completes them, lists open tasks and changes their priority. Use when the user mentions something they need to do."
+}
+
+func (t *tasksTool) Execute(input string) (string, error) {
+	return t.ExecuteFor(context.Background(), types.DefaultUserID, input)
+}
+
+func (t *tasksTool) ExecuteFor(ctx context.Context, userID, input string) (string, error) {
+	cmd := t.parser.Parse(input, time.Now())
+	log.Printf("TasksTool: Parsed action '%s' from input: %s", cmd.Action, input)
+
+	switch cmd.Action {
+	case tasks.ActionCreate:
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		task, err := t.service.Create(userID, tasks.Task{
+			Title:    cmd.Title,
+			DueAt:    cmd.DueAt,
+			Tags:     cmd.Tags,
+			Priority: cmd.Priority,
+		})
+		if err != nil {
+			return "", err
+		}
+		return "Added task: " + formatTask(*task), nil
+
+	case tasks.ActionList:
+		tag := ""
+		if len(cmd.Tags) > 0 {
+			tag = cmd.Tags[0]
+		}
+		open, err := t.service.List(userID, tasks.Filter{Status: tasks.StatusOpen, Tag: tag})
+		if err != nil {
+			return "", err
+		}
+		if len(open) == 0 {
+			return "No open tasks", nil
+		}
+		lines := make([]string, len(open))
+		for i, task := range open {
+			lines[i] = fmt.Sprintf("%d. %s", i+1, formatTask(task))
+		}
+		return fmt.Sprintf("%d open tasks:\n%s", len(open), strings.Join(lines, "\n")), nil
+
+	case tasks.ActionComplete, tasks.ActionReprioritize:
+		open, err := t.service.List(userID, tasks.Filter{Status: tasks.StatusOpen})
+		if err != nil {
+			return "", err
+		}
+		match := tasks.FindByRef(open, cmd.Ref)
+		if match == nil {
+			return "", fmt.Errorf("no open task matches %q", cmd.Ref)
+		}
+
+		if cmd.Action == tasks.ActionComplete {
+			if err := Commit(ctx); err != nil {
+				return "", err
+			}
+			task, err := t.service.Complete(userID, match.ID)
+			if err != nil {
+				return "", err
+			}
+			return "Completed task: " + task.Title, nil
+		}
+
+		priority := cmd.Priority
+		if priority == "" {
+			priority = tasks.PriorityHigh
+		}
+		if err := Commit(ctx); err != nil {
+			return "", err
+		}
+		task, err := t.service.Update(userID, match.ID, tasks.Update{Priority: &priority})
+		if err != nil {
+			return "", err
+		}
+		return "Reprioritized task: " + formatTask(*task), nil
+	}
+
+	return "", fmt.Errorf("unsupported task action %q", cmd.Action)
+}
+
+func formatTask(task tasks.Task) string {
+	parts := []string{task.Title, fmt.Sprintf("[%s]", task.Priority)}
+	if task.DueAt != nil {
+		parts = append(parts, "due "+task.DueAt.Format("Mon Jan 2 15:04"))
+	}
+	for _, tag := range task.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " ")
+}
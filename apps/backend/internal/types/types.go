@@ -3,8 +3,13 @@ package types
 import "time"
 
 type ProcessResponse struct {
-	Input  string        `json:"input"`
-	Result ProcessResult `json:"result"`
+	Input string `json:"input"`
+	// SessionID is the conversation the input was added to; send it with
+	// the next input to continue the conversation.
+	SessionID string `json:"session_id,omitempty"`
+	// InputID is the input log record kept for this input.
+	InputID string        `json:"input_id,omitempty"`
+	Result  ProcessResult `json:"result"`
 }
 
 type ProcessResult struct {
@@ -17,6 +22,11 @@ type ProcessingDetails struct {
 	LLMAnalysis    LLMAnalysisResult `json:"llm_analysis"`
 	ToolExecutions []ToolExecution   `json:"tool_executions"`
 	ProfileUpdate  ProfileUpdate     `json:"profile_update"`
+	Mood           *MoodDetails      `json:"mood,omitempty"`
+	UpcomingEvents []EventSummary    `json:"upcoming_events,omitempty"`
+	Conversation   *ConversationInfo `json:"conversation,omitempty"`
+	Redactions     *RedactionInfo    `json:"redactions,omitempty"`
+	ToolGraph      *ToolGraph        `json:"tool_graph,omitempty"`
 }
 
 type LLMAnalysisResult struct {
@@ -31,6 +41,30 @@ type ToolSelection struct {
 	Reason   string `json:"reason"`
 }
 
+// ConversationInfo describes the context the LLM saw besides the input.
+type ConversationInfo struct {
+	TurnsInContext      int              `json:"turns_in_context"`
+	ProfileSummaryChars int              `json:"profile_summary_chars"`
+	Memories            []RecalledMemory `json:"memories,omitempty"`
+}
+
+// RecalledMemory is an earlier input or item retrieved by similarity to the
+// current input.
+type RecalledMemory struct {
+	Kind  string    `json:"kind"`
+	RefID string    `json:"ref_id"`
+	Text  string    `json:"text"`
+	At    time.Time `json:"at"`
+	Score float64   `json:"score"`
+}
+
+// RedactionInfo counts the personal data replaced with placeholders in the
+// input and context before they were sent to the LLM.
+type RedactionInfo struct {
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"`
+}
+
 type ToolExecution struct {
 	ToolName      string `json:"tool_name"`
 	Input         string `json:"input"`
@@ -40,6 +74,42 @@ type ToolExecution struct {
 	Error         string `json:"error,omitempty"`
 }
 
+// ToolGraph is how the selected tools were ordered by their dependencies.
+// Indexes refer to ToolExecutions.
+type ToolGraph struct {
+	Nodes []ToolNode `json:"nodes"`
+	Edges []ToolEdge `json:"edges"`
+	// Levels lists the tools that ran together, one level after another.
+	Levels [][]int `json:"levels"`
+}
+
+type ToolNode struct {
+	Index    int    `json:"index"`
+	ToolName string `json:"tool_name"`
+	Level    int    `json:"level"`
+	Status   string `json:"status"`
+}
+
+// ToolEdge says the tool at To ran after the one at From. Data lists the
+// kinds of data From produces for To; Passed is set when its output was
+// actually handed over, which only happens when From succeeded.
+type ToolEdge struct {
+	From   int      `json:"from"`
+	To     int      `json:"to"`
+	Data   []string `json:"data,omitempty"`
+	Passed bool     `json:"passed"`
+}
+
+// ToolExecution statuses.
+const (
+	ToolStatusSuccess = "success"
+	ToolStatusError   = "error"
+	ToolStatusSkipped = "skipped"
+	// ToolStatusTimeout means the tool didn't answer within its timeout;
+	// it may still finish in the background, but its output is dropped.
+	ToolStatusTimeout = "timeout"
+)
+
 type ProfileUpdate struct {
 	ChangesMade         string `json:"changes_made"`
 	ProfileLengthBefore int    `json:"profile_length_before"`
@@ -48,6 +118,22 @@ type ProfileUpdate struct {
 	Success             bool   `json:"success"`
 }
 
+type MoodDetails struct {
+	Sentiment    float64  `json:"sentiment"`
+	Energy       float64  `json:"energy"`
+	Emotions     []string `json:"emotions"`
+	Trend        string   `json:"trend"`
+	TrendSummary string   `json:"trend_summary,omitempty"`
+}
+
+// EventSummary is an upcoming calendar event considered while processing.
+type EventSummary struct {
+	Summary  string    `json:"summary"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Location string    `json:"location,omitempty"`
+}
+
 type ProcessMetadata struct {
 	TotalProcessingTime string    `json:"total_processing_time"`
 	Timestamp           time.Time `json:"timestamp"`
@@ -72,4 +158,30 @@ type StatusResponse struct {
 	Environment  string `json:"environment"`
 	ToolsCount   int    `json:"tools_count"`
 	Version      string `json:"version"`
-}
\ No newline at end of file
+}
+type StructuredProfile struct {
+	UserID  string        `json:"user_id"`
+	Summary string        `json:"summary"`
+	Goals   []GoalSummary `json:"goals"`
+}
+
+type GoalSummary struct {
+	ID             string         `json:"id"`
+	Title          string         `json:"title"`
+	Motivation     string         `json:"motivation,omitempty"`
+	Status         string         `json:"status"`
+	Completion     float64        `json:"completion"`
+	MilestonesDone int            `json:"milestones_done"`
+	MilestonesLeft []string       `json:"milestones_left"`
+	CheckInStreak  int            `json:"check_in_streak"`
+	Habits         []HabitSummary `json:"habits"`
+}
+
+type HabitSummary struct {
+	Title         string `json:"title"`
+	Frequency     string `json:"frequency"`
+	CurrentStreak int    `json:"current_streak"`
+}
+
+// DefaultUserID is used when a request does not identify a user.
+const DefaultUserID = "default"
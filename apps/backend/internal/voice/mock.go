@@ -0,0 +1,31 @@
+package voice
+
+import "log"
+
+const defaultMockTranscript = "This is a mock transcript of a voice note."
+
+// MockTranscriber returns a fixed transcript without looking at the audio,
+// for tests and for running the voice flow without a whisper install.
+type MockTranscriber struct {
+	Transcript string
+	Err        error
+}
+
+func NewMock(transcript string) *MockTranscriber {
+	if transcript == "" {
+		transcript = defaultMockTranscript
+	}
+	return &MockTranscriber{Transcript: transcript}
+}
+
+func (m *MockTranscriber) Name() string {
+	return BackendMock
+}
+
+func (m *MockTranscriber) Transcribe(path string, format Format) (string, error) {
+	log.Printf("MockTranscriber: Transcribing %s (%s)", path, format)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.Transcript, nil
+}
@@ -0,0 +1,63 @@
+package voice
+
+import (
+	"fmt"
+	"time"
+)
+
+// Transcriber turns a recording on disk into text.
+type Transcriber interface {
+	Transcribe(path string, format Format) (string, error)
+	Name() string
+}
+
+// DefaultTranscriptionTimeout bounds a single transcription.
+const DefaultTranscriptionTimeout = 2 * time.Minute
+
+// Backends accepted by TranscriberConfig.Backend.
+const (
+	BackendNone        = ""
+	BackendWhisperHTTP = "whisper-http"
+	BackendWhisperCLI  = "whisper-cli"
+	BackendMock        = "mock"
+)
+
+type TranscriberConfig struct {
+	Backend string
+	// URL is the whisper.cpp server inference endpoint, e.g.
+	// http://127.0.0.1:8081/inference.
+	URL string
+	// Binary and Model locate whisper.cpp's whisper-cli and a ggml model.
+	Binary string
+	Model  string
+	// FFmpeg converts non-wav recordings for the CLI backend.
+	FFmpeg   string
+	Language string
+	Timeout  time.Duration
+}
+
+// NewTranscriber builds the configured backend. It returns nil when
+// transcription is disabled.
+func NewTranscriber(config TranscriberConfig) (Transcriber, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTranscriptionTimeout
+	}
+
+	switch config.Backend {
+	case BackendNone:
+		return nil, nil
+	case BackendWhisperHTTP:
+		if config.URL == "" {
+			return nil, fmt.Errorf("whisper-http transcriber needs WHISPER_URL")
+		}
+		return NewWhisperHTTP(config.URL, config.Language, config.Timeout), nil
+	case BackendWhisperCLI:
+		if config.Model == "" {
+			return nil, fmt.Errorf("whisper-cli transcriber needs WHISPER_MODEL")
+		}
+		return NewWhisperCLI(config.Binary, config.Model, config.FFmpeg, config.Language, config.Timeout), nil
+	case BackendMock:
+		return NewMock(""), nil
+	}
+	return nil, fmt.Errorf("unknown transcriber %q", config.Backend)
+}
@@ -0,0 +1,461 @@
+package voice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "voice_notes"
+
+// MaxAudioBytes caps a single upload (about 25 minutes of opus, or three of
+// 16-bit wav).
+const MaxAudioBytes = 25 << 20
+
+var (
+	ErrNotFound = errors.New("voice note not found")
+	ErrInvalid  = errors.New("invalid voice note")
+	// ErrUnavailable is returned by Transcribe when no transcriber is configured.
+	ErrUnavailable = errors.New("transcription unavailable")
+	// ErrTranscription wraps failures of the transcription backend itself.
+	ErrTranscription = errors.New("transcription failed")
+)
+
+// Format is the container of an uploaded recording, detected from its bytes.
+type Format string
+
+const (
+	FormatOgg  Format = "ogg"
+	FormatOpus Format = "opus"
+	FormatM4A  Format = "m4a"
+	FormatWAV  Format = "wav"
+	FormatWebM Format = "webm"
+	FormatMP3  Format = "mp3"
+)
+
+var contentTypes = map[Format]string{
+	FormatOgg:  "audio/ogg",
+	FormatOpus: "audio/ogg",
+	FormatM4A:  "audio/mp4",
+	FormatWAV:  "audio/wav",
+	FormatWebM: "audio/webm",
+	FormatMP3:  "audio/mpeg",
+}
+
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// Note is one uploaded recording. AudioRef points at the original audio,
+// relative to the voice directory, so the transcript can always be traced
+// back to what was said.
+type Note struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	Filename      string     `json:"filename,omitempty"`
+	Format        Format     `json:"format"`
+	Size          int64      `json:"size"`
+	AudioRef      string     `json:"audio_ref"`
+	Transcript    string     `json:"transcript,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	TranscribedAt *time.Time `json:"transcribed_at,omitempty"`
+}
+
+type VoiceService interface {
+	// Save stores an uploaded recording, rejecting anything that isn't a
+	// supported audio format.
+	Save(userID, filename string, audio io.Reader) (*Note, error)
+	// Transcribe runs the configured transcriber over a saved note and
+	// records the transcript (or the failure) on it.
+	Transcribe(userID, id string) (*Note, error)
+	// CanTranscribe reports whether a transcriber is configured.
+	CanTranscribe() bool
+	Get(userID, id string) (*Note, error)
+	List(userID string) ([]Note, error)
+	// Audio returns the original recording.
+	Audio(userID, id string) ([]byte, *Note, error)
+	Delete(userID, id string) error
+	// Purge deletes all of the user's voice notes and their recordings,
+	// returning how many notes there were.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+	// Reseal encrypts the user's recordings again with their current data
+	// key.
+	Reseal(userID string) error
+}
+
+type service struct {
+	store       storage.Store
+	dir         string
+	transcriber Transcriber
+	sealer      storage.BlobSealer
+	users       map[string][]Note
+	mutex       sync.RWMutex
+}
+
+// NewService keeps recordings under dir. transcriber may be nil, in which
+// case notes can still be uploaded and listed but not transcribed. With a
+// sealer, recordings are encrypted with the user's data key, including
+// any stored before encryption was enabled.
+func NewService(store storage.Store, dir string, transcriber Transcriber, sealer storage.BlobSealer) (VoiceService, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create voice dir: %w", err)
+	}
+	s := &service{
+		store:       store,
+		dir:         dir,
+		transcriber: transcriber,
+		sealer:      sealer,
+		users:       make(map[string][]Note),
+	}
+	if err := store.Load(storeName, &s.users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load voice notes: %w", err)
+	}
+	if sealer != nil {
+		sealed := 0
+		for userID, notes := range s.users {
+			for _, note := range notes {
+				done, err := s.sealFile(userID, note, false)
+				if err != nil {
+					return nil, err
+				}
+				if done {
+					sealed++
+				}
+			}
+		}
+		if sealed > 0 {
+			log.Printf("VoiceService: Encrypted %d recordings stored before encryption was enabled", sealed)
+		}
+	}
+	log.Printf("VoiceService: Loaded voice notes for %d users", len(s.users))
+	return s, nil
+}
+
+// audioName binds a sealed recording to its note.
+func audioName(note Note) string {
+	return "voice:" + note.ID
+}
+
+// writeAudio stores a recording, sealed when there is a sealer.
+func (s *service) writeAudio(userID string, note Note, data []byte) error {
+	if s.sealer != nil {
+		var err error
+		if data, err = s.sealer.SealBlob(userID, audioName(note), data); err != nil {
+			return err
+		}
+	}
+	// Written aside and renamed, so a failed reseal never loses the
+	// recording
+	path := filepath.Join(s.dir, note.AudioRef)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// readAudio returns a recording, opened if it was sealed.
+func (s *service) readAudio(userID string, note Note) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, note.AudioRef))
+	if err != nil {
+		return nil, fmt.Errorf("read audio: %w", err)
+	}
+	if s.sealer == nil {
+		if storage.IsSealedBlob(data) {
+			return nil, fmt.Errorf("read audio: recording %s is encrypted and no encryption key is configured", note.ID)
+		}
+		return data, nil
+	}
+	return s.sealer.OpenBlob(userID, audioName(note), data)
+}
+
+// sealFile seals a recording with the user's current data key. Unless
+// force is set, recordings that are already sealed are left alone. It
+// reports whether the file was rewritten.
+func (s *service) sealFile(userID string, note Note, force bool) (bool, error) {
+	path := filepath.Join(s.dir, note.AudioRef)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read audio for %s: %w", note.ID, err)
+	}
+	if storage.IsSealedBlob(data) && !force {
+		return false, nil
+	}
+	if data, err = s.sealer.OpenBlob(userID, audioName(note), data); err != nil {
+		return false, err
+	}
+	if err := s.writeAudio(userID, note, data); err != nil {
+		return false, fmt.Errorf("encrypt audio for %s: %w", note.ID, err)
+	}
+	return true, nil
+}
+
+func (s *service) persist() error {
+	return s.store.Save(storeName, s.users)
+}
+
+func (s *service) Save(userID, filename string, audio io.Reader) (*Note, error) {
+	data, err := io.ReadAll(io.LimitReader(audio, MaxAudioBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read audio: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty upload", ErrInvalid)
+	}
+	if len(data) > MaxAudioBytes {
+		return nil, fmt.Errorf("%w: recording is larger than %d MB", ErrInvalid, MaxAudioBytes>>20)
+	}
+	format, ok := DetectFormat(data)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported audio format (use ogg/opus, m4a, wav, webm or mp3)", ErrInvalid)
+	}
+
+	note := Note{
+		ID:        storage.NewID(),
+		UserID:    userID,
+		Filename:  filepath.Base(filename),
+		Format:    format,
+		Size:      int64(len(data)),
+		CreatedAt: time.Now(),
+	}
+	if note.Filename == "." || note.Filename == "/" {
+		note.Filename = ""
+	}
+	// Files are named by note ID only, so user IDs never reach the filesystem
+	note.AudioRef = note.ID + "." + string(format)
+	if err := s.writeAudio(userID, note, data); err != nil {
+		return nil, fmt.Errorf("write audio: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.users[userID] = append(s.users[userID], note)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("VoiceService: Saved %s voice note %s for user %s (%d bytes)", format, note.ID, userID, note.Size)
+	return &note, nil
+}
+
+func (s *service) Transcribe(userID, id string) (*Note, error) {
+	note, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.transcriber == nil {
+		return nil, ErrUnavailable
+	}
+	path := filepath.Join(s.dir, note.AudioRef)
+	if s.sealer != nil {
+		// Transcribers read a file, so they get a short-lived plaintext copy
+		data, err := s.readAudio(userID, *note)
+		if err != nil {
+			return nil, err
+		}
+		tmp, err := os.CreateTemp(s.dir, "transcribe-*."+string(note.Format))
+		if err != nil {
+			return nil, fmt.Errorf("write audio: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("write audio: %w", err)
+		}
+		path = tmp.Name()
+	}
+
+	start := time.Now()
+	transcript, transcribeErr := s.transcriber.Transcribe(path, note.Format)
+	transcript = strings.TrimSpace(transcript)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := findNote(s.users[userID], id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	stored := &s.users[userID][idx]
+	if transcribeErr != nil {
+		stored.Error = transcribeErr.Error()
+	} else {
+		now := time.Now()
+		stored.Transcript = transcript
+		stored.Error = ""
+		stored.TranscribedAt = &now
+	}
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	if transcribeErr != nil {
+		log.Printf("VoiceService: Transcription of %s failed: %v", id, transcribeErr)
+		return nil, fmt.Errorf("%w: %v", ErrTranscription, transcribeErr)
+	}
+
+	log.Printf("VoiceService: Transcribed %s in %s (%d chars)", id, time.Since(start), len(transcript))
+	result := *stored
+	return &result, nil
+}
+
+func (s *service) CanTranscribe() bool {
+	return s.transcriber != nil
+}
+
+func (s *service) Get(userID, id string) (*Note, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	idx := findNote(s.users[userID], id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	note := s.users[userID][idx]
+	return &note, nil
+}
+
+func (s *service) List(userID string) ([]Note, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := append(make([]Note, 0, len(s.users[userID])), s.users[userID]...)
+	// Newest first
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (s *service) Audio(userID, id string) ([]byte, *Note, error) {
+	note, err := s.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.readAudio(userID, *note)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, note, nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	notes := s.users[userID]
+	idx := findNote(notes, id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+	ref := notes[idx].AudioRef
+	s.users[userID] = append(notes[:idx], notes[idx+1:]...)
+	if err := s.persist(); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, ref)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("VoiceService: Failed to remove audio for %s: %v", id, err)
+	}
+
+	log.Printf("VoiceService: Deleted voice note %s for user %s", id, userID)
+	return nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	notes := s.users[userID]
+	if len(notes) == 0 {
+		return 0, nil
+	}
+	// Recordings go first: if one can't be removed the notes are kept, so
+	// a retry still knows where it is
+	for _, note := range notes {
+		if err := os.Remove(filepath.Join(s.dir, note.AudioRef)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("remove audio for %s: %w", note.ID, err)
+		}
+	}
+	delete(s.users, userID)
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("VoiceService: Purged %d voice notes for user %s", len(notes), userID)
+	return len(notes), nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.users[userID]), nil
+}
+
+func (s *service) Reseal(userID string) error {
+	if s.sealer == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, note := range s.users[userID] {
+		if _, err := s.sealFile(userID, note, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findNote(notes []Note, id string) int {
+	for i, note := range notes {
+		if note.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// DetectFormat sniffs the container from the first bytes of a recording.
+// Client-supplied content types are unreliable (browsers label opus as
+// video/webm, phones send application/octet-stream), so they're ignored.
+func DetectFormat(data []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		head := data[:min(len(data), 128)]
+		if bytes.Contains(head, []byte("OpusHead")) {
+			return FormatOpus, true
+		}
+		return FormatOgg, true
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WAVE":
+		return FormatWAV, true
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return FormatM4A, true
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return FormatWebM, true
+	case bytes.HasPrefix(data, []byte("ID3")), len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		return FormatMP3, true
+	}
+	return "", false
+}
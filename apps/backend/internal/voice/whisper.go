@@ -0,0 +1,158 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// whisperHTTP talks to whisper.cpp's example server (or anything exposing
+// the same /inference form API). The server must be started with --convert
+// to accept formats other than 16 kHz wav.
+type whisperHTTP struct {
+	url      string
+	language string
+	client   *http.Client
+}
+
+func NewWhisperHTTP(url, language string, timeout time.Duration) Transcriber {
+	return &whisperHTTP{
+		url:      url,
+		language: language,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *whisperHTTP) Name() string {
+	return BackendWhisperHTTP
+}
+
+func (w *whisperHTTP) Transcribe(path string, format Format) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", err
+	}
+	form.WriteField("response_format", "json")
+	form.WriteField("temperature", "0.0")
+	if w.language != "" {
+		form.WriteField("language", w.language)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("whisper server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("whisper server: %w", err)
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", fmt.Errorf("whisper server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", fmt.Errorf("whisper server returned %d: %s", resp.StatusCode, result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// whisperCLI shells out to whisper.cpp's whisper-cli. It only reads wav, so
+// other formats are converted to 16 kHz mono with ffmpeg first.
+type whisperCLI struct {
+	binary   string
+	model    string
+	ffmpeg   string
+	language string
+	timeout  time.Duration
+}
+
+func NewWhisperCLI(binary, model, ffmpeg, language string, timeout time.Duration) Transcriber {
+	if binary == "" {
+		binary = "whisper-cli"
+	}
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	return &whisperCLI{
+		binary:   binary,
+		model:    model,
+		ffmpeg:   ffmpeg,
+		language: language,
+		timeout:  timeout,
+	}
+}
+
+func (w *whisperCLI) Name() string {
+	return BackendWhisperCLI
+}
+
+func (w *whisperCLI) Transcribe(path string, format Format) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	input := path
+	if format != FormatWAV {
+		converted, err := os.CreateTemp("", "voice-*.wav")
+		if err != nil {
+			return "", err
+		}
+		converted.Close()
+		defer os.Remove(converted.Name())
+
+		convert := exec.CommandContext(ctx, w.ffmpeg, "-nostdin", "-loglevel", "error", "-y",
+			"-i", path, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", converted.Name())
+		if output, err := convert.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("convert %s to wav: %w: %s", format, err, strings.TrimSpace(string(output)))
+		}
+		input = converted.Name()
+	}
+
+	args := []string{"-m", w.model, "-f", input, "--no-timestamps", "--no-prints"}
+	if w.language != "" {
+		args = append(args, "-l", w.language)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, w.binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("whisper-cli: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Fields(stdout.String())
+	return strings.Join(lines, " "), nil
+}
@@ -0,0 +1,229 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inbound"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/netutil"
+)
+
+// Headers on every outbound delivery. They use the same signing scheme as
+// the inbound webhook: the signature is "sha256=" + hex HMAC-SHA256 of
+// timestamp + "." + nonce + "." + body, keyed with the endpoint secret.
+// The nonce is the delivery ID, which stays the same across retries.
+const (
+	HeaderEvent    = "X-Soul-Mirror-Event"
+	HeaderDelivery = "X-Soul-Mirror-Delivery"
+)
+
+type Config struct {
+	// Interval is how often due retries are checked; new deliveries are
+	// sent right away.
+	Interval time.Duration
+	Timeout  time.Duration
+	// MaxAttempts is how many times a delivery is tried before it fails.
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles per attempt
+	// up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// AllowPrivate permits endpoints on loopback and private networks.
+	// Leave it off in production so endpoints can't reach internal services.
+	AllowPrivate bool
+}
+
+// DefaultConfig retries for roughly a day before giving up.
+var DefaultConfig = Config{
+	Interval:    10 * time.Second,
+	Timeout:     10 * time.Second,
+	MaxAttempts: 8,
+	Backoff:     30 * time.Second,
+	MaxBackoff:  6 * time.Hour,
+}
+
+// Dispatcher sends queued deliveries in the background.
+type Dispatcher struct {
+	service WebhookService
+	config  Config
+	client  *http.Client
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func NewDispatcher(service WebhookService, config Config) *Dispatcher {
+	if config.Interval <= 0 {
+		config.Interval = DefaultConfig.Interval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultConfig.Backoff
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = max(DefaultConfig.MaxBackoff, config.Backoff)
+	}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivate {
+		dialer.Control = netutil.DenyPrivate
+	}
+	return &Dispatcher{
+		service: service,
+		config:  config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: http.ProxyFromEnvironment},
+			// A redirect could point anywhere; treat it as a failed delivery
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	go d.run()
+	log.Printf("WebhookDispatcher: Started (retry check every %s, %d attempts max)", d.config.Interval, d.config.MaxAttempts)
+}
+
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+		<-d.done
+		log.Printf("WebhookDispatcher: Stopped")
+	})
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	d.RunOnce(time.Now())
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		case <-d.service.Queued():
+		}
+		d.RunOnce(time.Now())
+	}
+}
+
+// RunOnce attempts every due delivery.
+func (d *Dispatcher) RunOnce(now time.Time) {
+	jobs, err := d.service.Due(now)
+	if err != nil {
+		log.Printf("WebhookDispatcher: Failed to load due deliveries: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+		d.attempt(job)
+	}
+}
+
+func (d *Dispatcher) attempt(job Job) {
+	delivery := job.Delivery
+	start := time.Now()
+	statusCode, err := d.send(job)
+	attempt := Attempt{
+		At:         start,
+		StatusCode: statusCode,
+		Duration:   time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	var next *time.Time
+	succeeded := err == nil
+	attempts := len(delivery.Attempts) + 1
+	switch {
+	case succeeded:
+		log.Printf("WebhookDispatcher: Delivered %s %s to %s (status %d)", delivery.Event, delivery.ID, job.Endpoint.URL, statusCode)
+	case attempts >= d.config.MaxAttempts:
+		log.Printf("WebhookDispatcher: Giving up on %s %s after %d attempts: %v", delivery.Event, delivery.ID, attempts, err)
+	default:
+		retryAt := start.Add(d.backoff(attempts))
+		next = &retryAt
+		log.Printf("WebhookDispatcher: Attempt %d for %s %s failed, retrying at %s: %v", attempts, delivery.Event, delivery.ID, retryAt.Format(time.RFC3339), err)
+	}
+
+	if err := d.service.RecordAttempt(delivery.ID, attempt, succeeded, next); err != nil {
+		log.Printf("WebhookDispatcher: Failed to record attempt for %s: %v", delivery.ID, err)
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.Backoff
+	for i := 1; i < attempts && wait < d.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.config.MaxBackoff)
+}
+
+func (d *Dispatcher) send(job Job) (int, error) {
+	if !job.Endpoint.Active {
+		return 0, errors.New("endpoint is inactive")
+	}
+
+	// Stored payloads may have been re-indented on disk; sign exactly what
+	// is sent
+	var body bytes.Buffer
+	if err := json.Compact(&body, job.Delivery.Payload); err != nil {
+		return 0, fmt.Errorf("encode payload: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Endpoint.URL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SoulMirror-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, job.Delivery.Event)
+	req.Header.Set(HeaderDelivery, job.Delivery.ID)
+	req.Header.Set(inbound.HeaderTimestamp, timestamp)
+	req.Header.Set(inbound.HeaderNonce, job.Delivery.ID)
+	req.Header.Set(inbound.HeaderSignature, inbound.Sign(job.Endpoint.Secret, timestamp, job.Delivery.ID, body.Bytes()))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
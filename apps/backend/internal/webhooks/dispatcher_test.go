@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/inbound"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+// receiver records what an endpoint was sent and answers with the next
+// status in statuses, then 200.
+type receiver struct {
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	mutex    sync.Mutex
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newDispatcher(t *testing.T, handler http.Handler, config Config) (WebhookService, *Dispatcher, *Endpoint) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := NewService(storage.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := service.Register("alice", Endpoint{URL: server.URL, Events: []string{EventTaskCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AllowPrivate = true
+	return service, NewDispatcher(service, config), endpoint
+}
+
+func deliveries(t *testing.T, service WebhookService, endpointID string) []Delivery {
+	t.Helper()
+	list, err := service.Deliveries("alice", endpointID, DeliveryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	receiver := &receiver{}
+	service, dispatcher, endpoint := newDispatcher(t, receiver, Config{})
+	if err := service.Publish("alice", EventTaskCreated, map[string]string{"title": "Call the bank"}); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.RunOnce(time.Now())
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("endpoint got %d requests, want 1", len(receiver.requests))
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	delivery := deliveries(t, service, endpoint.ID)[0]
+
+	headers := map[string]string{
+		"Content-Type":          "application/json",
+		HeaderEvent:             EventTaskCreated,
+		HeaderDelivery:          delivery.ID,
+		inbound.HeaderNonce:     delivery.ID,
+		inbound.HeaderSignature: inbound.Sign(endpoint.Secret, req.Header.Get(inbound.HeaderTimestamp), delivery.ID, body),
+	}
+	for name, want := range headers {
+		if got := req.Header.Get(name); got != want {
+			t.Fatalf("header %s = %q, want %q", name, got, want)
+		}
+	}
+	if delivery.Status != DeliverySucceeded || len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusOK {
+		t.Fatalf("delivery = %+v, want succeeded on the first attempt", delivery)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	config := Config{MaxAttempts: 3, Backoff: 30 * time.Second, MaxBackoff: time.Minute}
+
+	tests := []struct {
+		name     string
+		statuses []int
+		// wantNext is the wait before each retry, the last entry for the
+		// final state: zero once the delivery is finished
+		wantNext   []time.Duration
+		wantStatus DeliveryStatus
+	}{
+		{"succeeds on a retry", []int{http.StatusInternalServerError}, []time.Duration{30 * time.Second, 0}, DeliverySucceeded},
+		{"redirect counts as a failure", []int{http.StatusFound}, []time.Duration{30 * time.Second, 0}, DeliverySucceeded},
+		{"gives up after max attempts", []int{500, 502, 503}, []time.Duration{30 * time.Second, time.Minute, 0}, DeliveryFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &receiver{statuses: tt.statuses}
+			service, dispatcher, endpoint := newDispatcher(t, receiver, config)
+			if err := service.Publish("alice", EventTaskCreated, map[string]string{"title": "Call the bank"}); err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now()
+			for i, wantNext := range tt.wantNext {
+				before := time.Now()
+				dispatcher.RunOnce(now)
+				delivery := deliveries(t, service, endpoint.ID)[0]
+				if len(delivery.Attempts) != i+1 {
+					t.Fatalf("after run %d: %d attempts", i+1, len(delivery.Attempts))
+				}
+				if wantNext == 0 {
+					if delivery.Status != tt.wantStatus || delivery.NextAttemptAt != nil {
+						t.Fatalf("after run %d: %s, next %v, want %s", i+1, delivery.Status, delivery.NextAttemptAt, tt.wantStatus)
+					}
+					break
+				}
+				if delivery.Status != DeliveryPending || delivery.NextAttemptAt == nil ||
+					delivery.NextAttemptAt.Before(before.Add(wantNext)) || delivery.NextAttemptAt.After(time.Now().Add(wantNext)) {
+					t.Fatalf("after run %d: %s, next %v, want a retry in %s", i+1, delivery.Status, delivery.NextAttemptAt, wantNext)
+				}
+
+				// Not due until the backoff has passed
+				dispatcher.RunOnce(now)
+				if attempts := len(deliveries(t, service, endpoint.ID)[0].Attempts); attempts != i+1 {
+					t.Fatalf("retried before the backoff passed: %d attempts", attempts)
+				}
+				now = delivery.NextAttemptAt.Add(time.Second)
+			}
+
+			// Every attempt carries the same delivery ID as its nonce
+			for _, req := range receiver.requests {
+				if req.Header.Get(inbound.HeaderNonce) != receiver.requests[0].Header.Get(inbound.HeaderNonce) {
+					t.Fatal("retry used a different nonce")
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, Config{Backoff: 30 * time.Second, MaxBackoff: 2 * time.Minute})
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		6: 2 * time.Minute,
+	} {
+		if got := dispatcher.backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"log"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/goals"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/notes"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/profile"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/reminders"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/scheduler"
+	"github.com/kirillsobolev/soul-mirror/backend/internal/tasks"
+)
+
+// The Observe* wrappers publish events for changes made through a domain
+// service, whichever caller (tool, API handler) made them.
+
+func publish(publisher Publisher, userID, event string, data any) {
+	if err := publisher.Publish(userID, event, data); err != nil {
+		log.Printf("Webhooks: Failed to publish %s for user %s: %v", event, userID, err)
+	}
+}
+
+type observedTasks struct {
+	tasks.TaskService
+	publisher Publisher
+}
+
+func ObserveTasks(service tasks.TaskService, publisher Publisher) tasks.TaskService {
+	return &observedTasks{TaskService: service, publisher: publisher}
+}
+
+func (o *observedTasks) Create(userID string, task tasks.Task) (*tasks.Task, error) {
+	created, err := o.TaskService.Create(userID, task)
+	if err == nil {
+		publish(o.publisher, userID, EventTaskCreated, created)
+	}
+	return created, err
+}
+
+func (o *observedTasks) Complete(userID, id string) (*tasks.Task, error) {
+	completed, err := o.TaskService.Complete(userID, id)
+	if err == nil {
+		publish(o.publisher, userID, EventTaskCompleted, completed)
+	}
+	return completed, err
+}
+
+type observedNotes struct {
+	notes.NoteService
+	publisher Publisher
+}
+
+func ObserveNotes(service notes.NoteService, publisher Publisher) notes.NoteService {
+	return &observedNotes{NoteService: service, publisher: publisher}
+}
+
+func (o *observedNotes) Create(userID string, note notes.Note) (*notes.Note, error) {
+	created, err := o.NoteService.Create(userID, note)
+	if err == nil {
+		publish(o.publisher, userID, EventNoteCreated, created)
+	}
+	return created, err
+}
+
+type observedGoals struct {
+	goals.GoalService
+	publisher Publisher
+}
+
+func ObserveGoals(service goals.GoalService, publisher Publisher) goals.GoalService {
+	return &observedGoals{GoalService: service, publisher: publisher}
+}
+
+func (o *observedGoals) Create(userID string, goal goals.Goal) (*goals.Goal, error) {
+	created, err := o.GoalService.Create(userID, goal)
+	if err == nil {
+		publish(o.publisher, userID, EventGoalCreated, created)
+	}
+	return created, err
+}
+
+type observedReminders struct {
+	reminders.ReminderService
+	publisher Publisher
+}
+
+func ObserveReminders(service reminders.ReminderService, publisher Publisher) reminders.ReminderService {
+	return &observedReminders{ReminderService: service, publisher: publisher}
+}
+
+func (o *observedReminders) Create(userID string, reminder reminders.Reminder) (*reminders.Reminder, error) {
+	created, err := o.ReminderService.Create(userID, reminder)
+	if err == nil {
+		publish(o.publisher, userID, EventReminderCreated, created)
+	}
+	return created, err
+}
+
+type observedProfile struct {
+	profile.ProfileService
+	publisher Publisher
+}
+
+func ObserveProfile(service profile.ProfileService, publisher Publisher) profile.ProfileService {
+	return &observedProfile{ProfileService: service, publisher: publisher}
+}
+
+func (o *observedProfile) ProcessInput(userID, input string) error {
+	if err := o.ProfileService.ProcessInput(userID, input); err != nil {
+		return err
+	}
+	o.publishUpdate(userID)
+	return nil
+}
+
+func (o *observedProfile) Rewrite(userID, previous, content string, event profile.Event) (*profile.Event, error) {
+	recorded, err := o.ProfileService.Rewrite(userID, previous, content, event)
+	if err == nil {
+		o.publishUpdate(userID)
+	}
+	return recorded, err
+}
+
+func (o *observedProfile) publishUpdate(userID string) {
+	updated, err := o.ProfileService.Get(userID)
+	if err != nil {
+		log.Printf("Webhooks: Failed to read updated profile for user %s: %v", userID, err)
+		return
+	}
+	publish(o.publisher, userID, EventProfileUpdated, map[string]string{"profile": updated})
+}
+
+// NudgeDeliverer delivers nudges as nudge.fired events to users who
+// subscribed to them.
+type NudgeDeliverer struct {
+	service WebhookService
+}
+
+func NewNudgeDeliverer(service WebhookService) *NudgeDeliverer {
+	return &NudgeDeliverer{service: service}
+}
+
+func (d *NudgeDeliverer) Name() string {
+	return "webhook"
+}
+
+func (d *NudgeDeliverer) Deliver(nudge scheduler.Nudge) error {
+	if !d.service.Subscribed(nudge.UserID, EventNudgeFired) {
+		return scheduler.ErrNoRoute
+	}
+	return d.service.Publish(nudge.UserID, EventNudgeFired, nudge)
+}
@@ -0,0 +1,713 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+const storeName = "webhooks"
+
+// Event types users can subscribe to. EventAll subscribes to every type.
+const (
+	EventAll             = "*"
+	EventPing            = "ping"
+	EventProfileUpdated  = "profile.updated"
+	EventTaskCreated     = "task.created"
+	EventTaskCompleted   = "task.completed"
+	EventNoteCreated     = "note.created"
+	EventGoalCreated     = "goal.created"
+	EventReminderCreated = "reminder.created"
+	EventNudgeFired      = "nudge.fired"
+)
+
+// EventTypes lists the subscribable event types.
+var EventTypes = []string{
+	EventProfileUpdated,
+	EventTaskCreated,
+	EventTaskCompleted,
+	EventNoteCreated,
+	EventGoalCreated,
+	EventReminderCreated,
+	EventNudgeFired,
+}
+
+const (
+	// deliveryRetention is how long finished deliveries stay in the log.
+	deliveryRetention = 7 * 24 * time.Hour
+	// maxDeliveriesPerEndpoint caps the log kept for one endpoint.
+	maxDeliveriesPerEndpoint = 200
+)
+
+var (
+	ErrNotFound = errors.New("webhook not found")
+	ErrInvalid  = errors.New("invalid webhook")
+)
+
+// Endpoint is a URL a user registered to receive events.
+type Endpoint struct {
+	ID          string   `json:"id"`
+	UserID      string   `json:"user_id"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description,omitempty"`
+	// Secret signs every payload sent to the endpoint. It is only shown
+	// when the endpoint is registered.
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Redacted returns the endpoint without its secret.
+func (e Endpoint) Redacted() Endpoint {
+	e.Secret = ""
+	return e
+}
+
+func (e Endpoint) subscribes(event string) bool {
+	if event == EventPing {
+		return true
+	}
+	for _, subscribed := range e.Events {
+		if subscribed == EventAll || subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Attempt is one try at delivering an event.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+}
+
+// Delivery is one event queued for one endpoint.
+type Delivery struct {
+	ID            string          `json:"id"`
+	EndpointID    string          `json:"endpoint_id"`
+	UserID        string          `json:"user_id"`
+	EventID       string          `json:"event_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        DeliveryStatus  `json:"status"`
+	Attempts      []Attempt       `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+}
+
+// Envelope is the JSON body posted to endpoints.
+type Envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type EndpointUpdate struct {
+	URL         *string   `json:"url"`
+	Events      *[]string `json:"events"`
+	Description *string   `json:"description"`
+	Active      *bool     `json:"active"`
+}
+
+type DeliveryFilter struct {
+	Status DeliveryStatus
+	Limit  int
+}
+
+// Publisher is what the rest of the app uses to emit events.
+type Publisher interface {
+	Publish(userID, event string, data any) error
+}
+
+type fanout []Publisher
+
+// Fanout publishes every event to each of publishers in turn, returning
+// the first error.
+func Fanout(publishers ...Publisher) Publisher {
+	return fanout(publishers)
+}
+
+func (f fanout) Publish(userID, event string, data any) error {
+	var first error
+	for _, publisher := range f {
+		if err := publisher.Publish(userID, event, data); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type WebhookService interface {
+	Publisher
+	Register(userID string, endpoint Endpoint) (*Endpoint, error)
+	Get(userID, id string) (*Endpoint, error)
+	List(userID string) ([]Endpoint, error)
+	Update(userID, id string, update EndpointUpdate) (*Endpoint, error)
+	Delete(userID, id string) error
+	// Ping queues a test event for one endpoint.
+	Ping(userID, id string) (*Delivery, error)
+	Deliveries(userID, endpointID string, filter DeliveryFilter) ([]Delivery, error)
+	// Subscribed reports whether any active endpoint of the user wants event.
+	Subscribed(userID, event string) bool
+
+	// Due returns pending deliveries whose next attempt time has passed,
+	// with the endpoints they go to.
+	Due(now time.Time) ([]Job, error)
+	// RecordAttempt logs an attempt. A nil next marks the delivery
+	// finished: succeeded if the attempt did, failed otherwise.
+	RecordAttempt(deliveryID string, attempt Attempt, succeeded bool, next *time.Time) error
+	// Queued signals when new deliveries are queued.
+	Queued() <-chan struct{}
+
+	// Purge deletes the user's endpoints and every delivery queued or
+	// logged for them, returning how many there were together.
+	Purge(userID string) (int, error)
+	Count(userID string) (int, error)
+}
+
+// Job is a due delivery with the endpoint it goes to.
+type Job struct {
+	Delivery Delivery
+	Endpoint Endpoint
+}
+
+type webhookState struct {
+	Endpoints  map[string][]Endpoint `json:"endpoints"`
+	Deliveries []Delivery            `json:"deliveries"`
+}
+
+// userWebhooks is one user's part of the stored document, which is keyed
+// by user so it can be encrypted: payloads carry profile text and note
+// bodies, and endpoints carry their signing secrets.
+type userWebhooks struct {
+	Endpoints  []Endpoint `json:"endpoints"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+type service struct {
+	store storage.Store
+	state webhookState
+	// wake nudges the dispatcher when a delivery is queued
+	wake  chan struct{}
+	mutex sync.Mutex
+}
+
+func NewService(store storage.Store) (WebhookService, error) {
+	s := &service{
+		store: store,
+		state: webhookState{Endpoints: make(map[string][]Endpoint)},
+		wake:  make(chan struct{}, 1),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	log.Printf("WebhookService: Loaded endpoints for %d users, %d deliveries", len(s.state.Endpoints), len(s.state.Deliveries))
+	return s, nil
+}
+
+// load reads the per-user document, or the legacy one holding every
+// user's endpoints and deliveries together, which is saved per user on
+// the next change.
+func (s *service) load() error {
+	var raw map[string]json.RawMessage
+	if err := s.store.Load(storeName, &raw); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("load webhooks: %w", err)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("load webhooks: %w", err)
+	}
+	if _, legacy := raw["endpoints"]; legacy && len(raw) == 2 && raw["deliveries"] != nil {
+		if err := json.Unmarshal(data, &s.state); err != nil {
+			return fmt.Errorf("decode webhooks: %w", err)
+		}
+		if s.state.Endpoints == nil {
+			s.state.Endpoints = make(map[string][]Endpoint)
+		}
+		log.Printf("WebhookService: Moving endpoints and deliveries to the per-user layout")
+		return nil
+	}
+
+	var users map[string]*userWebhooks
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("decode webhooks: %w", err)
+	}
+	for userID, user := range users {
+		if len(user.Endpoints) > 0 {
+			s.state.Endpoints[userID] = user.Endpoints
+		}
+		s.state.Deliveries = append(s.state.Deliveries, user.Deliveries...)
+	}
+	// prune keeps the newest deliveries, so restore the queue order
+	sort.SliceStable(s.state.Deliveries, func(i, j int) bool {
+		return s.state.Deliveries[i].CreatedAt.Before(s.state.Deliveries[j].CreatedAt)
+	})
+	return nil
+}
+
+func (s *service) persist() error {
+	users := make(map[string]*userWebhooks)
+	user := func(userID string) *userWebhooks {
+		if users[userID] == nil {
+			users[userID] = &userWebhooks{Endpoints: []Endpoint{}, Deliveries: []Delivery{}}
+		}
+		return users[userID]
+	}
+	for userID, endpoints := range s.state.Endpoints {
+		user(userID).Endpoints = endpoints
+	}
+	for _, delivery := range s.state.Deliveries {
+		u := user(delivery.UserID)
+		u.Deliveries = append(u.Deliveries, delivery)
+	}
+	return s.store.Save(storeName, users)
+}
+
+func newSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
+
+func validateURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalid)
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("%w: url must not contain credentials", ErrInvalid)
+	}
+	return parsed.String(), nil
+}
+
+func validateEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required", ErrInvalid)
+	}
+	seen := make(map[string]bool)
+	var result []string
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if seen[event] {
+			continue
+		}
+		if event != EventAll && !containsString(EventTypes, event) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalid, event)
+		}
+		seen[event] = true
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+func (s *service) Register(userID string, endpoint Endpoint) (*Endpoint, error) {
+	endpointURL, err := validateURL(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateEvents(endpoint.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	endpoint.ID = storage.NewID()
+	endpoint.UserID = userID
+	endpoint.URL = endpointURL
+	endpoint.Events = events
+	endpoint.Description = strings.TrimSpace(endpoint.Description)
+	endpoint.Secret = secret
+	endpoint.Active = true
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+
+	s.state.Endpoints[userID] = append(s.state.Endpoints[userID], endpoint)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("WebhookService: Registered endpoint %s for user %s (events: %v)", endpoint.ID, userID, events)
+	return &endpoint, nil
+}
+
+func (s *service) find(userID, id string) int {
+	for i, endpoint := range s.state.Endpoints[userID] {
+		if endpoint.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *service) Get(userID, id string) (*Endpoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.find(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	endpoint := s.state.Endpoints[userID][idx].Redacted()
+	return &endpoint, nil
+}
+
+func (s *service) List(userID string) ([]Endpoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := make([]Endpoint, 0, len(s.state.Endpoints[userID]))
+	for _, endpoint := range s.state.Endpoints[userID] {
+		result = append(result, endpoint.Redacted())
+	}
+	return result, nil
+}
+
+func (s *service) Update(userID, id string, update EndpointUpdate) (*Endpoint, error) {
+	var endpointURL string
+	var events []string
+	var err error
+	if update.URL != nil {
+		if endpointURL, err = validateURL(*update.URL); err != nil {
+			return nil, err
+		}
+	}
+	if update.Events != nil {
+		if events, err = validateEvents(*update.Events); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.find(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	endpoint := &s.state.Endpoints[userID][idx]
+	if update.URL != nil {
+		endpoint.URL = endpointURL
+	}
+	if update.Events != nil {
+		endpoint.Events = events
+	}
+	if update.Description != nil {
+		endpoint.Description = strings.TrimSpace(*update.Description)
+	}
+	if update.Active != nil {
+		endpoint.Active = *update.Active
+	}
+	endpoint.UpdatedAt = time.Now()
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("WebhookService: Updated endpoint %s for user %s", id, userID)
+	result := endpoint.Redacted()
+	return &result, nil
+}
+
+func (s *service) Delete(userID, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.find(userID, id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+	endpoints := s.state.Endpoints[userID]
+	s.state.Endpoints[userID] = append(endpoints[:idx], endpoints[idx+1:]...)
+
+	kept := s.state.Deliveries[:0]
+	for _, delivery := range s.state.Deliveries {
+		if delivery.EndpointID != id {
+			kept = append(kept, delivery)
+		}
+	}
+	s.state.Deliveries = kept
+	if err := s.persist(); err != nil {
+		return err
+	}
+
+	log.Printf("WebhookService: Deleted endpoint %s for user %s", id, userID)
+	return nil
+}
+
+func (s *service) Purge(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.state.Endpoints[userID])
+	delete(s.state.Endpoints, userID)
+	kept := s.state.Deliveries[:0]
+	for _, delivery := range s.state.Deliveries {
+		if delivery.UserID != userID {
+			kept = append(kept, delivery)
+		} else {
+			count++
+		}
+	}
+	s.state.Deliveries = kept
+	if count == 0 {
+		return 0, nil
+	}
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+
+	log.Printf("WebhookService: Purged %d endpoints and deliveries for user %s", count, userID)
+	return count, nil
+}
+
+func (s *service) Count(userID string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := len(s.state.Endpoints[userID])
+	for _, delivery := range s.state.Deliveries {
+		if delivery.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *service) Subscribed(userID, event string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, endpoint := range s.state.Endpoints[userID] {
+		if endpoint.Active && endpoint.subscribes(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish queues event for every active endpoint of the user subscribed
+// to it.
+func (s *service) Publish(userID, event string, data any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var targets []Endpoint
+	for _, endpoint := range s.state.Endpoints[userID] {
+		if endpoint.Active && endpoint.subscribes(event) {
+			targets = append(targets, endpoint)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if _, err := s.enqueue(userID, event, data, targets); err != nil {
+		return err
+	}
+	log.Printf("WebhookService: Queued %s for user %s to %d endpoints", event, userID, len(targets))
+	return nil
+}
+
+func (s *service) Ping(userID, id string) (*Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idx := s.find(userID, id)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+	deliveries, err := s.enqueue(userID, EventPing, map[string]string{"message": "Webhook test from Soul Mirror"}, []Endpoint{s.state.Endpoints[userID][idx]})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// enqueue adds one delivery per target. Callers hold the mutex.
+func (s *service) enqueue(userID, event string, data any, targets []Endpoint) ([]Delivery, error) {
+	now := time.Now()
+	envelope := Envelope{
+		ID:        storage.NewID(),
+		Type:      event,
+		UserID:    userID,
+		CreatedAt: now,
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s event: %w", event, err)
+	}
+
+	deliveries := make([]Delivery, 0, len(targets))
+	for _, endpoint := range targets {
+		delivery := Delivery{
+			ID:            storage.NewID(),
+			EndpointID:    endpoint.ID,
+			UserID:        userID,
+			EventID:       envelope.ID,
+			Event:         event,
+			Payload:       payload,
+			Status:        DeliveryPending,
+			Attempts:      []Attempt{},
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	s.state.Deliveries = append(s.state.Deliveries, deliveries...)
+	s.prune(now)
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return deliveries, nil
+}
+
+// prune drops old finished deliveries and trims each endpoint's log.
+// Callers hold the mutex.
+func (s *service) prune(now time.Time) {
+	perEndpoint := make(map[string]int)
+	kept := make([]Delivery, 0, len(s.state.Deliveries))
+	// Walk newest first so the most recent entries survive the cap
+	for i := len(s.state.Deliveries) - 1; i >= 0; i-- {
+		delivery := s.state.Deliveries[i]
+		if delivery.Status != DeliveryPending {
+			if delivery.CompletedAt != nil && now.Sub(*delivery.CompletedAt) > deliveryRetention {
+				continue
+			}
+			if perEndpoint[delivery.EndpointID] >= maxDeliveriesPerEndpoint {
+				continue
+			}
+		}
+		perEndpoint[delivery.EndpointID]++
+		kept = append(kept, delivery)
+	}
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	s.state.Deliveries = kept
+}
+
+func (s *service) Deliveries(userID, endpointID string, filter DeliveryFilter) ([]Delivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.find(userID, endpointID) == -1 {
+		return nil, ErrNotFound
+	}
+	result := make([]Delivery, 0)
+	for _, delivery := range s.state.Deliveries {
+		if delivery.EndpointID != endpointID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		result = append(result, delivery)
+	}
+	// Newest first
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (s *service) Due(now time.Time) ([]Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var jobs []Job
+	for _, delivery := range s.state.Deliveries {
+		if delivery.Status != DeliveryPending || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		idx := s.find(delivery.UserID, delivery.EndpointID)
+		if idx == -1 {
+			continue
+		}
+		jobs = append(jobs, Job{Delivery: delivery, Endpoint: s.state.Endpoints[delivery.UserID][idx]})
+	}
+	return jobs, nil
+}
+
+func (s *service) RecordAttempt(deliveryID string, attempt Attempt, succeeded bool, next *time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.state.Deliveries {
+		delivery := &s.state.Deliveries[i]
+		if delivery.ID != deliveryID {
+			continue
+		}
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		delivery.NextAttemptAt = next
+		switch {
+		case succeeded:
+			delivery.Status = DeliverySucceeded
+		case next == nil:
+			delivery.Status = DeliveryFailed
+		}
+		if delivery.Status != DeliveryPending {
+			completedAt := attempt.At
+			delivery.CompletedAt = &completedAt
+			delivery.NextAttemptAt = nil
+		}
+		return s.persist()
+	}
+	// The endpoint was deleted while the attempt was in flight
+	return nil
+}
+
+func (s *service) Queued() <-chan struct{} {
+	return s.wake
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirillsobolev/soul-mirror/backend/internal/storage"
+)
+
+func newEncryptedFileStore(t *testing.T) (storage.Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	inner, err := storage.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master := make([]byte, 32)
+	if _, err := rand.Read(master); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := storage.NewKeyring(inner, base64.StdEncoding.EncodeToString(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.NewEncryptedStore(inner, keyring, storeName), dir
+}
+
+func TestStoredDeliveriesAreEncrypted(t *testing.T) {
+	store, dir := newEncryptedFileStore(t)
+	service, err := NewService(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := service.Register("alice", Endpoint{URL: "https://example.com/hook", Events: []string{EventAll}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := "Anxious about the custody hearing on Thursday"
+	if err := service.Publish("alice", EventProfileUpdated, map[string]string{"profile": profile}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, storeName+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"custody hearing", endpoint.Secret, "example.com"} {
+		if strings.Contains(string(data), plain) {
+			t.Fatalf("stored webhooks contain %q in plaintext", plain)
+		}
+	}
+
+	restarted, err := NewService(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := restarted.Due(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || !strings.Contains(string(jobs[0].Delivery.Payload), profile) || jobs[0].Endpoint.Secret != endpoint.Secret {
+		t.Fatalf("Due after restart = %+v, want the queued delivery", jobs)
+	}
+}
+
+func TestLoadLegacyState(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := time.Now()
+	legacy := webhookState{
+		Endpoints: map[string][]Endpoint{
+			"alice": {{ID: "e1", UserID: "alice", URL: "https://example.com/a", Events: []string{EventAll}, Active: true}},
+			"bob":   {{ID: "e2", UserID: "bob", URL: "https://example.com/b", Events: []string{EventAll}, Active: true}},
+		},
+		Deliveries: []Delivery{
+			{ID: "d1", EndpointID: "e1", UserID: "alice", Event: EventPing, Status: DeliveryPending, NextAttemptAt: &now, CreatedAt: now},
+			{ID: "d2", EndpointID: "e2", UserID: "bob", Event: EventPing, Status: DeliveryPending, NextAttemptAt: &now, CreatedAt: now.Add(time.Second)},
+		},
+	}
+	if err := store.Save(storeName, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewService(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Any change saves the per-user layout
+	if _, err := service.Update("bob", "e2", EndpointUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var users map[string]*userWebhooks
+	if err := store.Load(storeName, &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || len(users["alice"].Deliveries) != 1 || len(users["bob"].Endpoints) != 1 {
+		t.Fatalf("stored layout = %+v, want one part per user", users)
+	}
+	for _, userID := range []string{"alice", "bob"} {
+		if count, _ := service.Count(userID); count != 2 {
+			t.Fatalf("%s has %d endpoints and deliveries, want 2", userID, count)
+		}
+	}
+}